@@ -1,18 +1,205 @@
 package empire
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/pkg/timex"
 )
 
-// AccessToken represents a token that allow access to the api.
+// Scope limits what an AccessToken can be used for.
+type Scope string
+
+const (
+	// ScopeRead allows the token to be used for read only operations,
+	// like listing apps, releases and processes.
+	ScopeRead Scope = "read"
+
+	// ScopeDeploy allows the token to be used to deploy, in addition to
+	// read only operations.
+	ScopeDeploy Scope = "deploy"
+
+	// ScopeFull allows the token to be used for anything the user can do.
+	ScopeFull Scope = "full"
+)
+
+var (
+	ErrInvalidScope = &ValidationError{errors.New("Scope must be one of read, deploy or full.")}
+)
+
+// Scopes represents the list of scopes granted to an AccessToken.
+type Scopes []Scope
+
+// ParseScopes parses and validates a list of scopes. If no scopes are
+// provided, the result is full access.
+func ParseScopes(s []string) (Scopes, error) {
+	if len(s) == 0 {
+		return Scopes{ScopeFull}, nil
+	}
+
+	var scopes Scopes
+	for _, scope := range s {
+		switch Scope(scope) {
+		case ScopeRead, ScopeDeploy, ScopeFull:
+			scopes = append(scopes, Scope(scope))
+		default:
+			return nil, ErrInvalidScope
+		}
+	}
+	return scopes, nil
+}
+
+// Allows returns true if the scopes allow the given action to be performed.
+// Nil Scopes allow everything, since users that didn't authenticate with an
+// access token are unrestricted.
+func (s Scopes) Allows(action Action) bool {
+	if s == nil {
+		return true
+	}
+
+	for _, scope := range s {
+		switch scope {
+		case ScopeFull:
+			return true
+		case ScopeDeploy:
+			if action == ActionDeploy {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Grants returns true if the scopes include the given scope. The full scope
+// includes every other scope, and the deploy scope includes the read scope.
+// Nil Scopes grant everything.
+func (s Scopes) Grants(scope Scope) bool {
+	if s == nil {
+		return true
+	}
+
+	for _, granted := range s {
+		switch granted {
+		case ScopeFull:
+			return true
+		case ScopeDeploy:
+			if scope == ScopeDeploy || scope == ScopeRead {
+				return true
+			}
+		case ScopeRead:
+			if scope == ScopeRead {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Full returns true if the scopes allow full access.
+func (s Scopes) Full() bool {
+	if s == nil {
+		return true
+	}
+
+	for _, scope := range s {
+		if scope == ScopeFull {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Strings returns the scopes as a slice of strings.
+func (s Scopes) Strings() []string {
+	var strs []string
+	for _, scope := range s {
+		strs = append(strs, string(scope))
+	}
+	return strs
+}
+
+// Scan implements the sql.Scanner interface.
+func (s *Scopes) Scan(src interface{}) error {
+	if src == nil {
+		*s = nil
+		return nil
+	}
+
+	bytes, ok := src.([]byte)
+	if !ok {
+		return error(errors.New("Scan source was not []bytes"))
+	}
+
+	var scopes Scopes
+	if err := json.Unmarshal(bytes, &scopes); err != nil {
+		return err
+	}
+	*s = scopes
+
+	return nil
+}
+
+// Value implements the driver.Value interface.
+func (s Scopes) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return driver.Value(raw), nil
+}
+
+// AccessToken represents a token that allow access to the api. The token
+// itself is a signed JWT, and a record of it is stored in the database so
+// that it can be revoked.
 type AccessToken struct {
+	// A unique uuid that identifies the token. This is the "jti" claim in
+	// the JWT.
+	ID string
+
 	// The encoded token.
-	Token string
+	Token string `sql:"-"`
 
 	// The user that this AccessToken belongs to.
-	User *User
+	User *User `sql:"-"`
+
+	// The name of the user that this AccessToken belongs to.
+	UserName string
+
+	// A human friendly description of what the token is used for.
+	Description string
+
+	// What the token can be used for.
+	Scopes Scopes
+
+	// If provided, the time after which the token can no longer be used.
+	ExpiresAt *time.Time
+
+	// The last time that the token was used to authenticate.
+	LastUsedAt *time.Time
+
+	// When the token was revoked.
+	RevokedAt *time.Time
+
+	CreatedAt *time.Time
+}
+
+// BeforeCreate sets created_at before inserting.
+func (t *AccessToken) BeforeCreate() error {
+	now := timex.Now()
+	t.CreatedAt = &now
+	return nil
 }
 
 // IsValid returns nil if the AccessToken is valid.
@@ -24,13 +211,82 @@ func (t *AccessToken) IsValid() error {
 	return nil
 }
 
+// Active returns true if the token has not been revoked and hasn't expired.
+func (t *AccessToken) Active() bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+
+	if t.ExpiresAt != nil && !timex.Now().Before(*t.ExpiresAt) {
+		return false
+	}
+
+	return true
+}
+
+// AccessTokensQuery is a scope implementation for common things to filter
+// access tokens by.
+type AccessTokensQuery struct {
+	// If provided, finds the token with the given ID.
+	ID *string
+
+	// If provided, finds tokens belonging to the given user.
+	User *User
+}
+
+// scope implements the scope interface.
+func (q AccessTokensQuery) scope(db *gorm.DB) *gorm.DB {
+	var scope composedScope
+
+	if q.ID != nil {
+		scope = append(scope, idEquals(*q.ID))
+	}
+
+	if q.User != nil {
+		scope = append(scope, fieldEquals("user_name", q.User.Name))
+	}
+
+	scope = append(scope, order("created_at desc"))
+
+	return scope.scope(db)
+}
+
+// RevokeAccessTokenOpts are options provided when revoking an access token.
+type RevokeAccessTokenOpts struct {
+	// User performing the action.
+	User *User
+
+	// The ID of the token to revoke.
+	ID string
+}
+
 type accessTokensService struct {
 	*Empire
 }
 
 // AccessTokensCreate "creates" the token by jwt signing it and setting the
-// Token value.
+// Token value. A record of the token is inserted so that it can later be
+// revoked.
 func (s *accessTokensService) AccessTokensCreate(token *AccessToken) (*AccessToken, error) {
+	if err := token.IsValid(); err != nil {
+		return token, err
+	}
+
+	// A scoped token can't be used to create another token, otherwise
+	// it could be used to escalate it's own access.
+	if !token.User.Scopes.Full() {
+		return token, errScopedTokens(token.User)
+	}
+
+	if token.Scopes == nil {
+		token.Scopes = Scopes{ScopeFull}
+	}
+	token.UserName = token.User.Name
+
+	if err := accessTokensCreate(s.db, token); err != nil {
+		return token, err
+	}
+
 	signed, err := signToken(s.Secret, token)
 	if err != nil {
 		return token, err
@@ -38,7 +294,7 @@ func (s *accessTokensService) AccessTokensCreate(token *AccessToken) (*AccessTok
 
 	token.Token = signed
 
-	return token, token.IsValid()
+	return token, nil
 }
 
 func (s *accessTokensService) AccessTokensFind(token string) (*AccessToken, error) {
@@ -52,11 +308,101 @@ func (s *accessTokensService) AccessTokensFind(token string) (*AccessToken, erro
 		}
 	}
 
-	if at != nil {
+	if at == nil {
+		return nil, nil
+	}
+
+	// Tokens that were issued before tokens were stored don't have an id,
+	// so there's no record of them. They're still accepted with full
+	// access, but can only be invalidated by rotating the secret.
+	if at.ID == "" {
 		at.Token = token
+		at.Scopes = Scopes{ScopeFull}
+		at.User.Scopes = at.Scopes
+		return at, at.IsValid()
+	}
+
+	t, err := accessTokensFind(s.db, AccessTokensQuery{ID: &at.ID})
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !t.Active() || t.UserName != at.User.Name {
+		return nil, nil
+	}
+
+	if err := accessTokensTouch(s.db, t); err != nil {
+		return nil, err
+	}
+
+	t.Token = token
+	t.User = at.User
+	t.User.Scopes = t.Scopes
+
+	return t, t.IsValid()
+}
+
+// AccessTokensRevoke revokes the token, so that it can no longer be used.
+func (s *accessTokensService) AccessTokensRevoke(opts RevokeAccessTokenOpts) error {
+	if !opts.User.Scopes.Full() {
+		return errScopedTokens(opts.User)
+	}
+
+	// Users can only revoke their own tokens.
+	t, err := accessTokensFind(s.db, AccessTokensQuery{ID: &opts.ID, User: opts.User})
+	if err != nil {
+		return err
+	}
+
+	now := timex.Now()
+	t.RevokedAt = &now
+	return accessTokensUpdate(s.db, t)
+}
+
+// errScopedTokens returns the error returned when a scoped token is used to
+// manage access tokens.
+func errScopedTokens(user *User) error {
+	return &AccessDeniedError{
+		User:   user.Name,
+		Action: "manage",
+		App:    "access tokens",
+		Reason: fmt.Sprintf("access token is limited to %v", user.Scopes.Strings()),
+	}
+}
+
+// accessTokensFind returns the first matching access token.
+func accessTokensFind(db *gorm.DB, scope scope) (*AccessToken, error) {
+	var token AccessToken
+	return &token, first(db, scope, &token)
+}
+
+// accessTokens returns all access tokens matching the scope.
+func accessTokens(db *gorm.DB, scope scope) ([]*AccessToken, error) {
+	var tokens []*AccessToken
+	return tokens, find(db, scope, &tokens)
+}
+
+func accessTokensCreate(db *gorm.DB, token *AccessToken) error {
+	return db.Create(token).Error
+}
+
+func accessTokensUpdate(db *gorm.DB, token *AccessToken) error {
+	return db.Save(token).Error
+}
+
+// accessTokensTouch updates last_used_at for the token. To avoid a write on
+// every request, it's only updated once per minute.
+func accessTokensTouch(db *gorm.DB, token *AccessToken) error {
+	now := timex.Now()
+	if token.LastUsedAt != nil && now.Sub(*token.LastUsedAt) < time.Minute {
+		return nil
 	}
 
-	return at, at.IsValid()
+	token.LastUsedAt = &now
+	return db.Exec(`UPDATE access_tokens SET last_used_at = ? WHERE id = ?`, now, token.ID).Error
 }
 
 // signToken jwt signs the token and adds the signature to the Token field.
@@ -83,6 +429,10 @@ func parseToken(secret []byte, token string) (*AccessToken, error) {
 
 func accessTokenToJwt(token *AccessToken) *jwt.Token {
 	t := jwt.New(jwt.SigningMethodHS256)
+	t.Claims["jti"] = token.ID
+	if token.ExpiresAt != nil {
+		t.Claims["exp"] = token.ExpiresAt.Unix()
+	}
 	t.Claims["User"] = struct {
 		Name        string
 		GitHubToken string
@@ -98,6 +448,10 @@ func accessTokenToJwt(token *AccessToken) *jwt.Token {
 func jwtToAccessToken(t *jwt.Token) (*AccessToken, error) {
 	var token AccessToken
 
+	if id, ok := t.Claims["jti"].(string); ok {
+		token.ID = id
+	}
+
 	// TODO Should probably return an error here if a user isn't present.
 	if u, ok := t.Claims["User"].(map[string]interface{}); ok {
 		var user User
@@ -3,6 +3,9 @@ package empire
 import (
 	"reflect"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 var testSecret = []byte("secret")
@@ -20,3 +23,105 @@ func TestAccessTokensFind(t *testing.T) {
 		t.Fatal("Expected access token to be nil")
 	}
 }
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		in     []string
+		scopes Scopes
+		err    error
+	}{
+		{nil, Scopes{ScopeFull}, nil},
+		{[]string{"read"}, Scopes{ScopeRead}, nil},
+		{[]string{"read", "deploy"}, Scopes{ScopeRead, ScopeDeploy}, nil},
+		{[]string{"admin"}, nil, ErrInvalidScope},
+	}
+
+	for _, tt := range tests {
+		scopes, err := ParseScopes(tt.in)
+		assert.Equal(t, tt.err, err)
+		assert.Equal(t, tt.scopes, scopes)
+	}
+}
+
+func TestScopes_Allows(t *testing.T) {
+	tests := []struct {
+		scopes Scopes
+		action Action
+		allows bool
+	}{
+		{nil, ActionSet, true},
+		{Scopes{ScopeFull}, ActionSet, true},
+		{Scopes{ScopeRead}, ActionDeploy, false},
+		{Scopes{ScopeRead}, ActionSet, false},
+		{Scopes{ScopeDeploy}, ActionDeploy, true},
+		{Scopes{ScopeDeploy}, ActionSet, false},
+		{Scopes{ScopeRead, ScopeDeploy}, ActionDeploy, true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.allows, tt.scopes.Allows(tt.action), "%v %s", tt.scopes, tt.action)
+	}
+}
+
+func TestScopes_Grants(t *testing.T) {
+	tests := []struct {
+		scopes Scopes
+		scope  Scope
+		grants bool
+	}{
+		{nil, ScopeFull, true},
+		{Scopes{ScopeFull}, ScopeDeploy, true},
+		{Scopes{ScopeRead}, ScopeRead, true},
+		{Scopes{ScopeRead}, ScopeDeploy, false},
+		{Scopes{ScopeDeploy}, ScopeRead, true},
+		{Scopes{ScopeDeploy}, ScopeDeploy, true},
+		{Scopes{ScopeDeploy}, ScopeFull, false},
+		{Scopes{ScopeRead, ScopeDeploy}, ScopeDeploy, true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.grants, tt.scopes.Grants(tt.scope), "%v %s", tt.scopes, tt.scope)
+	}
+}
+
+func TestAccessTokensFind_Legacy(t *testing.T) {
+	s := &accessTokensService{Empire: &Empire{Secret: testSecret}}
+
+	// Tokens issued by older versions of Empire don't have a jti claim.
+	signed, err := signToken(testSecret, &AccessToken{
+		User: &User{Name: "ejholmes", GitHubToken: "abcd"},
+	})
+	assert.NoError(t, err)
+
+	at, err := s.AccessTokensFind(signed)
+	assert.NoError(t, err)
+	if assert.NotNil(t, at) {
+		assert.Equal(t, "", at.ID)
+		assert.Equal(t, "ejholmes", at.User.Name)
+		assert.Equal(t, Scopes{ScopeFull}, at.User.Scopes)
+	}
+}
+
+func TestAccessTokenJwt(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	token := &AccessToken{
+		ID:        "a7ed7a8c-4b3d-4b1e-8d2a-3f6c1d2e9b10",
+		User:      &User{Name: "ejholmes", GitHubToken: "abcd"},
+		ExpiresAt: &expiresAt,
+	}
+
+	signed, err := signToken(testSecret, token)
+	assert.NoError(t, err)
+
+	at, err := parseToken(testSecret, signed)
+	assert.NoError(t, err)
+	assert.Equal(t, token.ID, at.ID)
+	assert.Equal(t, token.User, at.User)
+
+	expiresAt = time.Now().Add(-time.Hour)
+	signed, err = signToken(testSecret, token)
+	assert.NoError(t, err)
+
+	_, err = parseToken(testSecret, signed)
+	assert.Error(t, err)
+}
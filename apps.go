@@ -115,6 +115,43 @@ func (s *appsService) Destroy(ctx context.Context, db *gorm.DB, app *App) error
 	return s.Scheduler.Remove(ctx, app.ID)
 }
 
+// Rename changes the name of the app, then submits the latest release to the
+// scheduler so that anything derived from the app name (e.g. DNS records)
+// follows the new name.
+func (s *appsService) Rename(ctx context.Context, db *gorm.DB, opts RenameOpts) error {
+	app := opts.App
+
+	existing, err := appsFind(db, AppsQuery{Name: &opts.Name})
+	if err != nil && err != gorm.RecordNotFound {
+		return err
+	}
+	if err == nil && existing.ID != app.ID {
+		return ErrNameTaken
+	}
+
+	// Permissions are granted by app name, so they need to follow the app.
+	if err := permissionsRenameApp(db, app.Name, opts.Name); err != nil {
+		return err
+	}
+
+	app.Name = opts.Name
+	if err := appsUpdate(db, app); err != nil {
+		return err
+	}
+
+	release, err := releasesFind(db, ReleasesQuery{App: app})
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			return nil
+		}
+
+		return err
+	}
+
+	release.App = app
+	return s.releases.Release(ctx, release, nil)
+}
+
 func (s *appsService) Restart(ctx context.Context, db *gorm.DB, opts RestartOpts) error {
 	if opts.PID != "" {
 		return s.Scheduler.Stop(ctx, opts.PID)
@@ -145,6 +182,12 @@ func (s *appsService) Scale(ctx context.Context, db *gorm.DB, opts ScaleOpts) ([
 			return nil, &ValidationError{Err: fmt.Errorf("no %s process type in release", t)}
 		}
 
+		// Autoscaled processes can only be scaled within their
+		// bounds.
+		if a := p.Autoscaling; a != nil && a.Clamp(q) != q {
+			return nil, &ValidationError{Err: fmt.Errorf("%s is autoscaled, and can only be scaled between %d and %d", t, a.Min, a.Max)}
+		}
+
 		eventUpdate := event.Updates[i]
 		eventUpdate.PreviousQuantity = p.Quantity
 		eventUpdate.PreviousConstraints = p.Constraints()
@@ -164,6 +207,10 @@ func (s *appsService) Scale(ctx context.Context, db *gorm.DB, opts ScaleOpts) ([
 		return nil, err
 	}
 
+	if err := recordEvent(db, opts.User, app, event); err != nil {
+		return nil, err
+	}
+
 	err = s.releases.Release(ctx, release, nil)
 	if err != nil {
 		return ps, err
@@ -172,6 +219,55 @@ func (s *appsService) Scale(ctx context.Context, db *gorm.DB, opts ScaleOpts) ([
 	return ps, s.PublishEvent(event)
 }
 
+// Autoscale changes the autoscaling settings for a process, and releases the
+// new formation.
+func (s *appsService) Autoscale(ctx context.Context, db *gorm.DB, opts AutoscaleOpts) (*Process, error) {
+	app := opts.App
+
+	release, err := releasesFind(db, ReleasesQuery{App: app})
+	if err != nil {
+		return nil, err
+	}
+	if release == nil {
+		return nil, &ValidationError{Err: fmt.Errorf("no releases for %s", app.Name)}
+	}
+
+	p, ok := release.Formation[opts.Process]
+	if !ok {
+		return nil, &ValidationError{Err: fmt.Errorf("no %s process type in release", opts.Process)}
+	}
+	if p.NoService {
+		return nil, &ValidationError{Err: fmt.Errorf("%s is not a service, and cannot be autoscaled", opts.Process)}
+	}
+
+	event := opts.Event()
+	event.PreviousQuantity = p.Quantity
+	event.PreviousAutoscaling = p.Autoscaling
+
+	p.Autoscaling = opts.Autoscaling
+	if p.Autoscaling != nil {
+		p.Quantity = p.Autoscaling.Clamp(p.Quantity)
+	}
+	event.Quantity = p.Quantity
+
+	release.Formation[opts.Process] = p
+
+	// Save the new formation.
+	if err := releasesUpdate(db, release); err != nil {
+		return nil, err
+	}
+
+	if err := recordEvent(db, opts.User, app, event); err != nil {
+		return nil, err
+	}
+
+	if err := s.releases.Release(ctx, release, nil); err != nil {
+		return &p, err
+	}
+
+	return &p, s.PublishEvent(event)
+}
+
 // appsEnsureRepo will set the repo if it's not set.
 func appsEnsureRepo(db *gorm.DB, app *App, repo string) error {
 	if app.Repo != nil {
@@ -0,0 +1,156 @@
+package empire
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire/pkg/headerutil"
+	"github.com/remind101/pkg/timex"
+)
+
+// EventRecord is a persisted Event, which makes up Empire's audit log.
+// Records are written in the same transaction as the action that triggered
+// the event, so the audit log only contains actions that actually happened.
+type EventRecord struct {
+	// A unique uuid that identifies the event.
+	ID string
+
+	// The type of event (e.g. "deploy", "set").
+	Type string
+
+	// The name of the user that triggered the event.
+	User string
+
+	// The id of the app that the event relates to, if any. This is not a
+	// foreign key, so that events for destroyed apps are kept.
+	AppID *string
+
+	// The name of the app at the time of the event.
+	App string
+
+	// A human readable description of the event.
+	Description string
+
+	// The event itself, encoded as JSON.
+	Data string
+
+	// The time that the event happened.
+	CreatedAt *time.Time
+}
+
+// TableName implements the gorm tabler interface.
+func (EventRecord) TableName() string {
+	return "events"
+}
+
+// BeforeCreate sets created_at before inserting.
+func (r *EventRecord) BeforeCreate() error {
+	t := timex.Now()
+	r.CreatedAt = &t
+	return nil
+}
+
+// EventsQuery is a scope implementation for common things to filter events
+// by.
+type EventsQuery struct {
+	// If provided, an app to filter by.
+	App *App
+
+	// If provided, the name of a user to filter by.
+	User *string
+
+	// If provided, an event type to filter by.
+	Type *string
+
+	// If provided, only events that happened at or after this time are
+	// returned.
+	Since *time.Time
+
+	// If provided, only events that happened before this time are returned.
+	Until *time.Time
+
+	// If provided, uses the limit and sorting parameters specified in the range.
+	Range headerutil.Range
+}
+
+// scope implements the scope interface.
+func (q EventsQuery) scope(db *gorm.DB) *gorm.DB {
+	var scope composedScope
+
+	if app := q.App; app != nil {
+		scope = append(scope, forApp(app))
+	}
+
+	if q.User != nil {
+		scope = append(scope, fieldEquals(`"user"`, *q.User))
+	}
+
+	if q.Type != nil {
+		scope = append(scope, fieldEquals(`"type"`, *q.Type))
+	}
+
+	if q.Since != nil {
+		scope = append(scope, scopeFunc(func(db *gorm.DB) *gorm.DB {
+			return db.Where("created_at >= ?", *q.Since)
+		}))
+	}
+
+	if q.Until != nil {
+		scope = append(scope, scopeFunc(func(db *gorm.DB) *gorm.DB {
+			return db.Where("created_at < ?", *q.Until)
+		}))
+	}
+
+	scope = append(scope, inRange(q.Range.WithDefaults(q.DefaultRange())))
+
+	return scope.scope(db)
+}
+
+// DefaultRange returns the default headerutil.Range used if values aren't
+// provided.
+func (q EventsQuery) DefaultRange() headerutil.Range {
+	sort, order, max := "created_at", "desc", 100
+	return headerutil.Range{
+		Sort:  &sort,
+		Order: &order,
+		Max:   &max,
+	}
+}
+
+// recordEvent persists the event to the audit log. db should be the
+// transaction that the action is being performed in.
+func recordEvent(db *gorm.DB, user *User, app *App, event Event) error {
+	raw, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	r := &EventRecord{
+		Type:        event.Event(),
+		Description: event.String(),
+		Data:        string(raw),
+	}
+
+	if user != nil {
+		r.User = user.Name
+	}
+
+	if app != nil {
+		r.AppID = &app.ID
+		r.App = app.Name
+	}
+
+	return eventRecordsCreate(db, r)
+}
+
+// eventRecords returns all events matching the scope.
+func eventRecords(db *gorm.DB, scope scope) ([]*EventRecord, error) {
+	var events []*EventRecord
+	return events, find(db, scope, &events)
+}
+
+// eventRecordsCreate inserts the event into the database.
+func eventRecordsCreate(db *gorm.DB, r *EventRecord) error {
+	return db.Create(r).Error
+}
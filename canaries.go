@@ -0,0 +1,690 @@
+package empire
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire/scheduler"
+	"github.com/remind101/pkg/reporter"
+	"github.com/remind101/pkg/timex"
+	"golang.org/x/net/context"
+)
+
+// DefaultCanaryInterval is the default amount of time to wait between checking
+// on running canaries.
+const DefaultCanaryInterval = 30 * time.Second
+
+// Defaults for canary deployments, used when they aren't provided.
+var (
+	// DefaultCanarySteps are the percentages of requests that are sent to
+	// a canary, in order.
+	DefaultCanarySteps = []int{5, 25, 100}
+
+	// DefaultCanaryPause is the amount of time to wait between steps.
+	DefaultCanaryPause = 5 * time.Minute
+
+	// DefaultCanaryThreshold is the percentage of requests to a canary that
+	// can fail with a 5xx response before it's aborted.
+	DefaultCanaryThreshold = 1.0
+)
+
+// Canary statuses.
+const (
+	CanaryRunning  = "running"
+	CanaryPromoted = "promoted"
+	CanaryAborted  = "aborted"
+)
+
+// Canary is a new release of an app that runs alongside the stable release,
+// and receives a share of the requests to the app, which is shifted towards
+// the canary in steps. After the last step, the canary is promoted. If too
+// many requests to the canary fail, it's aborted, and the app is rolled back
+// to the stable release.
+type Canary struct {
+	// A unique uuid that identifies the canary.
+	ID string
+
+	// The app that the canary belongs to.
+	AppID string
+	App   *App
+
+	// The version of the release that's running as a canary.
+	Version int
+
+	// The version of the release that receives the rest of the requests.
+	StableVersion int
+
+	// The percentage of requests that are sent to the canary at each step.
+	Steps CanarySteps
+
+	// The index of the current step.
+	Step int
+
+	// The amount of time to wait before moving on to the next step.
+	Pause time.Duration
+
+	// The percentage of requests to the canary that can fail with a 5xx
+	// response before it's aborted.
+	Threshold float64
+
+	// One of running, promoted or aborted.
+	Status string
+
+	// If the canary was aborted automatically, the reason why.
+	Reason *string
+
+	// The name of the user that deployed the canary.
+	UserName string
+
+	// The time that the current step started.
+	StepStartedAt *time.Time
+
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+}
+
+// BeforeCreate sets created_at, updated_at and step_started_at before
+// inserting.
+func (c *Canary) BeforeCreate() error {
+	t := timex.Now()
+	c.CreatedAt = &t
+	c.UpdatedAt = &t
+	c.StepStartedAt = &t
+	return nil
+}
+
+// Weight returns the percentage of requests that are currently sent to the
+// canary.
+func (c *Canary) Weight() int {
+	return c.Steps[c.Step]
+}
+
+// CanarySteps represents the percentages of requests that are sent to a canary,
+// in order.
+type CanarySteps []int
+
+// Scan implements the sql.Scanner interface.
+func (s *CanarySteps) Scan(src interface{}) error {
+	bytes, ok := src.([]byte)
+	if !ok {
+		return error(errors.New("Scan source was not []bytes"))
+	}
+
+	var steps CanarySteps
+	if err := json.Unmarshal(bytes, &steps); err != nil {
+		return err
+	}
+	*s = steps
+
+	return nil
+}
+
+// Value implements the driver.Value interface.
+func (s CanarySteps) Value() (driver.Value, error) {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return driver.Value(raw), nil
+}
+
+// CanariesQuery is a scope implementation for common things to filter canaries
+// by.
+type CanariesQuery struct {
+	// If provided, finds the canary with the given ID.
+	ID *string
+
+	// If provided, finds canaries for the given app.
+	App *App
+
+	// If provided, finds canaries with the given status.
+	Status *string
+}
+
+// scope implements the scope interface.
+func (q CanariesQuery) scope(db *gorm.DB) *gorm.DB {
+	var scope composedScope
+
+	if q.ID != nil {
+		scope = append(scope, idEquals(*q.ID))
+	}
+
+	if q.App != nil {
+		scope = append(scope, forApp(q.App))
+	}
+
+	if q.Status != nil {
+		scope = append(scope, fieldEquals("status", *q.Status))
+	}
+
+	scope = append(scope, order("created_at desc"))
+
+	return scope.scope(db)
+}
+
+// CanaryOpts are options provided when deploying a release as a canary.
+type CanaryOpts struct {
+	// The percentage of requests that are sent to the canary at each step,
+	// in ascending order. The canary is promoted after the last step.
+	Steps []int
+
+	// The amount of time to wait before moving on to the next step.
+	Pause time.Duration
+
+	// The percentage of requests to the canary that can fail with a 5xx
+	// response before it's aborted.
+	Threshold float64
+}
+
+// Validate checks that the steps, pause and threshold are valid.
+func (opts CanaryOpts) Validate() error {
+	if len(opts.Steps) == 0 {
+		return &ValidationError{Err: errors.New("A canary must have at least 1 step.")}
+	}
+
+	for i, step := range opts.Steps {
+		if step < 1 || step > 100 {
+			return &ValidationError{Err: fmt.Errorf("Canary steps must be between 1 and 100, got %d.", step)}
+		}
+		if i > 0 && step <= opts.Steps[i-1] {
+			return &ValidationError{Err: errors.New("Canary steps must be in ascending order.")}
+		}
+	}
+
+	if opts.Pause < 0 {
+		return &ValidationError{Err: errors.New("The pause between canary steps can't be negative.")}
+	}
+
+	if opts.Threshold < 0 || opts.Threshold > 100 {
+		return &ValidationError{Err: errors.New("The canary error threshold must be between 0 and 100.")}
+	}
+
+	return nil
+}
+
+// validateCanary checks that a canary can be deployed to the app.
+func (e *Empire) validateCanary(app *App) error {
+	// Canaries run alongside an existing release, so the app must already
+	// exist.
+	if app.ID == "" {
+		existing, err := appsFind(e.db, AppsQuery{Name: &app.Name})
+		if err != nil {
+			if err == gorm.RecordNotFound {
+				return &ValidationError{Err: fmt.Errorf("%s needs to be deployed before a canary can be deployed to it.", app.Name)}
+			}
+			return err
+		}
+		app = existing
+	}
+
+	if c, err := canariesFind(e.db, runningCanary(app)); err == nil {
+		return errCanaryRunning(c)
+	} else if err != gorm.RecordNotFound {
+		return err
+	}
+
+	return nil
+}
+
+// errCanaryRunning returns the error that's returned when trying to create a
+// new release while a canary is running.
+func errCanaryRunning(c *Canary) error {
+	return &ValidationError{Err: fmt.Errorf("Canary v%d of %s is running. Promote or abort it first.", c.Version, c.App.Name)}
+}
+
+// PromoteCanaryOpts are options provided when promoting the running canary of
+// an app.
+type PromoteCanaryOpts struct {
+	// User performing the action.
+	User *User
+
+	// The app with the running canary.
+	App *App
+
+	// Commit message
+	Message string
+}
+
+func (opts PromoteCanaryOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	return e.authorize(opts.User, ActionDeploy, opts.App)
+}
+
+// AbortCanaryOpts are options provided when aborting the running canary of an
+// app.
+type AbortCanaryOpts struct {
+	// User performing the action.
+	User *User
+
+	// The app with the running canary.
+	App *App
+
+	// Commit message
+	Message string
+}
+
+func (opts AbortCanaryOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	return e.authorize(opts.User, ActionDeploy, opts.App)
+}
+
+// runningCanary returns a scope that finds the running canary for the app.
+func runningCanary(app *App) CanariesQuery {
+	status := CanaryRunning
+	return CanariesQuery{App: app, Status: &status}
+}
+
+// canariesFind returns the first matching canary.
+func canariesFind(db *gorm.DB, scope scope) (*Canary, error) {
+	var canary Canary
+	scope = composedScope{preload("App"), scope}
+	return &canary, first(db, scope, &canary)
+}
+
+// canaries returns all canaries matching the scope.
+func canaries(db *gorm.DB, scope scope) ([]*Canary, error) {
+	var canaries []*Canary
+	scope = composedScope{preload("App"), scope}
+	return canaries, find(db, scope, &canaries)
+}
+
+func canariesCreate(db *gorm.DB, canary *Canary) (*Canary, error) {
+	return canary, db.Create(canary).Error
+}
+
+func canariesUpdate(db *gorm.DB, canary *Canary) error {
+	t := timex.Now()
+	canary.UpdatedAt = &t
+	return db.Save(canary).Error
+}
+
+// canariesLock locks the canary until the transaction ends, and returns it
+// with its current state. If it's no longer running, or it's locked and wait
+// is false, nil is returned.
+func canariesLock(db *gorm.DB, id string, wait bool) (*Canary, error) {
+	query := `SELECT id FROM canaries WHERE id = ? AND status = ? FOR UPDATE`
+	if !wait {
+		query += ` SKIP LOCKED`
+	}
+
+	if err := db.Raw(query, id, CanaryRunning).Row().Scan(&id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return canariesFind(db, CanariesQuery{ID: &id})
+}
+
+// canariesLockRunning locks the running canary for the app until the
+// transaction ends, waiting for any other lock to be released.
+func canariesLockRunning(db *gorm.DB, app *App) (*Canary, error) {
+	c, err := canariesFind(db, runningCanary(app))
+	if err != nil {
+		return c, err
+	}
+
+	c, err = canariesLock(db, c.ID, true)
+	if err == nil && c == nil {
+		// The canary was promoted or aborted while we were waiting
+		// for the lock.
+		err = gorm.RecordNotFound
+	}
+	return c, err
+}
+
+// canariesService runs releases as canaries, shifts requests to them, and
+// promotes or aborts them.
+type canariesService struct {
+	*Empire
+}
+
+// Create creates a canary for the newly created release, which runs alongside
+// the last release that didn't fail.
+func (s *canariesService) Create(db *gorm.DB, r *Release, user *User, opts *CanaryOpts) (*Canary, error) {
+	stable, err := releasesFind(db, composedScope{
+		ReleasesQuery{App: r.App},
+		scopeFunc(func(db *gorm.DB) *gorm.DB {
+			return db.Where("version < ? AND NOT failed", r.Version)
+		}),
+	})
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			return nil, &ValidationError{Err: fmt.Errorf("%s has no previous release to run the canary alongside.", r.App.Name)}
+		}
+		return nil, err
+	}
+
+	c, err := canariesCreate(db, &Canary{
+		AppID:         r.App.ID,
+		Version:       r.Version,
+		StableVersion: stable.Version,
+		Steps:         opts.Steps,
+		Pause:         opts.Pause,
+		Threshold:     opts.Threshold,
+		Status:        CanaryRunning,
+		UserName:      user.Name,
+	})
+	if err != nil {
+		return c, err
+	}
+	c.App = r.App
+
+	return c, nil
+}
+
+// Start submits the canary's release to the scheduler, alongside the stable
+// release. If it fails to start, the canary is aborted. Errors are added to the
+// jsonmessage stream.
+func (s *canariesService) Start(ctx context.Context, r *Release, user *User, w *DeploymentStream, ss scheduler.StatusStream) error {
+	c, err := canariesFind(s.db, runningCanary(r.App))
+	if err != nil {
+		return w.Error(err)
+	}
+
+	if err := w.Status(fmt.Sprintf("Created new release v%d for %s, running as a canary alongside v%d", r.Version, r.App.Name, c.StableVersion)); err != nil {
+		return err
+	}
+
+	if err := s.submitRelease(ctx, c, r, ss); err != nil {
+		if err := s.abortInTransaction(ctx, c, user, "", fmt.Sprintf("failed to start: %v", err), true); err != nil {
+			return w.Error(err)
+		}
+		return w.Error(err)
+	}
+
+	return w.Status(fmt.Sprintf("Sending %d%% of requests to canary v%d of %s", c.Weight(), c.Version, r.App.Name))
+}
+
+// submit submits the stable release to the scheduler, with the canary's
+// release running alongside it at its current weight.
+func (s *canariesService) submit(ctx context.Context, c *Canary, ss scheduler.StatusStream) error {
+	canary, err := releasesFind(s.db, ReleasesQuery{App: c.App, Version: &c.Version})
+	if err != nil {
+		return err
+	}
+
+	return s.submitRelease(ctx, c, canary, ss)
+}
+
+// submitRelease is like submit, but uses the given release for the canary,
+// which may have changes (e.g. to its formation) that aren't committed yet.
+func (s *canariesService) submitRelease(ctx context.Context, c *Canary, canary *Release, ss scheduler.StatusStream) error {
+	cs, ok := s.Scheduler.(scheduler.CanaryScheduler)
+	if !ok {
+		return scheduler.ErrCanaryNotSupported
+	}
+
+	stable, err := releasesFind(s.db, ReleasesQuery{App: c.App, Version: &c.StableVersion})
+	if err != nil {
+		return err
+	}
+
+	a, err := s.releases.schedulerApp(ctx, s.db, stable)
+	if err != nil {
+		return err
+	}
+
+	ca, err := s.releases.schedulerApp(ctx, s.db, canary)
+	if err != nil {
+		return err
+	}
+
+	a.Canary = &scheduler.Canary{
+		App:    ca,
+		Weight: c.Weight(),
+	}
+
+	return cs.SubmitCanary(ctx, a, ss)
+}
+
+// Check aborts the canary if too many requests to it failed during the
+// current step. Otherwise, once the pause is over, it moves on to the next
+// step, or promotes the canary after the last step.
+func (s *canariesService) Check(ctx context.Context, c *Canary) error {
+	cs, ok := s.Scheduler.(scheduler.CanaryScheduler)
+	if !ok {
+		return scheduler.ErrCanaryNotSupported
+	}
+
+	tx := s.db.Begin()
+
+	// Another Empire instance may be checking on the canary, or a user may
+	// be promoting or aborting it.
+	c, err := canariesLock(tx, c.ID, false)
+	if err != nil || c == nil {
+		tx.Rollback()
+		return err
+	}
+
+	user := &User{Name: c.UserName}
+
+	rate, err := cs.CanaryErrorRate(ctx, c.AppID, *c.StepStartedAt)
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("error getting error rate: %v", err)
+	}
+
+	var event *CanaryEvent
+	switch {
+	case rate > c.Threshold:
+		reason := fmt.Sprintf("%.2f%% of requests failed, which is more than the threshold of %.2f%%", rate, c.Threshold)
+		event, err = s.abort(ctx, tx, c, user, "", reason, true)
+	case timex.Now().Before(c.StepStartedAt.Add(c.Pause)):
+		tx.Rollback()
+		return nil
+	case c.Step+1 < len(c.Steps):
+		err = s.step(ctx, tx, c)
+	default:
+		event, err = s.promote(ctx, tx, c, user, "", true)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	if event == nil {
+		return nil
+	}
+
+	return s.PublishEvent(*event)
+}
+
+// step moves the canary on to the next step, and shifts more requests to it.
+func (s *canariesService) step(ctx context.Context, db *gorm.DB, c *Canary) error {
+	t := timex.Now()
+	c.Step++
+	c.StepStartedAt = &t
+	if err := canariesUpdate(db, c); err != nil {
+		return err
+	}
+
+	return s.submit(ctx, c, nil)
+}
+
+// promote releases the canary's release to all of the app's processes.
+func (s *canariesService) promote(ctx context.Context, db *gorm.DB, c *Canary, user *User, message string, automatic bool) (*CanaryEvent, error) {
+	r, err := releasesFind(db, ReleasesQuery{App: c.App, Version: &c.Version})
+	if err != nil {
+		return nil, err
+	}
+
+	c.Status = CanaryPromoted
+	if err := canariesUpdate(db, c); err != nil {
+		return nil, err
+	}
+
+	event := CanaryEvent{
+		User:          user.Name,
+		App:           c.App.Name,
+		Release:       c.Version,
+		StableRelease: c.StableVersion,
+		Promoted:      true,
+		Automatic:     automatic,
+		Message:       message,
+		app:           c.App,
+	}
+
+	if err := recordEvent(db, user, c.App, event); err != nil {
+		return nil, err
+	}
+
+	// The canary isn't running anymore, so the release is submitted
+	// without it.
+	return &event, s.releases.submit(ctx, r, nil)
+}
+
+// abort marks the canary's release as failed, and rolls the app back to the
+// stable release, which removes the canary.
+func (s *canariesService) abort(ctx context.Context, db *gorm.DB, c *Canary, user *User, message, reason string, automatic bool) (*CanaryEvent, error) {
+	r, err := releasesFind(db, ReleasesQuery{App: c.App, Version: &c.Version})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := releasesMarkFailed(db, r); err != nil {
+		return nil, err
+	}
+
+	c.Status = CanaryAborted
+	if reason != "" {
+		c.Reason = &reason
+	}
+	if err := canariesUpdate(db, c); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.releases.Rollback(ctx, db, RollbackOpts{
+		User:    user,
+		App:     c.App,
+		Version: c.StableVersion,
+		Message: fmt.Sprintf("canary v%d was aborted", c.Version),
+	}); err != nil {
+		return nil, err
+	}
+
+	event := CanaryEvent{
+		User:          user.Name,
+		App:           c.App.Name,
+		Release:       c.Version,
+		StableRelease: c.StableVersion,
+		Automatic:     automatic,
+		Reason:        reason,
+		Message:       message,
+		app:           c.App,
+	}
+
+	if err := recordEvent(db, user, c.App, event); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// abortInTransaction aborts the canary in a new transaction, and publishes the
+// event.
+func (s *canariesService) abortInTransaction(ctx context.Context, c *Canary, user *User, message, reason string, automatic bool) error {
+	tx := s.db.Begin()
+
+	c, err := canariesLock(tx, c.ID, true)
+	if err != nil || c == nil {
+		tx.Rollback()
+		return err
+	}
+
+	event, err := s.abort(ctx, tx, c, user, message, reason, automatic)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	return s.PublishEvent(*event)
+}
+
+// CheckCanaries checks on every running canary. Errors checking on a canary are
+// reported, so that they don't stop other canaries from being checked.
+func (e *Empire) CheckCanaries(ctx context.Context) error {
+	status := CanaryRunning
+	cs, err := canaries(e.db, CanariesQuery{Status: &status})
+	if err != nil {
+		return err
+	}
+
+	for _, c := range cs {
+		if err := e.canaries.Check(ctx, c); err != nil {
+			reporter.Report(ctx, fmt.Errorf("error checking canary v%d of %s: %v", c.Version, c.App.Name, err))
+		}
+	}
+
+	return nil
+}
+
+// CanaryWorker periodically checks on running canaries, shifting requests to
+// them in steps, and promoting or aborting them.
+type CanaryWorker struct {
+	// Context used when checking on canaries.
+	Context context.Context
+
+	// How often to check on running canaries. The default is
+	// DefaultCanaryInterval.
+	Interval time.Duration
+
+	empire  *Empire
+	stopped chan struct{}
+}
+
+// NewCanaryWorker returns a new CanaryWorker.
+func NewCanaryWorker(e *Empire) *CanaryWorker {
+	return &CanaryWorker{
+		Context: context.Background(),
+		empire:  e,
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start starts checking on running canaries. It blocks until Stop is called.
+func (w *CanaryWorker) Start() {
+	interval := w.Interval
+	if interval == 0 {
+		interval = DefaultCanaryInterval
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-w.stopped:
+			return
+		case <-t.C:
+			if err := w.empire.CheckCanaries(w.Context); err != nil {
+				reporter.Report(w.Context, err)
+			}
+		}
+	}
+}
+
+// Stop stops the worker.
+func (w *CanaryWorker) Stop() {
+	close(w.stopped)
+}
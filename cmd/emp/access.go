@@ -0,0 +1,128 @@
+package main
+
+import (
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/remind101/empire/pkg/heroku"
+)
+
+var cmdAccess = &Command{
+	Run:         runAccess,
+	Usage:       "access",
+	OptionalApp: true,
+	Category:    "access",
+	Short:       "list permissions",
+	Long: `
+Lists the permissions that have been granted. If an app is provided, only
+permissions that apply to that app are listed.
+
+Once a permission has been granted for an action on an app, only the
+principals that have been granted that action can perform it. Actions that
+have no permissions granted can be performed by anyone.
+
+Examples:
+
+    $ emp access -a payments-api
+    2f0ad5b2-4f6e-4a2b-9c1b-0b5c3f3b2a6d  payments-api  team:1234      set
+    9a1c3e07-1d2b-4c6a-8f3e-5b7d9e0f1a2c  *             user:ejholmes  *
+`,
+}
+
+func runAccess(cmd *Command, args []string) {
+	if len(args) != 0 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+
+	appname, _ := app()
+
+	permissions, err := client.PermissionList(appname, &heroku.ListRange{
+		Field: "app",
+		Max:   1000,
+	})
+	must(err)
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	defer w.Flush()
+
+	for _, p := range permissions {
+		listRec(w,
+			p.Id,
+			p.App,
+			p.Principal,
+			p.Action,
+		)
+	}
+}
+
+var cmdAccessGrant = &Command{
+	Run:             maybeMessage(runAccessGrant),
+	Usage:           "access-grant <principal> <action>",
+	NeedsApp:        true,
+	OptionalMessage: true,
+	Category:        "access",
+	Short:           "grant a permission",
+	Long: `
+Grants a principal permission to perform an action on an app. The principal
+can be a user (user:<name>), a GitHub team (team:<id>) or * for everyone.
+
+The action can be one of create, destroy, rename, deploy, set, run, scale,
+restart, rollback, access, reveal, config_group, or * for all actions. The
+access action allows the principal to grant and revoke permissions for the
+app, and has to be granted before any other action can be. The reveal action
+allows the principal to see the values of sensitive env vars. The
+config_group action allows the principal to set and destroy config groups,
+and is only checked on all apps (-a '*').
+
+Use -a '*' to grant the permission on all apps.
+
+Examples:
+
+    $ emp access-grant -a payments-api team:1234 access
+    Granted team:1234 access to access payments-api.
+    $ emp access-grant -a payments-api team:1234 set
+    Granted team:1234 access to set payments-api.
+`,
+}
+
+func runAccessGrant(cmd *Command, args []string) {
+	appname := mustApp()
+	if len(args) != 2 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+	principal, action := args[0], args[1]
+	message := getMessage()
+	_, err := client.PermissionCreate(appname, principal, action, message)
+	must(err)
+	log.Printf("Granted %s access to %s %s.", principal, action, appname)
+}
+
+var cmdAccessRevoke = &Command{
+	Run:             maybeMessage(runAccessRevoke),
+	Usage:           "access-revoke <id>",
+	OptionalMessage: true,
+	Category:        "access",
+	Short:           "revoke a permission",
+	Long: `
+Revokes a permission. The id can be found with emp access.
+
+Examples:
+
+    $ emp access-revoke 2f0ad5b2-4f6e-4a2b-9c1b-0b5c3f3b2a6d
+    Revoked permission 2f0ad5b2-4f6e-4a2b-9c1b-0b5c3f3b2a6d.
+`,
+}
+
+func runAccessRevoke(cmd *Command, args []string) {
+	if len(args) != 1 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+	id := args[0]
+	message := getMessage()
+	must(client.PermissionDelete(id, message))
+	log.Printf("Revoked permission %s.", id)
+}
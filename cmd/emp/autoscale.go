@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/remind101/empire/pkg/heroku"
+)
+
+var cmdAutoscale = &Command{
+	Run:             maybeMessage(runAutoscale),
+	Usage:           "autoscale [<type> (<min>-<max> <metric>=<target> | off)]",
+	NeedsApp:        true,
+	OptionalMessage: true,
+	Category:        "dyno",
+	Short:           "view or change autoscaling for process types",
+	Long: `
+Autoscale shows which process types are scaled automatically, or changes how a
+process type is scaled. Autoscaled processes are scaled between a minimum and
+maximum quantity to keep a metric at a target value, and can only be scaled
+manually with emp scale within those bounds.
+
+Metrics can be one of:
+
+    cpu       average CPU utilization, as a percentage
+    memory    average memory utilization, as a percentage
+    requests  requests per dyno, per minute (requires an application
+              load balancer)
+
+Examples:
+
+    $ emp autoscale
+    web     2-10  cpu=50
+    worker  1-5   memory=75
+
+    $ emp autoscale web 2-10 cpu=50
+    Autoscaling web on myapp between 2 and 10 dynos, targeting cpu=50.
+
+    $ emp autoscale web off
+    Disabled autoscaling for web on myapp.
+`,
+}
+
+func runAutoscale(cmd *Command, args []string) {
+	appname := mustApp()
+	message := getMessage()
+
+	switch len(args) {
+	case 0:
+		listAutoscaling(appname)
+	case 2:
+		if args[1] != "off" {
+			cmd.PrintUsage()
+			os.Exit(2)
+		}
+		must(client.AutoscalingDelete(appname, args[0], message))
+		log.Printf("Disabled autoscaling for %s on %s.", args[0], appname)
+	case 3:
+		opts, err := parseAutoscaleArgs(args[1], args[2])
+		if err != nil {
+			cmd.PrintUsage()
+			os.Exit(2)
+		}
+		a, err := client.AutoscalingUpdate(appname, args[0], opts, message)
+		must(err)
+		log.Printf("Autoscaling %s on %s between %d and %d dynos, targeting %s=%d.", a.Process, appname, a.Min, a.Max, a.Metric, a.Target)
+	default:
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+}
+
+func listAutoscaling(appname string) {
+	autoscaling, err := client.AutoscalingList(appname)
+	must(err)
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	defer w.Flush()
+
+	for _, a := range autoscaling {
+		listRec(w,
+			a.Process,
+			strconv.Itoa(a.Min)+"-"+strconv.Itoa(a.Max),
+			a.Metric+"="+strconv.Itoa(a.Target),
+		)
+	}
+}
+
+var errInvalidAutoscaleArg = errors.New("invalid argument")
+
+// parseAutoscaleArgs parses the bounds (e.g. "2-10") and target (e.g.
+// "cpu=50") arguments.
+func parseAutoscaleArgs(bounds, target string) (opts heroku.AutoscalingUpdateOpts, err error) {
+	b := strings.SplitN(bounds, "-", 2)
+	t := strings.SplitN(target, "=", 2)
+	if len(b) != 2 || len(t) != 2 || t[0] == "" {
+		return opts, errInvalidAutoscaleArg
+	}
+
+	if opts.Min, err = strconv.Atoi(b[0]); err != nil {
+		return opts, errInvalidAutoscaleArg
+	}
+	if opts.Max, err = strconv.Atoi(b[1]); err != nil {
+		return opts, errInvalidAutoscaleArg
+	}
+	if opts.Target, err = strconv.Atoi(t[1]); err != nil {
+		return opts, errInvalidAutoscaleArg
+	}
+	opts.Metric = t[0]
+
+	return opts, nil
+}
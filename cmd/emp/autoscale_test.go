@@ -0,0 +1,34 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/remind101/empire/pkg/heroku"
+)
+
+var parseAutoscaleTests = []struct {
+	bounds string
+	target string
+	opts   heroku.AutoscalingUpdateOpts
+	err    error
+}{
+	{"2-10", "cpu=50", heroku.AutoscalingUpdateOpts{Min: 2, Max: 10, Metric: "cpu", Target: 50}, nil},
+	{"0-1", "requests=1000", heroku.AutoscalingUpdateOpts{Min: 0, Max: 1, Metric: "requests", Target: 1000}, nil},
+	{"2", "cpu=50", heroku.AutoscalingUpdateOpts{}, errInvalidAutoscaleArg},
+	{"2-x", "cpu=50", heroku.AutoscalingUpdateOpts{Min: 2}, errInvalidAutoscaleArg},
+	{"2-10", "cpu", heroku.AutoscalingUpdateOpts{}, errInvalidAutoscaleArg},
+	{"2-10", "=50", heroku.AutoscalingUpdateOpts{}, errInvalidAutoscaleArg},
+	{"2-10", "cpu=high", heroku.AutoscalingUpdateOpts{Min: 2, Max: 10}, errInvalidAutoscaleArg},
+}
+
+func TestParseAutoscaleArgs(t *testing.T) {
+	for i, pt := range parseAutoscaleTests {
+		opts, err := parseAutoscaleArgs(pt.bounds, pt.target)
+		if opts != pt.opts {
+			t.Errorf("%d. parseAutoscaleArgs(%q, %q) => %+v, want %+v", i, pt.bounds, pt.target, opts, pt.opts)
+		}
+		if err != pt.err {
+			t.Errorf("%d. parseAutoscaleArgs(%q, %q).err => %v, want %v", i, pt.bounds, pt.target, err, pt.err)
+		}
+	}
+}
@@ -36,6 +36,6 @@ func runCertAttach(cmd *Command, args []string) {
 
 	_, err := client.AppUpdate(mustApp(), &heroku.AppUpdateOpts{
 		Cert: &cert,
-	})
+	}, "")
 	must(err)
 }
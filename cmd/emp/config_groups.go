@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+var cmdConfigGroups = &Command{
+	Run:      runConfigGroups,
+	Usage:    "config-groups",
+	Category: "config",
+	Short:    "list config groups",
+	Long: `
+Lists config groups, and the apps that they're attached to.
+
+Examples:
+
+    $ emp config-groups
+    shared   acme-inc, api
+    kafka    api
+`,
+}
+
+func runConfigGroups(cmd *Command, args []string) {
+	if len(args) != 0 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+
+	groups, err := client.ConfigGroupList()
+	must(err)
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	defer w.Flush()
+
+	for _, g := range groups {
+		listRec(w,
+			g.Name,
+			strings.Join(g.Apps, ", "),
+		)
+	}
+}
+
+var configGroupReveal bool
+
+var cmdConfigGroupEnv = &Command{
+	Run:      runConfigGroupEnv,
+	Usage:    "config-group-env [-r] <group>",
+	Category: "config",
+	Short:    "list config group env vars",
+	Long: `
+Show all env vars in a config group. Values are masked unless -r is provided,
+which requires permission to reveal config vars on every app that the config
+group is attached to.
+
+Options:
+
+    -r, --reveal  show the values of env vars
+
+Examples:
+
+    $ emp config-group-env shared
+    SENTRY_DSN=****/123
+    STATSD_HOST=****
+`,
+}
+
+func init() {
+	cmdConfigGroupEnv.Flag.BoolVarP(&configGroupReveal, "reveal", "r", false, "show the values of env vars")
+}
+
+func runConfigGroupEnv(cmd *Command, args []string) {
+	if len(args) != 1 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+	config, err := client.ConfigGroupVarInfo(args[0], configGroupReveal)
+	must(err)
+	var configKeys []string
+	for k := range config {
+		configKeys = append(configKeys, k)
+	}
+	sort.Strings(configKeys)
+	for _, k := range configKeys {
+		fmt.Printf("%s=%s\n", k, config[k])
+	}
+}
+
+var cmdConfigGroupSet = &Command{
+	Run:             maybeMessage(runConfigGroupSet),
+	Usage:           "config-group-set <group> <name>=<value>...",
+	OptionalMessage: true,
+	Category:        "config",
+	Short:           "set config group env vars",
+	Long: `
+Set env vars in a config group, creating the config group if it doesn't
+exist. Every app that the config group is attached to is released with the
+new values. Apps are released a few at a time, so it can take a few minutes
+for the change to reach all of them.
+
+Examples:
+
+    $ emp config-group-set shared STATSD_HOST=statsd.internal:8125
+    Set env vars on shared.
+`,
+}
+
+func runConfigGroupSet(cmd *Command, args []string) {
+	message := getMessage()
+	if len(args) < 2 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+	group := args[0]
+	config := make(map[string]*string)
+	for _, arg := range args[1:] {
+		i := strings.Index(arg, "=")
+		if i < 0 {
+			printFatal("bad format: %#q. See 'emp help config-group-set'", arg)
+		}
+		val := arg[i+1:]
+		config[arg[:i]] = &val
+	}
+	_, err := client.ConfigGroupVarUpdate(group, config, message)
+	must(err)
+	log.Printf("Set env vars on %s.", group)
+}
+
+var cmdConfigGroupUnset = &Command{
+	Run:             maybeMessage(runConfigGroupUnset),
+	Usage:           "config-group-unset <group> <name>...",
+	OptionalMessage: true,
+	Category:        "config",
+	Short:           "unset config group env vars",
+	Long: `
+Unset env vars in a config group. Every app that the config group is attached
+to is released without them.
+
+Examples:
+
+    $ emp config-group-unset shared STATSD_HOST
+    Unset env vars on shared.
+`,
+}
+
+func runConfigGroupUnset(cmd *Command, args []string) {
+	message := getMessage()
+	if len(args) < 2 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+	group := args[0]
+	config := make(map[string]*string)
+	for _, key := range args[1:] {
+		config[key] = nil
+	}
+	_, err := client.ConfigGroupVarUpdate(group, config, message)
+	must(err)
+	log.Printf("Unset env vars on %s.", group)
+}
+
+var cmdConfigGroupDestroy = &Command{
+	Run:             maybeMessage(runConfigGroupDestroy),
+	Usage:           "config-group-destroy <group>",
+	OptionalMessage: true,
+	Category:        "config",
+	Short:           "destroy a config group",
+	Long: `
+Destroys a config group. The config group must be detached from every app
+first.
+
+Examples:
+
+    $ emp config-group-destroy shared
+    Destroyed shared.
+`,
+}
+
+func runConfigGroupDestroy(cmd *Command, args []string) {
+	message := getMessage()
+	if len(args) != 1 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+	must(client.ConfigGroupDelete(args[0], message))
+	log.Printf("Destroyed %s.", args[0])
+}
+
+var cmdConfigGroupAttach = &Command{
+	Run:             maybeMessage(runConfigGroupAttach),
+	Usage:           "config-group-attach <group>",
+	NeedsApp:        true,
+	OptionalMessage: true,
+	Category:        "config",
+	Short:           "attach a config group to an app",
+	Long: `
+Attaches a config group to an app, and releases the app with the config
+group's env vars. The app's own env vars take precedence over env vars from
+its config groups, and config groups that were attached later take
+precedence over ones that were attached earlier.
+
+Examples:
+
+    $ emp config-group-attach shared -a acme-inc
+    Attached shared to acme-inc.
+`,
+}
+
+func runConfigGroupAttach(cmd *Command, args []string) {
+	appname := mustApp()
+	message := getMessage()
+	if len(args) != 1 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+	must(client.ConfigGroupAttach(appname, args[0], message))
+	log.Printf("Attached %s to %s.", args[0], appname)
+}
+
+var cmdConfigGroupDetach = &Command{
+	Run:             maybeMessage(runConfigGroupDetach),
+	Usage:           "config-group-detach <group>",
+	NeedsApp:        true,
+	OptionalMessage: true,
+	Category:        "config",
+	Short:           "detach a config group from an app",
+	Long: `
+Detaches a config group from an app, and releases the app without the config
+group's env vars.
+
+Examples:
+
+    $ emp config-group-detach shared -a acme-inc
+    Detached shared from acme-inc.
+`,
+}
+
+func runConfigGroupDetach(cmd *Command, args []string) {
+	appname := mustApp()
+	message := getMessage()
+	if len(args) != 1 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+	must(client.ConfigGroupDetach(appname, args[0], message))
+	log.Printf("Detached %s from %s.", args[0], appname)
+}
@@ -3,24 +3,37 @@ package main
 import (
 	"fmt"
 	"io"
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/docker/pkg/term"
 	"github.com/remind101/empire/pkg/heroku"
 )
 
-var stream bool
+var (
+	stream          bool
+	detach          bool
+	canary          bool
+	canarySteps     string
+	canaryPause     string
+	canaryThreshold string
+)
 
 var cmdDeploy = &Command{
 	Run:             maybeMessage(runDeploy),
-	Usage:           "deploy [<registry>]<image>:[<tag>] [-s]",
+	Usage:           "deploy [<registry>]<image>:[<tag>] [-s] [-d] [--canary]",
 	OptionalApp:     true,
 	OptionalMessage: true,
 	Category:        "deploy",
 	Short:           "deploy a docker image",
 	Long: `
-Deploy is used to deploy a docker image to an app.
+Deploy is used to deploy a docker image to an app. Deployments are performed
+one at a time for each app, in the order they were queued. Unless -d is
+given, the command waits for the deployment and shows its output.
 
 Options:
 
@@ -28,6 +41,26 @@ Options:
     command will wait until the scheduler has finished deploying the new
     release.
 
+    -d queue the deployment instead of waiting for it, and print its id. The
+    deployment is performed in the background, after any deployments to the
+    app that were queued before it. Use emp deploy:status to see how it's
+    going.
+
+    --canary run the new release as a canary alongside the current release,
+    instead of replacing it. A share of the requests to the app are sent to
+    the canary, which is increased in steps. After the last step, the canary
+    is promoted. If too many requests to the canary fail with a 5xx response,
+    it's aborted, and the app is rolled back. Use emp deploy:promote or
+    emp deploy:abort to do this sooner.
+
+    --canary-steps the percentages of requests to send to the canary, in
+    order (default 5,25,100).
+
+    --canary-pause how long to wait between steps (default 5m).
+
+    --canary-threshold the percentage of requests to the canary that can fail
+    before it's aborted (default 1).
+
 Examples:
 
     $ emp deploy remind101/acme-inc:latest
@@ -43,16 +76,66 @@ Examples:
     Status: Created new release v1 for acme-inc
     $ emp releases
     v1    Jan 1 12:55  Deploy remind101/acme-inc:latest
+
+    $ emp deploy -d remind101/acme-inc:latest
+    Queued deployment 5b7d9e0f-1a2c-4c6a-8f3e-9a1c3e071d2b of remind101/acme-inc:latest to acme-inc.
+
+    $ emp deploy --canary --canary-steps 10,50,100 remind101/acme-inc:latest
+    Status: Created new release v2 for acme-inc, running as a canary alongside v1
+    Status: Sending 10% of requests to canary v2 of acme-inc
 `,
 }
 
 func init() {
 	cmdDeploy.Flag.BoolVarP(&stream, "stream", "s", false, "boolean to enable the status stream")
+	cmdDeploy.Flag.BoolVarP(&detach, "detach", "d", false, "queue the deployment instead of waiting for it")
+	cmdDeploy.Flag.BoolVar(&canary, "canary", false, "run the new release as a canary")
+	cmdDeploy.Flag.StringVar(&canarySteps, "canary-steps", "", "percentages of requests to send to the canary, in order")
+	cmdDeploy.Flag.StringVar(&canaryPause, "canary-pause", "", "how long to wait between canary steps")
+	cmdDeploy.Flag.StringVar(&canaryThreshold, "canary-threshold", "", "percentage of requests to the canary that can fail")
 }
 
 type PostDeployForm struct {
-	Image  string `json:"image"`
-	Stream bool   `json:"stream"`
+	Image  string                `json:"image"`
+	Stream bool                  `json:"stream"`
+	Detach bool                  `json:"detach,omitempty"`
+	Canary *PostDeployCanaryForm `json:"canary,omitempty"`
+}
+
+type PostDeployCanaryForm struct {
+	Steps     []int    `json:"steps,omitempty"`
+	Pause     *string  `json:"pause,omitempty"`
+	Threshold *float64 `json:"threshold,omitempty"`
+}
+
+// canaryForm returns the canary options from the flags. Options that aren't
+// provided use Empire's defaults.
+func canaryForm() *PostDeployCanaryForm {
+	form := &PostDeployCanaryForm{}
+
+	if canarySteps != "" {
+		for _, s := range strings.Split(canarySteps, ",") {
+			step, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				printFatal("Invalid canary step: %s", s)
+			}
+			form.Steps = append(form.Steps, step)
+		}
+	}
+
+	if canaryPause != "" {
+		form.Pause = &canaryPause
+	}
+
+	if canaryThreshold != "" {
+		threshold, err := strconv.ParseFloat(canaryThreshold, 64)
+		if err != nil {
+			printFatal("Invalid canary threshold: %s", canaryThreshold)
+		}
+		form.Threshold = &threshold
+	}
+
+	return form
 }
 
 func runDeploy(cmd *Command, args []string) {
@@ -65,6 +148,12 @@ func runDeploy(cmd *Command, args []string) {
 	image := args[0]
 	message := getMessage()
 	form := &PostDeployForm{Image: image, Stream: stream}
+	if canary {
+		if detach {
+			printFatal("Canary deployments can't be queued")
+		}
+		form.Canary = canaryForm()
+	}
 
 	var endpoint string
 	appName, _ := app()
@@ -75,6 +164,15 @@ func runDeploy(cmd *Command, args []string) {
 	}
 
 	rh := heroku.RequestHeaders{CommitMessage: message}
+
+	if detach {
+		form.Detach = true
+		var d heroku.Deployment
+		must(client.PostWithHeaders(&d, endpoint, form, rh.Headers()))
+		log.Printf("Queued deployment %s of %s to %s.", d.Id, d.Image, d.App.Name)
+		return
+	}
+
 	go func() {
 		must(client.PostWithHeaders(w, endpoint, form, rh.Headers()))
 		must(w.Close())
@@ -83,3 +181,135 @@ func runDeploy(cmd *Command, args []string) {
 	outFd, isTerminalOut := term.GetFdInfo(os.Stdout)
 	must(jsonmessage.DisplayJSONMessagesStream(r, os.Stdout, outFd, isTerminalOut, nil))
 }
+
+var cmdDeployStatus = &Command{
+	Run:      runDeployStatus,
+	Usage:    "deploy:status <id>",
+	NeedsApp: true,
+	Category: "deploy",
+	Short:    "show the status of a queued deployment",
+	Long: `
+Deploy:status shows the status of a deployment that was queued with
+emp deploy -d. The status is one of pending, running, succeeded or failed.
+The output of the deployment so far is shown after the status.
+
+Examples:
+
+    $ emp deploy:status 5b7d9e0f-1a2c-4c6a-8f3e-9a1c3e071d2b
+    Id:       5b7d9e0f-1a2c-4c6a-8f3e-9a1c3e071d2b
+    Image:    remind101/acme-inc:latest
+    Status:   succeeded
+    Release:  v2
+    By:       ejholmes
+    Queued:   2015-01-01T12:55:00Z
+    Started:  2015-01-01T12:55:02Z
+    Finished: 2015-01-01T12:56:10Z
+
+    Pulling repository remind101/acme-inc
+    345c7524bc96: Download complete
+    Status: Downloaded newer image for remind101/acme-inc:latest
+    Status: Created new release v2 for acme-inc
+`,
+}
+
+func runDeployStatus(cmd *Command, args []string) {
+	appname := mustApp()
+	if len(args) != 1 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+
+	d, err := client.DeploymentInfo(appname, args[0])
+	must(err)
+
+	fmt.Printf("Id:       %s\n", d.Id)
+	fmt.Printf("Image:    %s\n", d.Image)
+	fmt.Printf("Status:   %s\n", d.Status)
+	if d.Release != nil {
+		fmt.Printf("Release:  v%d\n", *d.Release)
+	}
+	if d.Error != nil {
+		fmt.Printf("Error:    %s\n", *d.Error)
+	}
+	fmt.Printf("By:       %s\n", d.User)
+	fmt.Printf("Queued:   %s\n", d.CreatedAt.UTC().Format(time.RFC3339))
+	if d.StartedAt != nil {
+		fmt.Printf("Started:  %s\n", d.StartedAt.UTC().Format(time.RFC3339))
+	}
+	if d.FinishedAt != nil {
+		fmt.Printf("Finished: %s\n", d.FinishedAt.UTC().Format(time.RFC3339))
+	}
+
+	if d.Output != "" {
+		fmt.Println()
+		// A failed deployment ends with its error, which was already
+		// printed above.
+		err := jsonmessage.DisplayJSONMessagesStream(strings.NewReader(d.Output), os.Stdout, 0, false, nil)
+		if _, ok := err.(*jsonmessage.JSONError); !ok {
+			must(err)
+		}
+	}
+}
+
+var cmdDeployPromote = &Command{
+	Run:             maybeMessage(runDeployPromote),
+	Usage:           "deploy:promote",
+	NeedsApp:        true,
+	OptionalMessage: true,
+	Category:        "deploy",
+	Short:           "promote the running canary",
+	Long: `
+Deploy:promote promotes the canary that was deployed with emp deploy --canary,
+without waiting for the remaining steps. The canary's release is released to
+all of the app's processes.
+
+Examples:
+
+    $ emp deploy:promote -a acme-inc
+    Promoted canary v2 of acme-inc.
+`,
+}
+
+func runDeployPromote(cmd *Command, args []string) {
+	appname := mustApp()
+	message := getMessage()
+	if len(args) != 0 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+
+	c, err := client.CanaryPromote(appname, message)
+	must(err)
+	log.Printf("Promoted canary v%d of %s.", c.Release, appname)
+}
+
+var cmdDeployAbort = &Command{
+	Run:             maybeMessage(runDeployAbort),
+	Usage:           "deploy:abort",
+	NeedsApp:        true,
+	OptionalMessage: true,
+	Category:        "deploy",
+	Short:           "abort the running canary",
+	Long: `
+Deploy:abort aborts the canary that was deployed with emp deploy --canary, and
+rolls the app back to the release that the canary was running alongside.
+
+Examples:
+
+    $ emp deploy:abort -a acme-inc
+    Aborted canary v2 of acme-inc, and rolled back to v1.
+`,
+}
+
+func runDeployAbort(cmd *Command, args []string) {
+	appname := mustApp()
+	message := getMessage()
+	if len(args) != 0 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+
+	c, err := client.CanaryAbort(appname, message)
+	must(err)
+	log.Printf("Aborted canary v%d of %s, and rolled back to v%d.", c.Release, appname, c.StableRelease)
+}
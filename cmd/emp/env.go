@@ -6,16 +6,46 @@ import (
 	"log"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 )
 
+var envReveal bool
+
 var cmdEnv = &Command{
 	Run:      runEnv,
-	Usage:    "env",
+	Usage:    "env [-r]",
 	NeedsApp: true,
 	Category: "config",
 	Short:    "list env vars",
-	Long:     `Show all env vars.`,
+	Long: `
+Show all env vars. Env vars that reference a secret show the reference, not
+the value of the secret.
+
+The values of sensitive env vars are masked, showing only the last 4
+characters of long values. Use -r to show them in full, which requires
+permission to reveal config vars. Env vars can be marked as not sensitive
+with emp env-sensitivity.
+
+Options:
+
+    -r, --reveal  show the values of sensitive env vars
+
+Examples:
+
+    $ emp env
+    DATABASE_URL=****5432
+    RAILS_ENV=production
+
+    $ emp env -r
+    DATABASE_URL=postgres://localhost:5432
+    RAILS_ENV=production
+`,
+}
+
+func init() {
+	cmdEnv.Flag.BoolVarP(&envReveal, "reveal", "r", false, "show the values of sensitive env vars")
+	cmdGet.Flag.BoolVarP(&envReveal, "reveal", "r", false, "show the value if it's sensitive")
 }
 
 func runEnv(cmd *Command, args []string) {
@@ -23,8 +53,7 @@ func runEnv(cmd *Command, args []string) {
 		cmd.PrintUsage()
 		os.Exit(2)
 	}
-	config, err := client.ConfigVarInfo(mustApp())
-	must(err)
+	config := mustConfigVars(mustApp())
 	var configKeys []string
 	for k := range config {
 		configKeys = append(configKeys, k)
@@ -35,14 +64,35 @@ func runEnv(cmd *Command, args []string) {
 	}
 }
 
+// mustConfigVars returns the config vars for the app, revealing sensitive
+// values if -r was provided.
+func mustConfigVars(appname string) map[string]string {
+	var (
+		config map[string]string
+		err    error
+	)
+	if envReveal {
+		config, err = client.ConfigVarReveal(appname)
+	} else {
+		config, err = client.ConfigVarInfo(appname)
+	}
+	must(err)
+	return config
+}
+
 var cmdGet = &Command{
 	Run:      runGet,
-	Usage:    "get <name>",
+	Usage:    "get [-r] <name>",
 	NeedsApp: true,
 	Category: "config",
 	Short:    "get env var" + extra,
 	Long: `
-Get the value of an env var.
+Get the value of an env var. If the env var is sensitive, the value is
+masked unless -r is provided.
+
+Options:
+
+    -r, --reveal  show the value if it's sensitive
 
 Example:
 
@@ -56,8 +106,7 @@ func runGet(cmd *Command, args []string) {
 		cmd.PrintUsage()
 		os.Exit(2)
 	}
-	config, err := client.ConfigVarInfo(mustApp())
-	must(err)
+	config := mustConfigVars(mustApp())
 	value, found := config[args[0]]
 	if !found {
 		printFatal("No such key as '%s'", args[0])
@@ -75,10 +124,18 @@ var cmdSet = &Command{
 	Long: `
 Set the value of an env var.
 
-Example:
+If Empire has secret references enabled, the value can be a reference to a
+secret in SSM Parameter Store (ssm://<name>) or Secrets Manager
+(secretsmanager://<name>[#<key>]). The secret is resolved each time the app
+is released or run, and only the reference is stored in Empire.
+
+Examples:
 
     $ emp set BUILDPACK_URL=http://github.com/kr/heroku-buildpack-inline.git
     Set env vars and restarted myapp.
+
+    $ emp set DB_PASSWORD=ssm:///prod/api/db_password
+    Set env vars and restarted myapp.
 `,
 }
 
@@ -136,6 +193,65 @@ func runUnset(cmd *Command, args []string) {
 	log.Printf("Unset env vars and restarted %s.", appname)
 }
 
+var cmdEnvSensitivity = &Command{
+	Run:             maybeMessage(runEnvSensitivity),
+	Usage:           "env-sensitivity [<name>=<true|false>...]",
+	NeedsApp:        true,
+	OptionalMessage: true,
+	Category:        "config",
+	Short:           "mark env vars as sensitive",
+	Long: `
+Env vars are sensitive by default, so their values are masked by emp env and
+emp get. Marking an env var as not sensitive shows its value in full to
+anyone that can read the app's config.
+
+With no arguments, lists the env vars that aren't sensitive.
+
+Examples:
+
+    $ emp env-sensitivity RAILS_ENV=false PORT=false
+    Marked env vars on myapp.
+
+    $ emp env-sensitivity
+    PORT
+    RAILS_ENV
+`,
+}
+
+func runEnvSensitivity(cmd *Command, args []string) {
+	appname := mustApp()
+	if len(args) == 0 {
+		sensitivity, err := client.ConfigVarSensitivityInfo(appname)
+		must(err)
+		var names []string
+		for k := range sensitivity {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			fmt.Println(k)
+		}
+		return
+	}
+
+	message := getMessage()
+	sensitivity := make(map[string]bool)
+	for _, arg := range args {
+		i := strings.Index(arg, "=")
+		if i < 0 {
+			printFatal("bad format: %#q. See 'emp help env-sensitivity'", arg)
+		}
+		sensitive, err := strconv.ParseBool(arg[i+1:])
+		if err != nil {
+			printFatal("bad format: %#q. See 'emp help env-sensitivity'", arg)
+		}
+		sensitivity[arg[:i]] = sensitive
+	}
+	_, err := client.ConfigVarSensitivityUpdate(appname, sensitivity, message)
+	must(err)
+	log.Printf("Marked env vars on %s.", appname)
+}
+
 var cmdEnvLoad = &Command{
 	Run:             maybeMessage(runEnvLoad),
 	Usage:           "env-load <file>",
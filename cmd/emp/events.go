@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/remind101/empire/pkg/heroku"
+)
+
+var (
+	eventsCount int
+	eventsUser  string
+	eventsType  string
+	eventsSince string
+	eventsUntil string
+)
+
+var cmdEvents = &Command{
+	Run:         runEvents,
+	Usage:       "events [-n <limit>] [-u <user>] [-t <type>] [--since <time>] [--until <time>]",
+	OptionalApp: true,
+	Category:    "app",
+	Short:       "list events in the audit log",
+	Long: `
+Lists events in the audit log, newest first. If an app is provided, only
+events for that app are listed.
+
+Options:
+
+    -n <limit>      maximum number of events to display
+    -u <user>       only display events triggered by this user
+    -t <type>       only display events of this type (e.g. deploy, set)
+    --since <time>  only display events after this time
+    --until <time>  only display events before this time
+
+Times can be provided as a duration (e.g. 24h will show events from the last
+24 hours), a date (e.g. 2016-09-01), or in RFC3339 format.
+
+Examples:
+
+    $ emp events -a acme-inc -t set --since 168h
+    Sep 13 18:28  ejholmes  set  ejholmes changed environment variables on acme-inc (DATABASE_URL)
+`,
+}
+
+func init() {
+	cmdEvents.Flag.IntVarP(&eventsCount, "number", "n", 20, "max number of events to display")
+	cmdEvents.Flag.StringVarP(&eventsUser, "user", "u", "", "user to filter by")
+	cmdEvents.Flag.StringVarP(&eventsType, "type", "t", "", "event type to filter by")
+	cmdEvents.Flag.StringVar(&eventsSince, "since", "", "only show events after this time")
+	cmdEvents.Flag.StringVar(&eventsUntil, "until", "", "only show events before this time")
+}
+
+func runEvents(cmd *Command, args []string) {
+	if len(args) != 0 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+
+	appname, _ := app()
+
+	var opts heroku.EventListOpts
+	if eventsUser != "" {
+		opts.User = &eventsUser
+	}
+	if eventsType != "" {
+		opts.Type = &eventsType
+	}
+	if eventsSince != "" {
+		t, err := parseEventTime(eventsSince)
+		must(err)
+		opts.Since = &t
+	}
+	if eventsUntil != "" {
+		t, err := parseEventTime(eventsUntil)
+		must(err)
+		opts.Until = &t
+	}
+
+	events, err := client.EventList(appname, &opts, &heroku.ListRange{
+		Field:      "created_at",
+		Max:        eventsCount,
+		Descending: true,
+	})
+	must(err)
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	defer w.Flush()
+
+	for _, e := range events {
+		listRec(w,
+			prettyTime{e.CreatedAt},
+			abbrev(e.User, 10),
+			e.Type,
+			e.Description,
+		)
+	}
+}
+
+// parseEventTime parses s as either a duration relative to now, a date, or an
+// RFC3339 timestamp.
+func parseEventTime(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time: %s", s)
+}
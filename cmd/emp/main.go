@@ -110,13 +110,25 @@ var commands = []*Command{
 	cmdDynos,
 	cmdReleases,
 	cmdReleaseInfo,
+	cmdReleaseDiff,
 	cmdRollback,
+	cmdEvents,
 	cmdScale,
+	cmdAutoscale,
+	cmdScaleSchedule,
 	cmdRestart,
 	cmdEnvLoad,
 	cmdSet,
 	cmdUnset,
 	cmdEnv,
+	cmdEnvSensitivity,
+	cmdConfigGroups,
+	cmdConfigGroupEnv,
+	cmdConfigGroupSet,
+	cmdConfigGroupUnset,
+	cmdConfigGroupAttach,
+	cmdConfigGroupDetach,
+	cmdConfigGroupDestroy,
 	cmdRun,
 	cmdLog,
 	cmdInfo,
@@ -126,7 +138,20 @@ var commands = []*Command{
 	cmdDomainAdd,
 	cmdDomainRemove,
 	cmdCertAttach,
+	cmdAccess,
+	cmdAccessGrant,
+	cmdAccessRevoke,
+	cmdTokens,
+	cmdTokenCreate,
+	cmdTokenRevoke,
 	cmdDeploy,
+	cmdDeployStatus,
+	cmdDeployPromote,
+	cmdDeployAbort,
+	cmdPromote,
+	cmdPipelines,
+	cmdPipelineSet,
+	cmdPipelineDestroy,
 	cmdVersion,
 	cmdHelp,
 
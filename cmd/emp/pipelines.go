@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/term"
+	"github.com/remind101/empire/pkg/heroku"
+)
+
+var cmdPipelines = &Command{
+	Run:      runPipelines,
+	Usage:    "pipelines",
+	Category: "deploy",
+	Short:    "list pipelines",
+	Long: `
+Lists pipelines, and the apps in them in the order that releases are promoted.
+
+Examples:
+
+    $ emp pipelines
+    acme-inc  acme-inc-staging -> acme-inc
+`,
+}
+
+func runPipelines(cmd *Command, args []string) {
+	if len(args) != 0 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+
+	pipelines, err := client.PipelineList()
+	must(err)
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	defer w.Flush()
+
+	for _, p := range pipelines {
+		listRec(w,
+			p.Name,
+			strings.Join(p.Apps, " -> "),
+		)
+	}
+}
+
+var cmdPipelineSet = &Command{
+	Run:             maybeMessage(runPipelineSet),
+	Usage:           "pipeline-set <name> <app>...",
+	OptionalMessage: true,
+	Category:        "deploy",
+	Short:           "create or change a pipeline",
+	Long: `
+Creates a pipeline, or replaces the apps in an existing one. Apps are listed in
+the order that releases are promoted through them, and an app can only be in
+one pipeline.
+
+Examples:
+
+    $ emp pipeline-set acme-inc acme-inc-staging acme-inc
+    Set pipeline acme-inc to acme-inc-staging -> acme-inc.
+`,
+}
+
+func runPipelineSet(cmd *Command, args []string) {
+	message := getMessage()
+	if len(args) < 3 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+
+	p, err := client.PipelineSet(args[0], args[1:], message)
+	must(err)
+	log.Printf("Set pipeline %s to %s.", p.Name, strings.Join(p.Apps, " -> "))
+}
+
+var cmdPipelineDestroy = &Command{
+	Run:             maybeMessage(runPipelineDestroy),
+	Usage:           "pipeline-destroy <name>",
+	OptionalMessage: true,
+	Category:        "deploy",
+	Short:           "destroy a pipeline",
+	Long: `
+Destroys a pipeline. The apps in it aren't changed.
+
+Examples:
+
+    $ emp pipeline-destroy acme-inc
+    Destroyed pipeline acme-inc.
+`,
+}
+
+func runPipelineDestroy(cmd *Command, args []string) {
+	message := getMessage()
+	if len(args) != 1 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+
+	must(client.PipelineDelete(args[0], message))
+	log.Printf("Destroyed pipeline %s.", args[0])
+}
+
+var promoteStream bool
+
+var cmdPromote = &Command{
+	Run:             maybeMessage(runPromote),
+	Usage:           "promote [-s] [<version>]",
+	NeedsApp:        true,
+	OptionalMessage: true,
+	Category:        "deploy",
+	Short:           "promote a release to the next app in its pipeline",
+	Long: `
+Promote creates a new release on the next app in the app's pipeline, with the
+same image and Procfile as a release of this app, and the next app's config
+vars. The image isn't pulled again. By default, the latest release that
+didn't fail is promoted.
+
+Options:
+
+    -s enable the status stream during the deployment. If this is enabled, the
+    command will wait until the scheduler has finished deploying the new
+    release.
+
+Examples:
+
+    $ emp promote -a acme-inc-staging
+    Status: Created new release v7 for acme-inc
+    $ emp promote v12 -a acme-inc-staging
+    Status: Created new release v8 for acme-inc
+`,
+}
+
+func init() {
+	cmdPromote.Flag.BoolVarP(&promoteStream, "stream", "s", false, "boolean to enable the status stream")
+}
+
+type PostPromotionForm struct {
+	Version *int `json:"version,omitempty"`
+	Stream  bool `json:"stream"`
+}
+
+func runPromote(cmd *Command, args []string) {
+	appname := mustApp()
+	message := getMessage()
+	if len(args) > 1 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+
+	form := &PostPromotionForm{Stream: promoteStream}
+	if len(args) == 1 {
+		ver, err := strconv.Atoi(strings.TrimPrefix(args[0], "v"))
+		if err != nil {
+			printFatal("bad version: %#q. See 'emp help promote'", args[0])
+		}
+		form.Version = &ver
+	}
+
+	r, w := io.Pipe()
+
+	rh := heroku.RequestHeaders{CommitMessage: message}
+	go func() {
+		must(client.PostWithHeaders(w, fmt.Sprintf("/apps/%s/promotions", appname), form, rh.Headers()))
+		must(w.Close())
+	}()
+
+	outFd, isTerminalOut := term.GetFdInfo(os.Stdout)
+	must(jsonmessage.DisplayJSONMessagesStream(r, os.Stdout, outFd, isTerminalOut, nil))
+}
@@ -141,6 +141,9 @@ func listRelease(w io.Writer, r *Release) {
 	if r.Commit != "" && !strings.Contains(r.Description, r.Commit) {
 		desc += " (" + abbrev(r.Commit, 12) + ")"
 	}
+	if r.Status == "failed" {
+		desc += " [failed]"
+	}
 	listRec(w,
 		fmt.Sprintf("v%d", r.Version),
 		abbrev(r.Who, 10),
@@ -174,6 +177,7 @@ Examples:
     Version:  v116
     By:       user@test.com
     Change:   Deploy 62b3059
+    Status:   succeeded
     When:     2014-01-13T21:20:57Z
     Id:       abcd1234-5678-def0-8190-12347060474d
     Slug:     98765432-82ba-10ba-fedc-8d206789d062
@@ -193,6 +197,9 @@ func runReleaseInfo(cmd *Command, args []string) {
 	fmt.Printf("Version:  v%d\n", rel.Version)
 	fmt.Printf("By:       %s\n", rel.User.Email)
 	fmt.Printf("Change:   %s\n", rel.Description)
+	if rel.Status != "" {
+		fmt.Printf("Status:   %s\n", rel.Status)
+	}
 	fmt.Printf("When:     %s\n", rel.CreatedAt.UTC().Format(time.RFC3339))
 	fmt.Printf("Id:       %s\n", rel.Id)
 	if rel.Slug != nil {
@@ -200,6 +207,136 @@ func runReleaseInfo(cmd *Command, args []string) {
 	}
 }
 
+var releaseDiffValues bool
+
+var cmdReleaseDiff = &Command{
+	Run:      runReleaseDiff,
+	Usage:    "release-diff [-v] <version> [<version>]",
+	NeedsApp: true,
+	Category: "release",
+	Short:    "show what changed between releases",
+	Long: `
+release-diff shows what changed between two releases: the Docker image,
+config vars that were added (+), removed (-) or changed (~), and changes to
+processes in the formation, like the command, quantity or constraints. If
+only one version is given, it's compared with the release before it.
+
+Config var values are hidden unless -v is provided. Showing values requires
+permission to reveal config vars, and sensitive values are otherwise masked.
+
+Options:
+
+    -v  show config var values
+
+Examples:
+
+    $ emp release-diff v41 v42
+    Image:  remind101/acme-inc:a1b2c3d -> remind101/acme-inc:e4f5a6b
+
+    Config:
+      + REDIS_URL
+      ~ DATABASE_URL
+
+    Processes:
+      ~ web  quantity: 2 -> 4
+      ~ web  constraints: 1X -> 2X
+      + worker  command:  -> ./bin/worker
+`,
+}
+
+func init() {
+	cmdReleaseDiff.Flag.BoolVarP(&releaseDiffValues, "values", "v", false, "show config var values")
+}
+
+func runReleaseDiff(cmd *Command, args []string) {
+	appname := mustApp()
+	if len(args) < 1 || len(args) > 2 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+
+	to := strings.TrimPrefix(args[len(args)-1], "v")
+	from := ""
+	if len(args) == 2 {
+		from = strings.TrimPrefix(args[0], "v")
+	} else {
+		v, err := strconv.Atoi(to)
+		must(err)
+		from = strconv.Itoa(v - 1)
+	}
+
+	d, err := client.ReleaseDiffInfo(appname, from, to, releaseDiffValues)
+	must(err)
+
+	if d.Image == nil && len(d.Config) == 0 && len(d.Processes) == 0 {
+		fmt.Printf("No changes between v%d and v%d.\n", d.From, d.To)
+		return
+	}
+
+	// Sections are separated by a blank line.
+	var sections int
+	section := func(title string) {
+		if sections > 0 {
+			fmt.Println()
+		}
+		sections++
+		if title != "" {
+			fmt.Println(title)
+		}
+	}
+
+	if d.Image != nil {
+		section("")
+		fmt.Printf("Image:  %s -> %s\n", d.Image.From, d.Image.To)
+	}
+
+	if len(d.Config) > 0 {
+		section("Config:")
+		w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+		for _, c := range d.Config {
+			line := []interface{}{"  " + changeSymbol(c.Change), c.Name}
+			if releaseDiffValues {
+				line = append(line, fmt.Sprintf("%s -> %s", stringOrEmpty(c.From), stringOrEmpty(c.To)))
+			}
+			listRec(w, line...)
+		}
+		w.Flush()
+	}
+
+	if len(d.Processes) > 0 {
+		section("Processes:")
+		w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+		for _, p := range d.Processes {
+			for _, f := range p.Fields {
+				listRec(w,
+					"  "+changeSymbol(p.Change),
+					p.Name,
+					fmt.Sprintf("%s: %s -> %s", f.Field, f.From, f.To),
+				)
+			}
+		}
+		w.Flush()
+	}
+}
+
+func changeSymbol(change string) string {
+	switch change {
+	case "added":
+		return "+"
+	case "removed":
+		return "-"
+	default:
+		return "~"
+	}
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 var cmdRollback = &Command{
 	Run:             maybeMessage(runRollback),
 	Usage:           "rollback <version>",
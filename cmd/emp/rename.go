@@ -8,10 +8,11 @@ import (
 )
 
 var cmdRename = &Command{
-	Run:      runRename,
-	Usage:    "rename <oldname> <newname>",
-	Category: "app",
-	Short:    "rename an app",
+	Run:             maybeMessage(runRename),
+	Usage:           "rename <oldname> <newname>",
+	OptionalMessage: true,
+	Category:        "app",
+	Short:           "rename an app",
 	Long: `
 Rename renames a heroku app.
 
@@ -27,7 +28,8 @@ func runRename(cmd *Command, args []string) {
 		os.Exit(2)
 	}
 	oldname, newname := args[0], args[1]
-	app, err := client.AppUpdate(oldname, &heroku.AppUpdateOpts{Name: &newname})
+	message := getMessage()
+	app, err := client.AppUpdate(oldname, &heroku.AppUpdateOpts{Name: &newname}, message)
 	must(err)
 	log.Printf("Renamed %s to %s.", oldname, app.Name)
 	log.Println("Ensure you update your git remote URL.")
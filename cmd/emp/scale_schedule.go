@@ -0,0 +1,124 @@
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/remind101/empire/pkg/heroku"
+)
+
+var removeScaleSchedule string
+
+var cmdScaleSchedule = &Command{
+	Run:             maybeMessage(runScaleSchedule),
+	Usage:           "scale:schedule [<type>=<qty>[:<size>] <cron> | -r <id>]",
+	NeedsApp:        true,
+	OptionalMessage: true,
+	Category:        "dyno",
+	Short:           "schedule dyno quantities and sizes",
+	Long: `
+Scale:schedule lists, creates or removes schedules that scale a process type
+whenever a cron expression triggers. Cron expressions have 5 fields (minute,
+hour, day of month, month and day of week) and are evaluated in UTC.
+
+Schedules are run by Empire on behalf of the user that created them, exactly
+like emp scale, so they're shown in the event stream.
+
+Options:
+
+    -r <id>  remove the scale schedule with the given id
+
+Examples:
+
+    $ emp scale:schedule worker=20 "0 20 * * *"
+    Scheduled worker on myapp to scale to 20 at 0 20 * * *.
+
+    $ emp scale:schedule worker=2:1X "0 8 * * 1-5"
+    Scheduled worker on myapp to scale to 2:1X at 0 8 * * 1-5.
+
+    $ emp scale:schedule
+    5b7d9e0f-1a2c-4c6a-8f3e-9a1c3e071d2b  worker  20     0 20 * * *   Jan  2 20:00  ejholmes
+    0b5c3f3b-2a6d-4f6e-4a2b-9c1b2f0ad5b2  worker  2:1X   0 8 * * 1-5  Jan  3 08:00  ejholmes
+
+    $ emp scale:schedule -r 5b7d9e0f-1a2c-4c6a-8f3e-9a1c3e071d2b
+    Removed scale schedule 5b7d9e0f-1a2c-4c6a-8f3e-9a1c3e071d2b from myapp.
+`,
+}
+
+func init() {
+	cmdScaleSchedule.Flag.StringVarP(&removeScaleSchedule, "remove", "r", "", "remove the scale schedule with the given id")
+}
+
+func runScaleSchedule(cmd *Command, args []string) {
+	appname := mustApp()
+	message := getMessage()
+
+	if removeScaleSchedule != "" {
+		if len(args) != 0 {
+			cmd.PrintUsage()
+			os.Exit(2)
+		}
+		must(client.ScaleScheduleDelete(appname, removeScaleSchedule, message))
+		log.Printf("Removed scale schedule %s from %s.", removeScaleSchedule, appname)
+		return
+	}
+
+	switch len(args) {
+	case 0:
+		listScaleSchedules(appname)
+	case 2:
+		pstype, qty, size, err := parseScaleArg(args[0])
+		if err != nil || qty == -1 {
+			cmd.PrintUsage()
+			os.Exit(2)
+		}
+
+		opts := heroku.ScaleScheduleCreateOpts{
+			Process:  pstype,
+			Cron:     args[1],
+			Quantity: qty,
+		}
+		if size != "" {
+			opts.Size = &size
+		}
+
+		s, err := client.ScaleScheduleCreate(appname, opts, message)
+		must(err)
+		log.Printf("Scheduled %s on %s to scale to %s at %s.", s.Process, appname, formatScaleSchedule(s), s.Cron)
+	default:
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+}
+
+func listScaleSchedules(appname string) {
+	schedules, err := client.ScaleScheduleList(appname)
+	must(err)
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	defer w.Flush()
+
+	for i := range schedules {
+		s := &schedules[i]
+		listRec(w,
+			s.Id,
+			s.Process,
+			formatScaleSchedule(s),
+			s.Cron,
+			prettyTime{s.NextRunAt},
+			s.User,
+		)
+	}
+}
+
+// formatScaleSchedule returns the quantity, and size if it's changed, that a
+// schedule scales to (e.g. "20" or "2:1X").
+func formatScaleSchedule(s *heroku.ScaleSchedule) string {
+	scale := strconv.Itoa(s.Quantity)
+	if s.Size != "" {
+		scale += ":" + s.Size
+	}
+	return scale
+}
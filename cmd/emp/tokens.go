@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/remind101/empire/pkg/heroku"
+)
+
+var cmdTokens = &Command{
+	Run:      runTokens,
+	Usage:    "tokens",
+	Category: "emp",
+	Short:    "list access tokens",
+	Long: `
+Lists the access tokens that have been created for your user, including
+tokens created by emp login.
+
+Examples:
+
+    $ emp tokens
+    0b5c3f3b-2a6d-4f6e-4a2b-9c1b2f0ad5b2  deploy  Jan  2 15:04  Feb  1 15:04  active   CircleCI
+    5b7d9e0f-1a2c-4c6a-8f3e-9a1c3e071d2b  full    Jan  1 09:30  Jan 31 09:30  revoked  emp login from 2016-01-01T09:30:00Z
+`,
+}
+
+func runTokens(cmd *Command, args []string) {
+	if len(args) != 0 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+
+	tokens, err := client.OAuthAuthorizationList(&heroku.ListRange{
+		Field: "created_at",
+		Max:   1000,
+	})
+	must(err)
+
+	w := tabwriter.NewWriter(os.Stdout, 1, 2, 2, ' ', 0)
+	defer w.Flush()
+
+	for _, t := range tokens {
+		expires := "never"
+		if t.AccessToken != nil && t.AccessToken.ExpiresIn != nil {
+			expires = prettyTime{time.Now().Add(time.Duration(*t.AccessToken.ExpiresIn) * time.Second)}.String()
+		}
+
+		status := "active"
+		if t.RevokedAt != nil {
+			status = "revoked"
+		} else if t.AccessToken != nil && t.AccessToken.ExpiresIn != nil && *t.AccessToken.ExpiresIn == 0 {
+			status = "expired"
+		}
+
+		listRec(w,
+			t.Id,
+			strings.Join(t.Scope, ","),
+			prettyTime{t.CreatedAt},
+			expires,
+			status,
+			t.Description,
+		)
+	}
+}
+
+var (
+	tokenScopes  string
+	tokenExpires time.Duration
+)
+
+var cmdTokenCreate = &Command{
+	Run:      runTokenCreate,
+	Usage:    "token-create [-s <scope>,...] [-e <duration>] <description>",
+	Category: "emp",
+	Short:    "create an access token",
+	Long: `
+Creates a new access token, which can be used by CI systems and other
+automation. The token is only printed once, so make sure to store it
+somewhere safe.
+
+Scopes can be one or more of:
+
+    read    read only operations, like listing apps and releases
+    deploy  deploying, in addition to read only operations
+    full    anything that you can do (the default)
+
+Options:
+
+    -s <scope>,...  comma separated list of scopes
+    -e <duration>   how long until the token expires, up to 8760h (default: never)
+
+Examples:
+
+    $ emp token-create -s deploy -e 2160h CircleCI
+    eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9...
+`,
+}
+
+func init() {
+	cmdTokenCreate.Flag.StringVarP(&tokenScopes, "scope", "s", "", "comma separated list of scopes")
+	cmdTokenCreate.Flag.DurationVarP(&tokenExpires, "expires", "e", 0, "how long until the token expires")
+}
+
+func runTokenCreate(cmd *Command, args []string) {
+	if len(args) != 1 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+
+	var scopes []string
+	if tokenScopes != "" {
+		scopes = strings.Split(tokenScopes, ",")
+	}
+
+	opts := &heroku.OAuthAuthorizationCreateOpts{
+		Description: &args[0],
+	}
+	if tokenExpires != 0 {
+		expires := int(tokenExpires / time.Second)
+		opts.ExpiresIn = &expires
+	}
+
+	auth, err := client.OAuthAuthorizationCreate(scopes, opts)
+	must(err)
+
+	if auth.AccessToken == nil {
+		printFatal("access token missing from response")
+	}
+	fmt.Println(auth.AccessToken.Token)
+}
+
+var cmdTokenRevoke = &Command{
+	Run:      runTokenRevoke,
+	Usage:    "token-revoke <id>",
+	Category: "emp",
+	Short:    "revoke an access token",
+	Long: `
+Revokes an access token, so that it can no longer be used. The id can be
+found with emp tokens.
+
+Examples:
+
+    $ emp token-revoke 0b5c3f3b-2a6d-4f6e-4a2b-9c1b2f0ad5b2
+    Revoked token 0b5c3f3b-2a6d-4f6e-4a2b-9c1b2f0ad5b2.
+`,
+}
+
+func runTokenRevoke(cmd *Command, args []string) {
+	if len(args) != 1 {
+		cmd.PrintUsage()
+		os.Exit(2)
+	}
+	id := args[0]
+	must(client.OAuthAuthorizationDelete(id))
+	log.Printf("Revoked token %s.", id)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/codegangsta/cli"
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/server/cloudformation"
+)
+
+func runEncryptConfig(c *cli.Context) {
+	ctx, err := newContext(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := newDB(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	encrypter, err := newEncrypter(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if encrypter == nil {
+		log.Fatal(fmt.Errorf("--%s or --%s is required", FlagConfigEncryptionKMSKey, FlagConfigEncryptionKeyFile))
+	}
+
+	e := empire.New(db)
+	e.Encrypter = encrypter
+
+	n, err := e.EncryptConfigs()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Encrypted %d configs\n", n)
+
+	n, err = cloudformation.EncryptEnvironments(db.DB.DB(), encrypter)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Encrypted %d ECS environments\n", n)
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"html/template"
+	"io/ioutil"
 	"log"
+	"net/http"
 	"net/url"
 	"os"
 
@@ -19,14 +23,19 @@ import (
 	"github.com/remind101/empire/events/app"
 	"github.com/remind101/empire/events/sns"
 	"github.com/remind101/empire/events/stdout"
+	"github.com/remind101/empire/events/webhook"
 	"github.com/remind101/empire/pkg/dockerauth"
 	"github.com/remind101/empire/pkg/dockerutil"
 	"github.com/remind101/empire/pkg/ecsutil"
+	"github.com/remind101/empire/pkg/envelope"
+	"github.com/remind101/empire/pkg/secrets"
 	"github.com/remind101/empire/pkg/troposphere"
 	"github.com/remind101/empire/scheduler"
 	"github.com/remind101/empire/scheduler/cloudformation"
 	"github.com/remind101/empire/scheduler/docker"
 	"github.com/remind101/empire/scheduler/ecs"
+	"github.com/remind101/empire/scheduler/kubernetes"
+	githubauth "github.com/remind101/empire/server/auth/github"
 	"github.com/remind101/empire/stats"
 	"github.com/remind101/pkg/reporter"
 	"github.com/remind101/pkg/reporter/hb"
@@ -46,7 +55,7 @@ func newEmpire(db *empire.DB, c *Context) (*empire.Empire, error) {
 		return nil, err
 	}
 
-	scheduler, err := newScheduler(db, c)
+	s, err := newScheduler(db, c)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +65,7 @@ func newEmpire(db *empire.DB, c *Context) (*empire.Empire, error) {
 		return nil, err
 	}
 
-	streams, err := newEventStreams(c)
+	streams, err := newEventStreams(db, c)
 	if err != nil {
 		return nil, err
 	}
@@ -66,14 +75,28 @@ func newEmpire(db *empire.DB, c *Context) (*empire.Empire, error) {
 		return nil, err
 	}
 
+	encrypter, err := newEncrypter(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// The release phase runs the `release` process attached, so that a
+	// failure can abort the deploy.
+	if !scheduler.RunsAttached(s) {
+		return nil, fmt.Errorf("the %s scheduler can't run attached processes, which `emp run` and the release phase require", c.String(FlagScheduler))
+	}
+
 	e := empire.New(db)
-	e.Scheduler = scheduler
+	e.Encrypter = encrypter
+	e.Scheduler = s
 	e.Secret = []byte(c.String(FlagSecret))
 	e.EventStream = empire.AsyncEvents(streams)
 	e.ProcfileExtractor = empire.PullAndExtract(docker)
 	e.Environment = c.String(FlagEnvironment)
 	e.RunRecorder = runRecorder
 	e.MessagesRequired = c.Bool(FlagMessagesRequired)
+	e.AutoRollback = c.Bool(FlagAutoRollback)
+	e.MaxReviewApps = c.Int(FlagGithubReviewAppsMax)
 
 	switch c.String(FlagAllowedCommands) {
 	case "procfile":
@@ -85,9 +108,50 @@ func newEmpire(db *empire.DB, c *Context) (*empire.Empire, error) {
 		e.LogsStreamer = logs
 	}
 
+	if c.Bool(FlagSecretReferences) {
+		log.Println("Resolving secret references from SSM Parameter Store and Secrets Manager")
+		e.SecretResolver = secrets.MultiResolver{
+			secrets.SchemeSSM:            secrets.NewSSMResolver(c),
+			secrets.SchemeSecretsManager: secrets.NewSecretsManagerResolver(c),
+		}
+	}
+
+	// When users authenticate with GitHub, permissions can also be granted
+	// to GitHub teams.
+	if client := newGitHubAuthClient(c); client != nil {
+		a := empire.NewPolicyAuthorizer(db)
+		a.Groups = githubauth.NewTeams(client)
+		e.Authorizer = a
+	}
+
 	return e, nil
 }
 
+// Encryption ===========================
+
+func newEncrypter(c *Context) (*envelope.Encrypter, error) {
+	kmsKey, keyFile := c.String(FlagConfigEncryptionKMSKey), c.String(FlagConfigEncryptionKeyFile)
+
+	switch {
+	case kmsKey != "" && keyFile != "":
+		return nil, fmt.Errorf("only one of --%s and --%s can be provided", FlagConfigEncryptionKMSKey, FlagConfigEncryptionKeyFile)
+	case kmsKey != "":
+		log.Println("Encrypting config vars with KMS:")
+		log.Println(fmt.Sprintf("  KeyID: %s", kmsKey))
+		return envelope.NewEncrypter(envelope.NewKMSKeyProvider(kmsKey, c)), nil
+	case keyFile != "":
+		p, err := envelope.LoadKeyFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("Encrypting config vars with a local key:")
+		log.Println(fmt.Sprintf("  KeyFile: %s", keyFile))
+		return envelope.NewEncrypter(p), nil
+	default:
+		return nil, nil
+	}
+}
+
 // Scheduler ============================
 
 func newScheduler(db *empire.DB, c *Context) (scheduler.Scheduler, error) {
@@ -97,12 +161,16 @@ func newScheduler(db *empire.DB, c *Context) (scheduler.Scheduler, error) {
 	)
 
 	switch c.String(FlagScheduler) {
+	case "docker":
+		return newDockerScheduler(c)
 	case "ecs":
 		s, err = newECSScheduler(db, c)
 	case "cloudformation-migration":
 		s, err = newMigrationScheduler(db, c)
 	case "cloudformation":
 		s, err = newCloudFormationScheduler(db, c)
+	case "kubernetes":
+		s, err = newKubernetesScheduler(c)
 	default:
 		return nil, fmt.Errorf("unknown scheduler: %s", c.String(FlagScheduler))
 	}
@@ -121,6 +189,17 @@ func newScheduler(db *empire.DB, c *Context) (scheduler.Scheduler, error) {
 	return a, nil
 }
 
+func newDockerScheduler(c *Context) (*docker.Scheduler, error) {
+	d, err := newDockerClient(c)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Println("Using Docker backend")
+
+	return docker.NewScheduler(d), nil
+}
+
 func newMigrationScheduler(db *empire.DB, c *Context) (*cloudformation.MigrationScheduler, error) {
 	log.Println("Using the CloudFormation Migration backend")
 
@@ -162,6 +241,16 @@ func newCloudFormationScheduler(db *empire.DB, c *Context) (*cloudformation.Sche
 		ExtraOutputs: map[string]troposphere.Output{
 			"EmpireVersion": troposphere.Output{Value: empire.Version},
 		},
+		InternalSharedLoadBalancer: newSharedLoadBalancer(
+			c.String(FlagALBSharedPrivateDNSName),
+			c.String(FlagALBSharedPrivateHTTPListener),
+			c.String(FlagALBSharedPrivateHTTPSListener),
+		),
+		ExternalSharedLoadBalancer: newSharedLoadBalancer(
+			c.String(FlagALBSharedPublicDNSName),
+			c.String(FlagALBSharedPublicHTTPListener),
+			c.String(FlagALBSharedPublicHTTPSListener),
+		),
 	}
 
 	if err := t.Validate(); err != nil {
@@ -180,6 +269,11 @@ func newCloudFormationScheduler(db *empire.DB, c *Context) (*cloudformation.Sche
 	s.Bucket = c.String(FlagS3TemplateBucket)
 	s.Tags = tags
 
+	if c.Bool(FlagAutoRollback) {
+		s.StabilizeTimeout = c.Duration(FlagAutoRollbackTimeout)
+		s.MaxStoppedTasks = c.Int(FlagAutoRollbackMaxStoppedTasks)
+	}
+
 	log.Println("Using CloudFormation backend with the following configuration:")
 	log.Println(fmt.Sprintf("  Cluster: %v", s.Cluster))
 	log.Println(fmt.Sprintf("  InternalSecurityGroupID: %v", t.InternalSecurityGroupID))
@@ -188,10 +282,30 @@ func newCloudFormationScheduler(db *empire.DB, c *Context) (*cloudformation.Sche
 	log.Println(fmt.Sprintf("  ExternalSubnetIDs: %v", t.ExternalSubnetIDs))
 	log.Println(fmt.Sprintf("  ZoneID: %v", zoneID))
 	log.Println(fmt.Sprintf("  LogConfiguration: %v", t.LogConfiguration))
+	if lb := t.InternalSharedLoadBalancer; lb != nil {
+		log.Println(fmt.Sprintf("  InternalSharedLoadBalancer: %v", lb.DNSName))
+	}
+	if lb := t.ExternalSharedLoadBalancer; lb != nil {
+		log.Println(fmt.Sprintf("  ExternalSharedLoadBalancer: %v", lb.DNSName))
+	}
 
 	return s, nil
 }
 
+// newSharedLoadBalancer returns a cloudformation.SharedLoadBalancer, or nil if
+// the shared load balancer isn't configured.
+func newSharedLoadBalancer(dnsName, httpListener, httpsListener string) *cloudformation.SharedLoadBalancer {
+	if dnsName == "" && httpListener == "" && httpsListener == "" {
+		return nil
+	}
+
+	return &cloudformation.SharedLoadBalancer{
+		DNSName:          dnsName,
+		HTTPListenerArn:  httpListener,
+		HTTPSListenerArn: httpsListener,
+	}
+}
+
 // prefixedStackName returns a text/template that prefixes the stack name with
 // the given prefix, if it's set.
 func prefixedStackName(prefix string) *template.Template {
@@ -234,6 +348,37 @@ func newECSScheduler(db *empire.DB, c *Context) (*ecs.Scheduler, error) {
 	return s, nil
 }
 
+func newKubernetesScheduler(c *Context) (*kubernetes.Scheduler, error) {
+	client := kubernetes.NewClient(c.String(FlagKubernetesURL), c.String(FlagKubernetesToken))
+
+	if path := c.String(FlagKubernetesCACert); path != "" {
+		pem, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA certificate: %v", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", path)
+		}
+
+		client.Client = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		}
+	}
+
+	s := kubernetes.NewScheduler(client)
+	s.Namespace = c.String(FlagKubernetesNamespace)
+
+	log.Println("Using Kubernetes backend with the following configuration:")
+	log.Println(fmt.Sprintf("  URL: %v", client.URL))
+	log.Println(fmt.Sprintf("  Namespace: %v", s.Namespace))
+
+	return s, nil
+}
+
 // DockerClient ========================
 
 func newDockerClient(c *Context) (*dockerutil.Client, error) {
@@ -266,7 +411,7 @@ func newKinesisLogsStreamer(c *Context) (empire.LogsStreamer, error) {
 
 // Events ==============================
 
-func newEventStreams(c *Context) (empire.MultiEventStream, error) {
+func newEventStreams(db *empire.DB, c *Context) (empire.MultiEventStream, error) {
 	var streams empire.MultiEventStream
 	switch c.String(FlagEventsBackend) {
 	case "sns":
@@ -293,6 +438,14 @@ func newEventStreams(c *Context) (empire.MultiEventStream, error) {
 		}
 		streams = append(streams, e)
 	}
+
+	if len(c.StringSlice(FlagWebhooks)) > 0 {
+		e, err := newWebhookEventStream(db, c)
+		if err != nil {
+			return streams, err
+		}
+		streams = append(streams, e)
+	}
 	return streams, nil
 }
 
@@ -312,6 +465,34 @@ func newSNSEventStream(c *Context) (empire.EventStream, error) {
 	return e, nil
 }
 
+func newWebhookEventStream(db *empire.DB, c *Context) (empire.EventStream, error) {
+	e := newWebhookDeliveryWorker(db, c)
+
+	for _, s := range c.StringSlice(FlagWebhooks) {
+		w, err := webhook.ParseWebhook(s)
+		if err != nil {
+			return nil, err
+		}
+		e.Webhooks = append(e.Webhooks, w)
+	}
+
+	log.Println("Using webhook events backend with the following configuration:")
+	for _, w := range e.Webhooks {
+		log.Println(fmt.Sprintf("  URL: %s Events: %v", w.URL, w.Events))
+	}
+
+	return e, nil
+}
+
+// newWebhookDeliveryWorker returns a webhook.EventStream that delivers the
+// events that were queued by any Empire instance when it's started.
+func newWebhookDeliveryWorker(db *empire.DB, c *Context) *webhook.EventStream {
+	e := webhook.NewEventStream(db.DB.DB())
+	e.Context = c
+	e.Secret = []byte(c.String(FlagWebhookSecret))
+	return e
+}
+
 func newStdoutEventStream(c *Context) (empire.EventStream, error) {
 	e := stdout.NewEventStream(c)
 	log.Println("Using Stdout events backend")
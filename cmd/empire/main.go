@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"path"
+	"time"
 
 	"github.com/codegangsta/cli"
 	"github.com/remind101/empire"
@@ -20,8 +21,19 @@ const (
 	FlagMessagesRequired = "messages.required"
 	FlagAllowedCommands  = "commands.allowed"
 
+	FlagAutoRollback                = "rollback.auto"
+	FlagAutoRollbackTimeout         = "rollback.auto.timeout"
+	FlagAutoRollbackMaxStoppedTasks = "rollback.auto.max_stopped_tasks"
+
 	FlagStats = "stats"
 
+	FlagConfigGroupsInterval  = "config_groups.interval"
+	FlagConfigGroupsBatchSize = "config_groups.batch_size"
+
+	FlagDeploymentsConcurrency = "deployments.concurrency"
+
+	FlagCanariesInterval = "canaries.interval"
+
 	FlagGithubClient       = "github.client.id"
 	FlagGithubClientSecret = "github.client.secret"
 	FlagGithubOrg          = "github.organization"
@@ -33,6 +45,13 @@ const (
 	FlagGithubDeploymentsImageBuilder  = "github.deployments.image_builder"
 	FlagGithubDeploymentsImageTemplate = "github.deployments.template"
 	FlagGithubDeploymentsTugboatURL    = "github.deployments.tugboat.url"
+	FlagGithubDeploymentsURL           = "github.deployments.url"
+	FlagGithubToken                    = "github.token"
+
+	FlagGithubReviewAppsTemplates = "github.review_apps.templates"
+	FlagGithubReviewAppsURL       = "github.review_apps.url"
+	FlagGithubReviewAppsMax       = "github.review_apps.max"
+	FlagGithubReviewAppsTTL       = "github.review_apps.ttl"
 
 	FlagConveyorURL = "conveyor.url"
 
@@ -55,14 +74,34 @@ const (
 	FlagELBSGPublic  = "elb.sg.public"
 	FlagELBVpcId     = "elb.vpc.id"
 
+	FlagALBSharedPrivateDNSName       = "alb.shared.private.dns"
+	FlagALBSharedPrivateHTTPListener  = "alb.shared.private.listener.http"
+	FlagALBSharedPrivateHTTPSListener = "alb.shared.private.listener.https"
+	FlagALBSharedPublicDNSName        = "alb.shared.public.dns"
+	FlagALBSharedPublicHTTPListener   = "alb.shared.public.listener.http"
+	FlagALBSharedPublicHTTPSListener  = "alb.shared.public.listener.https"
+
 	FlagEC2SubnetsPrivate = "ec2.subnets.private"
 	FlagEC2SubnetsPublic  = "ec2.subnets.public"
 
 	FlagRoute53InternalZoneID = "route53.zoneid.internal"
 
+	FlagKubernetesURL       = "kubernetes.url"
+	FlagKubernetesToken     = "kubernetes.token"
+	FlagKubernetesCACert    = "kubernetes.cacert"
+	FlagKubernetesNamespace = "kubernetes.namespace"
+
 	FlagSNSTopic           = "sns.topic"
 	FlagCloudWatchLogGroup = "cloudwatch.loggroup"
 
+	FlagWebhooks      = "webhooks"
+	FlagWebhookSecret = "webhook.secret"
+
+	FlagConfigEncryptionKMSKey  = "config.encryption.kms.key"
+	FlagConfigEncryptionKeyFile = "config.encryption.keyfile"
+
+	FlagSecretReferences = "secrets.references"
+
 	FlagSecret       = "secret"
 	FlagReporter     = "reporter"
 	FlagRunner       = "runner"
@@ -94,7 +133,7 @@ var Commands = []cli.Command{
 			cli.StringFlag{
 				Name:   FlagScheduler,
 				Value:  "cloudformation-migration",
-				Usage:  "The scheduling backend to use. Current options are `ecs`, `cloudformation-migration`, and `cloudformation`.",
+				Usage:  "The scheduling backend to use. Current options are `ecs`, `cloudformation-migration`, `cloudformation`, `kubernetes` and `docker`.",
 				EnvVar: "EMPIRE_SCHEDULER",
 			},
 			cli.StringFlag{
@@ -157,13 +196,49 @@ var Commands = []cli.Command{
 				Usage:  "If provided, logs from deployments triggered via GitHub deployments will be sent to this tugboat instance.",
 				EnvVar: "EMPIRE_TUGBOAT_URL",
 			},
+			cli.StringFlag{
+				Name:   FlagGithubDeploymentsURL,
+				Value:  "",
+				Usage:  "A Go text/template, executed with the release, that determines the URL that's linked from GitHub deployment statuses (e.g. `https://empire.example.com/apps/{{ .App.Name }}/releases/{{ .Version }}`).",
+				EnvVar: "EMPIRE_GITHUB_DEPLOYMENTS_URL",
+			},
+			cli.StringFlag{
+				Name:   FlagGithubToken,
+				Value:  "",
+				Usage:  "A GitHub access token with the `repo_deployment` scope. If provided, it's used to create GitHub deployments for review apps, and to post deployment statuses when Tugboat isn't configured.",
+				EnvVar: "EMPIRE_GITHUB_TOKEN",
+			},
+			cli.StringFlag{
+				Name:   FlagGithubReviewAppsTemplates,
+				Value:  "",
+				Usage:  "If provided, pull requests are deployed to review apps. A comma separated list of `repo=app` pairs, which map a GitHub repository (e.g. `remind101/acme-inc`) to the app that its review apps are created from.",
+				EnvVar: "EMPIRE_GITHUB_REVIEW_APPS_TEMPLATES",
+			},
+			cli.StringFlag{
+				Name:   FlagGithubReviewAppsURL,
+				Value:  "",
+				Usage:  "A Go text/template, executed with the review app, that determines the URL that's linked from GitHub deployment statuses (e.g. `https://{{ .Name }}.review.example.com`).",
+				EnvVar: "EMPIRE_GITHUB_REVIEW_APPS_URL",
+			},
+			cli.IntFlag{
+				Name:   FlagGithubReviewAppsMax,
+				Value:  10,
+				Usage:  "The maximum number of review apps that can exist at once. 0 means there's no limit.",
+				EnvVar: "EMPIRE_GITHUB_REVIEW_APPS_MAX",
+			},
+			cli.DurationFlag{
+				Name:   FlagGithubReviewAppsTTL,
+				Value:  0,
+				Usage:  "If provided, review apps that haven't been deployed to for this long are destroyed.",
+				EnvVar: "EMPIRE_GITHUB_REVIEW_APPS_TTL",
+			},
 			cli.StringFlag{
 				Name:   FlagConveyorURL,
 				Value:  "",
 				Usage:  "When combined with the `--" + FlagGithubDeploymentsImageBuilder + "` flag when set to `conveyor`, this determines where the location of a Conveyor instance is to perform Docker image builds.",
 				EnvVar: "EMPIRE_CONVEYOR_URL",
 			},
-		}, append(CommonFlags, append(EmpireFlags, DBFlags...)...)...),
+		}, append(CommonFlags, append(EmpireFlags, append(EncryptionFlags, DBFlags...)...)...)...),
 		Action: runServer,
 	},
 	{
@@ -172,6 +247,12 @@ var Commands = []cli.Command{
 		Flags:  append(CommonFlags, DBFlags...),
 		Action: runMigrate,
 	},
+	{
+		Name:   "encrypt-config",
+		Usage:  "Encrypt config vars that were stored before encryption was enabled",
+		Flags:  append(CommonFlags, append(EncryptionFlags, DBFlags...)...),
+		Action: runEncryptConfig,
+	},
 }
 
 var CommonFlags = []cli.Flag{
@@ -204,6 +285,21 @@ var DBFlags = []cli.Flag{
 	},
 }
 
+var EncryptionFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:   FlagConfigEncryptionKMSKey,
+		Value:  "",
+		Usage:  "If provided, config vars will be encrypted at rest with data keys generated from this KMS key (ID, ARN or alias).",
+		EnvVar: "EMPIRE_CONFIG_ENCRYPTION_KMS_KEY",
+	},
+	cli.StringFlag{
+		Name:   FlagConfigEncryptionKeyFile,
+		Value:  "",
+		Usage:  "If provided, config vars will be encrypted at rest with the base64 encoded 256 bit key in this file. Intended for development, when KMS isn't available.",
+		EnvVar: "EMPIRE_CONFIG_ENCRYPTION_KEY_FILE",
+	},
+}
+
 var EmpireFlags = []cli.Flag{
 	cli.StringFlag{
 		Name:   FlagDockerSocket,
@@ -284,6 +380,42 @@ var EmpireFlags = []cli.Flag{
 		Usage:  "The comma separated private subnet ids",
 		EnvVar: "EMPIRE_ELB_VPC_ID",
 	},
+	cli.StringFlag{
+		Name:   FlagALBSharedPrivateDNSName,
+		Value:  "",
+		Usage:  "The DNS name of a shared internal ALB, which apps with LOAD_BALANCER_TYPE=shared will be attached to",
+		EnvVar: "EMPIRE_ALB_SHARED_PRIVATE_DNS",
+	},
+	cli.StringFlag{
+		Name:   FlagALBSharedPrivateHTTPListener,
+		Value:  "",
+		Usage:  "The ARN of the HTTP listener on the shared internal ALB",
+		EnvVar: "EMPIRE_ALB_SHARED_PRIVATE_LISTENER_HTTP",
+	},
+	cli.StringFlag{
+		Name:   FlagALBSharedPrivateHTTPSListener,
+		Value:  "",
+		Usage:  "The ARN of the HTTPS listener on the shared internal ALB",
+		EnvVar: "EMPIRE_ALB_SHARED_PRIVATE_LISTENER_HTTPS",
+	},
+	cli.StringFlag{
+		Name:   FlagALBSharedPublicDNSName,
+		Value:  "",
+		Usage:  "The DNS name of a shared internet facing ALB, which apps with LOAD_BALANCER_TYPE=shared will be attached to",
+		EnvVar: "EMPIRE_ALB_SHARED_PUBLIC_DNS",
+	},
+	cli.StringFlag{
+		Name:   FlagALBSharedPublicHTTPListener,
+		Value:  "",
+		Usage:  "The ARN of the HTTP listener on the shared internet facing ALB",
+		EnvVar: "EMPIRE_ALB_SHARED_PUBLIC_LISTENER_HTTP",
+	},
+	cli.StringFlag{
+		Name:   FlagALBSharedPublicHTTPSListener,
+		Value:  "",
+		Usage:  "The ARN of the HTTPS listener on the shared internet facing ALB",
+		EnvVar: "EMPIRE_ALB_SHARED_PUBLIC_LISTENER_HTTPS",
+	},
 	cli.StringSliceFlag{
 		Name:   FlagEC2SubnetsPrivate,
 		Value:  &cli.StringSlice{},
@@ -314,6 +446,30 @@ var EmpireFlags = []cli.Flag{
 		Usage:  "The route53 zone ID of the internal 'empire.' zone.",
 		EnvVar: "EMPIRE_ROUTE53_INTERNAL_ZONE_ID",
 	},
+	cli.StringFlag{
+		Name:   FlagKubernetesURL,
+		Value:  "",
+		Usage:  "When using the kubernetes backend, the URL of the Kubernetes API server.",
+		EnvVar: "EMPIRE_KUBERNETES_URL",
+	},
+	cli.StringFlag{
+		Name:   FlagKubernetesToken,
+		Value:  "",
+		Usage:  "When using the kubernetes backend, a bearer token used to authenticate with the Kubernetes API server.",
+		EnvVar: "EMPIRE_KUBERNETES_TOKEN",
+	},
+	cli.StringFlag{
+		Name:   FlagKubernetesCACert,
+		Value:  "",
+		Usage:  "When using the kubernetes backend, a path to a CA certificate used to verify the Kubernetes API server.",
+		EnvVar: "EMPIRE_KUBERNETES_CA_CERT",
+	},
+	cli.StringFlag{
+		Name:   FlagKubernetesNamespace,
+		Value:  "default",
+		Usage:  "When using the kubernetes backend, the namespace to create resources in.",
+		EnvVar: "EMPIRE_KUBERNETES_NAMESPACE",
+	},
 	cli.StringFlag{
 		Name:   FlagLogsStreamer,
 		Value:  "",
@@ -338,6 +494,18 @@ var EmpireFlags = []cli.Flag{
 		Usage:  "When using the SNS events backend, this is the SNS topic that gets published to",
 		EnvVar: "EMPIRE_SNS_TOPIC",
 	},
+	cli.StringSliceFlag{
+		Name:   FlagWebhooks,
+		Value:  &cli.StringSlice{},
+		Usage:  "A url to POST events to. Prefix the url with `deploy|rollback=` to only deliver certain types of events. Can be provided multiple times.",
+		EnvVar: "EMPIRE_WEBHOOKS",
+	},
+	cli.StringFlag{
+		Name:   FlagWebhookSecret,
+		Value:  "",
+		Usage:  "If provided, webhook request bodies will be signed with this secret, and the signature provided in the X-Empire-Signature header",
+		EnvVar: "EMPIRE_WEBHOOK_SECRET",
+	},
 	cli.StringFlag{
 		Name:   FlagEnvironment,
 		Value:  "",
@@ -350,6 +518,11 @@ var EmpireFlags = []cli.Flag{
 		Usage:  "When using the `cloudwatch` backend with the `--" + FlagRunLogsBackend + "` flag , this is the log group that CloudWatch log streams will be created in.",
 		EnvVar: "EMPIRE_CLOUDWATCH_LOG_GROUP",
 	},
+	cli.BoolFlag{
+		Name:   FlagSecretReferences,
+		Usage:  "If true, config vars can reference secrets in SSM Parameter Store (ssm://) and Secrets Manager (secretsmanager://), which are resolved when apps are released or run.",
+		EnvVar: "EMPIRE_SECRET_REFERENCES",
+	},
 	cli.BoolFlag{
 		Name:   FlagMessagesRequired,
 		Usage:  "If true, messages will be required for empire actions that emit events.",
@@ -361,6 +534,47 @@ var EmpireFlags = []cli.Flag{
 		Usage:  "Specifies what commands are allowed when using `emp run`. Can be `any`, or `procfile`.",
 		EnvVar: "EMPIRE_ALLOWED_COMMANDS",
 	},
+	cli.BoolFlag{
+		Name:   FlagAutoRollback,
+		Usage:  "If true, streamed deployments that fail to stabilize will be automatically rolled back to the previous release. Only supported by the `cloudformation` scheduler.",
+		EnvVar: "EMPIRE_AUTO_ROLLBACK",
+	},
+	cli.DurationFlag{
+		Name:   FlagAutoRollbackTimeout,
+		Value:  10 * time.Minute,
+		Usage:  "When auto rollback is enabled, how long to wait for a deployment to stabilize before rolling it back.",
+		EnvVar: "EMPIRE_AUTO_ROLLBACK_TIMEOUT",
+	},
+	cli.IntFlag{
+		Name:   FlagAutoRollbackMaxStoppedTasks,
+		Value:  3,
+		Usage:  "When auto rollback is enabled, the number of tasks that can stop during a deployment before it's rolled back.",
+		EnvVar: "EMPIRE_AUTO_ROLLBACK_MAX_STOPPED_TASKS",
+	},
+	cli.DurationFlag{
+		Name:   FlagConfigGroupsInterval,
+		Value:  empire.DefaultConfigGroupReleaseInterval,
+		Usage:  "How often to release a batch of the apps that a changed config group is attached to.",
+		EnvVar: "EMPIRE_CONFIG_GROUPS_INTERVAL",
+	},
+	cli.IntFlag{
+		Name:   FlagConfigGroupsBatchSize,
+		Value:  empire.DefaultConfigGroupReleaseBatchSize,
+		Usage:  "The maximum number of apps to release in each batch after a config group changes.",
+		EnvVar: "EMPIRE_CONFIG_GROUPS_BATCH_SIZE",
+	},
+	cli.IntFlag{
+		Name:   FlagDeploymentsConcurrency,
+		Value:  empire.DefaultDeploymentConcurrency,
+		Usage:  "The maximum number of queued deployments that this instance will perform at once. Deployments to the same app are always performed one at a time.",
+		EnvVar: "EMPIRE_DEPLOYMENTS_CONCURRENCY",
+	},
+	cli.DurationFlag{
+		Name:   FlagCanariesInterval,
+		Value:  empire.DefaultCanaryInterval,
+		Usage:  "How often to check on running canaries, which are aborted if too many requests to them fail, and otherwise move on to the next step once the pause is over.",
+		EnvVar: "EMPIRE_CANARIES_INTERVAL",
+	},
 	cli.BoolFlag{
 		Name:   FlagXShowAttached,
 		Usage:  "If true, attached runs will be shown in `emp ps` output.",
@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
 	"text/template"
 	"time"
 
 	"github.com/codegangsta/cli"
+	githubapi "github.com/google/go-github/github"
 	"github.com/remind101/conveyor/client/conveyor"
 	"github.com/remind101/empire"
 	"github.com/remind101/empire/server"
@@ -64,6 +66,44 @@ func runServer(c *cli.Context) {
 		go p.Start()
 	}
 
+	if len(c.StringSlice(FlagWebhooks)) > 0 {
+		ww := newWebhookDeliveryWorker(db, ctx)
+		log.Println("Starting webhook delivery worker")
+		go ww.Start()
+	}
+
+	w := empire.NewScaleScheduleWorker(e)
+	w.Context = ctx
+	log.Println("Starting scale schedule worker")
+	go w.Start()
+
+	cw := empire.NewConfigGroupReleaseWorker(e)
+	cw.Context = ctx
+	cw.Interval = c.Duration(FlagConfigGroupsInterval)
+	cw.BatchSize = c.Int(FlagConfigGroupsBatchSize)
+	log.Println("Starting config group release worker")
+	go cw.Start()
+
+	dw := empire.NewDeploymentWorker(e)
+	dw.Context = ctx
+	dw.Concurrency = c.Int(FlagDeploymentsConcurrency)
+	log.Println("Starting deployment worker")
+	go dw.Start()
+
+	kw := empire.NewCanaryWorker(e)
+	kw.Context = ctx
+	kw.Interval = c.Duration(FlagCanariesInterval)
+	log.Println("Starting canary worker")
+	go kw.Start()
+
+	if ttl := c.Duration(FlagGithubReviewAppsTTL); ttl != 0 {
+		rw := empire.NewReviewAppExpiryWorker(e)
+		rw.Context = ctx
+		rw.TTL = ttl
+		log.Printf("Starting review app expiry worker (ttl: %v)", ttl)
+		go rw.Start()
+	}
+
 	s := newServer(ctx, e)
 	log.Printf("Starting on port %s", port)
 	log.Fatal(http.ListenAndServe(":"+port, s))
@@ -76,6 +116,14 @@ func newServer(c *Context, e *empire.Empire) http.Handler {
 	opts.GitHub.Deployments.Environments = strings.Split(c.String(FlagGithubDeploymentsEnvironments), ",")
 	opts.GitHub.Deployments.ImageBuilder = newImageBuilder(c)
 	opts.GitHub.Deployments.TugboatURL = c.String(FlagGithubDeploymentsTugboatURL)
+	if url := c.String(FlagGithubDeploymentsURL); url != "" {
+		opts.GitHub.Deployments.URL = template.Must(template.New("url").Parse(url))
+	}
+	opts.GitHub.Client = newGitHubDeploymentsClient(c)
+	opts.GitHub.ReviewApps.Templates = parseReviewAppTemplates(c.String(FlagGithubReviewAppsTemplates))
+	if url := c.String(FlagGithubReviewAppsURL); url != "" {
+		opts.GitHub.ReviewApps.URL = template.Must(template.New("url").Parse(url))
+	}
 
 	h := middleware.Common(server.New(e, opts))
 	return middleware.Handler(c, h)
@@ -123,19 +171,12 @@ func newAuthenticator(c *Context, e *empire.Empire) auth.Authenticator {
 			Name: "fake",
 		}))
 	} else {
-		config := &oauth2.Config{
-			ClientID:     c.String(FlagGithubClient),
-			ClientSecret: c.String(FlagGithubClientSecret),
-			Scopes:       []string{"repo_deployment", "read:org"},
-		}
-
-		client = githubauth.NewClient(config)
-		client.URL = c.String(FlagGithubApiURL)
+		client = newGitHubAuthClient(c)
 
 		log.Println("Using GitHub authentication backend with the following configuration:")
-		log.Println(fmt.Sprintf("  ClientID: %v", config.ClientID))
+		log.Println(fmt.Sprintf("  ClientID: %v", client.ClientID))
 		log.Println(fmt.Sprintf("  ClientSecret: ****"))
-		log.Println(fmt.Sprintf("  Scopes: %v", config.Scopes))
+		log.Println(fmt.Sprintf("  Scopes: %v", client.Scopes))
 		log.Println(fmt.Sprintf("  GitHubAPI: %v", client.URL))
 
 		// an authenticator for authenticating requests with a users github
@@ -179,3 +220,57 @@ func newAuthenticator(c *Context, e *empire.Empire) auth.Authenticator {
 
 	return authenticator
 }
+
+// newGitHubAuthClient returns a client for authenticating users with GitHub,
+// or nil if a GitHub client id isn't configured.
+// newGitHubDeploymentsClient returns a client for the GitHub Deployments API, or
+// nil if no GitHub access token was provided.
+func newGitHubDeploymentsClient(c *Context) github.DeploymentsClient {
+	token := c.String(FlagGithubToken)
+	if token == "" {
+		return nil
+	}
+
+	client := githubapi.NewClient(oauth2.NewClient(oauth2.NoContext, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})))
+	if u := c.String(FlagGithubApiURL); u != "" {
+		baseURL, err := url.Parse(strings.TrimSuffix(u, "/") + "/")
+		if err != nil {
+			panic(err)
+		}
+		client.BaseURL = baseURL
+	}
+	return client.Repositories
+}
+
+// parseReviewAppTemplates parses a list of repo=app pairs.
+func parseReviewAppTemplates(s string) map[string]string {
+	if s == "" {
+		return nil
+	}
+
+	templates := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			panic(fmt.Sprintf("invalid review app template: %s", pair))
+		}
+		templates[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return templates
+}
+
+func newGitHubAuthClient(c *Context) *githubauth.Client {
+	if c.String(FlagGithubClient) == "" {
+		return nil
+	}
+
+	config := &oauth2.Config{
+		ClientID:     c.String(FlagGithubClient),
+		ClientSecret: c.String(FlagGithubClientSecret),
+		Scopes:       []string{"repo_deployment", "read:org"},
+	}
+
+	client := githubauth.NewClient(config)
+	client.URL = c.String(FlagGithubApiURL)
+	return client
+}
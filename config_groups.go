@@ -0,0 +1,644 @@
+package empire
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/pkg/reporter"
+	"github.com/remind101/pkg/timex"
+	"golang.org/x/net/context"
+)
+
+const (
+	// DefaultConfigGroupReleaseInterval is the default amount of time to
+	// wait between releasing batches of apps after a config group changes.
+	DefaultConfigGroupReleaseInterval = 30 * time.Second
+
+	// DefaultConfigGroupReleaseBatchSize is the default number of apps that
+	// are released in each batch after a config group changes.
+	DefaultConfigGroupReleaseBatchSize = 5
+
+	// configGroupReleaseClaimTimeout is how long a queued release is claimed
+	// for. If the Empire instance that claimed it stops before the app is
+	// released, it's released again once the claim expires.
+	configGroupReleaseClaimTimeout = 5 * time.Minute
+
+	// maxConfigGroupReleaseBackoff is the longest to wait before retrying
+	// a release that failed.
+	maxConfigGroupReleaseBackoff = time.Hour
+)
+
+// ErrInvalidConfigGroupName is returned when the name of a config group isn't
+// valid.
+var ErrInvalidConfigGroupName = &ValidationError{
+	errors.New("A config group name must be alphanumeric and dashes only, 3-30 chars in length."),
+}
+
+// ConfigGroup is a named set of config vars that's shared by multiple apps.
+// Apps that are attached to a config group inherit its vars, but the app's own
+// vars take precedence.
+type ConfigGroup struct {
+	// A unique uuid that identifies the config group.
+	ID string
+
+	// The unique name of the config group.
+	Name string
+
+	// The environment variables in this config group.
+	Vars Vars
+
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+}
+
+// BeforeCreate sets created_at and updated_at before inserting.
+func (g *ConfigGroup) BeforeCreate() error {
+	t := timex.Now()
+	g.CreatedAt = &t
+	g.UpdatedAt = &t
+	return nil
+}
+
+// ConfigGroupsQuery is a scope implementation for common things to filter
+// config groups by.
+type ConfigGroupsQuery struct {
+	// If provided, finds the config group with the given id.
+	ID *string
+
+	// If provided, finds the config group with the given name.
+	Name *string
+
+	// If provided, finds the config groups that the app is attached to.
+	App *App
+}
+
+// scope implements the scope interface.
+func (q ConfigGroupsQuery) scope(db *gorm.DB) *gorm.DB {
+	var scope composedScope
+
+	if q.ID != nil {
+		scope = append(scope, idEquals(*q.ID))
+	}
+
+	if q.Name != nil {
+		scope = append(scope, fieldEquals("name", *q.Name))
+	}
+
+	if q.App != nil {
+		scope = append(scope, scopeFunc(func(db *gorm.DB) *gorm.DB {
+			return db.Where("id IN (SELECT config_group_id FROM app_config_groups WHERE app_id = ?)", q.App.ID)
+		}))
+	}
+
+	scope = append(scope, order("name"))
+
+	return scope.scope(db)
+}
+
+// SetConfigGroupOpts are options provided when setting config vars on a config
+// group.
+type SetConfigGroupOpts struct {
+	// User performing the action.
+	User *User
+
+	// The name of the config group. The config group is created if it
+	// doesn't exist.
+	Name string
+
+	// The new vars to merge into the config group's vars.
+	Vars Vars
+
+	// Commit message
+	Message string
+}
+
+func (opts SetConfigGroupOpts) Event() ConfigGroupSetEvent {
+	var changed []string
+	for k := range opts.Vars {
+		changed = append(changed, string(k))
+	}
+	sort.Strings(changed)
+
+	return ConfigGroupSetEvent{
+		User:    opts.User.Name,
+		Group:   opts.Name,
+		Changed: changed,
+		Message: opts.Message,
+	}
+}
+
+func (opts SetConfigGroupOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	if !NamePattern.Match([]byte(opts.Name)) {
+		return ErrInvalidConfigGroupName
+	}
+
+	if err := e.validateVars(opts.Vars); err != nil {
+		return err
+	}
+
+	// Access to the apps that the config group is attached to is checked
+	// when it's set, in the same transaction.
+	return e.authorize(opts.User, ActionConfigGroup, &App{Name: Wildcard})
+}
+
+// DestroyConfigGroupOpts are options provided when destroying a config group.
+type DestroyConfigGroupOpts struct {
+	// User performing the action.
+	User *User
+
+	// The config group to destroy.
+	Group *ConfigGroup
+
+	// Commit message
+	Message string
+}
+
+func (opts DestroyConfigGroupOpts) Event() ConfigGroupDestroyEvent {
+	return ConfigGroupDestroyEvent{
+		User:    opts.User.Name,
+		Group:   opts.Group.Name,
+		Message: opts.Message,
+	}
+}
+
+func (opts DestroyConfigGroupOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	return e.authorize(opts.User, ActionConfigGroup, &App{Name: Wildcard})
+}
+
+// AttachConfigGroupOpts are options provided when attaching a config group to
+// an app, or detaching it.
+type AttachConfigGroupOpts struct {
+	// User performing the action.
+	User *User
+
+	// The associated app.
+	App *App
+
+	// The config group to attach, or detach.
+	Group *ConfigGroup
+
+	// Commit message
+	Message string
+}
+
+func (opts AttachConfigGroupOpts) Event() ConfigGroupAttachEvent {
+	return ConfigGroupAttachEvent{
+		User:    opts.User.Name,
+		App:     opts.App.Name,
+		Group:   opts.Group.Name,
+		Message: opts.Message,
+		app:     opts.App,
+	}
+}
+
+func (opts AttachConfigGroupOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	return e.authorize(opts.User, ActionSet, opts.App)
+}
+
+// ReadConfigGroupOpts are options provided when reading the config vars of a
+// config group.
+type ReadConfigGroupOpts struct {
+	// User performing the action.
+	User *User
+
+	// The config group to read.
+	Group *ConfigGroup
+
+	// When true, the values of config vars are returned in full, rather
+	// than masked.
+	Reveal bool
+}
+
+func (opts ReadConfigGroupOpts) Event() ConfigEvent {
+	return ConfigEvent{
+		User:     opts.User.Name,
+		Group:    opts.Group.Name,
+		Revealed: opts.Reveal,
+	}
+}
+
+func (opts ReadConfigGroupOpts) Validate(e *Empire) error {
+	if !opts.Reveal {
+		return nil
+	}
+
+	// Revealing a config group reveals config vars on every app that's
+	// attached to it.
+	as, err := apps(e.db, configGroupAppsScope(opts.Group.Name))
+	if err != nil {
+		return err
+	}
+
+	for _, app := range as {
+		if err := e.authorize(opts.User, ActionReveal, app); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// configGroupsFind returns the first matching config group.
+func configGroupsFind(db *gorm.DB, scope scope) (*ConfigGroup, error) {
+	var group ConfigGroup
+	return &group, first(db, scope, &group)
+}
+
+// configGroups returns all config groups matching the scope.
+func configGroups(db *gorm.DB, scope scope) ([]*ConfigGroup, error) {
+	var groups []*ConfigGroup
+	return groups, find(db, scope, &groups)
+}
+
+func configGroupsCreate(db *gorm.DB, group *ConfigGroup) (*ConfigGroup, error) {
+	return group, db.Create(group).Error
+}
+
+func configGroupsUpdate(db *gorm.DB, group *ConfigGroup) error {
+	t := timex.Now()
+	group.UpdatedAt = &t
+	return db.Save(group).Error
+}
+
+func configGroupsDestroy(db *gorm.DB, group *ConfigGroup) error {
+	return db.Delete(group).Error
+}
+
+// configGroupsLock locks the named config group until the transaction ends, so
+// that it can't be attached to apps while it's being changed. Attaching a
+// config group takes a shared lock.
+func configGroupsLock(db *gorm.DB, name string, exclusive bool) error {
+	mode := "SHARE"
+	if exclusive {
+		mode = "UPDATE"
+	}
+	return db.Exec(fmt.Sprintf(`SELECT id FROM config_groups WHERE name = ? FOR %s`, mode), name).Error
+}
+
+// configGroupAppsScope returns a scope that finds the apps that are attached
+// to the named config group.
+func configGroupAppsScope(name string) scope {
+	return scopeFunc(func(db *gorm.DB) *gorm.DB {
+		return db.Where("id IN (SELECT a.app_id FROM app_config_groups a JOIN config_groups g ON g.id = a.config_group_id WHERE g.name = ?)", name)
+	})
+}
+
+// configGroupReleasesEnqueue queues a release for every app that's attached to
+// the config group. An app that's already queued is only released once. If
+// it's being released, or waiting to be retried, it's released again as soon
+// as possible.
+func configGroupReleasesEnqueue(db *gorm.DB, group *ConfigGroup, user *User, message string) error {
+	return db.Exec(`INSERT INTO config_group_releases (app_id, user_name, message)
+SELECT app_id, ?, ? FROM app_config_groups WHERE config_group_id = ?
+ON CONFLICT (app_id) DO UPDATE SET user_name = excluded.user_name, message = excluded.message, attempts = 0, next_attempt_at = excluded.next_attempt_at, last_error = NULL`, user.Name, message, group.ID).Error
+}
+
+// configGroupRelease is an app that's queued to be released after a config
+// group changed.
+type configGroupRelease struct {
+	AppID    string
+	UserName string
+	Message  string
+
+	// The number of times that the release has been claimed, including
+	// this one.
+	Attempts int
+}
+
+// configGroupReleasesClaim claims up to n apps that are due to be released,
+// oldest first, by moving their next attempt past the claim timeout. Rows
+// that are locked by another Empire instance are skipped.
+func configGroupReleasesClaim(db *gorm.DB, n int) ([]*configGroupRelease, error) {
+	rows, err := db.Raw(`UPDATE config_group_releases SET attempts = attempts + 1, next_attempt_at = (now() at time zone 'utc') + ? * interval '1 second' WHERE app_id IN (
+  SELECT app_id FROM config_group_releases WHERE next_attempt_at <= (now() at time zone 'utc') ORDER BY queued_at LIMIT ? FOR UPDATE SKIP LOCKED
+) RETURNING app_id, user_name, message, attempts`, configGroupReleaseClaimTimeout.Seconds(), n).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var releases []*configGroupRelease
+	for rows.Next() {
+		var r configGroupRelease
+		if err := rows.Scan(&r.AppID, &r.UserName, &r.Message, &r.Attempts); err != nil {
+			return nil, err
+		}
+		releases = append(releases, &r)
+	}
+
+	return releases, rows.Err()
+}
+
+// configGroupReleasesDone removes a claimed release from the queue. If the app
+// was queued again after it was claimed, it's left in the queue.
+func configGroupReleasesDone(db *gorm.DB, r *configGroupRelease) error {
+	return db.Exec(`DELETE FROM config_group_releases WHERE app_id = ? AND attempts = ?`, r.AppID, r.Attempts).Error
+}
+
+// configGroupReleasesRetry schedules a claimed release to be retried, backing
+// off exponentially with the number of attempts.
+func configGroupReleasesRetry(db *gorm.DB, r *configGroupRelease, releaseErr error) error {
+	backoff := configGroupReleaseBackoff(r.Attempts)
+	return db.Exec(`UPDATE config_group_releases SET next_attempt_at = (now() at time zone 'utc') + ? * interval '1 second', last_error = ? WHERE app_id = ? AND attempts = ?`, backoff.Seconds(), releaseErr.Error(), r.AppID, r.Attempts).Error
+}
+
+// configGroupReleaseBackoff returns how long to wait before the next attempt,
+// after the given number of attempts failed.
+func configGroupReleaseBackoff(attempts int) time.Duration {
+	backoff := DefaultConfigGroupReleaseInterval
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= maxConfigGroupReleaseBackoff {
+			return maxConfigGroupReleaseBackoff
+		}
+	}
+	return backoff
+}
+
+// configGroupVars returns the decrypted vars that the app inherits from its
+// config groups. Config groups that were attached later take precedence.
+func (e *Empire) configGroupVars(db *gorm.DB, app *App) (Vars, error) {
+	rows, err := db.Raw(`SELECT g.vars FROM config_groups g JOIN app_config_groups a ON a.config_group_id = g.id WHERE a.app_id = ? ORDER BY a.created_at`, app.ID).Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []Vars
+	for rows.Next() {
+		var vars Vars
+		if err := rows.Scan(&vars); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		groups = append(groups, vars)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	merged := make(Vars)
+	for _, vars := range groups {
+		decrypted, err := e.decryptVars(vars)
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeVars(merged, decrypted)
+	}
+
+	return merged, nil
+}
+
+type configGroupsService struct {
+	*Empire
+}
+
+// Set merges the new vars into the config group, creating it if it doesn't
+// exist, and queues a release for every app that's attached to it. It returns
+// the config group, with its vars decrypted, and the attached apps.
+func (s *configGroupsService) Set(ctx context.Context, db *gorm.DB, opts SetConfigGroupOpts) (*ConfigGroup, []*App, error) {
+	if err := configGroupsLock(db, opts.Name, true); err != nil {
+		return nil, nil, err
+	}
+
+	// Changing a config group changes the config of every app that's
+	// attached to it.
+	as, err := apps(db, configGroupAppsScope(opts.Name))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, app := range as {
+		if err := s.authorize(opts.User, ActionSet, app); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	group, err := configGroupsFind(db, ConfigGroupsQuery{Name: &opts.Name})
+	if err != nil {
+		if err != gorm.RecordNotFound {
+			return nil, nil, err
+		}
+		group = &ConfigGroup{Name: opts.Name}
+	}
+
+	old, err := s.decryptVars(group.Vars)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vars := mergeVars(old, opts.Vars)
+
+	encrypted, err := s.encryptVars(vars)
+	if err != nil {
+		return nil, nil, err
+	}
+	group.Vars = encrypted[0]
+
+	if group.ID == "" {
+		group, err = configGroupsCreate(db, group)
+	} else {
+		err = configGroupsUpdate(db, group)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := configGroupReleasesEnqueue(db, group, opts.User, opts.Message); err != nil {
+		return nil, nil, err
+	}
+
+	decrypted := *group
+	decrypted.Vars = vars
+	return &decrypted, as, nil
+}
+
+// Destroy destroys a config group that isn't attached to any apps.
+func (s *configGroupsService) Destroy(ctx context.Context, db *gorm.DB, opts DestroyConfigGroupOpts) error {
+	if err := configGroupsLock(db, opts.Group.Name, true); err != nil {
+		return err
+	}
+
+	as, err := apps(db, configGroupAppsScope(opts.Group.Name))
+	if err != nil {
+		return err
+	}
+
+	if len(as) > 0 {
+		return &ValidationError{Err: fmt.Errorf("config group %s is still attached to %d apps", opts.Group.Name, len(as))}
+	}
+
+	return configGroupsDestroy(db, opts.Group)
+}
+
+// Attach attaches the config group to the app, and releases the app with the
+// config group's vars.
+func (s *configGroupsService) Attach(ctx context.Context, db *gorm.DB, opts AttachConfigGroupOpts) error {
+	if err := configGroupsLock(db, opts.Group.Name, false); err != nil {
+		return err
+	}
+
+	groups, err := configGroups(db, ConfigGroupsQuery{App: opts.App})
+	if err != nil {
+		return err
+	}
+
+	for _, g := range groups {
+		if g.ID == opts.Group.ID {
+			return &ValidationError{Err: fmt.Errorf("config group %s is already attached to %s", opts.Group.Name, opts.App.Name)}
+		}
+	}
+
+	if err := db.Exec(`INSERT INTO app_config_groups (app_id, config_group_id) VALUES (?, ?)`, opts.App.ID, opts.Group.ID).Error; err != nil {
+		return err
+	}
+
+	desc := appendMessageToDescription(fmt.Sprintf("Attach config group %s", opts.Group.Name), opts.User, opts.Message)
+	return s.configs.ApplyGroups(ctx, db, opts.App, desc)
+}
+
+// Detach detaches the config group from the app, and releases the app without
+// the config group's vars.
+func (s *configGroupsService) Detach(ctx context.Context, db *gorm.DB, opts AttachConfigGroupOpts) error {
+	r := db.Exec(`DELETE FROM app_config_groups WHERE app_id = ? AND config_group_id = ?`, opts.App.ID, opts.Group.ID)
+	if err := r.Error; err != nil {
+		return err
+	}
+
+	if r.RowsAffected == 0 {
+		return &ValidationError{Err: fmt.Errorf("config group %s isn't attached to %s", opts.Group.Name, opts.App.Name)}
+	}
+
+	desc := appendMessageToDescription(fmt.Sprintf("Detach config group %s", opts.Group.Name), opts.User, opts.Message)
+	return s.configs.ApplyGroups(ctx, db, opts.App, desc)
+}
+
+// RunConfigGroupReleases releases up to n of the apps that are queued after a
+// config group changed. Each app gets a new release with the current vars from
+// its config groups, and is removed from the queue once the release is
+// committed. An app that fails to release is retried, with an exponential
+// backoff.
+func (e *Empire) RunConfigGroupReleases(ctx context.Context, n int) error {
+	tx := e.db.Begin()
+
+	releases, err := configGroupReleasesClaim(tx, n)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	for _, r := range releases {
+		if err := e.runConfigGroupRelease(ctx, r); err != nil {
+			reporter.Report(ctx, fmt.Errorf("error releasing app %s after a config group changed (attempt %d): %v", r.AppID, r.Attempts, err))
+
+			if err := configGroupReleasesRetry(e.db, r, err); err != nil {
+				reporter.Report(ctx, fmt.Errorf("error scheduling a retry to release app %s: %v", r.AppID, err))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (e *Empire) runConfigGroupRelease(ctx context.Context, r *configGroupRelease) error {
+	tx := e.db.Begin()
+
+	app, err := appsFind(tx, AppsQuery{ID: &r.AppID})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	desc := appendMessageToDescription("Update config groups", &User{Name: r.UserName}, r.Message)
+	if err := e.configs.ApplyGroups(ctx, tx, app, desc); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := configGroupReleasesDone(tx, r); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// ConfigGroupReleaseWorker periodically releases batches of apps after their
+// config groups change, so that changing a config group that's shared by many
+// apps doesn't release all of them at once.
+type ConfigGroupReleaseWorker struct {
+	// Root context.Context to use. If a reporter.Reporter is embedded,
+	// errors generated will be reporter there.
+	Context context.Context
+
+	// The amount of time to wait between batches. The zero value is
+	// DefaultConfigGroupReleaseInterval.
+	Interval time.Duration
+
+	// The maximum number of apps to release in each batch. The zero value
+	// is DefaultConfigGroupReleaseBatchSize.
+	BatchSize int
+
+	empire  *Empire
+	stopped chan struct{}
+}
+
+// NewConfigGroupReleaseWorker returns a new ConfigGroupReleaseWorker.
+func NewConfigGroupReleaseWorker(e *Empire) *ConfigGroupReleaseWorker {
+	return &ConfigGroupReleaseWorker{
+		Context: context.Background(),
+		empire:  e,
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start starts releasing apps. It blocks until Stop is called.
+func (w *ConfigGroupReleaseWorker) Start() {
+	interval := w.Interval
+	if interval == 0 {
+		interval = DefaultConfigGroupReleaseInterval
+	}
+
+	batchSize := w.BatchSize
+	if batchSize == 0 {
+		batchSize = DefaultConfigGroupReleaseBatchSize
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-w.stopped:
+			return
+		case <-t.C:
+			if err := w.empire.RunConfigGroupReleases(w.Context, batchSize); err != nil {
+				reporter.Report(w.Context, err)
+			}
+		}
+	}
+}
+
+// Stop stops releasing apps.
+func (w *ConfigGroupReleaseWorker) Stop() {
+	close(w.stopped)
+}
@@ -0,0 +1,25 @@
+package empire
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigGroupReleaseBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		backoff  time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{8, time.Hour},
+		{100, time.Hour},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.backoff, configGroupReleaseBackoff(tt.attempts))
+	}
+}
@@ -3,15 +3,22 @@ package empire
 import (
 	"database/sql"
 	"database/sql/driver"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
 
 	"github.com/jinzhu/gorm"
 	"github.com/lib/pq/hstore"
+	"github.com/remind101/empire/pkg/envelope"
+	"github.com/remind101/empire/pkg/secrets"
 	"golang.org/x/net/context"
 )
 
+// ErrNoEncrypter is returned when a config has encrypted values, but Empire
+// isn't configured with an Encrypter to decrypt them.
+var ErrNoEncrypter = errors.New("config vars are encrypted, but no encryption key is configured")
+
 // Config represents a collection of environment variables.
 type Config struct {
 	// A unique uuid representing this Config.
@@ -20,6 +27,11 @@ type Config struct {
 	// The environment variables in this config.
 	Vars Vars
 
+	// The environment variables that were inherited from the app's config
+	// groups when this config was created. Vars take precedence over
+	// GroupVars.
+	GroupVars Vars
+
 	// The id of the app that this config relates to.
 	AppID string
 
@@ -33,8 +45,20 @@ func newConfig(old *Config, vars Vars) *Config {
 	v := mergeVars(old.Vars, vars)
 
 	return &Config{
-		AppID: old.AppID,
-		Vars:  v,
+		AppID:     old.AppID,
+		Vars:      v,
+		GroupVars: old.GroupVars,
+	}
+}
+
+// flattenConfig returns a copy of the config, with the vars from config groups
+// merged into Vars. This is the environment that the app actually gets.
+func flattenConfig(c *Config) *Config {
+	return &Config{
+		ID:    c.ID,
+		AppID: c.AppID,
+		App:   c.App,
+		Vars:  mergeVars(c.GroupVars, c.Vars),
 	}
 }
 
@@ -132,7 +156,63 @@ func (s *configsService) Set(ctx context.Context, db *gorm.DB, opts SetOpts) (*C
 		return nil, err
 	}
 
-	c, err := configsCreate(db, newConfig(old, vars))
+	old, err = s.decryptConfig(old)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newConfig(old, vars)
+
+	// Pick up any changes to the app's config groups that haven't been
+	// released yet.
+	c.GroupVars, err = s.configGroupVars(db, app)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.release(ctx, db, app, c, configsApplyReleaseDesc(opts))
+}
+
+// ApplyGroups creates a new config for the app with the current vars from its
+// config groups, and releases it if the app has been released. Nothing happens
+// if the vars from the app's config groups haven't changed.
+func (s *configsService) ApplyGroups(ctx context.Context, db *gorm.DB, app *App, desc string) error {
+	old, err := s.Config(db, app)
+	if err != nil {
+		return err
+	}
+
+	old, err = s.decryptConfig(old)
+	if err != nil {
+		return err
+	}
+
+	groupVars, err := s.configGroupVars(db, app)
+	if err != nil {
+		return err
+	}
+
+	if len(diffVars(old.GroupVars, groupVars)) == 0 {
+		return nil
+	}
+
+	_, err = s.release(ctx, db, app, &Config{
+		AppID:     app.ID,
+		Vars:      old.Vars,
+		GroupVars: groupVars,
+	}, desc)
+	return err
+}
+
+// release encrypts and stores the new config, and creates a new release with
+// it if the app has been released.
+func (s *configsService) release(ctx context.Context, db *gorm.DB, app *App, c *Config, desc string) (*Config, error) {
+	c, err := s.encryptConfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err = configsCreate(db, c)
 	if err != nil {
 		return c, err
 	}
@@ -151,7 +231,7 @@ func (s *configsService) Set(ctx context.Context, db *gorm.DB, opts SetOpts) (*C
 		App:         release.App,
 		Config:      c,
 		Slug:        release.Slug,
-		Description: configsApplyReleaseDesc(opts),
+		Description: desc,
 	}, nil)
 	return c, err
 }
@@ -184,6 +264,313 @@ func (s *configsService) Config(db *gorm.DB, app *App) (*Config, error) {
 	return r.Config, nil
 }
 
+// encryptConfig returns a copy of the config with all of its values encrypted
+// with a single data key. If no Encrypter is configured, the config is
+// returned as is.
+func (e *Empire) encryptConfig(c *Config) (*Config, error) {
+	encrypted, err := e.encryptVars(c.Vars, c.GroupVars)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		ID:        c.ID,
+		AppID:     c.AppID,
+		App:       c.App,
+		Vars:      encrypted[0],
+		GroupVars: encrypted[1],
+	}, nil
+}
+
+// decryptConfig returns a copy of the config with all of its values
+// decrypted. Values that were stored before encryption was enabled are
+// returned as is.
+func (e *Empire) decryptConfig(c *Config) (*Config, error) {
+	vars, err := e.decryptVars(c.Vars)
+	if err != nil {
+		return nil, err
+	}
+
+	groupVars, err := e.decryptVars(c.GroupVars)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Config{
+		ID:        c.ID,
+		AppID:     c.AppID,
+		App:       c.App,
+		Vars:      vars,
+		GroupVars: groupVars,
+	}, nil
+}
+
+// encryptVars returns copies of the vars with all of their values encrypted
+// with a single data key. If no Encrypter is configured, the vars are returned
+// as is.
+func (e *Empire) encryptVars(vs ...Vars) ([]Vars, error) {
+	if e.Encrypter == nil {
+		return vs, nil
+	}
+
+	var names [][]Variable
+	var values []string
+	for _, vars := range vs {
+		var n []Variable
+		for k, v := range vars {
+			n = append(n, k)
+			values = append(values, *v)
+		}
+		names = append(names, n)
+	}
+
+	if len(values) == 0 {
+		return vs, nil
+	}
+
+	encrypted, err := e.Encrypter.Encrypt(values...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Vars, len(vs))
+	for i, n := range names {
+		if vs[i] == nil {
+			continue
+		}
+
+		result[i] = make(Vars)
+		for _, k := range n {
+			result[i][k] = &encrypted[0]
+			encrypted = encrypted[1:]
+		}
+	}
+
+	return result, nil
+}
+
+// decryptVars returns a copy of the vars with all of their values decrypted.
+func (e *Empire) decryptVars(vars Vars) (Vars, error) {
+	if vars == nil {
+		return nil, nil
+	}
+
+	decrypted := make(Vars)
+	for n, v := range vars {
+		if !envelope.IsEncrypted(*v) {
+			decrypted[n] = v
+			continue
+		}
+
+		if e.Encrypter == nil {
+			return nil, ErrNoEncrypter
+		}
+
+		plaintext, err := e.Encrypter.Decrypt(*v)
+		if err != nil {
+			return nil, fmt.Errorf("error decrypting %s: %v", n, err)
+		}
+		decrypted[n] = &plaintext
+	}
+
+	return decrypted, nil
+}
+
+// decryptRelease returns a copy of the release, with its config decrypted, to
+// build the scheduler.App from. The copy should never be saved.
+func (e *Empire) decryptRelease(r *Release) (*Release, error) {
+	c, err := e.decryptConfig(r.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	decrypted := *r
+	decrypted.Config = c
+	return &decrypted, nil
+}
+
+// resolveRelease returns a copy of the release to build the scheduler.App from,
+// with its config decrypted and flattened, and any references to secrets
+// resolved. The copy should never be saved.
+func (e *Empire) resolveRelease(ctx context.Context, r *Release) (*Release, error) {
+	r, err := e.decryptRelease(r)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Config = flattenConfig(r.Config)
+
+	if e.SecretResolver == nil {
+		return r, nil
+	}
+
+	vars := make(Vars)
+	for n, v := range r.Config.Vars {
+		if !secrets.IsReference(*v) {
+			vars[n] = v
+			continue
+		}
+
+		ref, err := secrets.ParseReference(*v)
+		if err != nil {
+			return nil, err
+		}
+
+		value, err := e.SecretResolver.Resolve(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving %s: %v", n, err)
+		}
+		vars[n] = &value
+	}
+
+	r.Config = &Config{
+		ID:    r.Config.ID,
+		AppID: r.Config.AppID,
+		App:   r.Config.App,
+		Vars:  vars,
+	}
+	return r, nil
+}
+
+// configsEncrypt encrypts the values of any configs that were stored before
+// encryption was enabled, returning the number of configs that were updated.
+func (e *Empire) configsEncrypt(db *gorm.DB) (int, error) {
+	if e.Encrypter == nil {
+		return 0, ErrNoEncrypter
+	}
+
+	var configs []*Config
+	if err := db.Find(&configs).Error; err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, c := range configs {
+		if !hasPlaintextVars(c.Vars) && !hasPlaintextVars(c.GroupVars) {
+			continue
+		}
+
+		d, err := e.decryptConfig(c)
+		if err != nil {
+			return n, err
+		}
+
+		encrypted, err := e.encryptConfig(d)
+		if err != nil {
+			return n, err
+		}
+
+		if err := db.Exec(`UPDATE configs SET vars = ?, group_vars = ? WHERE id = ?`, encrypted.Vars, encrypted.GroupVars, c.ID).Error; err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// hasPlaintextVars returns true if any of the values aren't encrypted.
+func hasPlaintextVars(vars Vars) bool {
+	for _, v := range vars {
+		if !envelope.IsEncrypted(*v) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskedPrefix replaces the value of a sensitive config var when it's masked.
+const maskedPrefix = "****"
+
+// maskValue masks the value of a sensitive config var. The last 4 characters
+// are left visible, to help tell values apart, but only for values that are
+// long enough that they don't give much of the value away.
+func maskValue(v string) string {
+	r := []rune(v)
+	if len(r) < 12 {
+		return maskedPrefix
+	}
+	return maskedPrefix + string(r[len(r)-4:])
+}
+
+// maskVar returns the masked value of a sensitive config var. Empty values and
+// references to secrets are returned as is, since they don't contain the
+// secret itself.
+func maskVar(v *string) *string {
+	if v == nil || *v == "" || secrets.IsReference(*v) {
+		return v
+	}
+
+	masked := maskValue(*v)
+	return &masked
+}
+
+// maskConfig returns a copy of the decrypted config, with the values of
+// sensitive vars masked.
+func maskConfig(c *Config, insensitive map[Variable]bool) *Config {
+	vars := make(Vars)
+	for n, v := range c.Vars {
+		if insensitive[n] {
+			vars[n] = v
+			continue
+		}
+		vars[n] = maskVar(v)
+	}
+
+	return &Config{
+		ID:    c.ID,
+		AppID: c.AppID,
+		App:   c.App,
+		Vars:  vars,
+	}
+}
+
+// MaskVars returns a copy of the decrypted vars, with all of the values
+// masked.
+func MaskVars(vars Vars) Vars {
+	return maskConfig(&Config{Vars: vars}, nil).Vars
+}
+
+// insensitiveConfigVars returns the names of the app's config vars that have
+// been marked as not sensitive. All other vars are sensitive.
+func insensitiveConfigVars(db *gorm.DB, appID string) (map[Variable]bool, error) {
+	rows, err := db.Raw(`SELECT name FROM insensitive_config_vars WHERE app_id = ?`, appID).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	insensitive := make(map[Variable]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		insensitive[Variable(name)] = true
+	}
+
+	return insensitive, rows.Err()
+}
+
+// markConfigVars marks the app's config vars as sensitive, or not sensitive.
+func markConfigVars(db *gorm.DB, app *App, sensitivity map[Variable]bool) error {
+	for n, sensitive := range sensitivity {
+		if err := db.Exec(`DELETE FROM insensitive_config_vars WHERE app_id = ? AND name = ?`, app.ID, string(n)).Error; err != nil {
+			return err
+		}
+
+		if sensitive {
+			continue
+		}
+
+		if err := db.Exec(`INSERT INTO insensitive_config_vars (app_id, name) VALUES (?, ?)`, app.ID, string(n)).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // mergeVars copies all of the vars from a, and merges b into them, returning a
 // new Vars.
 func mergeVars(old, new Vars) Vars {
@@ -3,6 +3,11 @@ package empire
 import (
 	"reflect"
 	"testing"
+
+	"github.com/remind101/empire/pkg/envelope"
+	"github.com/remind101/empire/pkg/secrets"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
 )
 
 func TestConfigsQuery(t *testing.T) {
@@ -125,3 +130,95 @@ func TestReleaseDesc(t *testing.T) {
 		}
 	}
 }
+
+func TestEncryptConfig(t *testing.T) {
+	p, err := envelope.NewLocalKeyProvider([]byte("0123456789abcdef0123456789abcdef"))
+	assert.NoError(t, err)
+	e := &Empire{Encrypter: envelope.NewEncrypter(p)}
+
+	secret := "secret"
+	c := &Config{ID: "1234", AppID: "4321", Vars: Vars{"SECRET": &secret}}
+
+	encrypted, err := e.encryptConfig(c)
+	assert.NoError(t, err)
+	assert.Equal(t, "1234", encrypted.ID)
+	assert.Equal(t, "4321", encrypted.AppID)
+	assert.True(t, envelope.IsEncrypted(*encrypted.Vars["SECRET"]))
+	assert.False(t, hasPlaintextVars(encrypted.Vars))
+
+	// The original config is untouched.
+	assert.Equal(t, "secret", *c.Vars["SECRET"])
+
+	decrypted, err := e.decryptConfig(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, c, decrypted)
+
+	// Without an Encrypter, configs are stored in plaintext, but
+	// encrypted values can't be read.
+	e.Encrypter = nil
+
+	plaintext, err := e.encryptConfig(c)
+	assert.NoError(t, err)
+	assert.Equal(t, c, plaintext)
+
+	_, err = e.decryptConfig(encrypted)
+	assert.Equal(t, ErrNoEncrypter, err)
+}
+
+func TestResolveRelease(t *testing.T) {
+	e := &Empire{
+		SecretResolver: secrets.MemoryResolver{
+			"ssm:///prod/api/db_password": "hunter2",
+		},
+	}
+
+	ref, env := "ssm:///prod/api/db_password", "production"
+	r := &Release{
+		Version: 1,
+		Config: &Config{Vars: Vars{
+			"DB_PASSWORD": &ref,
+			"RAILS_ENV":   &env,
+		}},
+	}
+
+	resolved, err := e.resolveRelease(context.Background(), r)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, resolved.Version)
+	assert.Equal(t, "hunter2", *resolved.Config.Vars["DB_PASSWORD"])
+	assert.Equal(t, "production", *resolved.Config.Vars["RAILS_ENV"])
+
+	// The reference is kept in the release.
+	assert.Equal(t, "ssm:///prod/api/db_password", *r.Config.Vars["DB_PASSWORD"])
+
+	missing := "ssm:///prod/api/other"
+	r.Config.Vars["OTHER"] = &missing
+	_, err = e.resolveRelease(context.Background(), r)
+	assert.EqualError(t, err, "error resolving OTHER: secret not found: ssm:///prod/api/other")
+}
+
+func TestMaskConfig(t *testing.T) {
+	url, env, short, empty, ref := "postgres://localhost:5432", "production", "secret", "", "ssm:///prod/api/db_password"
+	unicode, shortUnicode := "パスワードはとても長いです", "パスワード"
+	c := &Config{ID: "1234", AppID: "4321", Vars: Vars{
+		"UNICODE":       &unicode,
+		"SHORT_UNICODE": &shortUnicode,
+		"DATABASE_URL":  &url,
+		"RAILS_ENV":     &env,
+		"SECRET":        &short,
+		"EMPTY":         &empty,
+		"DB_PASSWORD":   &ref,
+	}}
+
+	masked := maskConfig(c, map[Variable]bool{"RAILS_ENV": true})
+	assert.Equal(t, "1234", masked.ID)
+	assert.Equal(t, "****5432", *masked.Vars["DATABASE_URL"])
+	assert.Equal(t, "production", *masked.Vars["RAILS_ENV"])
+	assert.Equal(t, "****", *masked.Vars["SECRET"])
+	assert.Equal(t, "", *masked.Vars["EMPTY"])
+	assert.Equal(t, "ssm:///prod/api/db_password", *masked.Vars["DB_PASSWORD"])
+	assert.Equal(t, "****長いです", *masked.Vars["UNICODE"])
+	assert.Equal(t, "****", *masked.Vars["SHORT_UNICODE"])
+
+	// The original config is untouched.
+	assert.Equal(t, "postgres://localhost:5432", *c.Vars["DATABASE_URL"])
+}
@@ -80,6 +80,10 @@ func (db *DB) Reset() error {
 	exec(`TRUNCATE TABLE apps CASCADE`)
 	exec(`TRUNCATE TABLE ports CASCADE`)
 	exec(`TRUNCATE TABLE slugs CASCADE`)
+	exec(`TRUNCATE TABLE events`)
+	exec(`TRUNCATE TABLE webhook_deliveries`)
+	exec(`TRUNCATE TABLE permissions`)
+	exec(`TRUNCATE TABLE access_tokens`)
 	exec(`INSERT INTO ports (port) (SELECT generate_series(9000,10000))`)
 
 	return err
@@ -0,0 +1,581 @@
+package empire
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire/pkg/envelope"
+	"github.com/remind101/empire/pkg/image"
+	pglock "github.com/remind101/empire/pkg/pg/lock"
+	"github.com/remind101/pkg/reporter"
+	"golang.org/x/net/context"
+)
+
+// DefaultDeploymentPollInterval is the default amount of time to wait between
+// checking for queued deployments.
+const DefaultDeploymentPollInterval = 5 * time.Second
+
+// DefaultDeploymentConcurrency is the default number of queued deployments
+// that a DeploymentWorker will perform at once.
+const DefaultDeploymentConcurrency = 10
+
+// DefaultDeploymentOutputPollInterval is the default amount of time to wait
+// between checking for new output from a deployment that's being followed.
+const DefaultDeploymentOutputPollInterval = 1 * time.Second
+
+// deploymentOutputBufferSize is the amount of output from a deployment that's
+// buffered before it's stored.
+const deploymentOutputBufferSize = 4096
+
+// Deployment statuses.
+const (
+	DeploymentPending   = "pending"
+	DeploymentRunning   = "running"
+	DeploymentSucceeded = "succeeded"
+	DeploymentFailed    = "failed"
+)
+
+// Deployment is a deployment of an image that was queued, to be performed by a
+// DeploymentWorker. Deployments to the same app are performed one at a time,
+// in the order that they were queued.
+type Deployment struct {
+	// A unique uuid that identifies the deployment.
+	ID string
+
+	// The app that's being deployed to.
+	AppID string
+	App   *App
+
+	// The image that's being deployed.
+	Image image.Image
+
+	// The name of the user that queued the deployment.
+	UserName string
+
+	// The scopes of the access token that the user queued the deployment
+	// with. Nil means that the user was unrestricted.
+	UserScopes Scopes
+
+	// Commit message
+	Message string
+
+	// Whether the deployment waits for the new release to become stable.
+	Stream bool
+
+	// One of pending, running, succeeded or failed.
+	Status string
+
+	// The version of the release that was created, once there is one.
+	ReleaseVersion *int
+
+	// If the deployment failed, the reason why.
+	Error *string
+
+	// The output of the deployment so far, as a stream of jsonmessages.
+	Output string
+
+	// Timestamps are set by Postgres, so that deployments queued by
+	// different Empire instances are performed in the right order.
+	CreatedAt  *time.Time
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+}
+
+// Err returns an error if the deployment failed.
+func (d *Deployment) Err() error {
+	if d.Status != DeploymentFailed {
+		return nil
+	}
+	if d.Error == nil {
+		return errors.New("deployment failed")
+	}
+	return errors.New(*d.Error)
+}
+
+// DeploymentsQuery is a scope implementation for common things to filter
+// deployments by.
+type DeploymentsQuery struct {
+	// If provided, finds the deployment with the given ID.
+	ID *string
+
+	// If provided, finds deployments to the given app.
+	App *App
+}
+
+// scope implements the scope interface.
+func (q DeploymentsQuery) scope(db *gorm.DB) *gorm.DB {
+	var scope composedScope
+
+	if q.ID != nil {
+		scope = append(scope, idEquals(*q.ID))
+	}
+
+	if q.App != nil {
+		scope = append(scope, forApp(q.App))
+	}
+
+	scope = append(scope, order("created_at desc"))
+
+	return scope.scope(db)
+}
+
+// deploymentsFind returns the first matching deployment.
+func deploymentsFind(db *gorm.DB, scope scope) (*Deployment, error) {
+	var deployment Deployment
+	scope = composedScope{preload("App"), scope}
+	return &deployment, first(db, scope, &deployment)
+}
+
+// deploymentsCreate inserts the deployment. The GitHub token of the user that
+// queued it is stored until the deployment finishes, so that permissions
+// granted to GitHub teams can be checked when it's performed.
+func deploymentsCreate(db *gorm.DB, deployment *Deployment, githubToken *string) (*Deployment, error) {
+	return deployment, db.Raw(`INSERT INTO deployments (app_id, image, user_name, user_scopes, user_github_token, message, stream, status) VALUES (?, ?, ?, ?, ?, ?, ?, ?) RETURNING id, created_at`, deployment.AppID, deployment.Image, deployment.UserName, deployment.UserScopes, githubToken, deployment.Message, deployment.Stream, deployment.Status).Row().Scan(&deployment.ID, &deployment.CreatedAt)
+}
+
+// deploymentsFinish records the result of performing the deployment.
+func deploymentsFinish(db *gorm.DB, deployment *Deployment, r *Release, deployErr error) error {
+	deployment.Status = DeploymentSucceeded
+	if r != nil {
+		deployment.ReleaseVersion = &r.Version
+	}
+	if deployErr != nil {
+		msg := deployErr.Error()
+		deployment.Status = DeploymentFailed
+		deployment.Error = &msg
+	}
+	return db.Raw(`UPDATE deployments SET status = ?, release_version = ?, error = ?, user_github_token = NULL, finished_at = (now() at time zone 'utc') WHERE id = ? RETURNING finished_at`, deployment.Status, deployment.ReleaseVersion, deployment.Error, deployment.ID).Row().Scan(&deployment.FinishedAt)
+}
+
+// deploymentsFollow writes the output of the deployment to w as it's appended,
+// until the deployment has finished, then returns the finished deployment.
+func deploymentsFollow(ctx context.Context, db *gorm.DB, d *Deployment, w io.Writer, interval time.Duration) (*Deployment, error) {
+	if interval == 0 {
+		interval = DefaultDeploymentOutputPollInterval
+	}
+
+	// The number of characters of output that have been written to w.
+	var offset int
+
+	for {
+		var output, status string
+		if err := db.Raw(`SELECT substr(output, ?), status FROM deployments WHERE id = ?`, offset+1, d.ID).Row().Scan(&output, &status); err != nil {
+			return nil, err
+		}
+
+		if output != "" {
+			if _, err := io.WriteString(w, output); err != nil {
+				return nil, err
+			}
+			offset += utf8.RuneCountInString(output)
+		}
+
+		if status == DeploymentSucceeded || status == DeploymentFailed {
+			return deploymentsFind(db, DeploymentsQuery{ID: &d.ID})
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// deploymentOutput is an io.Writer that appends to the output of a deployment,
+// so that it can be followed while the deployment is being performed. Output is
+// buffered, and stored once the buffer is full, or every interval, so that a
+// chatty deployment doesn't rewrite its output on every write.
+type deploymentOutput struct {
+	ctx context.Context
+	db  *gorm.DB
+	id  string
+
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	stopped chan struct{}
+	done    chan struct{}
+}
+
+// newDeploymentOutput returns a deploymentOutput that stores buffered output
+// every interval, until it's closed.
+func newDeploymentOutput(ctx context.Context, db *gorm.DB, id string, interval time.Duration) *deploymentOutput {
+	if interval == 0 {
+		interval = DefaultDeploymentOutputPollInterval
+	}
+
+	w := &deploymentOutput{
+		ctx:     ctx,
+		db:      db,
+		id:      id,
+		stopped: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go func() {
+		defer close(w.done)
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-w.stopped:
+				return
+			case <-t.C:
+				w.mu.Lock()
+				w.flush()
+				w.mu.Unlock()
+			}
+		}
+	}()
+
+	return w
+}
+
+// Write implements the io.Writer interface.
+func (w *deploymentOutput) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	if w.buf.Len() >= deploymentOutputBufferSize {
+		w.flush()
+	}
+
+	return len(p), nil
+}
+
+// Close stores any buffered output. It should be called before the deployment
+// is marked as finished, so that followers see all of the output.
+func (w *deploymentOutput) Close() error {
+	close(w.stopped)
+	<-w.done
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.flush()
+
+	return nil
+}
+
+// flush stores the buffered output. The output is informational, so failing to
+// store it is reported instead of failing the deployment. The caller must hold
+// w.mu.
+func (w *deploymentOutput) flush() {
+	if w.buf.Len() == 0 {
+		return
+	}
+
+	if err := w.db.Exec(`UPDATE deployments SET output = output || ? WHERE id = ?`, w.buf.String(), w.id).Error; err != nil {
+		reporter.Report(w.ctx, fmt.Errorf("error storing output of deployment %s: %v", w.id, err))
+	}
+
+	w.buf.Reset()
+}
+
+// deploymentLockKey returns the advisory lock key that's held while a
+// deployment is running.
+func deploymentLockKey(id string) uint32 {
+	return crc32.ChecksumIEEE([]byte(fmt.Sprintf("deployment_%s", id)))
+}
+
+// claimedDeployment is a deployment that was claimed by this process. The
+// advisory lock is held until the deployment finishes, so that other Empire
+// instances can tell that it's still running.
+type claimedDeployment struct {
+	*Deployment
+	lock *pglock.AdvisoryLock
+
+	// The stored GitHub token of the user that queued the deployment,
+	// which may be encrypted.
+	githubToken string
+}
+
+// requeueOrphanedDeployments moves deployments that are marked as running,
+// but aren't held by any Empire instance (e.g. because it was restarted), back
+// to the queue.
+func (e *Empire) requeueOrphanedDeployments() error {
+	tx := e.db.Begin()
+
+	rows, err := tx.Raw(`SELECT id FROM deployments WHERE status = ? FOR UPDATE SKIP LOCKED`, DeploymentRunning).Rows()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, id := range ids {
+		// If we can obtain the lock, the instance that was performing
+		// the deployment is gone. The lock is released when the
+		// transaction ends.
+		var orphaned bool
+		if err := tx.Raw(`SELECT pg_try_advisory_xact_lock(?)`, deploymentLockKey(id)).Row().Scan(&orphaned); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if !orphaned {
+			continue
+		}
+
+		if err := tx.Exec(`UPDATE deployments SET status = ?, started_at = NULL WHERE id = ?`, DeploymentPending, id).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// claimDeployment claims the next deployment in the queue, and marks it as
+// running. A deployment is only claimed if it's the oldest pending deployment
+// for the app, and no other deployment to the app is running. If there are no
+// deployments to claim, nil is returned.
+func (e *Empire) claimDeployment() (*claimedDeployment, error) {
+	tx := e.db.Begin()
+
+	var id string
+	err := tx.Raw(`SELECT id FROM deployments d WHERE status = ? AND NOT EXISTS (
+  SELECT 1 FROM deployments o WHERE o.app_id = d.app_id AND (o.status = ? OR (o.status = ? AND (o.created_at, o.id) < (d.created_at, d.id)))
+) ORDER BY created_at, id LIMIT 1 FOR UPDATE SKIP LOCKED`, DeploymentPending, DeploymentRunning, DeploymentPending).Row().Scan(&id)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Obtain the lock before the deployment is marked as running, so it's
+	// never considered to be orphaned.
+	l, err := pglock.NewAdvisoryLock(e.db.DB(), deploymentLockKey(id))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	l.Context = fmt.Sprintf("deployment %s", id)
+	if err := l.Lock(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Exec(`UPDATE deployments SET status = ?, started_at = (now() at time zone 'utc') WHERE id = ?`, DeploymentRunning, id).Error; err != nil {
+		tx.Rollback()
+		l.Unlock()
+		return nil, err
+	}
+
+	d, err := deploymentsFind(tx, DeploymentsQuery{ID: &id})
+	if err != nil {
+		tx.Rollback()
+		l.Unlock()
+		return nil, err
+	}
+
+	var githubToken string
+	if err := tx.Raw(`SELECT coalesce(user_github_token, '') FROM deployments WHERE id = ?`, id).Row().Scan(&githubToken); err != nil {
+		tx.Rollback()
+		l.Unlock()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		l.Unlock()
+		return nil, err
+	}
+
+	return &claimedDeployment{Deployment: d, lock: l, githubToken: githubToken}, nil
+}
+
+// encryptGitHubToken returns the GitHub token to store with a queued
+// deployment, encrypted if an Encrypter is configured. Nil is returned if
+// there's no token.
+func (e *Empire) encryptGitHubToken(token string) (*string, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	if e.Encrypter == nil {
+		return &token, nil
+	}
+
+	encrypted, err := e.Encrypter.Encrypt(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &encrypted[0], nil
+}
+
+// decryptGitHubToken decrypts a GitHub token that was returned from
+// encryptGitHubToken.
+func (e *Empire) decryptGitHubToken(token string) (string, error) {
+	if !envelope.IsEncrypted(token) {
+		return token, nil
+	}
+
+	if e.Encrypter == nil {
+		return "", ErrNoEncrypter
+	}
+
+	return e.Encrypter.Decrypt(token)
+}
+
+// runDeployment performs a claimed deployment, and records the result. The
+// deployment is authorized again on behalf of the user that queued it, with the
+// same scopes, since permissions may have been revoked while it was queued.
+func (e *Empire) runDeployment(ctx context.Context, d *claimedDeployment) error {
+	defer func() {
+		if err := d.lock.Unlock(); err != nil {
+			reporter.Report(ctx, err)
+		}
+	}()
+
+	output := newDeploymentOutput(ctx, e.db, d.ID, e.DeploymentOutputPollInterval)
+
+	opts := DeployOpts{
+		User:    &User{Name: d.UserName, Scopes: d.UserScopes},
+		App:     d.App,
+		Image:   d.Image,
+		Output:  NewDeploymentStream(output),
+		Message: d.Message,
+		Stream:  d.Stream,
+	}
+
+	var r *Release
+	githubToken, deployErr := e.decryptGitHubToken(d.githubToken)
+	if deployErr == nil {
+		opts.User.GitHubToken = githubToken
+		deployErr = opts.Validate(e)
+	}
+	if deployErr == nil {
+		r, deployErr = e.deployer.Deploy(ctx, opts)
+	}
+	output.Close()
+
+	if err := deploymentsFinish(e.db, d.Deployment, r, deployErr); err != nil {
+		return err
+	}
+
+	if deployErr != nil {
+		return nil
+	}
+
+	return e.PublishEvent(e.deployEvent(opts, r))
+}
+
+// DeploymentWorker performs queued deployments. Deployments to different apps
+// are performed concurrently, and deployments to the same app are performed
+// one at a time.
+type DeploymentWorker struct {
+	// Root context.Context to use. If a reporter.Reporter is embedded,
+	// errors generated will be reporter there.
+	Context context.Context
+
+	// The amount of time to wait between checking for queued deployments.
+	// The zero value is DefaultDeploymentPollInterval.
+	PollInterval time.Duration
+
+	// The maximum number of deployments to perform at once. The zero value
+	// is DefaultDeploymentConcurrency.
+	Concurrency int
+
+	empire  *Empire
+	stopped chan struct{}
+}
+
+// NewDeploymentWorker returns a new DeploymentWorker.
+func NewDeploymentWorker(e *Empire) *DeploymentWorker {
+	return &DeploymentWorker{
+		Context: context.Background(),
+		empire:  e,
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start starts performing queued deployments. It blocks until Stop is called.
+// Deployments that are in progress when Stop is called aren't canceled.
+func (w *DeploymentWorker) Start() {
+	interval := w.PollInterval
+	if interval == 0 {
+		interval = DefaultDeploymentPollInterval
+	}
+
+	concurrency := w.Concurrency
+	if concurrency == 0 {
+		concurrency = DefaultDeploymentConcurrency
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	slots := make(chan struct{}, concurrency)
+
+	for {
+		select {
+		case <-w.stopped:
+			return
+		case <-t.C:
+			if err := w.empire.requeueOrphanedDeployments(); err != nil {
+				reporter.Report(w.Context, err)
+			}
+			if err := w.claim(slots); err != nil {
+				reporter.Report(w.Context, err)
+			}
+		}
+	}
+}
+
+// claim claims deployments until there are none left, or every slot is in
+// use, and performs each one in a goroutine.
+func (w *DeploymentWorker) claim(slots chan struct{}) error {
+	for {
+		select {
+		case slots <- struct{}{}:
+		default:
+			return nil
+		}
+
+		d, err := w.empire.claimDeployment()
+		if err != nil || d == nil {
+			<-slots
+			return err
+		}
+
+		go func() {
+			defer func() { <-slots }()
+			if err := w.empire.runDeployment(w.Context, d); err != nil {
+				reporter.Report(w.Context, fmt.Errorf("error performing deployment %s: %v", d.ID, err))
+			}
+		}()
+	}
+}
+
+// Stop stops performing queued deployments.
+func (w *DeploymentWorker) Stop() {
+	close(w.stopped)
+}
@@ -2,6 +2,7 @@ package empire
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 
@@ -17,34 +18,39 @@ type deployerService struct {
 	*Empire
 }
 
-// createRelease creates a new release that can be deployed
-func (s *deployerService) createRelease(ctx context.Context, db *gorm.DB, ss scheduler.StatusStream, opts DeployOpts) (*Release, error) {
+// newRelease builds a new release that can be deployed, without creating
+// anything. The procfile is extracted from the image, but the slug isn't
+// created. If the app doesn't exist yet, the release is for a new app, which is
+// created with the release.
+func (s *deployerService) newRelease(ctx context.Context, db *gorm.DB, opts DeployOpts) (*Release, error) {
 	app, img := opts.App, opts.Image
 
 	// If no app is specified, attempt to find the app that relates to this
-	// images repository, or create it if not found.
+	// images repository.
 	if app == nil {
-		var err error
-		app, err = appsFindOrCreateByRepo(db, img.Repository)
-		if err != nil {
+		n := appNameFromRepo(img.Repository)
+		a, err := appsFind(db, AppsQuery{Name: &n})
+		if err != nil && err != gorm.RecordNotFound {
 			return nil, err
 		}
-	} else {
-		// If the app doesn't already have a repo attached to it, we'll attach
-		// this image's repo.
-		if err := appsEnsureRepo(db, app, img.Repository); err != nil {
-			return nil, err
+		app = a
+		if err == gorm.RecordNotFound {
+			app = &App{Name: n}
 		}
 	}
 
-	// Grab the latest config.
-	config, err := s.configs.Config(db, app)
-	if err != nil {
-		return nil, err
+	// Grab the latest config. A new app doesn't have any.
+	config := &Config{App: app, Vars: make(Vars)}
+	if app.ID != "" {
+		var err error
+		config, err = s.configs.Config(db, app)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Create a new slug for the docker image.
-	slug, err := s.slugs.Create(ctx, db, img, opts.Output)
+	// Extract the procfile for the docker image.
+	slug, err := slugsExtract(ctx, s.ProcfileExtractor, img, opts.Output)
 	if err != nil {
 		return nil, err
 	}
@@ -54,25 +60,173 @@ func (s *deployerService) createRelease(ctx context.Context, db *gorm.DB, ss sch
 	desc := fmt.Sprintf("Deploy %s", img.String())
 	desc = appendMessageToDescription(desc, opts.User, opts.Message)
 
-	r, err := s.releases.Create(ctx, db, &Release{
+	return &Release{
 		App:         app,
 		Config:      config,
 		Slug:        slug,
 		Description: desc,
-	})
-	return r, err
+	}, nil
+}
+
+// createRelease creates the release that was built by newRelease, along with
+// the app and slug.
+func (s *deployerService) createRelease(ctx context.Context, db *gorm.DB, r *Release, opts DeployOpts) (*Release, error) {
+	repo := opts.Image.Repository
+
+	// If the app doesn't exist yet, create it. Otherwise, if the app doesn't
+	// already have a repo attached to it, we'll attach this image's repo.
+	if r.App.ID == "" {
+		app, err := appsFindOrCreateByRepo(db, repo)
+		if err != nil {
+			return nil, err
+		}
+		r.App = app
+	} else {
+		if err := appsEnsureRepo(db, r.App, repo); err != nil {
+			return nil, err
+		}
+	}
+
+	// The config is read again, so that changes made while the release
+	// phase was running aren't reverted.
+	config, err := s.configs.Config(db, r.App)
+	if err != nil {
+		return nil, err
+	}
+	r.Config = config
+
+	slug, err := slugsCreate(db, r.Slug)
+	if err != nil {
+		return nil, err
+	}
+	r.Slug = slug
+
+	return s.releases.Create(ctx, db, r)
 }
 
+// createInTransaction creates the release, the app if it doesn't exist yet, and
+// the slug, in a single transaction. The release phase runs before the
+// transaction, so nothing is created if it fails.
 func (s *deployerService) createInTransaction(ctx context.Context, stream scheduler.StatusStream, opts DeployOpts) (*Release, error) {
+	r, err := s.newRelease(ctx, s.db, opts)
+	if err != nil {
+		return r, err
+	}
+
+	// The release phase can take a while, so it runs before the
+	// transaction that creates the release, which locks the app's releases.
+	if err := s.releasePhase(ctx, r, opts.User, opts.Output); err != nil {
+		return r, err
+	}
+
 	tx := s.db.Begin()
-	r, err := s.createRelease(ctx, tx, stream, opts)
+	r, err = s.createRelease(ctx, tx, r, opts)
 	if err != nil {
 		tx.Rollback()
 		return r, err
 	}
+	if opts.Canary != nil {
+		if _, err := s.canaries.Create(tx, r, opts.User, opts.Canary); err != nil {
+			tx.Rollback()
+			return r, err
+		}
+	}
+	if err := recordEvent(tx, opts.User, r.App, s.deployEvent(opts, r)); err != nil {
+		tx.Rollback()
+		return r, err
+	}
 	return r, tx.Commit().Error
 }
 
+// releasePhase runs the `release` process from the Procfile, if there is one,
+// as an attached one-off process, before the release is created. The output is
+// written to the deployment stream.
+func (s *deployerService) releasePhase(ctx context.Context, r *Release, user *User, w *DeploymentStream) error {
+	next, err := nextRelease(s.db, r)
+	if err != nil {
+		return err
+	}
+
+	p, ok := next.Formation[releaseProcessType]
+	if !ok {
+		return nil
+	}
+
+	if !scheduler.RunsAttached(s.Scheduler) {
+		return &ReleasePhaseError{Err: ErrAttachedRunnerRequired}
+	}
+
+	if err := w.Status(fmt.Sprintf("Running release phase for v%d of %s: %s", next.Version, next.App.Name, p.Command)); err != nil {
+		return err
+	}
+
+	p.Quantity = 1
+	dr, err := s.resolveRelease(ctx, next)
+	if err != nil {
+		return err
+	}
+
+	a := newSchedulerApp(dr)
+	sp := newSchedulerProcess(next, releaseProcessType, p)
+	sp.Labels["empire.user"] = user.Name
+
+	if err := s.Scheduler.Run(ctx, a, sp, nil, &streamWriter{w}); err != nil {
+		return &ReleasePhaseError{Err: err}
+	}
+
+	return nil
+}
+
+// nextRelease returns a copy of a release that hasn't been created yet, with the
+// formation and version that it will most likely be created with. The app's
+// releases aren't locked, so the version can differ if another release is
+// created concurrently.
+func nextRelease(db *gorm.DB, r *Release) (*Release, error) {
+	next := *r
+
+	// An app that hasn't been created yet doesn't have any releases.
+	if r.App.ID == "" {
+		next.Version = 1
+		if next.Formation == nil {
+			f, err := next.Slug.Formation()
+			if err != nil {
+				return nil, err
+			}
+			next.Formation = f
+		}
+		return &next, nil
+	}
+
+	if next.Formation == nil {
+		if err := buildFormation(db, &next); err != nil {
+			return nil, err
+		}
+	}
+
+	v, err := releasesLastVersion(db, r.App.ID)
+	if err != nil {
+		return nil, err
+	}
+	next.Version = v + 1
+
+	return &next, nil
+}
+
+// ErrAttachedRunnerRequired is returned when an app has a release phase, but
+// the scheduler can't run attached processes.
+var ErrAttachedRunnerRequired = errors.New("the scheduler can't run attached processes, which is required to run the release process")
+
+// ReleasePhaseError is returned when the release phase fails, which aborts
+// the deployment.
+type ReleasePhaseError struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ReleasePhaseError) Error() string {
+	return fmt.Sprintf("release phase failed: %v", e.Err)
+}
+
 // Deploy is a thin wrapper around deploy to that adds the error to the
 // jsonmessage stream.
 func (s *deployerService) Deploy(ctx context.Context, opts DeployOpts) (*Release, error) {
@@ -88,15 +242,290 @@ func (s *deployerService) Deploy(ctx context.Context, opts DeployOpts) (*Release
 		return r, w.Error(err)
 	}
 
+	if opts.Canary != nil {
+		return r, s.canaries.Start(ctx, r, opts.User, w, stream)
+	}
+
+	return r, s.release(ctx, r, opts.User, w, stream)
+}
+
+// release submits a newly created release to the scheduler, rolling it back
+// if it fails to stabilize and auto rollback is enabled. Errors are added to
+// the jsonmessage stream.
+func (s *deployerService) release(ctx context.Context, r *Release, user *User, w *DeploymentStream, stream scheduler.StatusStream) error {
 	if err := w.Status(fmt.Sprintf("Created new release v%d for %s", r.Version, r.App.Name)); err != nil {
-		return r, err
+		return err
 	}
 
 	if err := s.releases.Release(ctx, r, stream); err != nil {
+		if _, ok := err.(*scheduler.UnstableError); ok && s.AutoRollback {
+			if err := s.autoRollback(ctx, r, user, w); err != nil {
+				return w.Error(err)
+			}
+		}
+		return w.Error(err)
+	}
+
+	return w.Status(fmt.Sprintf("Finished processing events for release v%d of %s", r.Version, r.App.Name))
+}
+
+// Promote creates a new release on the next app in the pipeline, using the
+// Slug from the promoted release and the Config of the next app, then submits
+// it to the scheduler. The image isn't pulled again, and the Procfile isn't
+// extracted again.
+func (s *deployerService) Promote(ctx context.Context, from *Release, to *App, opts PromoteOpts) (*Release, error) {
+	w := opts.Output
+
+	var stream scheduler.StatusStream
+	if opts.Stream {
+		stream = w
+	}
+
+	r, err := s.promoteInTransaction(ctx, from, to, opts)
+	if err != nil {
+		return r, w.Error(err)
+	}
+
+	return r, s.release(ctx, r, opts.User, w, stream)
+}
+
+func (s *deployerService) promoteInTransaction(ctx context.Context, from *Release, to *App, opts PromoteOpts) (*Release, error) {
+	config, err := s.configs.Config(s.db, to)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := fmt.Sprintf("Promote %s v%d (%s)", from.App.Name, from.Version, from.Slug.Image.String())
+	desc = appendMessageToDescription(desc, opts.User, opts.Message)
+
+	r := &Release{
+		App:         to,
+		Config:      config,
+		Slug:        from.Slug,
+		Description: desc,
+	}
+
+	// The release phase runs against the next app's config, so database
+	// migrations run against its database.
+	if err := s.releasePhase(ctx, r, opts.User, opts.Output); err != nil {
+		return r, err
+	}
+
+	tx := s.db.Begin()
+
+	// The config is read again, so that changes made while the release
+	// phase was running aren't reverted.
+	r.Config, err = s.configs.Config(tx, to)
+	if err != nil {
+		tx.Rollback()
+		return r, err
+	}
+
+	r, err = s.releases.Create(ctx, tx, r)
+	if err != nil {
+		tx.Rollback()
+		return r, err
+	}
+
+	if err := recordEvent(tx, opts.User, to, s.promoteEvent(opts, from, r)); err != nil {
+		tx.Rollback()
+		return r, err
+	}
+
+	return r, tx.Commit().Error
+}
+
+// DeployReviewApp deploys an image to the review app for a pull request,
+// creating the review app from the template app if it doesn't exist.
+func (s *deployerService) DeployReviewApp(ctx context.Context, opts DeployReviewAppOpts) (*Release, error) {
+	w := opts.Output
+
+	var stream scheduler.StatusStream
+	if opts.Stream {
+		stream = w
+	}
+
+	r, err := s.deployReviewAppInTransaction(ctx, opts)
+	if err != nil {
 		return r, w.Error(err)
 	}
 
-	return r, w.Status(fmt.Sprintf("Finished processing events for release v%d of %s", r.Version, r.App.Name))
+	return r, s.release(ctx, r, opts.User, w, stream)
+}
+
+func (s *deployerService) deployReviewAppInTransaction(ctx context.Context, opts DeployReviewAppOpts) (*Release, error) {
+	ra, err := s.findOrCreateReviewApp(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := s.configs.Config(s.db, ra.App)
+	if err != nil {
+		return nil, err
+	}
+
+	// The slug is created with the release, so that nothing is left
+	// behind if the release phase fails.
+	slug, err := slugsExtract(ctx, s.ProcfileExtractor, opts.Image, opts.Output)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := fmt.Sprintf("Deploy %s", opts.Image.String())
+	desc = appendMessageToDescription(desc, opts.User, opts.Message)
+
+	release := &Release{
+		App:         ra.App,
+		Config:      config,
+		Slug:        slug,
+		Description: desc,
+	}
+
+	// The template's formation is only used for the first release. After
+	// that, the review app keeps its own formation.
+	if _, err := releasesFind(s.db, ReleasesQuery{App: ra.App}); err == gorm.RecordNotFound {
+		formation, err := currentFormation(s.db, opts.Template)
+		if err != nil && err != gorm.RecordNotFound {
+			return nil, err
+		}
+
+		if formation != nil {
+			f, err := slug.Formation()
+			if err != nil {
+				return nil, err
+			}
+			release.Formation = f.Merge(formation)
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := s.releasePhase(ctx, release, opts.User, opts.Output); err != nil {
+		return release, err
+	}
+
+	tx := s.db.Begin()
+
+	// The config is read again, so that changes made while the release
+	// phase was running aren't reverted.
+	release.Config, err = s.configs.Config(tx, ra.App)
+	if err != nil {
+		tx.Rollback()
+		return release, err
+	}
+
+	release.Slug, err = slugsCreate(tx, slug)
+	if err != nil {
+		tx.Rollback()
+		return release, err
+	}
+
+	r, err := s.releases.Create(ctx, tx, release)
+	if err != nil {
+		tx.Rollback()
+		return r, err
+	}
+
+	if err := recordEvent(tx, opts.User, r.App, s.reviewAppDeployEvent(opts, r)); err != nil {
+		tx.Rollback()
+		return r, err
+	}
+
+	return r, tx.Commit().Error
+}
+
+// findOrCreateReviewApp finds the review app for the pull request, creating it
+// from the template app if it doesn't exist. The review app is kept if the
+// deployment fails, and is used by the next deployment to the pull request.
+func (s *deployerService) findOrCreateReviewApp(ctx context.Context, opts DeployReviewAppOpts) (*ReviewApp, error) {
+	tx := s.db.Begin()
+
+	ra, err := reviewAppsFind(tx, ReviewAppsQuery{Repo: &opts.Repo, PullRequest: &opts.PullRequest})
+	if err != nil {
+		if err != gorm.RecordNotFound {
+			tx.Rollback()
+			return nil, err
+		}
+
+		ra, err = s.createReviewApp(ctx, tx, opts)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := reviewAppsTouch(tx, ra, opts.User); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return ra, tx.Commit().Error
+}
+
+// autoRollback marks the release as failed, then rolls the app back to the last
+// release that didn't fail.
+func (s *deployerService) autoRollback(ctx context.Context, failed *Release, user *User, w *DeploymentStream) error {
+	app := failed.App
+
+	tx := s.db.Begin()
+
+	if err := releasesMarkFailed(tx, failed); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	previous, err := releasesFind(tx, composedScope{
+		ReleasesQuery{App: app},
+		scopeFunc(func(db *gorm.DB) *gorm.DB {
+			return db.Where("version < ? AND NOT failed", failed.Version)
+		}),
+	})
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			if err := w.Status(fmt.Sprintf("Release v%d of %s failed, but there's no previous release to roll back to", failed.Version, app.Name)); err != nil {
+				tx.Rollback()
+				return err
+			}
+			return tx.Commit().Error
+		}
+		tx.Rollback()
+		return err
+	}
+
+	if err := w.Status(fmt.Sprintf("Release v%d of %s failed, rolling back to v%d", failed.Version, app.Name, previous.Version)); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := s.releases.Rollback(ctx, tx, RollbackOpts{
+		User:    user,
+		App:     app,
+		Version: previous.Version,
+		Message: fmt.Sprintf("v%d failed to stabilize", failed.Version),
+	}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	event := RollbackEvent{
+		User:          user.Name,
+		App:           app.Name,
+		Version:       previous.Version,
+		Automatic:     true,
+		FailedVersion: failed.Version,
+		app:           app,
+	}
+
+	if err := recordEvent(tx, user, app, event); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	return s.PublishEvent(event)
 }
 
 // DeploymentStream provides a wrapper around an io.Writer for writing
@@ -141,6 +570,20 @@ func (w *DeploymentStream) Error(err error) error {
 	return err
 }
 
+// streamWriter is an io.Writer that writes raw output, like the output from a
+// one-off process, to the jsonmessage stream.
+type streamWriter struct {
+	*DeploymentStream
+}
+
+// Write implements the io.Writer interface.
+func (w *streamWriter) Write(b []byte) (int, error) {
+	if err := w.encode(jsonmessage.JSONMessage{Stream: string(b)}); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
 // encode encodes m into the stream.
 func (w *DeploymentStream) encode(m jsonmessage.JSONMessage) error {
 	return w.enc.Encode(m)
@@ -4,12 +4,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"time"
 
 	"github.com/fsouza/go-dockerclient"
 	"github.com/jinzhu/gorm"
 	"github.com/remind101/empire/pkg/dockerutil"
+	"github.com/remind101/empire/pkg/envelope"
 	"github.com/remind101/empire/pkg/image"
+	"github.com/remind101/empire/pkg/secrets"
 	"github.com/remind101/empire/scheduler"
 	"golang.org/x/net/context"
 )
@@ -17,6 +20,11 @@ import (
 const (
 	// webProcessType is the process type we assume are web server processes.
 	webProcessType = "web"
+
+	// releaseProcessType is the process type that's run as a one-off
+	// process before a new release is deployed. For example, to run
+	// database migrations.
+	releaseProcessType = "release"
 )
 
 // Various errors that may be returned.
@@ -30,6 +38,11 @@ var (
 	ErrInvalidName = &ValidationError{
 		errors.New("An app name must be alphanumeric and dashes only, 3-30 chars in length."),
 	}
+	// ErrNameTaken is used to indicate that another app already has the
+	// name.
+	ErrNameTaken = &ValidationError{
+		errors.New("Name is already taken."),
+	}
 )
 
 // AllowedCommands specifies what commands are allowed to be Run with Empire.
@@ -66,20 +79,34 @@ type Empire struct {
 	DB *DB
 	db *gorm.DB
 
-	accessTokens *accessTokensService
-	apps         *appsService
-	configs      *configsService
-	domains      *domainsService
-	tasks        *tasksService
-	releases     *releasesService
-	deployer     *deployerService
-	runner       *runnerService
-	slugs        *slugsService
-	certs        *certsService
+	accessTokens   *accessTokensService
+	apps           *appsService
+	configs        *configsService
+	domains        *domainsService
+	tasks          *tasksService
+	releases       *releasesService
+	deployer       *deployerService
+	runner         *runnerService
+	slugs          *slugsService
+	certs          *certsService
+	permissions    *permissionsService
+	scaleSchedules *scaleSchedulesService
+	configGroups   *configGroupsService
+	pipelines      *pipelinesService
+	canaries       *canariesService
 
 	// Secret is used to sign JWT access tokens.
 	Secret []byte
 
+	// Encrypter is used to encrypt config vars at rest. The zero value
+	// stores config vars in plaintext.
+	Encrypter *envelope.Encrypter
+
+	// SecretResolver is used to resolve config vars that reference secrets
+	// (e.g. ssm:///prod/api/db_password) when an app is released or run.
+	// The zero value doesn't allow secret references.
+	SecretResolver secrets.Resolver
+
 	// Scheduler is the backend scheduler used to run applications.
 	Scheduler scheduler.Scheduler
 
@@ -105,6 +132,25 @@ type Empire struct {
 	// Configures what type of commands are allowed to be run with the Run
 	// method. The zero value allows all commands to be run.
 	AllowedCommands AllowedCommands
+
+	// Authorizer is used to check that a user is allowed to perform an
+	// action on an app. The default is a PolicyAuthorizer backed by the
+	// permissions table.
+	Authorizer Authorizer
+
+	// When true, a streamed deployment that fails to stabilize is
+	// automatically rolled back to the previous release.
+	AutoRollback bool
+
+	// The maximum number of review apps that can exist at once. The zero
+	// value means there's no limit.
+	MaxReviewApps int
+
+	// The amount of time to wait between checking for new output from a
+	// queued deployment that's being followed, and between storing the
+	// buffered output of a queued deployment that's being performed. The
+	// zero value is DefaultDeploymentOutputPollInterval.
+	DeploymentOutputPollInterval time.Duration
 }
 
 // New returns a new Empire instance.
@@ -127,6 +173,12 @@ func New(db *DB) *Empire {
 	e.runner = &runnerService{Empire: e}
 	e.releases = &releasesService{Empire: e}
 	e.certs = &certsService{Empire: e}
+	e.permissions = &permissionsService{Empire: e}
+	e.scaleSchedules = &scaleSchedulesService{Empire: e}
+	e.configGroups = &configGroupsService{Empire: e}
+	e.pipelines = &pipelinesService{Empire: e}
+	e.canaries = &canariesService{Empire: e}
+	e.Authorizer = NewPolicyAuthorizer(db)
 	return e
 }
 
@@ -140,6 +192,16 @@ func (e *Empire) AccessTokensCreate(accessToken *AccessToken) (*AccessToken, err
 	return e.accessTokens.AccessTokensCreate(accessToken)
 }
 
+// AccessTokens returns all AccessTokens matching the query.
+func (e *Empire) AccessTokens(q AccessTokensQuery) ([]*AccessToken, error) {
+	return accessTokens(e.db, q)
+}
+
+// AccessTokensRevoke revokes an AccessToken, so that it can no longer be used.
+func (e *Empire) AccessTokensRevoke(opts RevokeAccessTokenOpts) error {
+	return e.accessTokens.AccessTokensRevoke(opts)
+}
+
 // AppsFind finds the first app matching the query.
 func (e *Empire) AppsFind(q AppsQuery) (*App, error) {
 	return appsFind(e.db, q)
@@ -178,7 +240,11 @@ func (opts CreateOpts) Event() CreateEvent {
 }
 
 func (opts CreateOpts) Validate(e *Empire) error {
-	return e.requireMessages(opts.Message)
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	return e.authorize(opts.User, ActionCreate, &App{Name: opts.Name})
 }
 
 // Create creates a new app.
@@ -187,12 +253,26 @@ func (e *Empire) Create(ctx context.Context, opts CreateOpts) (*App, error) {
 		return nil, err
 	}
 
-	a, err := appsCreate(e.db, &App{Name: opts.Name})
+	tx := e.db.Begin()
+
+	a, err := appsCreate(tx, &App{Name: opts.Name})
 	if err != nil {
+		tx.Rollback()
+		return a, err
+	}
+
+	event := opts.Event()
+
+	if err := recordEvent(tx, opts.User, a, event); err != nil {
+		tx.Rollback()
 		return a, err
 	}
 
-	return a, e.PublishEvent(opts.Event())
+	if err := tx.Commit().Error; err != nil {
+		return a, err
+	}
+
+	return a, e.PublishEvent(event)
 }
 
 // DestroyOpts are options provided when destroying an application.
@@ -216,7 +296,11 @@ func (opts DestroyOpts) Event() DestroyEvent {
 }
 
 func (opts DestroyOpts) Validate(e *Empire) error {
-	return e.requireMessages(opts.Message)
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	return e.authorize(opts.User, ActionDestroy, opts.App)
 }
 
 // Destroy destroys an app.
@@ -225,6 +309,8 @@ func (e *Empire) Destroy(ctx context.Context, opts DestroyOpts) error {
 		return err
 	}
 
+	event := opts.Event()
+
 	tx := e.db.Begin()
 
 	if err := e.apps.Destroy(ctx, tx, opts.App); err != nil {
@@ -232,11 +318,83 @@ func (e *Empire) Destroy(ctx context.Context, opts DestroyOpts) error {
 		return err
 	}
 
+	if err := recordEvent(tx, opts.User, opts.App, event); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return err
 	}
 
-	return e.PublishEvent(opts.Event())
+	return e.PublishEvent(event)
+}
+
+// RenameOpts are options provided when renaming an application.
+type RenameOpts struct {
+	// User performing the action.
+	User *User
+
+	// The associated app.
+	App *App
+
+	// The new name for the app.
+	Name string
+
+	// Commit message
+	Message string
+}
+
+func (opts RenameOpts) Event() RenameEvent {
+	return RenameEvent{
+		User:         opts.User.Name,
+		PreviousName: opts.App.Name,
+		Name:         opts.Name,
+		Message:      opts.Message,
+		app:          opts.App,
+	}
+}
+
+func (opts RenameOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	if !NamePattern.Match([]byte(opts.Name)) {
+		return ErrInvalidName
+	}
+
+	return e.authorize(opts.User, ActionRename, opts.App)
+}
+
+// Rename renames an app. The app's ID doesn't change, so any resources in the
+// scheduler are updated in place to follow the new name.
+func (e *Empire) Rename(ctx context.Context, opts RenameOpts) (*App, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, err
+	}
+
+	// Build the event before the app is renamed, so that it includes the
+	// old name.
+	event := opts.Event()
+
+	tx := e.db.Begin()
+
+	if err := e.apps.Rename(ctx, tx, opts); err != nil {
+		tx.Rollback()
+		return opts.App, err
+	}
+
+	if err := recordEvent(tx, opts.User, opts.App, event); err != nil {
+		tx.Rollback()
+		return opts.App, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return opts.App, err
+	}
+
+	return opts.App, e.PublishEvent(event)
 }
 
 // Config returns the current Config for a given app.
@@ -253,7 +411,215 @@ func (e *Empire) Config(app *App) (*Config, error) {
 		return c, err
 	}
 
-	return c, nil
+	return e.decryptConfig(c)
+}
+
+// ReadConfigOpts are options provided when reading the config vars of an app.
+type ReadConfigOpts struct {
+	// User performing the action.
+	User *User
+
+	// The associated app.
+	App *App
+
+	// When true, the values of sensitive vars are returned in full, rather
+	// than masked.
+	Reveal bool
+}
+
+func (opts ReadConfigOpts) Event() ConfigEvent {
+	return ConfigEvent{
+		User:     opts.User.Name,
+		App:      opts.App.Name,
+		Revealed: opts.Reveal,
+		app:      opts.App,
+	}
+}
+
+func (opts ReadConfigOpts) Validate(e *Empire) error {
+	if !opts.Reveal {
+		return nil
+	}
+
+	return e.authorize(opts.User, ActionReveal, opts.App)
+}
+
+// ReadConfig returns the current Config for an app, as it should be shown to
+// the user. Vars from config groups are flattened into Vars, and the values of
+// sensitive vars are masked, unless opts.Reveal is true.
+//
+// Reads are recorded in the audit log, but aren't published to the
+// EventStream, since they happen far more often than changes.
+func (e *Empire) ReadConfig(ctx context.Context, opts ReadConfigOpts) (*Config, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, err
+	}
+
+	tx := e.db.Begin()
+
+	c, err := e.configs.Config(tx, opts.App)
+	if err != nil {
+		tx.Rollback()
+		return c, err
+	}
+
+	insensitive, err := insensitiveConfigVars(tx, opts.App.ID)
+	if err != nil {
+		tx.Rollback()
+		return c, err
+	}
+
+	if err := recordEvent(tx, opts.User, opts.App, opts.Event()); err != nil {
+		tx.Rollback()
+		return c, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c, err
+	}
+
+	if c, err = e.decryptConfig(c); err != nil {
+		return c, err
+	}
+
+	c = flattenConfig(c)
+
+	if opts.Reveal {
+		return c, nil
+	}
+
+	return maskConfig(c, insensitive), nil
+}
+
+// MaskConfig returns a flattened copy of the decrypted Config, with the values
+// of sensitive vars masked.
+func (e *Empire) MaskConfig(c *Config) (*Config, error) {
+	insensitive, err := insensitiveConfigVars(e.db, c.AppID)
+	if err != nil {
+		return c, err
+	}
+
+	return maskConfig(flattenConfig(c), insensitive), nil
+}
+
+// InsensitiveConfigVars returns the names of the app's config vars that have
+// been marked as not sensitive, sorted by name.
+func (e *Empire) InsensitiveConfigVars(app *App) ([]Variable, error) {
+	insensitive, err := insensitiveConfigVars(e.db, app.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for n := range insensitive {
+		names = append(names, string(n))
+	}
+	sort.Strings(names)
+
+	vars := make([]Variable, len(names))
+	for i, n := range names {
+		vars[i] = Variable(n)
+	}
+	return vars, nil
+}
+
+// SetConfigSensitivityOpts are options provided when marking config vars as
+// sensitive, or not sensitive.
+type SetConfigSensitivityOpts struct {
+	// User performing the action.
+	User *User
+
+	// The associated app.
+	App *App
+
+	// Whether each var is sensitive. Config vars are sensitive unless
+	// they're marked otherwise.
+	Sensitivity map[Variable]bool
+
+	// Commit message
+	Message string
+}
+
+func (opts SetConfigSensitivityOpts) Event() ConfigSensitivityEvent {
+	var sensitive, insensitive []string
+	for n, s := range opts.Sensitivity {
+		if s {
+			sensitive = append(sensitive, string(n))
+		} else {
+			insensitive = append(insensitive, string(n))
+		}
+	}
+	sort.Strings(sensitive)
+	sort.Strings(insensitive)
+
+	return ConfigSensitivityEvent{
+		User:        opts.User.Name,
+		App:         opts.App.Name,
+		Sensitive:   sensitive,
+		Insensitive: insensitive,
+		Message:     opts.Message,
+		app:         opts.App,
+	}
+}
+
+func (opts SetConfigSensitivityOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	if len(opts.Sensitivity) == 0 {
+		return &ValidationError{Err: errors.New("no config vars were provided")}
+	}
+
+	return e.authorize(opts.User, ActionSet, opts.App)
+}
+
+// SetConfigSensitivity marks config vars as sensitive, or not sensitive, and
+// returns the names of the app's config vars that aren't sensitive.
+func (e *Empire) SetConfigSensitivity(ctx context.Context, opts SetConfigSensitivityOpts) ([]Variable, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, err
+	}
+
+	tx := e.db.Begin()
+
+	if err := markConfigVars(tx, opts.App, opts.Sensitivity); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	event := opts.Event()
+
+	if err := recordEvent(tx, opts.User, opts.App, event); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	vars, err := e.InsensitiveConfigVars(opts.App)
+	if err != nil {
+		return vars, err
+	}
+
+	return vars, e.PublishEvent(event)
+}
+
+// EncryptConfigs encrypts the config vars for all apps that were stored
+// before encryption was enabled, returning the number of configs that were
+// encrypted.
+func (e *Empire) EncryptConfigs() (int, error) {
+	tx := e.db.Begin()
+
+	n, err := e.configsEncrypt(tx)
+	if err != nil {
+		tx.Rollback()
+		return n, err
+	}
+
+	return n, tx.Commit().Error
 }
 
 // SetOpts are options provided when setting new config vars on an app.
@@ -277,74 +643,564 @@ func (opts SetOpts) Event() SetEvent {
 		changed = append(changed, string(k))
 	}
 
-	return SetEvent{
-		User:    opts.User.Name,
-		App:     opts.App.Name,
-		Changed: changed,
-		Message: opts.Message,
-		app:     opts.App,
+	return SetEvent{
+		User:    opts.User.Name,
+		App:     opts.App.Name,
+		Changed: changed,
+		Message: opts.Message,
+		app:     opts.App,
+	}
+}
+
+func (opts SetOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	if err := e.validateVars(opts.Vars); err != nil {
+		return err
+	}
+
+	return e.authorize(opts.User, ActionSet, opts.App)
+}
+
+// validateVars checks that the new values of config vars can be stored.
+func (e *Empire) validateVars(vars Vars) error {
+	for k, v := range vars {
+		if v == nil {
+			continue
+		}
+
+		if envelope.IsEncrypted(*v) {
+			return &ValidationError{Err: fmt.Errorf("value for %s can't start with %s", k, envelope.Prefix)}
+		}
+
+		if secrets.IsReference(*v) {
+			if e.SecretResolver == nil {
+				return &ValidationError{Err: fmt.Errorf("value for %s is a secret reference, but secret references aren't enabled", k)}
+			}
+
+			if _, err := secrets.ParseReference(*v); err != nil {
+				return &ValidationError{Err: err}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Set applies the new config vars to the apps current Config, returning the new
+// Config. If the app has a running release, a new release will be created and
+// run.
+func (e *Empire) Set(ctx context.Context, opts SetOpts) (*Config, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, err
+	}
+
+	tx := e.db.Begin()
+
+	c, err := e.configs.Set(ctx, tx, opts)
+	if err != nil {
+		tx.Rollback()
+		return c, err
+	}
+
+	event := opts.Event()
+
+	if err := recordEvent(tx, opts.User, opts.App, event); err != nil {
+		tx.Rollback()
+		return c, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c, err
+	}
+
+	if c, err = e.decryptConfig(c); err != nil {
+		return c, err
+	}
+
+	return c, e.PublishEvent(event)
+}
+
+// ConfigGroupsFind returns the first config group matching the query.
+func (e *Empire) ConfigGroupsFind(q ConfigGroupsQuery) (*ConfigGroup, error) {
+	return configGroupsFind(e.db, q)
+}
+
+// ConfigGroups returns all config groups matching the query.
+func (e *Empire) ConfigGroups(q ConfigGroupsQuery) ([]*ConfigGroup, error) {
+	return configGroups(e.db, q)
+}
+
+// ConfigGroupApps returns the apps that are attached to the config group.
+func (e *Empire) ConfigGroupApps(group *ConfigGroup) ([]*App, error) {
+	return apps(e.db, configGroupAppsScope(group.Name))
+}
+
+// ReadConfigGroup returns the config vars of a config group, as they should be
+// shown to the user. Values are masked, unless opts.Reveal is true. Like
+// ReadConfig, reads are recorded in the audit log, but aren't published to the
+// EventStream.
+func (e *Empire) ReadConfigGroup(ctx context.Context, opts ReadConfigGroupOpts) (Vars, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, err
+	}
+
+	tx := e.db.Begin()
+
+	if err := recordEvent(tx, opts.User, nil, opts.Event()); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	vars, err := e.decryptVars(opts.Group.Vars)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Reveal {
+		return vars, nil
+	}
+
+	return MaskVars(vars), nil
+}
+
+// SetConfigGroup merges new config vars into a config group, creating it if it
+// doesn't exist. Apps that are attached to the config group are queued to be
+// released in batches by a ConfigGroupReleaseWorker.
+func (e *Empire) SetConfigGroup(ctx context.Context, opts SetConfigGroupOpts) (*ConfigGroup, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, err
+	}
+
+	tx := e.db.Begin()
+
+	group, apps, err := e.configGroups.Set(ctx, tx, opts)
+	if err != nil {
+		tx.Rollback()
+		return group, err
+	}
+
+	event := opts.Event()
+	for _, app := range apps {
+		event.Apps = append(event.Apps, app.Name)
+	}
+
+	if err := recordEvent(tx, opts.User, nil, event); err != nil {
+		tx.Rollback()
+		return group, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return group, err
+	}
+
+	return group, e.PublishEvent(event)
+}
+
+// DestroyConfigGroup destroys a config group. Config groups can only be
+// destroyed once they're detached from every app.
+func (e *Empire) DestroyConfigGroup(ctx context.Context, opts DestroyConfigGroupOpts) error {
+	if err := opts.Validate(e); err != nil {
+		return err
+	}
+
+	tx := e.db.Begin()
+
+	if err := e.configGroups.Destroy(ctx, tx, opts); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	event := opts.Event()
+
+	if err := recordEvent(tx, opts.User, nil, event); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	return e.PublishEvent(event)
+}
+
+// AttachConfigGroup attaches a config group to an app, and releases the app
+// with the config group's vars.
+func (e *Empire) AttachConfigGroup(ctx context.Context, opts AttachConfigGroupOpts) error {
+	if err := opts.Validate(e); err != nil {
+		return err
+	}
+
+	tx := e.db.Begin()
+
+	if err := e.configGroups.Attach(ctx, tx, opts); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	event := opts.Event()
+
+	if err := recordEvent(tx, opts.User, opts.App, event); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	return e.PublishEvent(event)
+}
+
+// DetachConfigGroup detaches a config group from an app, and releases the app
+// without the config group's vars.
+func (e *Empire) DetachConfigGroup(ctx context.Context, opts AttachConfigGroupOpts) error {
+	if err := opts.Validate(e); err != nil {
+		return err
+	}
+
+	tx := e.db.Begin()
+
+	if err := e.configGroups.Detach(ctx, tx, opts); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	event := opts.Event()
+	event.Detached = true
+
+	if err := recordEvent(tx, opts.User, opts.App, event); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	return e.PublishEvent(event)
+}
+
+// PipelinesFind returns the first pipeline matching the query.
+func (e *Empire) PipelinesFind(q PipelinesQuery) (*Pipeline, error) {
+	return pipelinesFind(e.db, q)
+}
+
+// Pipelines returns all pipelines matching the query.
+func (e *Empire) Pipelines(q PipelinesQuery) ([]*Pipeline, error) {
+	return pipelines(e.db, q)
+}
+
+// PipelineApps returns the apps in the pipeline, in the order that releases
+// are promoted through them.
+func (e *Empire) PipelineApps(pipeline *Pipeline) ([]*App, error) {
+	return pipelineApps(e.db, pipeline)
+}
+
+// SetPipeline creates a pipeline, or replaces the apps in an existing one.
+func (e *Empire) SetPipeline(ctx context.Context, opts SetPipelineOpts) (*Pipeline, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, err
+	}
+
+	tx := e.db.Begin()
+
+	pipeline, err := e.pipelines.Set(ctx, tx, opts)
+	if err != nil {
+		tx.Rollback()
+		return pipeline, err
+	}
+
+	event := opts.Event()
+
+	if err := recordEvent(tx, opts.User, nil, event); err != nil {
+		tx.Rollback()
+		return pipeline, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return pipeline, err
+	}
+
+	return pipeline, e.PublishEvent(event)
+}
+
+// DestroyPipeline destroys a pipeline.
+func (e *Empire) DestroyPipeline(ctx context.Context, opts DestroyPipelineOpts) error {
+	if err := opts.Validate(e); err != nil {
+		return err
+	}
+
+	tx := e.db.Begin()
+
+	if err := e.pipelines.Destroy(ctx, tx, opts); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	event := opts.Event()
+
+	if err := recordEvent(tx, opts.User, nil, event); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	return e.PublishEvent(event)
+}
+
+// Promote promotes a release to the next app in the app's pipeline, and
+// streams the output to opts.Output. Since this is a streaming operation,
+// errors are also written to opts.Output.
+func (e *Empire) Promote(ctx context.Context, opts PromoteOpts) (*Release, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, opts.Output.Error(err)
+	}
+
+	to, err := pipelinesNextApp(e.db, opts.App)
+	if err != nil {
+		return nil, opts.Output.Error(err)
+	}
+
+	scope := composedScope{ReleasesQuery{App: opts.App, Version: opts.Version}}
+	if opts.Version == nil {
+		scope = append(scope, scopeFunc(func(db *gorm.DB) *gorm.DB {
+			return db.Where("NOT failed")
+		}))
+	}
+
+	from, err := releasesFind(e.db, scope)
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			err = &ValidationError{Err: fmt.Errorf("%s has no releases to promote", opts.App.Name)}
+		}
+		return nil, opts.Output.Error(err)
+	}
+
+	r, err := e.deployer.Promote(ctx, from, to, opts)
+	if err != nil {
+		return r, err
+	}
+
+	return r, e.PublishEvent(e.promoteEvent(opts, from, r))
+}
+
+// promoteEvent returns the PromoteEvent for the release that was created by
+// the promotion.
+func (e *Empire) promoteEvent(opts PromoteOpts, from, r *Release) PromoteEvent {
+	event := opts.Event()
+	event.FromVersion = from.Version
+	event.App = r.App.Name
+	event.Release = r.Version
+	event.Image = from.Slug.Image.String()
+	event.app = r.App
+	return event
+}
+
+// ReviewAppsFind returns the first review app matching the query.
+func (e *Empire) ReviewAppsFind(q ReviewAppsQuery) (*ReviewApp, error) {
+	return reviewAppsFind(e.db, q)
+}
+
+// DeployReviewApp deploys an image to the review app for a pull request, and
+// streams the output to opts.Output. If the review app doesn't exist, it's
+// created as a copy of opts.Template.
+func (e *Empire) DeployReviewApp(ctx context.Context, opts DeployReviewAppOpts) (*Release, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, opts.Output.Error(err)
+	}
+
+	r, err := e.deployer.DeployReviewApp(ctx, opts)
+	if err != nil {
+		return r, err
+	}
+
+	return r, e.PublishEvent(e.reviewAppDeployEvent(opts, r))
+}
+
+// reviewAppDeployEvent returns the DeployEvent for the release that was created
+// by deploying a review app.
+func (e *Empire) reviewAppDeployEvent(opts DeployReviewAppOpts, r *Release) DeployEvent {
+	event := opts.Event()
+	event.Release = r.Version
+	event.Environment = e.Environment
+	event.app = r.App
+	return event
+}
+
+// DomainsFind returns the first domain matching the query.
+func (e *Empire) DomainsFind(q DomainsQuery) (*Domain, error) {
+	return domainsFind(e.db, q)
+}
+
+// Domains returns all domains matching the query.
+func (e *Empire) Domains(q DomainsQuery) ([]*Domain, error) {
+	return domains(e.db, q)
+}
+
+// DomainsCreate adds a new Domain for an App.
+func (e *Empire) DomainsCreate(ctx context.Context, domain *Domain) (*Domain, error) {
+	tx := e.db.Begin()
+
+	d, err := e.domains.DomainsCreate(ctx, tx, domain)
+	if err != nil {
+		tx.Rollback()
+		return d, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return d, err
+	}
+
+	return d, nil
+}
+
+// DomainsDestroy removes a Domain for an App.
+func (e *Empire) DomainsDestroy(ctx context.Context, domain *Domain) error {
+	tx := e.db.Begin()
+
+	if err := e.domains.DomainsDestroy(ctx, tx, domain); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// PermissionsFind returns the first permission matching the query.
+func (e *Empire) PermissionsFind(q PermissionsQuery) (*Permission, error) {
+	return permissionsFind(e.db, q)
+}
+
+// Permissions returns all permissions matching the query.
+func (e *Empire) Permissions(q PermissionsQuery) ([]*Permission, error) {
+	return permissions(e.db, q)
+}
+
+// Grant grants a principal permission to perform an action on an app.
+func (e *Empire) Grant(ctx context.Context, opts GrantOpts) (*Permission, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, err
+	}
+
+	tx := e.db.Begin()
+
+	p, err := e.permissions.Grant(ctx, tx, opts)
+	if err != nil {
+		tx.Rollback()
+		return p, err
+	}
+
+	event := opts.Event()
+
+	if err := recordEvent(tx, opts.User, nil, event); err != nil {
+		tx.Rollback()
+		return p, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return p, err
 	}
-}
 
-func (opts SetOpts) Validate(e *Empire) error {
-	return e.requireMessages(opts.Message)
+	return p, e.PublishEvent(event)
 }
 
-// Set applies the new config vars to the apps current Config, returning the new
-// Config. If the app has a running release, a new release will be created and
-// run.
-func (e *Empire) Set(ctx context.Context, opts SetOpts) (*Config, error) {
+// Revoke revokes a previously granted permission.
+func (e *Empire) Revoke(ctx context.Context, opts RevokeOpts) error {
 	if err := opts.Validate(e); err != nil {
-		return nil, err
+		return err
 	}
 
 	tx := e.db.Begin()
 
-	c, err := e.configs.Set(ctx, tx, opts)
-	if err != nil {
+	if err := e.permissions.Revoke(ctx, tx, opts); err != nil {
 		tx.Rollback()
-		return c, err
+		return err
+	}
+
+	event := opts.Event()
+
+	if err := recordEvent(tx, opts.User, nil, event); err != nil {
+		tx.Rollback()
+		return err
 	}
 
 	if err := tx.Commit().Error; err != nil {
-		return c, err
+		return err
 	}
 
-	return c, e.PublishEvent(opts.Event())
+	return e.PublishEvent(event)
 }
 
-// DomainsFind returns the first domain matching the query.
-func (e *Empire) DomainsFind(q DomainsQuery) (*Domain, error) {
-	return domainsFind(e.db, q)
+// ScaleSchedulesFind returns the first scale schedule matching the query.
+func (e *Empire) ScaleSchedulesFind(q ScaleSchedulesQuery) (*ScaleSchedule, error) {
+	return scaleSchedulesFind(e.db, q)
 }
 
-// Domains returns all domains matching the query.
-func (e *Empire) Domains(q DomainsQuery) ([]*Domain, error) {
-	return domains(e.db, q)
+// ScaleSchedules returns all scale schedules matching the query.
+func (e *Empire) ScaleSchedules(q ScaleSchedulesQuery) ([]*ScaleSchedule, error) {
+	return scaleSchedules(e.db, q)
 }
 
-// DomainsCreate adds a new Domain for an App.
-func (e *Empire) DomainsCreate(ctx context.Context, domain *Domain) (*Domain, error) {
+// ScaleScheduleCreate schedules a process to be scaled whenever a cron
+// expression triggers.
+func (e *Empire) ScaleScheduleCreate(ctx context.Context, opts CreateScaleScheduleOpts) (*ScaleSchedule, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, err
+	}
+
 	tx := e.db.Begin()
 
-	d, err := e.domains.DomainsCreate(ctx, tx, domain)
+	schedule, err := e.scaleSchedules.Create(ctx, tx, opts)
 	if err != nil {
 		tx.Rollback()
-		return d, err
+		return schedule, err
+	}
+
+	event := opts.Event()
+
+	if err := recordEvent(tx, opts.User, opts.App, event); err != nil {
+		tx.Rollback()
+		return schedule, err
 	}
 
 	if err := tx.Commit().Error; err != nil {
-		return d, err
+		return schedule, err
 	}
 
-	return d, nil
+	return schedule, e.PublishEvent(event)
 }
 
-// DomainsDestroy removes a Domain for an App.
-func (e *Empire) DomainsDestroy(ctx context.Context, domain *Domain) error {
+// ScaleScheduleDestroy removes a scale schedule.
+func (e *Empire) ScaleScheduleDestroy(ctx context.Context, opts DestroyScaleScheduleOpts) error {
+	if err := opts.Validate(e); err != nil {
+		return err
+	}
+
 	tx := e.db.Begin()
 
-	if err := e.domains.DomainsDestroy(ctx, tx, domain); err != nil {
+	if err := e.scaleSchedules.Destroy(ctx, tx, opts); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	event := opts.Event()
+
+	if err := recordEvent(tx, opts.User, opts.Schedule.App, event); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -353,7 +1209,7 @@ func (e *Empire) DomainsDestroy(ctx context.Context, domain *Domain) error {
 		return err
 	}
 
-	return nil
+	return e.PublishEvent(event)
 }
 
 // Tasks returns the Tasks for the given app.
@@ -388,7 +1244,11 @@ func (opts RestartOpts) Event() RestartEvent {
 }
 
 func (opts RestartOpts) Validate(e *Empire) error {
-	return e.requireMessages(opts.Message)
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	return e.authorize(opts.User, ActionRestart, opts.App)
 }
 
 // Restart restarts processes matching the given prefix for the given Release.
@@ -398,11 +1258,25 @@ func (e *Empire) Restart(ctx context.Context, opts RestartOpts) error {
 		return err
 	}
 
-	if err := e.apps.Restart(ctx, e.db, opts); err != nil {
+	tx := e.db.Begin()
+
+	if err := e.apps.Restart(ctx, tx, opts); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	event := opts.Event()
+
+	if err := recordEvent(tx, opts.User, opts.App, event); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
 		return err
 	}
 
-	return e.PublishEvent(opts.Event())
+	return e.PublishEvent(event)
 
 }
 
@@ -450,7 +1324,11 @@ func (opts RunOpts) Event() RunEvent {
 }
 
 func (opts RunOpts) Validate(e *Empire) error {
-	return e.requireMessages(opts.Message)
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	return e.authorize(opts.User, ActionRun, opts.App)
 }
 
 // Run runs a one-off process for a given App and command.
@@ -483,6 +1361,10 @@ func (e *Empire) Run(ctx context.Context, opts RunOpts) error {
 		opts.Output = io.MultiWriter(w, opts.Output)
 	}
 
+	if err := recordEvent(e.db, opts.User, opts.App, event); err != nil {
+		return err
+	}
+
 	if err := e.PublishEvent(event); err != nil {
 		return err
 	}
@@ -500,11 +1382,25 @@ func (e *Empire) Releases(q ReleasesQuery) ([]*Release, error) {
 	return releases(e.db, q)
 }
 
+// Events returns the events in the audit log matching the query.
+func (e *Empire) Events(q EventsQuery) ([]*EventRecord, error) {
+	return eventRecords(e.db, q)
+}
+
 // ReleasesFind returns the first releases for a given App.
 func (e *Empire) ReleasesFind(q ReleasesQuery) (*Release, error) {
 	return releasesFind(e.db, q)
 }
 
+// ReleasesDiff returns what changed between two releases of an app.
+func (e *Empire) ReleasesDiff(opts ReleaseDiffOpts) (*ReleaseDiff, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, err
+	}
+
+	return e.releasesDiff(e.db, opts)
+}
+
 // RollbackOpts are options provided when rolling back to an old release.
 type RollbackOpts struct {
 	// The user performing the action.
@@ -531,7 +1427,11 @@ func (opts RollbackOpts) Event() RollbackEvent {
 }
 
 func (opts RollbackOpts) Validate(e *Empire) error {
-	return e.requireMessages(opts.Message)
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	return e.authorize(opts.User, ActionRollback, opts.App)
 }
 
 // Rollback rolls an app back to a specific release version. Returns a
@@ -549,11 +1449,18 @@ func (e *Empire) Rollback(ctx context.Context, opts RollbackOpts) (*Release, err
 		return r, err
 	}
 
+	event := opts.Event()
+
+	if err := recordEvent(tx, opts.User, opts.App, event); err != nil {
+		tx.Rollback()
+		return r, err
+	}
+
 	if err := tx.Commit().Error; err != nil {
 		return r, err
 	}
 
-	return r, e.PublishEvent(opts.Event())
+	return r, e.PublishEvent(event)
 }
 
 // DeployOpts represents options that can be passed when deploying to
@@ -580,6 +1487,10 @@ type DeployOpts struct {
 
 	// Stream boolean for whether or not a status stream should be created.
 	Stream bool
+
+	// If provided, the new release runs as a canary alongside the current
+	// release, instead of replacing it.
+	Canary *CanaryOpts
 }
 
 func (opts DeployOpts) Event() DeployEvent {
@@ -587,6 +1498,7 @@ func (opts DeployOpts) Event() DeployEvent {
 		User:    opts.User.Name,
 		Image:   opts.Image.String(),
 		Message: opts.Message,
+		Canary:  opts.Canary != nil,
 	}
 	if opts.App != nil {
 		e.App = opts.App.Name
@@ -597,7 +1509,29 @@ func (opts DeployOpts) Event() DeployEvent {
 }
 
 func (opts DeployOpts) Validate(e *Empire) error {
-	return e.requireMessages(opts.Message)
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	// When an app isn't provided, the image is deployed to the app with
+	// the same name as the repository.
+	app := opts.App
+	if app == nil {
+		app = &App{Name: appNameFromRepo(opts.Image.Repository)}
+	}
+
+	if err := e.authorize(opts.User, ActionDeploy, app); err != nil {
+		return err
+	}
+
+	if opts.Canary != nil {
+		if err := opts.Canary.Validate(); err != nil {
+			return err
+		}
+		return e.validateCanary(app)
+	}
+
+	return nil
 }
 
 // Deploy deploys an image and streams the output to w.
@@ -611,6 +1545,158 @@ func (e *Empire) Deploy(ctx context.Context, opts DeployOpts) (*Release, error)
 		return r, err
 	}
 
+	return r, e.PublishEvent(e.deployEvent(opts, r))
+}
+
+// QueueDeploy queues a deployment of an image, which will be performed by a
+// DeploymentWorker. opts.Output is ignored.
+func (e *Empire) QueueDeploy(ctx context.Context, opts DeployOpts) (*Deployment, error) {
+	if opts.Canary != nil {
+		return nil, &ValidationError{Err: errors.New("Canary deployments can't be queued.")}
+	}
+
+	if err := opts.Validate(e); err != nil {
+		return nil, err
+	}
+
+	githubToken, err := e.encryptGitHubToken(opts.User.GitHubToken)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := e.db.Begin()
+
+	// If no app is specified, attempt to find the app that relates to this
+	// images repository, or create it if not found.
+	app := opts.App
+	if app == nil {
+		app, err = appsFindOrCreateByRepo(tx, opts.Image.Repository)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	d, err := deploymentsCreate(tx, &Deployment{
+		AppID:      app.ID,
+		Image:      opts.Image,
+		UserName:   opts.User.Name,
+		UserScopes: opts.User.Scopes,
+		Message:    opts.Message,
+		Stream:     opts.Stream,
+		Status:     DeploymentPending,
+	}, githubToken)
+	if err != nil {
+		tx.Rollback()
+		return d, err
+	}
+	d.App = app
+
+	return d, tx.Commit().Error
+}
+
+// FollowDeployment writes the output of a queued deployment to w while it's
+// being performed, and returns the deployment once it has finished.
+func (e *Empire) FollowDeployment(ctx context.Context, d *Deployment, w io.Writer) (*Deployment, error) {
+	return deploymentsFollow(ctx, e.db, d, w, e.DeploymentOutputPollInterval)
+}
+
+// QueueDeployAndWait queues a deployment like QueueDeploy, then waits for a
+// DeploymentWorker to perform it, writing its output to opts.Output. Like
+// Deploy, it returns the release that was created.
+func (e *Empire) QueueDeployAndWait(ctx context.Context, opts DeployOpts) (*Release, error) {
+	d, err := e.QueueDeploy(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err = e.FollowDeployment(ctx, d, opts.Output)
+	if err != nil {
+		return nil, err
+	}
+
+	var r *Release
+	if d.ReleaseVersion != nil {
+		r, err = releasesFind(e.db, ReleasesQuery{App: d.App, Version: d.ReleaseVersion})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return r, d.Err()
+}
+
+// CanariesFind returns the first canary matching the query.
+func (e *Empire) CanariesFind(q CanariesQuery) (*Canary, error) {
+	return canariesFind(e.db, q)
+}
+
+// PromoteCanary promotes the running canary of an app, which releases it to all
+// of the app's processes. If the app doesn't have a running canary,
+// gorm.RecordNotFound is returned.
+func (e *Empire) PromoteCanary(ctx context.Context, opts PromoteCanaryOpts) (*Canary, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, err
+	}
+
+	tx := e.db.Begin()
+
+	c, err := canariesLockRunning(tx, opts.App)
+	if err != nil {
+		tx.Rollback()
+		return c, err
+	}
+
+	event, err := e.canaries.promote(ctx, tx, c, opts.User, opts.Message, false)
+	if err != nil {
+		tx.Rollback()
+		return c, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c, err
+	}
+
+	return c, e.PublishEvent(*event)
+}
+
+// AbortCanary aborts the running canary of an app, and rolls the app back to
+// the stable release. If the app doesn't have a running canary,
+// gorm.RecordNotFound is returned.
+func (e *Empire) AbortCanary(ctx context.Context, opts AbortCanaryOpts) (*Canary, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, err
+	}
+
+	tx := e.db.Begin()
+
+	c, err := canariesLockRunning(tx, opts.App)
+	if err != nil {
+		tx.Rollback()
+		return c, err
+	}
+
+	event, err := e.canaries.abort(ctx, tx, c, opts.User, opts.Message, "", false)
+	if err != nil {
+		tx.Rollback()
+		return c, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c, err
+	}
+
+	return c, e.PublishEvent(*event)
+}
+
+// DeploymentsFind returns the first deployment matching the query.
+func (e *Empire) DeploymentsFind(q DeploymentsQuery) (*Deployment, error) {
+	return deploymentsFind(e.db, q)
+}
+
+// deployEvent returns the DeployEvent for the release that was created by the
+// deployment.
+func (e *Empire) deployEvent(opts DeployOpts, r *Release) DeployEvent {
 	event := opts.Event()
 	event.Release = r.Version
 	event.Environment = e.Environment
@@ -619,8 +1705,7 @@ func (e *Empire) Deploy(ctx context.Context, opts DeployOpts) (*Release, error)
 		event.App = r.App.Name
 		event.app = r.App
 	}
-
-	return r, e.PublishEvent(event)
+	return event
 }
 
 type ProcessUpdate struct {
@@ -646,14 +1731,18 @@ type ScaleOpts struct {
 
 	// Commit message
 	Message string
+
+	// Set when the process is being scaled by a scale schedule.
+	automatic bool
 }
 
 func (opts ScaleOpts) Event() ScaleEvent {
 	e := ScaleEvent{
-		User:    opts.User.Name,
-		App:     opts.App.Name,
-		Message: opts.Message,
-		app:     opts.App,
+		User:      opts.User.Name,
+		App:       opts.App.Name,
+		Message:   opts.Message,
+		Automatic: opts.automatic,
+		app:       opts.App,
 	}
 
 	var updates []*ScaleEventUpdate
@@ -672,7 +1761,11 @@ func (opts ScaleOpts) Event() ScaleEvent {
 }
 
 func (opts ScaleOpts) Validate(e *Empire) error {
-	return e.requireMessages(opts.Message)
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	return e.authorize(opts.User, ActionScale, opts.App)
 }
 
 // Scale scales an apps processes.
@@ -692,6 +1785,69 @@ func (e *Empire) Scale(ctx context.Context, opts ScaleOpts) ([]*Process, error)
 	return ps, tx.Commit().Error
 }
 
+// AutoscaleOpts are options provided when changing how a process is scaled
+// automatically.
+type AutoscaleOpts struct {
+	// User that's performing the action.
+	User *User
+
+	// The associated app.
+	App *App
+
+	// The process to autoscale.
+	Process string
+
+	// The new autoscaling settings. When nil, autoscaling is disabled, and
+	// the process keeps its current quantity.
+	Autoscaling *Autoscaling
+
+	// Commit message
+	Message string
+}
+
+func (opts AutoscaleOpts) Event() AutoscaleEvent {
+	return AutoscaleEvent{
+		User:        opts.User.Name,
+		App:         opts.App.Name,
+		Process:     opts.Process,
+		Autoscaling: opts.Autoscaling,
+		Message:     opts.Message,
+		app:         opts.App,
+	}
+}
+
+func (opts AutoscaleOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	if opts.Autoscaling != nil {
+		if err := opts.Autoscaling.IsValid(); err != nil {
+			return &ValidationError{Err: err}
+		}
+	}
+
+	return e.authorize(opts.User, ActionScale, opts.App)
+}
+
+// Autoscale changes how a process is scaled automatically. The current
+// quantity of the process is adjusted to fit within the new bounds.
+func (e *Empire) Autoscale(ctx context.Context, opts AutoscaleOpts) (*Process, error) {
+	if err := opts.Validate(e); err != nil {
+		return nil, err
+	}
+
+	tx := e.db.Begin()
+
+	p, err := e.apps.Autoscale(ctx, tx, opts)
+	if err != nil {
+		tx.Rollback()
+		return p, err
+	}
+
+	return p, tx.Commit().Error
+}
+
 // ListScale lists the current scale settings for a given App
 func (e *Empire) ListScale(ctx context.Context, app *App) (Formation, error) {
 	return currentFormation(e.db, app)
@@ -7,6 +7,7 @@ import (
 	"os"
 	"testing"
 	"text/template"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -49,6 +50,7 @@ func NewEmpire(t testing.TB) *empire.Empire {
 	e.Scheduler = scheduler.NewFakeScheduler()
 	e.ProcfileExtractor = ExtractProcfile(nil)
 	e.RunRecorder = empire.RecordTo(ioutil.Discard)
+	e.DeploymentOutputPollInterval = 10 * time.Millisecond
 
 	if err := e.Reset(); err != nil {
 		t.Fatal(err)
@@ -60,6 +62,8 @@ func NewEmpire(t testing.TB) *empire.Empire {
 type Server struct {
 	*empire.Empire
 	*httptest.Server
+
+	deployments *empire.DeploymentWorker
 }
 
 // NewServer builds a new empire.Empire instance and returns an httptest.Server
@@ -79,15 +83,23 @@ func NewTestServer(t testing.TB, e *empire.Empire, opts server.Options) *Server
 		e = NewEmpire(t)
 	}
 
+	// Deployments are queued, so a worker needs to be running to perform
+	// them.
+	dw := empire.NewDeploymentWorker(e)
+	dw.PollInterval = 10 * time.Millisecond
+	go dw.Start()
+
 	s := server.New(e, opts)
 	return &Server{
-		Empire: e,
-		Server: httptest.NewServer(middleware.Handler(context.Background(), s)),
+		Empire:      e,
+		Server:      httptest.NewServer(middleware.Handler(context.Background(), s)),
+		deployments: dw,
 	}
 }
 
 func (s *Server) Close() {
 	s.Server.Close()
+	s.deployments.Stop()
 }
 
 var dblock = "/tmp/empire.lock"
@@ -94,13 +94,19 @@ type ScaleEventUpdate struct {
 	PreviousConstraints Constraints
 }
 
-// ScaleEvent is triggered when a manual scaling event happens.
+// ScaleEvent is triggered when a process is scaled, either manually, or
+// automatically.
 type ScaleEvent struct {
 	User    string
 	App     string
 	Updates []*ScaleEventUpdate
 	Message string
 
+	// True when the quantity was changed automatically, like by a scale
+	// schedule, rather than by a user. User is who set up the automatic
+	// scaling.
+	Automatic bool
+
 	app *App
 }
 
@@ -126,17 +132,30 @@ func (e ScaleEvent) String() string {
 			newConstraints.Nproc = previousConstraints.Nproc
 		}
 
-		msg += fmt.Sprintf(
-			"%s%s scaled `%s` on %s from %d(%s) to %d(%s)",
-			sep,
-			e.User,
-			up.Process,
-			e.App,
-			up.PreviousQuantity,
-			up.PreviousConstraints,
-			up.Quantity,
-			newConstraints,
-		)
+		if e.Automatic {
+			msg += fmt.Sprintf(
+				"%s`%s` on %s was automatically scaled from %d(%s) to %d(%s)",
+				sep,
+				up.Process,
+				e.App,
+				up.PreviousQuantity,
+				up.PreviousConstraints,
+				up.Quantity,
+				newConstraints,
+			)
+		} else {
+			msg += fmt.Sprintf(
+				"%s%s scaled `%s` on %s from %d(%s) to %d(%s)",
+				sep,
+				e.User,
+				up.Process,
+				e.App,
+				up.PreviousQuantity,
+				up.PreviousConstraints,
+				up.Quantity,
+				newConstraints,
+			)
+		}
 		sep = "\n"
 	}
 	return appendCommitMessage(msg, e.Message)
@@ -146,6 +165,43 @@ func (e ScaleEvent) GetApp() *App {
 	return e.app
 }
 
+// AutoscaleEvent is triggered when a user changes how a process is scaled
+// automatically.
+type AutoscaleEvent struct {
+	User    string
+	App     string
+	Process string
+	Message string
+
+	// The new autoscaling settings, or nil if autoscaling was disabled.
+	Autoscaling         *Autoscaling
+	PreviousAutoscaling *Autoscaling
+
+	// The quantity is adjusted to fit within the new bounds.
+	Quantity         int
+	PreviousQuantity int
+
+	app *App
+}
+
+func (e AutoscaleEvent) Event() string {
+	return "autoscale"
+}
+
+func (e AutoscaleEvent) String() string {
+	var msg string
+	if e.Autoscaling == nil {
+		msg = fmt.Sprintf("%s disabled autoscaling for `%s` on %s", e.User, e.Process, e.App)
+	} else {
+		msg = fmt.Sprintf("%s autoscaled `%s` on %s to %s", e.User, e.Process, e.App, e.Autoscaling)
+	}
+	return appendCommitMessage(msg, e.Message)
+}
+
+func (e AutoscaleEvent) GetApp() *App {
+	return e.app
+}
+
 // DeployEvent is triggered when a user deploys a new image to an app.
 type DeployEvent struct {
 	User        string
@@ -155,6 +211,9 @@ type DeployEvent struct {
 	Release     int
 	Message     string
 
+	// Set when the release was deployed as a canary.
+	Canary bool `json:",omitempty"`
+
 	app *App
 }
 
@@ -169,6 +228,9 @@ func (e DeployEvent) String() string {
 	} else {
 		msg = fmt.Sprintf("%s deployed %s to %s %s (v%d)", e.User, e.Image, e.App, e.Environment, e.Release)
 	}
+	if e.Canary {
+		msg += " as a canary"
+	}
 	return appendCommitMessage(msg, e.Message)
 }
 
@@ -183,6 +245,11 @@ type RollbackEvent struct {
 	Version int
 	Message string
 
+	// Set when Empire rolled back the app because the release with the
+	// FailedVersion failed to stabilize.
+	Automatic     bool
+	FailedVersion int `json:",omitempty"`
+
 	app *App
 }
 
@@ -191,6 +258,10 @@ func (e RollbackEvent) Event() string {
 }
 
 func (e RollbackEvent) String() string {
+	if e.Automatic {
+		return fmt.Sprintf("%s was automatically rolled back to v%d after v%d (deployed by %s) failed to stabilize", e.App, e.Version, e.FailedVersion, e.User)
+	}
+
 	msg := fmt.Sprintf("%s rolled back %s to v%d", e.User, e.App, e.Version)
 	return appendCommitMessage(msg, e.Message)
 }
@@ -223,6 +294,232 @@ func (e SetEvent) GetApp() *App {
 	return e.app
 }
 
+// ConfigEvent is triggered when a user reads the config vars of an
+// application, or a config group.
+type ConfigEvent struct {
+	User     string
+	App      string
+	Group    string
+	Revealed bool
+
+	app *App
+}
+
+func (e ConfigEvent) Event() string {
+	return "config"
+}
+
+func (e ConfigEvent) String() string {
+	target := e.App
+	if e.Group != "" {
+		target = fmt.Sprintf("config group %s", e.Group)
+	}
+
+	if e.Revealed {
+		return fmt.Sprintf("%s revealed environment variables on %s", e.User, target)
+	}
+	return fmt.Sprintf("%s viewed environment variables on %s", e.User, target)
+}
+
+func (e ConfigEvent) GetApp() *App {
+	return e.app
+}
+
+// ConfigSensitivityEvent is triggered when a user marks environment variables
+// as sensitive, or not sensitive.
+type ConfigSensitivityEvent struct {
+	User        string
+	App         string
+	Sensitive   []string
+	Insensitive []string
+	Message     string
+
+	app *App
+}
+
+func (e ConfigSensitivityEvent) Event() string {
+	return "config_sensitivity"
+}
+
+func (e ConfigSensitivityEvent) String() string {
+	var changes []string
+	if len(e.Sensitive) > 0 {
+		changes = append(changes, fmt.Sprintf("%s as sensitive", strings.Join(e.Sensitive, ", ")))
+	}
+	if len(e.Insensitive) > 0 {
+		changes = append(changes, fmt.Sprintf("%s as not sensitive", strings.Join(e.Insensitive, ", ")))
+	}
+	msg := fmt.Sprintf("%s marked %s on %s", e.User, strings.Join(changes, " and "), e.App)
+	return appendCommitMessage(msg, e.Message)
+}
+
+func (e ConfigSensitivityEvent) GetApp() *App {
+	return e.app
+}
+
+// ConfigGroupSetEvent is triggered when a user changes the config vars of a
+// config group. Apps that are attached to the config group are released
+// afterwards, without an event for each app.
+type ConfigGroupSetEvent struct {
+	User    string
+	Group   string
+	Changed []string
+	Apps    []string
+	Message string
+}
+
+func (e ConfigGroupSetEvent) Event() string {
+	return "config_group_set"
+}
+
+func (e ConfigGroupSetEvent) String() string {
+	msg := fmt.Sprintf("%s changed environment variables on config group %s (%s)", e.User, e.Group, strings.Join(e.Changed, ", "))
+	if len(e.Apps) > 0 {
+		msg = fmt.Sprintf("%s, releasing %s", msg, strings.Join(e.Apps, ", "))
+	}
+	return appendCommitMessage(msg, e.Message)
+}
+
+// ConfigGroupDestroyEvent is triggered when a user destroys a config group.
+type ConfigGroupDestroyEvent struct {
+	User    string
+	Group   string
+	Message string
+}
+
+func (e ConfigGroupDestroyEvent) Event() string {
+	return "config_group_destroy"
+}
+
+func (e ConfigGroupDestroyEvent) String() string {
+	msg := fmt.Sprintf("%s destroyed config group %s", e.User, e.Group)
+	return appendCommitMessage(msg, e.Message)
+}
+
+// ConfigGroupAttachEvent is triggered when a user attaches a config group to
+// an application, or detaches it.
+type ConfigGroupAttachEvent struct {
+	User     string
+	App      string
+	Group    string
+	Detached bool
+	Message  string
+
+	app *App
+}
+
+func (e ConfigGroupAttachEvent) Event() string {
+	return "config_group_attach"
+}
+
+func (e ConfigGroupAttachEvent) String() string {
+	var msg string
+	if e.Detached {
+		msg = fmt.Sprintf("%s detached config group %s from %s", e.User, e.Group, e.App)
+	} else {
+		msg = fmt.Sprintf("%s attached config group %s to %s", e.User, e.Group, e.App)
+	}
+	return appendCommitMessage(msg, e.Message)
+}
+
+func (e ConfigGroupAttachEvent) GetApp() *App {
+	return e.app
+}
+
+// PromoteEvent is triggered when a user promotes a release to the next app in
+// a pipeline.
+type PromoteEvent struct {
+	User        string
+	App         string
+	From        string
+	FromVersion int
+	Image       string
+	Release     int
+	Message     string
+
+	app *App
+}
+
+func (e PromoteEvent) Event() string {
+	return "promote"
+}
+
+func (e PromoteEvent) String() string {
+	msg := fmt.Sprintf("%s promoted %s v%d (%s) to %s (v%d)", e.User, e.From, e.FromVersion, e.Image, e.App, e.Release)
+	return appendCommitMessage(msg, e.Message)
+}
+
+func (e PromoteEvent) GetApp() *App {
+	return e.app
+}
+
+// CanaryEvent is triggered when a canary is promoted or aborted, either by a
+// user, or automatically by Empire.
+type CanaryEvent struct {
+	User          string
+	App           string
+	Release       int
+	StableRelease int
+	Promoted      bool
+	Message       string
+
+	// Set when Empire promoted or aborted the canary. User is the user
+	// that deployed the canary.
+	Automatic bool
+
+	// Set when the canary was aborted automatically.
+	Reason string `json:",omitempty"`
+
+	app *App
+}
+
+func (e CanaryEvent) Event() string {
+	return "canary"
+}
+
+func (e CanaryEvent) String() string {
+	switch {
+	case e.Promoted && e.Automatic:
+		return fmt.Sprintf("canary v%d of %s (deployed by %s) was automatically promoted", e.Release, e.App, e.User)
+	case e.Automatic:
+		return fmt.Sprintf("canary v%d of %s (deployed by %s) was automatically aborted and rolled back to v%d: %s", e.Release, e.App, e.User, e.StableRelease, e.Reason)
+	case e.Promoted:
+		msg := fmt.Sprintf("%s promoted canary v%d of %s", e.User, e.Release, e.App)
+		return appendCommitMessage(msg, e.Message)
+	default:
+		msg := fmt.Sprintf("%s aborted canary v%d of %s, and rolled back to v%d", e.User, e.Release, e.App, e.StableRelease)
+		return appendCommitMessage(msg, e.Message)
+	}
+}
+
+func (e CanaryEvent) GetApp() *App {
+	return e.app
+}
+
+// PipelineEvent is triggered when a user creates or changes a pipeline, or
+// destroys it.
+type PipelineEvent struct {
+	User      string
+	Name      string
+	Apps      []string
+	Destroyed bool
+	Message   string
+}
+
+func (e PipelineEvent) Event() string {
+	return "pipeline"
+}
+
+func (e PipelineEvent) String() string {
+	var msg string
+	if e.Destroyed {
+		msg = fmt.Sprintf("%s destroyed pipeline %s", e.User, e.Name)
+	} else {
+		msg = fmt.Sprintf("%s set pipeline %s to %s", e.User, e.Name, strings.Join(e.Apps, " -> "))
+	}
+	return appendCommitMessage(msg, e.Message)
+}
+
 // CreateEvent is triggered when a user creates a new application.
 type CreateEvent struct {
 	User    string
@@ -255,6 +552,98 @@ func (e DestroyEvent) String() string {
 	return appendCommitMessage(msg, e.Message)
 }
 
+// RenameEvent is triggered when a user renames an application.
+type RenameEvent struct {
+	User         string
+	PreviousName string
+	Name         string
+	Message      string
+
+	app *App
+}
+
+func (e RenameEvent) Event() string {
+	return "rename"
+}
+
+func (e RenameEvent) String() string {
+	msg := fmt.Sprintf("%s renamed %s to %s", e.User, e.PreviousName, e.Name)
+	return appendCommitMessage(msg, e.Message)
+}
+
+func (e RenameEvent) GetApp() *App {
+	return e.app
+}
+
+// GrantEvent is triggered when a user grants a permission on an application.
+type GrantEvent struct {
+	User      string
+	App       string
+	Principal string
+	Action    string
+	Message   string
+}
+
+func (e GrantEvent) Event() string {
+	return "grant"
+}
+
+func (e GrantEvent) String() string {
+	msg := fmt.Sprintf("%s granted %s access to %s %s", e.User, e.Principal, e.Action, e.App)
+	return appendCommitMessage(msg, e.Message)
+}
+
+// RevokeEvent is triggered when a user revokes a permission on an
+// application.
+type RevokeEvent struct {
+	User      string
+	App       string
+	Principal string
+	Action    string
+	Message   string
+}
+
+func (e RevokeEvent) Event() string {
+	return "revoke"
+}
+
+func (e RevokeEvent) String() string {
+	msg := fmt.Sprintf("%s revoked %s access to %s %s", e.User, e.Principal, e.Action, e.App)
+	return appendCommitMessage(msg, e.Message)
+}
+
+// ScaleScheduleEvent is triggered when a user schedules a process to be
+// scaled, or removes a schedule.
+type ScaleScheduleEvent struct {
+	User     string
+	App      string
+	Process  string
+	Cron     string
+	Quantity int
+	Removed  bool
+	Message  string
+
+	app *App
+}
+
+func (e ScaleScheduleEvent) Event() string {
+	return "scale_schedule"
+}
+
+func (e ScaleScheduleEvent) String() string {
+	var msg string
+	if e.Removed {
+		msg = fmt.Sprintf("%s removed the schedule to scale `%s` on %s to %d at `%s`", e.User, e.Process, e.App, e.Quantity, e.Cron)
+	} else {
+		msg = fmt.Sprintf("%s scheduled `%s` on %s to scale to %d at `%s`", e.User, e.Process, e.App, e.Quantity, e.Cron)
+	}
+	return appendCommitMessage(msg, e.Message)
+}
+
+func (e ScaleScheduleEvent) GetApp() *App {
+	return e.app
+}
+
 // Event represents an event triggered within Empire.
 type Event interface {
 	// Returns the name of the event.
@@ -0,0 +1,137 @@
+package webhook
+
+import (
+	"database/sql"
+	"time"
+)
+
+// delivery represents a pending delivery of an event to a webhook.
+type delivery struct {
+	ID    string
+	URL   string
+	Event string
+	Body  []byte
+
+	// The number of failed attempts so far.
+	Attempts int
+
+	// The error from the last failed attempt.
+	LastError string
+
+	// When the next attempt should be made.
+	NextAttemptAt time.Time
+
+	// Set after an attempt if the delivery succeeded.
+	Delivered bool
+
+	// Set after an attempt if the delivery should no longer be retried.
+	Failed bool
+}
+
+// deliveryQueue represents a durable queue of deliveries.
+type deliveryQueue interface {
+	// push adds a new delivery to the queue.
+	push(*delivery) error
+
+	// process claims up to n deliveries that are due, calls fn for each,
+	// then persists the updated state of the delivery. Deliveries that are
+	// claimed aren't claimed again until their result is persisted, or
+	// the claim expires. It returns the number of deliveries that were
+	// claimed.
+	process(n int, fn func(*delivery) error) (int, error)
+}
+
+// dbQueue is a deliveryQueue backed by the webhook_deliveries table in
+// Postgres.
+type dbQueue struct {
+	db *sql.DB
+}
+
+func (q *dbQueue) push(d *delivery) error {
+	_, err := q.db.Exec(`INSERT INTO webhook_deliveries (url, event, body) VALUES ($1, $2, $3)`, d.URL, d.Event, string(d.Body))
+	return err
+}
+
+// claimTimeout is how long a delivery is claimed for. If the Empire instance
+// that claimed it stops before recording the result, the delivery is
+// attempted again once the claim expires.
+const claimTimeout = 5 * time.Minute
+
+func (q *dbQueue) process(n int, fn func(*delivery) error) (int, error) {
+	deliveries, err := q.claim(n)
+	if err != nil {
+		return 0, err
+	}
+
+	// Deliveries are made after the claim is committed, so that row locks
+	// and connections aren't held while waiting on webhooks.
+	for _, d := range deliveries {
+		if err := fn(d); err != nil {
+			return 0, err
+		}
+
+		if err := q.update(d); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// claim claims up to n deliveries that are due, by moving their next attempt
+// forward, so that other Empire instances don't deliver them at the same time.
+func (q *dbQueue) claim(n int) ([]*delivery, error) {
+	tx, err := q.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`SELECT id, url, event, body, attempts FROM webhook_deliveries WHERE failed_at IS NULL AND next_attempt_at <= (now() at time zone 'utc') ORDER BY next_attempt_at LIMIT $1 FOR UPDATE SKIP LOCKED`, n)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var deliveries []*delivery
+	for rows.Next() {
+		var (
+			d    delivery
+			body string
+		)
+		if err := rows.Scan(&d.ID, &d.URL, &d.Event, &body, &d.Attempts); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, err
+		}
+		d.Body = []byte(body)
+		deliveries = append(deliveries, &d)
+	}
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	claimedUntil := time.Now().Add(claimTimeout).UTC()
+	for _, d := range deliveries {
+		if _, err := tx.Exec(`UPDATE webhook_deliveries SET next_attempt_at = $2 WHERE id = $1`, d.ID, claimedUntil); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	return deliveries, tx.Commit()
+}
+
+// update persists the result of a delivery attempt.
+func (q *dbQueue) update(d *delivery) error {
+	var err error
+	switch {
+	case d.Delivered:
+		_, err = q.db.Exec(`DELETE FROM webhook_deliveries WHERE id = $1`, d.ID)
+	case d.Failed:
+		_, err = q.db.Exec(`UPDATE webhook_deliveries SET attempts = $2, last_error = $3, failed_at = (now() at time zone 'utc') WHERE id = $1`, d.ID, d.Attempts, d.LastError)
+	default:
+		_, err = q.db.Exec(`UPDATE webhook_deliveries SET attempts = $2, last_error = $3, next_attempt_at = $4 WHERE id = $1`, d.ID, d.Attempts, d.LastError, d.NextAttemptAt.UTC())
+	}
+	return err
+}
@@ -0,0 +1,313 @@
+// Package webhook provides an empire.EventStream implementation that POSTs
+// events to HTTP endpoints.
+//
+// Events are not delivered inline. Instead, a delivery for each matching
+// webhook is written to a durable queue in Postgres, and a worker (started
+// with Start) POSTs them, retrying failed deliveries with exponential backoff.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/remind101/empire"
+	"github.com/remind101/pkg/reporter"
+	"golang.org/x/net/context"
+)
+
+// SignatureHeader is the HTTP header that contains the HMAC signature of the
+// request body, in the form "sha256=<hex digest>".
+const SignatureHeader = "X-Empire-Signature"
+
+// EventHeader is the HTTP header that contains the type of event being
+// delivered.
+const EventHeader = "X-Empire-Event"
+
+const (
+	// DefaultMaxAttempts is the default number of times a delivery will be
+	// attempted before it's marked as failed.
+	DefaultMaxAttempts = 10
+
+	// DefaultPollInterval is the default amount of time to wait between
+	// checking for pending deliveries.
+	DefaultPollInterval = 5 * time.Second
+
+	// The number of deliveries to claim from the queue at once.
+	batchSize = 10
+)
+
+var (
+	// The base amount of time to wait before retrying a failed delivery.
+	// This doubles after each failed attempt.
+	baseBackoff = 10 * time.Second
+
+	// The maximum amount of time to wait before retrying a failed delivery.
+	maxBackoff = 1 * time.Hour
+)
+
+// Event represents the schema for a webhook payload. This is the same
+// envelope that's used by the sns backend.
+type Event struct {
+	Event   string
+	Message string
+	Data    interface{}
+}
+
+// Webhook represents an HTTP endpoint that events should be delivered to.
+type Webhook struct {
+	// The url to POST events to.
+	URL string
+
+	// If provided, only events of these types will be delivered to this
+	// webhook. The default is to deliver all events.
+	Events []string
+}
+
+// ParseWebhook parses a webhook in the form "url" or
+// "event1|event2=url".
+func ParseWebhook(s string) (*Webhook, error) {
+	w := &Webhook{URL: s}
+
+	if i := strings.Index(s, "="); i != -1 && !strings.Contains(s[:i], "/") {
+		w.URL = s[i+1:]
+		for _, event := range strings.Split(s[:i], "|") {
+			if event != "" {
+				w.Events = append(w.Events, event)
+			}
+		}
+	}
+
+	if w.URL == "" {
+		return nil, fmt.Errorf("webhook: no url provided in %q", s)
+	}
+
+	return w, nil
+}
+
+// Accepts returns true if events of the given type should be delivered to
+// this webhook.
+func (w *Webhook) Accepts(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// EventStream is an implementation of the empire.EventStream interface that
+// delivers events to webhooks.
+type EventStream struct {
+	// Root context.Context to use. If a reporter.Reporter is embedded,
+	// errors generated will be reporter there.
+	Context context.Context
+
+	// The webhooks to deliver events to.
+	Webhooks []*Webhook
+
+	// If provided, request bodies will be signed with this secret, and the
+	// signature will be provided in the X-Empire-Signature header.
+	Secret []byte
+
+	// The number of times a delivery will be attempted before giving up.
+	// The zero value is DefaultMaxAttempts.
+	MaxAttempts int
+
+	// The amount of time to wait between checking for pending deliveries.
+	// The zero value is DefaultPollInterval.
+	PollInterval time.Duration
+
+	// The http.Client used to make requests. The zero value is a client
+	// with a 10 second timeout.
+	Client *http.Client
+
+	queue deliveryQueue
+
+	stopped chan struct{}
+}
+
+// NewEventStream returns a new EventStream that queues deliveries in the
+// given database.
+func NewEventStream(db *sql.DB) *EventStream {
+	return &EventStream{
+		Context: context.Background(),
+		queue:   &dbQueue{db: db},
+		stopped: make(chan struct{}),
+	}
+}
+
+// PublishEvent queues a delivery of the event to each webhook that accepts
+// it.
+func (e *EventStream) PublishEvent(event empire.Event) error {
+	raw, err := json.Marshal(&Event{
+		Event:   event.Event(),
+		Message: event.String(),
+		Data:    event,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, w := range e.Webhooks {
+		if !w.Accepts(event.Event()) {
+			continue
+		}
+
+		if err := e.queue.push(&delivery{
+			URL:   w.URL,
+			Event: event.Event(),
+			Body:  raw,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Start starts delivering queued events. It blocks until Stop is called.
+func (e *EventStream) Start() {
+	t := time.NewTicker(e.pollInterval())
+	defer t.Stop()
+
+	for {
+		select {
+		case <-e.stopped:
+			return
+		case <-t.C:
+			if err := e.deliverPending(); err != nil {
+				reporter.Report(e.Context, err)
+			}
+		}
+	}
+}
+
+// Stop stops delivering events.
+func (e *EventStream) Stop() {
+	close(e.stopped)
+}
+
+// deliverPending attempts to deliver all deliveries that are currently due.
+func (e *EventStream) deliverPending() error {
+	for {
+		n, err := e.queue.process(batchSize, func(d *delivery) error {
+			return e.attempt(d)
+		})
+		if err != nil {
+			return err
+		}
+
+		if n < batchSize {
+			return nil
+		}
+	}
+}
+
+// attempt attempts to deliver d, and updates it's state to reflect the
+// result. Errors returned from here are errors updating the queue; delivery
+// errors are recorded on the delivery itself.
+func (e *EventStream) attempt(d *delivery) error {
+	err := e.deliver(d)
+	if err == nil {
+		d.Delivered = true
+		return nil
+	}
+
+	d.Attempts++
+	d.LastError = err.Error()
+	if d.Attempts >= e.maxAttempts() {
+		d.Failed = true
+		reporter.Report(e.Context, fmt.Errorf("webhook: giving up delivering %s event to %s after %d attempts: %v", d.Event, d.URL, d.Attempts, err))
+	} else {
+		d.NextAttemptAt = time.Now().Add(backoff(d.Attempts))
+	}
+
+	return nil
+}
+
+// deliver POSTs the delivery to the webhook url. Any non 2xx response is
+// treated as an error.
+func (e *EventStream) deliver(d *delivery) error {
+	req, err := http.NewRequest("POST", d.URL, bytes.NewReader(d.Body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, d.Event)
+	if len(e.Secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(e.Secret, d.Body))
+	}
+
+	resp, err := e.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Drain the body so that the connection can be reused.
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected response: %s", resp.Status)
+	}
+
+	return nil
+}
+
+func (e *EventStream) maxAttempts() int {
+	if e.MaxAttempts == 0 {
+		return DefaultMaxAttempts
+	}
+	return e.MaxAttempts
+}
+
+func (e *EventStream) pollInterval() time.Duration {
+	if e.PollInterval == 0 {
+		return DefaultPollInterval
+	}
+	return e.PollInterval
+}
+
+func (e *EventStream) client() *http.Client {
+	if e.Client == nil {
+		return &http.Client{Timeout: 10 * time.Second}
+	}
+	return e.Client
+}
+
+// Sign returns the value of the X-Empire-Signature header for the given body.
+// Receivers can use this to verify that a request came from Empire.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the amount of time to wait before the next attempt, after
+// the given number of failed attempts.
+func backoff(attempts int) time.Duration {
+	d := baseBackoff
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestEvents_PublishEvent(t *testing.T) {
+	q := new(memQueue)
+	e := &EventStream{
+		Webhooks: []*Webhook{
+			{URL: "http://example.com/all"},
+			{URL: "http://example.com/deploys", Events: []string{"deploy"}},
+		},
+		queue: q,
+	}
+
+	err := e.PublishEvent(fakeEvent{
+		User: "ejholmes",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []*delivery{
+		{URL: "http://example.com/all", Event: "fake", Body: []byte(`{"Event":"fake","Message":"ejholmes did something","Data":{"User":"ejholmes"}}`)},
+	}, q.deliveries)
+}
+
+func TestEvents_Deliver(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "fake", r.Header.Get(EventHeader))
+		assert.Equal(t, Sign([]byte("secret"), raw), r.Header.Get(SignatureHeader))
+	}))
+	defer s.Close()
+
+	q := new(memQueue)
+	e := &EventStream{
+		Webhooks: []*Webhook{{URL: s.URL}},
+		Secret:   []byte("secret"),
+		queue:    q,
+	}
+
+	err := e.PublishEvent(fakeEvent{User: "ejholmes"})
+	assert.NoError(t, err)
+
+	err = e.deliverPending()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(q.deliveries))
+}
+
+func TestEvents_Deliver_Retry(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer s.Close()
+
+	q := new(memQueue)
+	e := &EventStream{
+		Context:     context.Background(),
+		Webhooks:    []*Webhook{{URL: s.URL}},
+		MaxAttempts: 2,
+		queue:       q,
+	}
+
+	err := e.PublishEvent(fakeEvent{User: "ejholmes"})
+	assert.NoError(t, err)
+
+	err = e.deliverPending()
+	assert.NoError(t, err)
+	d := q.deliveries[0]
+	assert.Equal(t, 1, d.Attempts)
+	assert.Equal(t, "unexpected response: 500 Internal Server Error", d.LastError)
+	assert.False(t, d.Failed)
+	assert.True(t, d.NextAttemptAt.After(time.Now()))
+
+	// Not due yet.
+	err = e.deliverPending()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, d.Attempts)
+
+	d.NextAttemptAt = time.Time{}
+	err = e.deliverPending()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, d.Attempts)
+	assert.True(t, d.Failed)
+}
+
+func TestParseWebhook(t *testing.T) {
+	tests := []struct {
+		in  string
+		out *Webhook
+	}{
+		{"http://example.com", &Webhook{URL: "http://example.com"}},
+		{"http://example.com/?a=b", &Webhook{URL: "http://example.com/?a=b"}},
+		{"deploy=http://example.com", &Webhook{URL: "http://example.com", Events: []string{"deploy"}}},
+		{"deploy|rollback=http://example.com/?a=b", &Webhook{URL: "http://example.com/?a=b", Events: []string{"deploy", "rollback"}}},
+	}
+
+	for _, tt := range tests {
+		w, err := ParseWebhook(tt.in)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.out, w)
+	}
+
+	_, err := ParseWebhook("deploy=")
+	assert.Error(t, err)
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		out      time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{9, 2560 * time.Second},
+		{10, 1 * time.Hour},
+		{100, 1 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.out, backoff(tt.attempts))
+	}
+}
+
+type fakeEvent struct {
+	User string
+}
+
+func (e fakeEvent) Event() string  { return "fake" }
+func (e fakeEvent) String() string { return fmt.Sprintf("%s did something", e.User) }
+
+// memQueue is an in memory implementation of the deliveryQueue interface.
+type memQueue struct {
+	deliveries []*delivery
+}
+
+func (q *memQueue) push(d *delivery) error {
+	q.deliveries = append(q.deliveries, d)
+	return nil
+}
+
+func (q *memQueue) process(n int, fn func(*delivery) error) (int, error) {
+	var (
+		claimed int
+		pending []*delivery
+	)
+	for _, d := range q.deliveries {
+		if claimed < n && !d.Failed && !d.NextAttemptAt.After(time.Now()) {
+			claimed++
+			if err := fn(d); err != nil {
+				return claimed, err
+			}
+		}
+		if !d.Delivered {
+			pending = append(pending, d)
+		}
+	}
+	q.deliveries = pending
+	return claimed, nil
+}
@@ -87,27 +87,100 @@ func TestEvents_String(t *testing.T) {
 			},
 			Message: "commit message",
 		}, "ejholmes scaled `web` on acme-inc from 5(512:1.00kb) to 10(512:1.00kb): 'commit message'"},
+		{ScaleEvent{
+			User:      "ejholmes",
+			App:       "acme-inc",
+			Automatic: true,
+			Updates: []*ScaleEventUpdate{
+				&ScaleEventUpdate{Process: "worker", Quantity: 20, PreviousQuantity: 0, PreviousConstraints: Constraints{CPUShare: 512, Memory: 1024}},
+			},
+			Message: "Scheduled (0 20 * * *)",
+		}, "`worker` on acme-inc was automatically scaled from 0(512:1.00kb) to 20(512:1.00kb): 'Scheduled (0 20 * * *)'"},
+		{AutoscaleEvent{
+			User:        "ejholmes",
+			App:         "acme-inc",
+			Process:     "web",
+			Quantity:    2,
+			Autoscaling: &Autoscaling{Min: 2, Max: 10, Metric: "cpu", Target: 50},
+		}, "ejholmes autoscaled `web` on acme-inc to 2-10 cpu=50"},
+		{AutoscaleEvent{
+			User:                "ejholmes",
+			App:                 "acme-inc",
+			Process:             "web",
+			Quantity:            2,
+			PreviousQuantity:    2,
+			PreviousAutoscaling: &Autoscaling{Min: 2, Max: 10, Metric: "cpu", Target: 50},
+			Message:             "commit message",
+		}, "ejholmes disabled autoscaling for `web` on acme-inc: 'commit message'"},
 
 		// DeployEvent
 		{DeployEvent{User: "ejholmes", App: "acme-inc", Image: "remind101/acme-inc:master", Environment: "production", Release: 32}, "ejholmes deployed remind101/acme-inc:master to acme-inc production (v32)"},
 		{DeployEvent{User: "ejholmes", Image: "remind101/acme-inc:master"}, "ejholmes deployed remind101/acme-inc:master"},
 		{DeployEvent{User: "ejholmes", App: "acme-inc", Image: "remind101/acme-inc:master", Environment: "production", Release: 32, Message: "commit message"}, "ejholmes deployed remind101/acme-inc:master to acme-inc production (v32): 'commit message'"},
 		{DeployEvent{User: "ejholmes", Image: "remind101/acme-inc:master", Message: "commit message"}, "ejholmes deployed remind101/acme-inc:master: 'commit message'"},
+		{DeployEvent{User: "ejholmes", App: "acme-inc", Image: "remind101/acme-inc:master", Environment: "production", Release: 32, Message: "commit message", Canary: true}, "ejholmes deployed remind101/acme-inc:master to acme-inc production (v32) as a canary: 'commit message'"},
 
 		// RollbackEvent
 		{RollbackEvent{User: "ejholmes", App: "acme-inc", Version: 1}, "ejholmes rolled back acme-inc to v1"},
 		{RollbackEvent{User: "ejholmes", App: "acme-inc", Version: 1, Message: "commit message"}, "ejholmes rolled back acme-inc to v1: 'commit message'"},
+		{RollbackEvent{User: "ejholmes", App: "acme-inc", Version: 1, Automatic: true, FailedVersion: 2}, "acme-inc was automatically rolled back to v1 after v2 (deployed by ejholmes) failed to stabilize"},
 
 		// SetEvent
 		{SetEvent{User: "ejholmes", App: "acme-inc", Changed: []string{"RAILS_ENV"}}, "ejholmes changed environment variables on acme-inc (RAILS_ENV)"},
 		{SetEvent{User: "ejholmes", App: "acme-inc", Changed: []string{"RAILS_ENV"}, Message: "commit message"}, "ejholmes changed environment variables on acme-inc (RAILS_ENV): 'commit message'"},
 
+		// ConfigEvent
+		{ConfigEvent{User: "ejholmes", App: "acme-inc"}, "ejholmes viewed environment variables on acme-inc"},
+		{ConfigEvent{User: "ejholmes", App: "acme-inc", Revealed: true}, "ejholmes revealed environment variables on acme-inc"},
+
+		// ConfigSensitivityEvent
+		{ConfigSensitivityEvent{User: "ejholmes", App: "acme-inc", Insensitive: []string{"RAILS_ENV"}}, "ejholmes marked RAILS_ENV as not sensitive on acme-inc"},
+		{ConfigSensitivityEvent{User: "ejholmes", App: "acme-inc", Sensitive: []string{"SECRET"}, Insensitive: []string{"PORT", "RAILS_ENV"}, Message: "commit message"}, "ejholmes marked SECRET as sensitive and PORT, RAILS_ENV as not sensitive on acme-inc: 'commit message'"},
+
+		{ConfigEvent{User: "ejholmes", Group: "shared"}, "ejholmes viewed environment variables on config group shared"},
+
+		// ConfigGroupSetEvent
+		{ConfigGroupSetEvent{User: "ejholmes", Group: "shared", Changed: []string{"STATSD_HOST"}}, "ejholmes changed environment variables on config group shared (STATSD_HOST)"},
+		{ConfigGroupSetEvent{User: "ejholmes", Group: "shared", Changed: []string{"STATSD_HOST"}, Apps: []string{"acme-inc", "api"}, Message: "commit message"}, "ejholmes changed environment variables on config group shared (STATSD_HOST), releasing acme-inc, api: 'commit message'"},
+
+		// ConfigGroupDestroyEvent
+		{ConfigGroupDestroyEvent{User: "ejholmes", Group: "shared"}, "ejholmes destroyed config group shared"},
+
+		// ConfigGroupAttachEvent
+		{ConfigGroupAttachEvent{User: "ejholmes", App: "acme-inc", Group: "shared"}, "ejholmes attached config group shared to acme-inc"},
+		{ConfigGroupAttachEvent{User: "ejholmes", App: "acme-inc", Group: "shared", Detached: true, Message: "commit message"}, "ejholmes detached config group shared from acme-inc: 'commit message'"},
+
+		// PromoteEvent
+		{PromoteEvent{User: "ejholmes", App: "acme-inc", From: "acme-inc-staging", FromVersion: 12, Image: "remind101/acme-inc:master", Release: 4}, "ejholmes promoted acme-inc-staging v12 (remind101/acme-inc:master) to acme-inc (v4)"},
+		{PromoteEvent{User: "ejholmes", App: "acme-inc", From: "acme-inc-staging", FromVersion: 12, Image: "remind101/acme-inc:master", Release: 4, Message: "commit message"}, "ejholmes promoted acme-inc-staging v12 (remind101/acme-inc:master) to acme-inc (v4): 'commit message'"},
+
+		// CanaryEvent
+		{CanaryEvent{User: "ejholmes", App: "acme-inc", Release: 33, StableRelease: 32, Promoted: true, Message: "commit message"}, "ejholmes promoted canary v33 of acme-inc: 'commit message'"},
+		{CanaryEvent{User: "ejholmes", App: "acme-inc", Release: 33, StableRelease: 32, Promoted: true, Automatic: true}, "canary v33 of acme-inc (deployed by ejholmes) was automatically promoted"},
+		{CanaryEvent{User: "ejholmes", App: "acme-inc", Release: 33, StableRelease: 32}, "ejholmes aborted canary v33 of acme-inc, and rolled back to v32"},
+		{CanaryEvent{User: "ejholmes", App: "acme-inc", Release: 33, StableRelease: 32, Automatic: true, Reason: "2.50% of requests failed, which is more than the threshold of 1.00%"}, "canary v33 of acme-inc (deployed by ejholmes) was automatically aborted and rolled back to v32: 2.50% of requests failed, which is more than the threshold of 1.00%"},
+
+		// PipelineEvent
+		{PipelineEvent{User: "ejholmes", Name: "acme-inc", Apps: []string{"acme-inc-staging", "acme-inc"}}, "ejholmes set pipeline acme-inc to acme-inc-staging -> acme-inc"},
+		{PipelineEvent{User: "ejholmes", Name: "acme-inc", Destroyed: true, Message: "commit message"}, "ejholmes destroyed pipeline acme-inc: 'commit message'"},
+
 		// CreateEvent
 		{CreateEvent{User: "ejholmes", Name: "acme-inc"}, "ejholmes created acme-inc"},
 		{CreateEvent{User: "ejholmes", Name: "acme-inc", Message: "commit message"}, "ejholmes created acme-inc: 'commit message'"},
 
+		// RenameEvent
+		{RenameEvent{User: "ejholmes", PreviousName: "acme-inc", Name: "acme-corp"}, "ejholmes renamed acme-inc to acme-corp"},
+		{RenameEvent{User: "ejholmes", PreviousName: "acme-inc", Name: "acme-corp", Message: "commit message"}, "ejholmes renamed acme-inc to acme-corp: 'commit message'"},
+
 		// DestroyEvent
 		{DestroyEvent{User: "ejholmes", App: "acme-inc", Message: "commit message"}, "ejholmes destroyed acme-inc: 'commit message'"},
+
+		// GrantEvent
+		{GrantEvent{User: "ejholmes", App: "acme-inc", Principal: "team:1234", Action: "set"}, "ejholmes granted team:1234 access to set acme-inc"},
+		{GrantEvent{User: "ejholmes", App: "*", Principal: "user:ejholmes", Action: "*", Message: "commit message"}, "ejholmes granted user:ejholmes access to * *: 'commit message'"},
+
+		// RevokeEvent
+		{RevokeEvent{User: "ejholmes", App: "acme-inc", Principal: "team:1234", Action: "set"}, "ejholmes revoked team:1234 access to set acme-inc"},
 	}
 
 	for _, tt := range tests {
@@ -213,7 +213,8 @@ func formationFromStandardProcfile(p procfile.StandardProcfile) (Formation, erro
 		}
 
 		f[name] = Process{
-			Command: cmd,
+			Command:   cmd,
+			NoService: name == releaseProcessType,
 		}
 	}
 
@@ -242,11 +243,66 @@ func formationFromExtendedProcfile(p procfile.ExtendedProcfile) (Formation, erro
 		}
 
 		f[name] = Process{
-			Command:   cmd,
-			Cron:      process.Cron,
-			NoService: process.NoService,
+			Command:     cmd,
+			Cron:        process.Cron,
+			NoService:   process.NoService || name == releaseProcessType,
+			Exposure:    processExposureFromProcfile(process.Expose),
+			HealthCheck: healthCheckFromProcfile(process.HealthCheck),
 		}
 	}
 
+	if err := f.IsValid(); err != nil {
+		return nil, err
+	}
+
 	return f, nil
 }
+
+// healthCheckFromProcfile converts the health check from an extended Procfile
+// into a HealthCheck, using the defaults for any settings that aren't
+// provided.
+func healthCheckFromProcfile(h *procfile.HealthCheck) *HealthCheck {
+	if h == nil {
+		return nil
+	}
+
+	return &HealthCheck{
+		Path:                h.Path,
+		Interval:            intOrDefault(h.Interval, DefaultHealthCheckInterval),
+		Timeout:             intOrDefault(h.Timeout, DefaultHealthCheckTimeout),
+		HealthyThreshold:    intOrDefault(h.HealthyThreshold, DefaultHealthCheckHealthyThreshold),
+		UnhealthyThreshold:  intOrDefault(h.UnhealthyThreshold, DefaultHealthCheckUnhealthyThreshold),
+		DeregistrationDelay: intOrDefault(h.DeregistrationDelay, DefaultHealthCheckDeregistrationDelay),
+	}
+}
+
+func intOrDefault(v, d int) int {
+	if v == 0 {
+		return d
+	}
+	return v
+}
+
+// processExposureFromProcfile converts the exposure settings from an extended
+// Procfile into a ProcessExposure. If no protocol is given, it defaults to https
+// when a cert is provided, and http otherwise.
+func processExposureFromProcfile(e *procfile.Expose) *ProcessExposure {
+	if e == nil {
+		return nil
+	}
+
+	protocol := e.Protocol
+	if protocol == "" {
+		protocol = exposeHTTP
+		if e.Cert != "" {
+			protocol = exposeHTTPS
+		}
+	}
+
+	return &ProcessExposure{
+		External: e.External,
+		Protocol: protocol,
+		Cert:     e.Cert,
+		Port:     e.Port,
+	}
+}
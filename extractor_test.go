@@ -11,6 +11,8 @@ import (
 	"github.com/remind101/empire/pkg/dockerutil"
 	"github.com/remind101/empire/pkg/httpmock"
 	"github.com/remind101/empire/pkg/image"
+	"github.com/remind101/empire/procfile"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestCMDExtractor(t *testing.T) {
@@ -218,3 +220,109 @@ func tarProcfile(t *testing.T) string {
 
 	return buf.String()
 }
+
+func TestFormationFromProcfile_Release(t *testing.T) {
+	tests := []procfile.Procfile{
+		procfile.StandardProcfile{
+			"web":     "./bin/web",
+			"release": "./bin/migrate",
+		},
+		procfile.ExtendedProcfile{
+			"web":     procfile.Process{Command: "./bin/web"},
+			"release": procfile.Process{Command: "./bin/migrate"},
+		},
+	}
+
+	for _, p := range tests {
+		f, err := formationFromProcfile(p)
+		assert.NoError(t, err)
+		assert.False(t, f["web"].NoService)
+		assert.True(t, f["release"].NoService)
+		assert.Equal(t, Command{"./bin/migrate"}, f["release"].Command)
+	}
+}
+
+func TestFormationFromProcfile_Expose(t *testing.T) {
+	f, err := formationFromProcfile(procfile.ExtendedProcfile{
+		"web": procfile.Process{
+			Command: "./bin/web",
+			Expose:  &procfile.Expose{External: true, Cert: "AcmeIncDotCom"},
+		},
+		"admin": procfile.Process{
+			Command: "./bin/admin",
+			Expose:  &procfile.Expose{},
+		},
+		"worker": procfile.Process{
+			Command: "./bin/worker",
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &ProcessExposure{External: true, Protocol: "https", Cert: "AcmeIncDotCom"}, f["web"].Exposure)
+	assert.Equal(t, &ProcessExposure{Protocol: "http"}, f["admin"].Exposure)
+	assert.Nil(t, f["worker"].Exposure)
+
+	_, err = formationFromProcfile(procfile.ExtendedProcfile{
+		"web": procfile.Process{
+			Command: "./bin/web",
+			Expose:  &procfile.Expose{Protocol: "https"},
+		},
+	})
+	assert.EqualError(t, err, "process web is not valid: a cert is required to expose https")
+
+	_, err = formationFromProcfile(procfile.ExtendedProcfile{
+		"redis": procfile.Process{
+			Command: "./bin/redis",
+			Expose:  &procfile.Expose{Protocol: "tcp"},
+		},
+	})
+	assert.EqualError(t, err, "process redis is not valid: a port between 1 and 65535 is required to expose tcp")
+
+	_, err = formationFromProcfile(procfile.ExtendedProcfile{
+		"smtp": procfile.Process{
+			Command: "./bin/smtp",
+			Expose:  &procfile.Expose{Protocol: "ssl", Port: 465},
+		},
+	})
+	assert.EqualError(t, err, "process smtp is not valid: a cert is required to expose ssl")
+
+	_, err = formationFromProcfile(procfile.ExtendedProcfile{
+		"migrate": procfile.Process{
+			Command:   "./bin/migrate",
+			NoService: true,
+			Expose:    &procfile.Expose{},
+		},
+	})
+	assert.EqualError(t, err, "process migrate is not valid: non-service processes cannot be exposed")
+}
+
+func TestFormationFromProcfile_HealthCheck(t *testing.T) {
+	f, err := formationFromProcfile(procfile.ExtendedProcfile{
+		"web": procfile.Process{
+			Command: "./bin/web",
+			HealthCheck: &procfile.HealthCheck{
+				Path:     "/health",
+				Interval: 60,
+			},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &HealthCheck{
+		Path:                "/health",
+		Interval:            60,
+		Timeout:             DefaultHealthCheckTimeout,
+		HealthyThreshold:    DefaultHealthCheckHealthyThreshold,
+		UnhealthyThreshold:  DefaultHealthCheckUnhealthyThreshold,
+		DeregistrationDelay: DefaultHealthCheckDeregistrationDelay,
+	}, f["web"].HealthCheck)
+
+	_, err = formationFromProcfile(procfile.ExtendedProcfile{
+		"web": procfile.Process{
+			Command: "./bin/web",
+			HealthCheck: &procfile.HealthCheck{
+				Interval: 10,
+				Timeout:  10,
+			},
+		},
+	})
+	assert.EqualError(t, err, "process web is not valid: health check timeout must be less than the interval")
+}
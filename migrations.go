@@ -560,6 +560,339 @@ ALTER TABLE apps ADD COLUMN exposure TEXT NOT NULL default 'private'`,
 			`DROP TABLE ecs_environment`,
 		}),
 	},
+
+	// This migration adds a table that stores an audit log of events.
+	{
+		ID: 19,
+		Up: migrate.Queries([]string{
+			`CREATE TABLE events (
+  id uuid NOT NULL DEFAULT uuid_generate_v4() primary key,
+  "type" text NOT NULL,
+  "user" text NOT NULL,
+  app_id uuid,
+  app text NOT NULL,
+  description text NOT NULL,
+  data json NOT NULL,
+  created_at timestamp without time zone default (now() at time zone 'utc')
+)`,
+			`CREATE INDEX index_events_on_app_id ON events USING btree (app_id)`,
+			`CREATE INDEX index_events_on_created_at ON events USING btree (created_at)`,
+		}),
+		Down: migrate.Queries([]string{
+			`DROP TABLE events`,
+		}),
+	},
+
+	// This migration adds a table that's used as a durable queue for webhook
+	// deliveries.
+	{
+		ID: 20,
+		Up: migrate.Queries([]string{
+			`CREATE TABLE webhook_deliveries (
+  id uuid NOT NULL DEFAULT uuid_generate_v4() primary key,
+  url text NOT NULL,
+  event text NOT NULL,
+  body text NOT NULL,
+  attempts integer NOT NULL DEFAULT 0,
+  last_error text,
+  next_attempt_at timestamp without time zone NOT NULL default (now() at time zone 'utc'),
+  failed_at timestamp without time zone,
+  created_at timestamp without time zone default (now() at time zone 'utc')
+)`,
+			`CREATE INDEX index_webhook_deliveries_on_next_attempt_at ON webhook_deliveries USING btree (next_attempt_at) WHERE failed_at IS NULL`,
+		}),
+		Down: migrate.Queries([]string{
+			`DROP TABLE webhook_deliveries`,
+		}),
+	},
+
+	// This migration adds a table that stores permissions for per app
+	// authorization.
+	{
+		ID: 21,
+		Up: migrate.Queries([]string{
+			`CREATE TABLE permissions (
+  id uuid NOT NULL DEFAULT uuid_generate_v4() primary key,
+  app text NOT NULL,
+  principal text NOT NULL,
+  action text NOT NULL,
+  created_at timestamp without time zone default (now() at time zone 'utc')
+)`,
+			`CREATE UNIQUE INDEX index_permissions_on_app_and_principal_and_action ON permissions USING btree (app, principal, action)`,
+		}),
+		Down: migrate.Queries([]string{
+			`DROP TABLE permissions`,
+		}),
+	},
+
+	// This migration adds a table that stores issued access tokens, so they
+	// can be revoked.
+	{
+		ID: 22,
+		Up: migrate.Queries([]string{
+			`CREATE TABLE access_tokens (
+  id uuid NOT NULL DEFAULT uuid_generate_v4() primary key,
+  user_name text NOT NULL,
+  description text NOT NULL DEFAULT '',
+  scopes json NOT NULL,
+  expires_at timestamp without time zone,
+  last_used_at timestamp without time zone,
+  revoked_at timestamp without time zone,
+  created_at timestamp without time zone default (now() at time zone 'utc')
+)`,
+			`CREATE INDEX index_access_tokens_on_user_name ON access_tokens USING btree (user_name)`,
+		}),
+		Down: migrate.Queries([]string{
+			`DROP TABLE access_tokens`,
+		}),
+	},
+
+	// This migration adds a column to mark releases that failed to deploy.
+	{
+		ID: 23,
+		Up: migrate.Queries([]string{
+			`ALTER TABLE releases ADD COLUMN failed boolean NOT NULL DEFAULT false`,
+		}),
+		Down: migrate.Queries([]string{
+			`ALTER TABLE releases DROP COLUMN failed`,
+		}),
+	},
+
+	// This migration adds a table of priorities for listener rules on
+	// shared Application Load Balancers. Like ports, the priorities are
+	// allocated by a custom resource.
+	{
+		ID: 24,
+		Up: migrate.Queries([]string{
+			`CREATE TABLE listener_rule_priorities (
+  priority integer NOT NULL primary key,
+  taken text
+)`,
+			`INSERT INTO listener_rule_priorities (priority) (SELECT generate_series(1,50000))`,
+		}),
+		Down: migrate.Queries([]string{
+			`DROP TABLE listener_rule_priorities`,
+		}),
+	},
+
+	// This migration adds a table of scheduled scale actions.
+	{
+		ID: 25,
+		Up: migrate.Queries([]string{
+			`CREATE TABLE scale_schedules (
+  id uuid NOT NULL DEFAULT uuid_generate_v4() primary key,
+  app_id uuid NOT NULL references apps(id) ON DELETE CASCADE,
+  process text NOT NULL,
+  cron text NOT NULL,
+  quantity integer NOT NULL,
+  size text NOT NULL DEFAULT '',
+  user_name text NOT NULL,
+  next_run_at timestamp without time zone NOT NULL,
+  last_run_at timestamp without time zone,
+  created_at timestamp without time zone default (now() at time zone 'utc')
+)`,
+			`CREATE INDEX index_scale_schedules_on_next_run_at ON scale_schedules USING btree (next_run_at)`,
+		}),
+		Down: migrate.Queries([]string{
+			`DROP TABLE scale_schedules`,
+		}),
+	},
+
+	// This migration adds a table of config vars that have been marked as
+	// not sensitive, so their values aren't masked.
+	{
+		ID: 26,
+		Up: migrate.Queries([]string{
+			`CREATE TABLE insensitive_config_vars (
+  app_id uuid NOT NULL references apps(id) ON DELETE CASCADE,
+  name text NOT NULL,
+  PRIMARY KEY (app_id, name)
+)`,
+		}),
+		Down: migrate.Queries([]string{
+			`DROP TABLE insensitive_config_vars`,
+		}),
+	},
+
+	// This migration adds config groups, which are shared by multiple apps,
+	// and a queue of apps that need to be released after a config group
+	// changes.
+	{
+		ID: 27,
+		Up: migrate.Queries([]string{
+			`ALTER TABLE configs ADD COLUMN group_vars hstore NOT NULL DEFAULT ''::hstore`,
+			`CREATE TABLE config_groups (
+  id uuid NOT NULL DEFAULT uuid_generate_v4() primary key,
+  name text NOT NULL,
+  vars hstore NOT NULL DEFAULT ''::hstore,
+  created_at timestamp without time zone default (now() at time zone 'utc'),
+  updated_at timestamp without time zone default (now() at time zone 'utc')
+)`,
+			`CREATE UNIQUE INDEX index_config_groups_on_name ON config_groups USING btree (name)`,
+			`CREATE TABLE app_config_groups (
+  app_id uuid NOT NULL references apps(id) ON DELETE CASCADE,
+  config_group_id uuid NOT NULL references config_groups(id) ON DELETE CASCADE,
+  created_at timestamp without time zone default (now() at time zone 'utc'),
+  PRIMARY KEY (app_id, config_group_id)
+)`,
+			`CREATE TABLE config_group_releases (
+  app_id uuid NOT NULL references apps(id) ON DELETE CASCADE primary key,
+  user_name text NOT NULL,
+  message text NOT NULL DEFAULT '',
+  queued_at timestamp without time zone default (now() at time zone 'utc')
+)`,
+		}),
+		Down: migrate.Queries([]string{
+			`DROP TABLE config_group_releases`,
+			`DROP TABLE app_config_groups`,
+			`DROP TABLE config_groups`,
+			`ALTER TABLE configs DROP COLUMN group_vars`,
+		}),
+	},
+
+	// This migration adds pipelines, which link apps so that releases can
+	// be promoted from one app to the next.
+	{
+		ID: 28,
+		Up: migrate.Queries([]string{
+			`CREATE TABLE pipelines (
+  id uuid NOT NULL DEFAULT uuid_generate_v4() primary key,
+  name text NOT NULL,
+  created_at timestamp without time zone default (now() at time zone 'utc'),
+  updated_at timestamp without time zone default (now() at time zone 'utc')
+)`,
+			`CREATE UNIQUE INDEX index_pipelines_on_name ON pipelines USING btree (name)`,
+			`CREATE TABLE pipeline_apps (
+  pipeline_id uuid NOT NULL references pipelines(id) ON DELETE CASCADE,
+  app_id uuid NOT NULL references apps(id) ON DELETE CASCADE primary key,
+  position integer NOT NULL
+)`,
+			`CREATE UNIQUE INDEX index_pipeline_apps_on_pipeline_id_and_position ON pipeline_apps USING btree (pipeline_id, position)`,
+		}),
+		Down: migrate.Queries([]string{
+			`DROP TABLE pipeline_apps`,
+			`DROP TABLE pipelines`,
+		}),
+	},
+
+	// This migration adds review apps, which are created from a template
+	// app for each pull request.
+	{
+		ID: 29,
+		Up: migrate.Queries([]string{
+			`CREATE TABLE review_apps (
+  app_id uuid NOT NULL references apps(id) ON DELETE CASCADE primary key,
+  repo text NOT NULL,
+  pull_request integer NOT NULL,
+  user_name text NOT NULL,
+  created_at timestamp without time zone default (now() at time zone 'utc'),
+  updated_at timestamp without time zone default (now() at time zone 'utc')
+)`,
+			`CREATE UNIQUE INDEX index_review_apps_on_repo_and_pull_request ON review_apps USING btree (repo, pull_request)`,
+		}),
+		Down: migrate.Queries([]string{
+			`DROP TABLE review_apps`,
+		}),
+	},
+
+	// This migration adds a queue of deployments, which are performed by
+	// a background worker.
+	{
+		ID: 30,
+		Up: migrate.Queries([]string{
+			`CREATE TABLE deployments (
+  id uuid NOT NULL DEFAULT uuid_generate_v4() primary key,
+  app_id uuid NOT NULL references apps(id) ON DELETE CASCADE,
+  image text NOT NULL,
+  user_name text NOT NULL,
+  message text NOT NULL DEFAULT '',
+  stream boolean NOT NULL DEFAULT false,
+  status text NOT NULL,
+  release_version integer,
+  error text,
+  created_at timestamp without time zone default (now() at time zone 'utc'),
+  started_at timestamp without time zone,
+  finished_at timestamp without time zone
+)`,
+			`CREATE INDEX index_deployments_on_app_id_and_created_at ON deployments USING btree (app_id, created_at)`,
+			`CREATE INDEX index_deployments_on_status ON deployments USING btree (status) WHERE status IN ('pending', 'running')`,
+		}),
+		Down: migrate.Queries([]string{
+			`DROP TABLE deployments`,
+		}),
+	},
+
+	// This migration adds canaries, which run a new release alongside the
+	// stable release of an app. Only one canary can be running for an app
+	// at a time.
+	{
+		ID: 31,
+		Up: migrate.Queries([]string{
+			`CREATE TABLE canaries (
+  id uuid NOT NULL DEFAULT uuid_generate_v4() primary key,
+  app_id uuid NOT NULL references apps(id) ON DELETE CASCADE,
+  version integer NOT NULL,
+  stable_version integer NOT NULL,
+  steps text NOT NULL,
+  step integer NOT NULL DEFAULT 0,
+  pause bigint NOT NULL,
+  threshold double precision NOT NULL,
+  status text NOT NULL,
+  reason text,
+  user_name text NOT NULL,
+  step_started_at timestamp without time zone,
+  created_at timestamp without time zone,
+  updated_at timestamp without time zone
+)`,
+			`CREATE UNIQUE INDEX index_canaries_on_app_id_running ON canaries USING btree (app_id) WHERE status = 'running'`,
+		}),
+		Down: migrate.Queries([]string{
+			`DROP TABLE canaries`,
+		}),
+	},
+
+	// This migration allows queued config group releases to be claimed and
+	// retried, rather than removed from the queue before they're released.
+	{
+		ID: 32,
+		Up: migrate.Queries([]string{
+			`ALTER TABLE config_group_releases ADD COLUMN attempts integer NOT NULL DEFAULT 0`,
+			`ALTER TABLE config_group_releases ADD COLUMN next_attempt_at timestamp without time zone default (now() at time zone 'utc')`,
+			`ALTER TABLE config_group_releases ADD COLUMN last_error text`,
+			`CREATE INDEX index_config_group_releases_on_next_attempt_at ON config_group_releases USING btree (next_attempt_at)`,
+		}),
+		Down: migrate.Queries([]string{
+			`ALTER TABLE config_group_releases DROP COLUMN last_error`,
+			`ALTER TABLE config_group_releases DROP COLUMN next_attempt_at`,
+			`ALTER TABLE config_group_releases DROP COLUMN attempts`,
+		}),
+	},
+
+	// This migration stores the output of queued deployments.
+	{
+		ID: 33,
+		Up: migrate.Queries([]string{
+			`ALTER TABLE deployments ADD COLUMN output text NOT NULL DEFAULT ''`,
+		}),
+		Down: migrate.Queries([]string{
+			`ALTER TABLE deployments DROP COLUMN output`,
+		}),
+	},
+
+	// This migration stores the scopes and GitHub token of the user that
+	// queued a deployment, so that it can be authorized again when it's
+	// performed.
+	{
+		ID: 34,
+		Up: migrate.Queries([]string{
+			`ALTER TABLE deployments ADD COLUMN user_scopes json`,
+			`ALTER TABLE deployments ADD COLUMN user_github_token text`,
+		}),
+		Down: migrate.Queries([]string{
+			`ALTER TABLE deployments DROP COLUMN user_scopes`,
+			`ALTER TABLE deployments DROP COLUMN user_github_token`,
+		}),
+	},
 }
 
 // latestSchema returns the schema version that this version of Empire should be
@@ -0,0 +1,520 @@
+package empire
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/pkg/timex"
+	"golang.org/x/net/context"
+)
+
+// Action represents something that a user can do to an app. The names match
+// the name of the event that the action triggers.
+type Action string
+
+const (
+	ActionCreate   Action = "create"
+	ActionDestroy  Action = "destroy"
+	ActionRename   Action = "rename"
+	ActionDeploy   Action = "deploy"
+	ActionSet      Action = "set"
+	ActionRun      Action = "run"
+	ActionScale    Action = "scale"
+	ActionRestart  Action = "restart"
+	ActionRollback Action = "rollback"
+
+	// ActionAccess allows a user to grant and revoke permissions on an app.
+	ActionAccess Action = "access"
+
+	// ActionReveal allows a user to see the values of sensitive config vars
+	// in full, rather than masked.
+	ActionReveal Action = "reveal"
+
+	// ActionConfigGroup allows a user to set and destroy config groups. A
+	// config group can be attached to any app, so it's authorized on all
+	// apps ("*").
+	ActionConfigGroup Action = "config_group"
+)
+
+// Actions are all of the actions that can be authorized.
+var Actions = []Action{
+	ActionCreate,
+	ActionDestroy,
+	ActionRename,
+	ActionDeploy,
+	ActionSet,
+	ActionRun,
+	ActionScale,
+	ActionRestart,
+	ActionRollback,
+	ActionAccess,
+	ActionReveal,
+	ActionConfigGroup,
+}
+
+// Wildcard can be used in place of an app name, principal or action in a
+// Permission to match anything.
+const Wildcard = "*"
+
+var (
+	ErrInvalidAction    = &ValidationError{errors.New("Action is not valid.")}
+	ErrInvalidPrincipal = &ValidationError{errors.New("Principal must be \"*\", \"user:<name>\" or \"team:<id>\".")}
+	ErrPermissionExists = &ValidationError{errors.New("Permission has already been granted.")}
+
+	// ErrAccessUnrestricted is returned when granting a permission for an
+	// app that anyone can still grant permissions on, since anyone could
+	// grant themselves the same permission.
+	ErrAccessUnrestricted = &ValidationError{errors.New("Grant the access action on this app first, otherwise anyone could grant themselves this permission.")}
+
+	// ErrAccessRequired is returned when revoking the last permission that
+	// restricts the access action on an app that has other permissions.
+	ErrAccessRequired = &ValidationError{errors.New("Other permissions are granted on this app, which anyone could grant themselves without the access action being restricted. Revoke them first.")}
+)
+
+// AccessDeniedError is returned when a user is not allowed to perform an
+// action on an app.
+type AccessDeniedError struct {
+	User   string
+	Action Action
+	App    string
+
+	// If provided, a more specific reason for why access was denied.
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *AccessDeniedError) Error() string {
+	msg := fmt.Sprintf("%s is not allowed to %s %s", e.User, e.Action, e.App)
+	if e.Reason != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Reason)
+	}
+	return msg
+}
+
+// Authorizer determines whether a user is allowed to perform an action on an
+// app.
+type Authorizer interface {
+	// Authorize should return an AccessDeniedError if the user is not
+	// allowed to perform the action on the app.
+	Authorize(user *User, action Action, app *App) error
+}
+
+// AuthorizerFunc is a function that implements the Authorizer interface.
+type AuthorizerFunc func(*User, Action, *App) error
+
+func (fn AuthorizerFunc) Authorize(user *User, action Action, app *App) error {
+	return fn(user, action, app)
+}
+
+// AllowAll is an Authorizer that allows any user to perform any action.
+var AllowAll = AuthorizerFunc(func(*User, Action, *App) error {
+	return nil
+})
+
+// GroupMembership checks whether a user belongs to a group of users, like a
+// GitHub team.
+type GroupMembership interface {
+	IsMember(user *User, group string) (bool, error)
+}
+
+// Permission grants a principal the ability to perform an action on an app.
+type Permission struct {
+	// A unique uuid that identifies the permission.
+	ID string
+
+	// The name of the app, or "*" for all apps. This is a name, rather
+	// than a foreign key, so that permissions can be granted before an app
+	// is created.
+	App string
+
+	// Who the permission is granted to. This can be "user:<name>",
+	// "team:<id>" or "*" for everyone.
+	Principal string
+
+	// The action that's allowed, or "*" for all actions.
+	Action string
+
+	CreatedAt *time.Time
+}
+
+// BeforeCreate sets created_at before inserting.
+func (p *Permission) BeforeCreate() error {
+	t := timex.Now()
+	p.CreatedAt = &t
+	return nil
+}
+
+// PolicyAuthorizer is an Authorizer backed by the permissions table.
+//
+// Actions are unrestricted by default. Once a permission is granted for an
+// action on an app (or on "*"), only the principals that have been granted
+// that action can perform it. For example, granting "set" on payments-api to
+// a single team restricts `emp set` to that team, while leaving deploys and
+// scaling open to everyone. Other actions can only be restricted on an app
+// once the "access" action is, so that the restriction can't be bypassed by
+// granting it to yourself. The "reveal" action is the exception, and is
+// denied unless it has been granted.
+type PolicyAuthorizer struct {
+	// If provided, used to check membership of "team:<id>" principals.
+	// If nil, team principals never match.
+	Groups GroupMembership
+
+	db *gorm.DB
+}
+
+// NewPolicyAuthorizer returns a new PolicyAuthorizer that reads permissions
+// from the given database.
+func NewPolicyAuthorizer(db *DB) *PolicyAuthorizer {
+	return &PolicyAuthorizer{db: db.DB}
+}
+
+// Authorize implements the Authorizer interface.
+func (a *PolicyAuthorizer) Authorize(user *User, action Action, app *App) error {
+	ps, err := permissions(a.db, PermissionsQuery{
+		Apps:    []string{app.Name, Wildcard},
+		Actions: []string{string(action), Wildcard},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Nothing restricts this action. Revealing sensitive config vars is the
+	// exception, since it has to be granted.
+	if len(ps) == 0 {
+		if action == ActionReveal {
+			return &AccessDeniedError{
+				User:   user.Name,
+				Action: action,
+				App:    app.Name,
+				Reason: "the reveal action has to be granted",
+			}
+		}
+		return nil
+	}
+
+	for _, p := range ps {
+		ok, err := a.matches(user, p.Principal)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			return nil
+		}
+	}
+
+	return &AccessDeniedError{
+		User:   user.Name,
+		Action: action,
+		App:    app.Name,
+	}
+}
+
+// matches returns true if the principal refers to the user.
+func (a *PolicyAuthorizer) matches(user *User, principal string) (bool, error) {
+	kind, name := splitPrincipal(principal)
+	switch kind {
+	case Wildcard:
+		return true, nil
+	case "user":
+		return user.Name == name, nil
+	case "team":
+		if a.Groups == nil {
+			return false, nil
+		}
+		return a.Groups.IsMember(user, name)
+	default:
+		return false, nil
+	}
+}
+
+// splitPrincipal splits a principal like "user:ejholmes" into it's kind and
+// name.
+func splitPrincipal(principal string) (kind, name string) {
+	if principal == Wildcard {
+		return Wildcard, ""
+	}
+
+	parts := strings.SplitN(principal, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", ""
+	}
+
+	return parts[0], parts[1]
+}
+
+// authorize checks that the user is allowed to perform the action on the app.
+func (e *Empire) authorize(user *User, action Action, app *App) error {
+	if !user.Scopes.Allows(action) {
+		return &AccessDeniedError{
+			User:   user.Name,
+			Action: action,
+			App:    app.Name,
+			Reason: fmt.Sprintf("access token is limited to %v", user.Scopes.Strings()),
+		}
+	}
+
+	if e.Authorizer == nil {
+		return nil
+	}
+
+	return e.Authorizer.Authorize(user, action, app)
+}
+
+// PermissionsQuery is a scope implementation for common things to filter
+// permissions by.
+type PermissionsQuery struct {
+	// If provided, finds the permission with the given ID.
+	ID *string
+
+	// If provided, finds permissions for any of the given app names.
+	Apps []string
+
+	// If provided, finds permissions granted to the given principal.
+	Principal *string
+
+	// If provided, finds permissions for any of the given actions.
+	Actions []string
+}
+
+// scope implements the scope interface.
+func (q PermissionsQuery) scope(db *gorm.DB) *gorm.DB {
+	var scope composedScope
+
+	if q.ID != nil {
+		scope = append(scope, idEquals(*q.ID))
+	}
+
+	if len(q.Apps) > 0 {
+		scope = append(scope, scopeFunc(func(db *gorm.DB) *gorm.DB {
+			return db.Where("app IN (?)", q.Apps)
+		}))
+	}
+
+	if q.Principal != nil {
+		scope = append(scope, fieldEquals("principal", *q.Principal))
+	}
+
+	if len(q.Actions) > 0 {
+		scope = append(scope, scopeFunc(func(db *gorm.DB) *gorm.DB {
+			return db.Where("action IN (?)", q.Actions)
+		}))
+	}
+
+	scope = append(scope, order("app, principal, action"))
+
+	return scope.scope(db)
+}
+
+// GrantOpts are options provided when granting a permission.
+type GrantOpts struct {
+	// User performing the action.
+	User *User
+
+	// The name of the app, or "*" for all apps.
+	App string
+
+	// The principal to grant the permission to.
+	Principal string
+
+	// The action to allow, or "*" for all actions.
+	Action string
+
+	// Commit message
+	Message string
+}
+
+func (opts GrantOpts) Event() GrantEvent {
+	return GrantEvent{
+		User:      opts.User.Name,
+		App:       opts.App,
+		Principal: opts.Principal,
+		Action:    opts.Action,
+		Message:   opts.Message,
+	}
+}
+
+func (opts GrantOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	if opts.App != Wildcard && !NamePattern.Match([]byte(opts.App)) {
+		return ErrInvalidName
+	}
+
+	if kind, _ := splitPrincipal(opts.Principal); kind != Wildcard && kind != "user" && kind != "team" {
+		return ErrInvalidPrincipal
+	}
+
+	if !validAction(opts.Action) {
+		return ErrInvalidAction
+	}
+
+	return e.authorize(opts.User, ActionAccess, &App{Name: opts.App})
+}
+
+// RevokeOpts are options provided when revoking a permission.
+type RevokeOpts struct {
+	// User performing the action.
+	User *User
+
+	// The permission to revoke.
+	Permission *Permission
+
+	// Commit message
+	Message string
+}
+
+func (opts RevokeOpts) Event() RevokeEvent {
+	return RevokeEvent{
+		User:      opts.User.Name,
+		App:       opts.Permission.App,
+		Principal: opts.Permission.Principal,
+		Action:    opts.Permission.Action,
+		Message:   opts.Message,
+	}
+}
+
+func (opts RevokeOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	return e.authorize(opts.User, ActionAccess, &App{Name: opts.Permission.App})
+}
+
+func validAction(action string) bool {
+	if action == Wildcard {
+		return true
+	}
+
+	for _, a := range Actions {
+		if string(a) == action {
+			return true
+		}
+	}
+
+	return false
+}
+
+// permissionsFind returns the first matching permission.
+func permissionsFind(db *gorm.DB, scope scope) (*Permission, error) {
+	var permission Permission
+	return &permission, first(db, scope, &permission)
+}
+
+// permissions returns all permissions matching the scope.
+func permissions(db *gorm.DB, scope scope) ([]*Permission, error) {
+	var permissions []*Permission
+	return permissions, find(db, scope, &permissions)
+}
+
+func permissionsCreate(db *gorm.DB, permission *Permission) (*Permission, error) {
+	return permission, db.Create(permission).Error
+}
+
+func permissionsDestroy(db *gorm.DB, permission *Permission) error {
+	return db.Delete(permission).Error
+}
+
+// permissionsRenameApp updates permissions for an app that's being renamed.
+func permissionsRenameApp(db *gorm.DB, from, to string) error {
+	return db.Exec(`UPDATE permissions SET app = ? WHERE app = ?`, to, from).Error
+}
+
+type permissionsService struct {
+	*Empire
+}
+
+func (s *permissionsService) Grant(ctx context.Context, db *gorm.DB, opts GrantOpts) (*Permission, error) {
+	if !grantsAccess(opts.Action) {
+		ok, err := accessRestricted(db, opts.App, "")
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, ErrAccessUnrestricted
+		}
+	}
+
+	_, err := permissionsFind(db, PermissionsQuery{
+		Apps:      []string{opts.App},
+		Principal: &opts.Principal,
+		Actions:   []string{opts.Action},
+	})
+	if err != gorm.RecordNotFound {
+		if err == nil {
+			return nil, ErrPermissionExists
+		}
+		return nil, err
+	}
+
+	return permissionsCreate(db, &Permission{
+		App:       opts.App,
+		Principal: opts.Principal,
+		Action:    opts.Action,
+	})
+}
+
+func (s *permissionsService) Revoke(ctx context.Context, db *gorm.DB, opts RevokeOpts) error {
+	p := opts.Permission
+
+	// Make sure that every other permission that this one covers is still
+	// protected by an access permission once it's revoked.
+	if grantsAccess(p.Action) {
+		var scope composedScope
+		if p.App != Wildcard {
+			scope = append(scope, PermissionsQuery{Apps: []string{p.App}})
+		}
+		scope = append(scope, scopeFunc(func(db *gorm.DB) *gorm.DB {
+			return db.Where("id != ? AND action NOT IN (?)", p.ID, []string{string(ActionAccess), Wildcard})
+		}))
+
+		restrictions, err := permissions(db, scope)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range restrictions {
+			ok, err := accessRestricted(db, r.App, p.ID)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return ErrAccessRequired
+			}
+		}
+	}
+
+	return permissionsDestroy(db, p)
+}
+
+// grantsAccess returns true if a permission for the action also grants the
+// access action.
+func grantsAccess(action string) bool {
+	return action == string(ActionAccess) || action == Wildcard
+}
+
+// accessRestricted returns true if a permission restricts who can perform the
+// access action on the app, ignoring the permission with the given id.
+func accessRestricted(db *gorm.DB, app, exclude string) (bool, error) {
+	ps, err := permissions(db, PermissionsQuery{
+		Apps:    []string{app, Wildcard},
+		Actions: []string{string(ActionAccess), Wildcard},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, p := range ps {
+		if p.ID != exclude {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,102 @@
+package empire
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissionsQuery(t *testing.T) {
+	id := "1234"
+	principal := "user:ejholmes"
+
+	tests := scopeTests{
+		{PermissionsQuery{}, "ORDER BY app, principal, action", []interface{}{}},
+		{PermissionsQuery{ID: &id}, "WHERE (id = $1) ORDER BY app, principal, action", []interface{}{id}},
+		{PermissionsQuery{Principal: &principal}, "WHERE (principal = $1) ORDER BY app, principal, action", []interface{}{principal}},
+		{PermissionsQuery{Apps: []string{"acme-inc", "*"}, Actions: []string{"set", "*"}}, "WHERE (app IN ($1,$2)) AND (action IN ($3,$4)) ORDER BY app, principal, action", []interface{}{"acme-inc", "*", "set", "*"}},
+	}
+
+	tests.Run(t)
+}
+
+func TestPolicyAuthorizer_Matches(t *testing.T) {
+	a := &PolicyAuthorizer{
+		Groups: fakeGroups{"1234": {"ejholmes"}},
+	}
+
+	tests := []struct {
+		user      string
+		principal string
+		out       bool
+	}{
+		{"ejholmes", "*", true},
+		{"ejholmes", "user:ejholmes", true},
+		{"bob", "user:ejholmes", false},
+		{"ejholmes", "team:1234", true},
+		{"bob", "team:1234", false},
+		{"ejholmes", "team:5678", false},
+		{"ejholmes", "ejholmes", false},
+		{"ejholmes", "user:", false},
+	}
+
+	for _, tt := range tests {
+		ok, err := a.matches(&User{Name: tt.user}, tt.principal)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.out, ok, "%s %s", tt.user, tt.principal)
+	}
+}
+
+func TestGrantOpts_Validate(t *testing.T) {
+	e := &Empire{}
+	user := &User{Name: "ejholmes"}
+
+	tests := []struct {
+		opts GrantOpts
+		err  error
+	}{
+		{GrantOpts{User: user, App: "acme-inc", Principal: "team:1234", Action: "set"}, nil},
+		{GrantOpts{User: user, App: "*", Principal: "*", Action: "*"}, nil},
+		{GrantOpts{User: user, App: "Acme Inc", Principal: "team:1234", Action: "set"}, ErrInvalidName},
+		{GrantOpts{User: user, App: "acme-inc", Principal: "ejholmes", Action: "set"}, ErrInvalidPrincipal},
+		{GrantOpts{User: user, App: "acme-inc", Principal: "group:1234", Action: "set"}, ErrInvalidPrincipal},
+		{GrantOpts{User: user, App: "acme-inc", Principal: "team:1234", Action: "dance"}, ErrInvalidAction},
+	}
+
+	for _, tt := range tests {
+		err := tt.opts.Validate(e)
+		assert.Equal(t, tt.err, err)
+	}
+}
+
+func TestEmpire_Authorize(t *testing.T) {
+	e := &Empire{
+		Authorizer: AuthorizerFunc(func(user *User, action Action, app *App) error {
+			if action == ActionDestroy {
+				return &AccessDeniedError{User: user.Name, Action: action, App: app.Name}
+			}
+			return nil
+		}),
+	}
+	user := &User{Name: "ejholmes"}
+	app := &App{Name: "acme-inc"}
+
+	err := DestroyOpts{User: user, App: app}.Validate(e)
+	assert.EqualError(t, err, "ejholmes is not allowed to destroy acme-inc")
+
+	err = ScaleOpts{User: user, App: app}.Validate(e)
+	assert.NoError(t, err)
+}
+
+// fakeGroups is a GroupMembership implementation that maps a group to its
+// members.
+type fakeGroups map[string][]string
+
+func (g fakeGroups) IsMember(user *User, group string) (bool, error) {
+	for _, name := range g[group] {
+		if name == user.Name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
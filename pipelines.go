@@ -0,0 +1,344 @@
+package empire
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/pkg/timex"
+	"golang.org/x/net/context"
+)
+
+var (
+	// ErrInvalidPipelineName is returned when the name of a pipeline isn't
+	// valid.
+	ErrInvalidPipelineName = &ValidationError{
+		errors.New("A pipeline name must be alphanumeric and dashes only, 3-30 chars in length."),
+	}
+
+	// ErrPipelineTooShort is returned when a pipeline has less than 2
+	// apps.
+	ErrPipelineTooShort = &ValidationError{
+		errors.New("A pipeline must have at least 2 apps."),
+	}
+)
+
+// Pipeline links apps in order, so that releases can be promoted from one app
+// to the next (e.g. from acme-inc-staging to acme-inc). An app can only be in
+// one pipeline.
+type Pipeline struct {
+	// A unique uuid that identifies the pipeline.
+	ID string
+
+	// The unique name of the pipeline.
+	Name string
+
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+}
+
+// BeforeCreate sets created_at and updated_at before inserting.
+func (p *Pipeline) BeforeCreate() error {
+	t := timex.Now()
+	p.CreatedAt = &t
+	p.UpdatedAt = &t
+	return nil
+}
+
+// PipelinesQuery is a scope implementation for common things to filter
+// pipelines by.
+type PipelinesQuery struct {
+	// If provided, finds the pipeline with the given name.
+	Name *string
+
+	// If provided, finds the pipeline that the app is in.
+	App *App
+}
+
+// scope implements the scope interface.
+func (q PipelinesQuery) scope(db *gorm.DB) *gorm.DB {
+	var scope composedScope
+
+	if q.Name != nil {
+		scope = append(scope, fieldEquals("name", *q.Name))
+	}
+
+	if q.App != nil {
+		scope = append(scope, scopeFunc(func(db *gorm.DB) *gorm.DB {
+			return db.Where("id IN (SELECT pipeline_id FROM pipeline_apps WHERE app_id = ?)", q.App.ID)
+		}))
+	}
+
+	scope = append(scope, order("name"))
+
+	return scope.scope(db)
+}
+
+// SetPipelineOpts are options provided when creating a pipeline, or changing
+// the apps in it.
+type SetPipelineOpts struct {
+	// User performing the action.
+	User *User
+
+	// The name of the pipeline. The pipeline is created if it doesn't
+	// exist.
+	Name string
+
+	// The apps in the pipeline, in the order that releases are promoted
+	// through them.
+	Apps []*App
+
+	// Commit message
+	Message string
+}
+
+func (opts SetPipelineOpts) Event() PipelineEvent {
+	event := PipelineEvent{
+		User:    opts.User.Name,
+		Name:    opts.Name,
+		Message: opts.Message,
+	}
+	for _, app := range opts.Apps {
+		event.Apps = append(event.Apps, app.Name)
+	}
+	return event
+}
+
+func (opts SetPipelineOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	if !NamePattern.Match([]byte(opts.Name)) {
+		return ErrInvalidPipelineName
+	}
+
+	if len(opts.Apps) < 2 {
+		return ErrPipelineTooShort
+	}
+
+	seen := make(map[string]bool)
+	for _, app := range opts.Apps {
+		if seen[app.ID] {
+			return &ValidationError{Err: fmt.Errorf("%s is in the pipeline more than once", app.Name)}
+		}
+		seen[app.ID] = true
+	}
+
+	// A pipeline allows releases to be deployed to every app in it.
+	for _, app := range opts.Apps {
+		if err := e.authorize(opts.User, ActionDeploy, app); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DestroyPipelineOpts are options provided when destroying a pipeline.
+type DestroyPipelineOpts struct {
+	// User performing the action.
+	User *User
+
+	// The pipeline to destroy.
+	Pipeline *Pipeline
+
+	// Commit message
+	Message string
+}
+
+func (opts DestroyPipelineOpts) Event() PipelineEvent {
+	return PipelineEvent{
+		User:      opts.User.Name,
+		Name:      opts.Pipeline.Name,
+		Destroyed: true,
+		Message:   opts.Message,
+	}
+}
+
+func (opts DestroyPipelineOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	apps, err := pipelineApps(e.db, opts.Pipeline)
+	if err != nil {
+		return err
+	}
+
+	// Destroying a pipeline changes where releases are promoted to, so it
+	// requires the same access as creating it.
+	for _, app := range apps {
+		if err := e.authorize(opts.User, ActionDeploy, app); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// PromoteOpts are options provided when promoting a release to the next app
+// in a pipeline.
+type PromoteOpts struct {
+	// User performing the action.
+	User *User
+
+	// The app to promote a release from.
+	App *App
+
+	// If provided, the version of the release to promote. The default is
+	// the latest release that didn't fail.
+	Version *int
+
+	// Output is a DeploymentStream where the output of the release phase,
+	// and status updates, will be streamed in jsonmessage format.
+	Output *DeploymentStream
+
+	// Commit message
+	Message string
+
+	// Stream boolean for whether or not a status stream should be created.
+	Stream bool
+}
+
+func (opts PromoteOpts) Event() PromoteEvent {
+	return PromoteEvent{
+		User:    opts.User.Name,
+		From:    opts.App.Name,
+		Message: opts.Message,
+	}
+}
+
+func (opts PromoteOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	to, err := pipelinesNextApp(e.db, opts.App)
+	if err != nil {
+		return err
+	}
+
+	// Promoting deploys the release to the next app in the pipeline.
+	return e.authorize(opts.User, ActionDeploy, to)
+}
+
+// pipelinesFind returns the first matching pipeline.
+func pipelinesFind(db *gorm.DB, scope scope) (*Pipeline, error) {
+	var pipeline Pipeline
+	return &pipeline, first(db, scope, &pipeline)
+}
+
+// pipelines returns all pipelines matching the scope.
+func pipelines(db *gorm.DB, scope scope) ([]*Pipeline, error) {
+	var pipelines []*Pipeline
+	return pipelines, find(db, scope, &pipelines)
+}
+
+func pipelinesCreate(db *gorm.DB, pipeline *Pipeline) (*Pipeline, error) {
+	return pipeline, db.Create(pipeline).Error
+}
+
+func pipelinesUpdate(db *gorm.DB, pipeline *Pipeline) error {
+	t := timex.Now()
+	pipeline.UpdatedAt = &t
+	return db.Save(pipeline).Error
+}
+
+func pipelinesDestroy(db *gorm.DB, pipeline *Pipeline) error {
+	return db.Delete(pipeline).Error
+}
+
+// pipelineApps returns the apps in the pipeline, in order.
+func pipelineApps(db *gorm.DB, pipeline *Pipeline) ([]*App, error) {
+	var ids []string
+	if err := db.Table("pipeline_apps").Where("pipeline_id = ?", pipeline.ID).Order("position").Pluck("app_id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	var apps []*App
+	for _, id := range ids {
+		id := id
+		app, err := appsFind(db, AppsQuery{ID: &id})
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, app)
+	}
+
+	return apps, nil
+}
+
+// pipelinesNextApp returns the app that releases from app are promoted to.
+func pipelinesNextApp(db *gorm.DB, app *App) (*App, error) {
+	pipeline, err := pipelinesFind(db, PipelinesQuery{App: app})
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			return nil, &ValidationError{Err: fmt.Errorf("%s isn't in a pipeline", app.Name)}
+		}
+		return nil, err
+	}
+
+	apps, err := pipelineApps(db, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, a := range apps {
+		if a.ID == app.ID && i+1 < len(apps) {
+			return apps[i+1], nil
+		}
+	}
+
+	return nil, &ValidationError{Err: fmt.Errorf("%s is the last app in pipeline %s", app.Name, pipeline.Name)}
+}
+
+// pipelinesService manages pipelines, and promotes releases through them.
+type pipelinesService struct {
+	*Empire
+}
+
+// Set creates the pipeline if it doesn't exist, and replaces the apps in it.
+func (s *pipelinesService) Set(ctx context.Context, db *gorm.DB, opts SetPipelineOpts) (*Pipeline, error) {
+	pipeline, err := pipelinesFind(db, PipelinesQuery{Name: &opts.Name})
+	if err != nil {
+		if err != gorm.RecordNotFound {
+			return pipeline, err
+		}
+
+		pipeline, err = pipelinesCreate(db, &Pipeline{Name: opts.Name})
+		if err != nil {
+			return pipeline, err
+		}
+	} else {
+		if err := pipelinesUpdate(db, pipeline); err != nil {
+			return pipeline, err
+		}
+	}
+
+	if err := db.Exec(`DELETE FROM pipeline_apps WHERE pipeline_id = ?`, pipeline.ID).Error; err != nil {
+		return pipeline, err
+	}
+
+	for i, app := range opts.Apps {
+		other, err := pipelinesFind(db, PipelinesQuery{App: app})
+		if err == nil {
+			return pipeline, &ValidationError{Err: fmt.Errorf("%s is already in pipeline %s", app.Name, other.Name)}
+		}
+		if err != gorm.RecordNotFound {
+			return pipeline, err
+		}
+
+		if err := db.Exec(`INSERT INTO pipeline_apps (pipeline_id, app_id, position) VALUES (?, ?, ?)`, pipeline.ID, app.ID, i).Error; err != nil {
+			return pipeline, err
+		}
+	}
+
+	return pipeline, nil
+}
+
+// Destroy destroys the pipeline. The apps in it aren't changed.
+func (s *pipelinesService) Destroy(ctx context.Context, db *gorm.DB, opts DestroyPipelineOpts) error {
+	return pipelinesDestroy(db, opts.Pipeline)
+}
@@ -0,0 +1,52 @@
+package awsutil
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/jsonrpc"
+)
+
+// JSONClient is a minimal client for AWS services that use the json-rpc
+// protocol, like KMS or SSM. It's used for services that aren't included in
+// the vendored aws-sdk-go, when only a couple of operations are needed.
+type JSONClient struct {
+	*client.Client
+}
+
+// NewJSONClient returns a new JSONClient for the service.
+func NewJSONClient(p client.ConfigProvider, serviceName, apiVersion, targetPrefix string, cfgs ...*aws.Config) *JSONClient {
+	c := p.ClientConfig(serviceName, cfgs...)
+
+	svc := client.New(
+		*c.Config,
+		metadata.ClientInfo{
+			ServiceName:   serviceName,
+			SigningRegion: c.SigningRegion,
+			Endpoint:      c.Endpoint,
+			APIVersion:    apiVersion,
+			JSONVersion:   "1.1",
+			TargetPrefix:  targetPrefix,
+		},
+		c.Handlers,
+	)
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(jsonrpc.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(jsonrpc.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(jsonrpc.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(jsonrpc.UnmarshalErrorHandler)
+
+	return &JSONClient{Client: svc}
+}
+
+// Send performs the operation, unmarshaling the response into output.
+func (c *JSONClient) Send(operation string, input, output interface{}) error {
+	req := c.NewRequest(&request.Operation{
+		Name:       operation,
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}, input, output)
+	return req.Send()
+}
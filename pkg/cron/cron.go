@@ -0,0 +1,193 @@
+// Package cron parses standard, 5 field cron expressions, and calculates when
+// they next trigger.
+//
+// The fields are minute (0-59), hour (0-23), day of month (1-31), month (1-12)
+// and day of week (0-6, where 0 and 7 are Sunday). Each field can be a "*", a
+// number, a range ("1-5"), a step ("*/15" or "0-30/10") or a comma separated
+// list of any of those. Like Vixie cron, when both the day of month and day of
+// week are restricted, a time matches if either of them match.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule represents a parsed cron expression.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// True when the day of month and day of week fields are "*".
+	anyDom, anyDow bool
+}
+
+// bounds are the minimum and maximum values for a field.
+type bounds struct {
+	name     string
+	min, max uint
+}
+
+var (
+	minutes = bounds{"minute", 0, 59}
+	hours   = bounds{"hour", 0, 23}
+	doms    = bounds{"day of month", 1, 31}
+	months  = bounds{"month", 1, 12}
+	dows    = bounds{"day of week", 0, 7}
+)
+
+// Parse parses a cron expression.
+func Parse(expr string) (*Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron: expected 5 fields, found %d: %q", len(fields), expr)
+	}
+
+	s := &Schedule{
+		anyDom: fields[2] == "*",
+		anyDow: fields[4] == "*",
+	}
+
+	var err error
+	for _, f := range []struct {
+		field  string
+		bounds bounds
+		bits   *uint64
+	}{
+		{fields[0], minutes, &s.minute},
+		{fields[1], hours, &s.hour},
+		{fields[2], doms, &s.dom},
+		{fields[3], months, &s.month},
+		{fields[4], dows, &s.dow},
+	} {
+		if *f.bits, err = parseField(f.field, f.bounds); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sunday can be either 0 or 7.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 << 0
+	}
+
+	return s, nil
+}
+
+// parseField parses a single field into a bitset of the values that match.
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		min, max, step := b.min, b.max, uint(1)
+
+		rng := part
+		if i := strings.Index(part, "/"); i != -1 {
+			rng = part[:i]
+			n, err := strconv.ParseUint(part[i+1:], 10, 8)
+			if err != nil || n == 0 {
+				return 0, fmt.Errorf("cron: invalid step in %s field: %q", b.name, part)
+			}
+			step = uint(n)
+		}
+
+		switch {
+		case rng == "*":
+		case strings.Contains(rng, "-"):
+			ends := strings.SplitN(rng, "-", 2)
+			lo, err := parseValue(ends[0], b)
+			if err != nil {
+				return 0, err
+			}
+			hi, err := parseValue(ends[1], b)
+			if err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("cron: invalid range in %s field: %q", b.name, rng)
+			}
+			min, max = lo, hi
+		default:
+			v, err := parseValue(rng, b)
+			if err != nil {
+				return 0, err
+			}
+			min, max = v, v
+			// A step on a single value, like "5/15", continues
+			// to the end of the field.
+			if step > 1 {
+				max = b.max
+			}
+		}
+
+		for v := min; v <= max; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+func parseValue(s string, b bounds) (uint, error) {
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil || uint(v) < b.min || uint(v) > b.max {
+		return 0, fmt.Errorf("cron: %s must be between %d and %d: %q", b.name, b.min, b.max, s)
+	}
+	return uint(v), nil
+}
+
+// Next returns the first time after t that matches the schedule, in t's
+// location. If there's no matching time in the next 5 years (e.g. "0 0 30 2
+// *"), the zero time is returned.
+func (s *Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+
+	// Start at the next whole minute.
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+
+	limit := t.Year() + 5
+	for t.Year() <= limit {
+		if !has(s.month, uint(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !has(s.hour, uint(t.Hour())) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if !has(s.minute, uint(t.Minute())) {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, uint(t.Day()))
+	dow := has(s.dow, uint(t.Weekday()))
+
+	switch {
+	case s.anyDom && s.anyDow:
+		return true
+	case s.anyDom:
+		return dow
+	case s.anyDow:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+func has(bits uint64, v uint) bool {
+	return bits&(1<<v) != 0
+}
@@ -0,0 +1,67 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		expr string
+		err  string
+	}{
+		{"* * * *", `cron: expected 5 fields, found 4: "* * * *"`},
+		{"60 * * * *", `cron: minute must be between 0 and 59: "60"`},
+		{"* 24 * * *", `cron: hour must be between 0 and 23: "24"`},
+		{"* * 0 * *", `cron: day of month must be between 1 and 31: "0"`},
+		{"* * * 13 *", `cron: month must be between 1 and 12: "13"`},
+		{"* * * * 8", `cron: day of week must be between 0 and 7: "8"`},
+		{"*/0 * * * *", `cron: invalid step in minute field: "*/0"`},
+		{"* 5-1 * * *", `cron: invalid range in hour field: "5-1"`},
+		{"* * * JAN *", `cron: month must be between 1 and 12: "JAN"`},
+	}
+
+	for _, tt := range tests {
+		_, err := Parse(tt.expr)
+		assert.EqualError(t, err, tt.err)
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	// A Wednesday.
+	now := time.Date(2016, time.June, 15, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2016, time.June, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2016, time.June, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2016, time.June, 15, 11, 0, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2016, time.June, 16, 10, 30, 0, 0, time.UTC)},
+		{"0 20 * * *", time.Date(2016, time.June, 15, 20, 0, 0, 0, time.UTC)},
+		{"0 8,20 * * *", time.Date(2016, time.June, 15, 20, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2016, time.June, 16, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2016, time.June, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2016, time.June, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2016, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2016, time.June, 15, 10, 45, 0, 0, time.UTC)},
+
+		// When both the day of month and day of week are restricted,
+		// either can match.
+		{"0 0 1 * 5", time.Date(2016, time.June, 17, 0, 0, 0, 0, time.UTC)},
+
+		// Never matches.
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.next, s.Next(now), tt.expr)
+	}
+}
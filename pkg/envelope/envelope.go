@@ -0,0 +1,203 @@
+// Package envelope provides envelope encryption of small secrets, like config
+// var values.
+//
+// Each call to Encrypt generates a new data key from a KeyProvider, which is
+// used to encrypt the values with AES-256-GCM. The data key, encrypted with the
+// KeyProvider's master key, is stored alongside each value, so the master key
+// never leaves the KeyProvider (e.g. KMS).
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/golang/groupcache/lru"
+)
+
+// Prefix is prepended to values that were encrypted by an Encrypter.
+const Prefix = "envelope:v1:"
+
+// MaxCachedKeys is the maximum number of decrypted data keys that an Encrypter
+// keeps in memory. Every call to Encrypt generates a new data key, so the least
+// recently used keys are evicted.
+const MaxCachedKeys = 1024
+
+// ErrMalformed is returned when an encrypted value can't be parsed.
+var ErrMalformed = errors.New("envelope: malformed encrypted value")
+
+// KeyProvider generates and decrypts data keys.
+type KeyProvider interface {
+	// GenerateDataKey returns a new 256 bit data key, in plaintext, and
+	// encrypted with the master key.
+	GenerateDataKey() (plaintext, ciphertext []byte, err error)
+
+	// DecryptDataKey decrypts a data key that was returned from
+	// GenerateDataKey.
+	DecryptDataKey(ciphertext []byte) ([]byte, error)
+}
+
+// Encrypter encrypts and decrypts values with data keys from a KeyProvider.
+type Encrypter struct {
+	KeyProvider
+
+	// Decrypted data keys, by their encrypted form, so that values that
+	// were encrypted together only need one call to DecryptDataKey.
+	mu   sync.Mutex
+	keys *lru.Cache
+}
+
+// NewEncrypter returns a new Encrypter that uses the KeyProvider.
+func NewEncrypter(p KeyProvider) *Encrypter {
+	return &Encrypter{
+		KeyProvider: p,
+		keys:        lru.New(MaxCachedKeys),
+	}
+}
+
+// IsEncrypted returns true if the value was encrypted by an Encrypter.
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Encrypt encrypts the values with a single, new data key. The returned values
+// are in the same order.
+func (e *Encrypter) Encrypt(values ...string) ([]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	plaintext, ciphertext, err := e.GenerateDataKey()
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(plaintext)
+	if err != nil {
+		return nil, err
+	}
+
+	key := base64.StdEncoding.EncodeToString(ciphertext)
+	e.cache(key, plaintext)
+
+	encrypted := make([]string, len(values))
+	for i, v := range values {
+		sealed, err := seal(gcm, []byte(v))
+		if err != nil {
+			return nil, err
+		}
+		encrypted[i] = Prefix + key + ":" + base64.StdEncoding.EncodeToString(sealed)
+	}
+
+	return encrypted, nil
+}
+
+// Decrypt decrypts a value that was returned from Encrypt. Values that aren't
+// encrypted are returned as is.
+func (e *Encrypter) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(value, Prefix), ":", 2)
+	if len(parts) != 2 {
+		return "", ErrMalformed
+	}
+
+	plaintext, err := e.dataKey(parts[0])
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	gcm, err := newGCM(plaintext)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := open(gcm, sealed)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// dataKey returns the plaintext data key for the base64 encoded, encrypted
+// data key.
+func (e *Encrypter) dataKey(key string) ([]byte, error) {
+	if plaintext, ok := e.cached(key); ok {
+		return plaintext, nil
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	plaintext, err := e.DecryptDataKey(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("envelope: error decrypting data key: %v", err)
+	}
+
+	e.cache(key, plaintext)
+	return plaintext, nil
+}
+
+func (e *Encrypter) cached(key string) ([]byte, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.keys == nil {
+		return nil, false
+	}
+	plaintext, ok := e.keys.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return plaintext.([]byte), true
+}
+
+func (e *Encrypter) cache(key string, plaintext []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.keys == nil {
+		e.keys = lru.New(MaxCachedKeys)
+	}
+	e.keys.Add(key, plaintext)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts b with a random nonce, which is prepended to the result.
+func seal(gcm cipher.AEAD, b []byte) ([]byte, error) {
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, b, nil), nil
+}
+
+// open decrypts b, which was returned from seal.
+func open(gcm cipher.AEAD, b []byte) ([]byte, error) {
+	if len(b) < gcm.NonceSize() {
+		return nil, ErrMalformed
+	}
+	nonce, ciphertext := b[:gcm.NonceSize()], b[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
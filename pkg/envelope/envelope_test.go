@@ -0,0 +1,115 @@
+package envelope
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+// countingKeyProvider counts the number of data keys that were decrypted.
+type countingKeyProvider struct {
+	KeyProvider
+	decrypted int
+}
+
+func (p *countingKeyProvider) DecryptDataKey(ciphertext []byte) ([]byte, error) {
+	p.decrypted++
+	return p.KeyProvider.DecryptDataKey(ciphertext)
+}
+
+func newTestEncrypter(t testing.TB) *Encrypter {
+	p, err := NewLocalKeyProvider(testKey)
+	assert.NoError(t, err)
+	return NewEncrypter(p)
+}
+
+func TestEncrypter(t *testing.T) {
+	e := newTestEncrypter(t)
+
+	encrypted, err := e.Encrypt("secret", "")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(encrypted))
+
+	for _, v := range encrypted {
+		assert.True(t, IsEncrypted(v))
+		assert.False(t, strings.Contains(v, "secret"))
+	}
+
+	// Values encrypted together share a data key.
+	assert.Equal(t, strings.Split(encrypted[0], ":")[2], strings.Split(encrypted[1], ":")[2])
+
+	v, err := e.Decrypt(encrypted[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", v)
+
+	v, err = e.Decrypt(encrypted[1])
+	assert.NoError(t, err)
+	assert.Equal(t, "", v)
+}
+
+func TestEncrypter_Decrypt_Plaintext(t *testing.T) {
+	e := newTestEncrypter(t)
+
+	v, err := e.Decrypt("secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", v)
+}
+
+func TestEncrypter_Decrypt_Malformed(t *testing.T) {
+	e := newTestEncrypter(t)
+
+	_, err := e.Decrypt(Prefix + "foo")
+	assert.Equal(t, ErrMalformed, err)
+
+	encrypted, err := e.Encrypt("secret")
+	assert.NoError(t, err)
+
+	// Tampering with the ciphertext is detected.
+	_, err = e.Decrypt(encrypted[0][:len(encrypted[0])-4] + "AAAA")
+	assert.Error(t, err)
+}
+
+func TestEncrypter_Decrypt_CachesDataKeys(t *testing.T) {
+	p, err := NewLocalKeyProvider(testKey)
+	assert.NoError(t, err)
+
+	encrypted, err := NewEncrypter(p).Encrypt("a", "b")
+	assert.NoError(t, err)
+
+	c := &countingKeyProvider{KeyProvider: p}
+	e := NewEncrypter(c)
+	for _, v := range encrypted {
+		_, err := e.Decrypt(v)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, c.decrypted)
+}
+
+func TestEncrypter_Encrypt_EvictsDataKeys(t *testing.T) {
+	e := newTestEncrypter(t)
+
+	for i := 0; i < MaxCachedKeys+10; i++ {
+		_, err := e.Encrypt("secret")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, MaxCachedKeys, e.keys.Len())
+}
+
+func TestEncrypter_Decrypt_WrongKey(t *testing.T) {
+	encrypted, err := newTestEncrypter(t).Encrypt("secret")
+	assert.NoError(t, err)
+
+	p, err := NewLocalKeyProvider([]byte("fedcba9876543210fedcba9876543210"))
+	assert.NoError(t, err)
+
+	_, err = NewEncrypter(p).Decrypt(encrypted[0])
+	assert.Error(t, err)
+}
+
+func TestNewLocalKeyProvider_InvalidKey(t *testing.T) {
+	_, err := NewLocalKeyProvider([]byte("short"))
+	assert.EqualError(t, err, "envelope: master key must be 32 bytes, got 5")
+}
@@ -0,0 +1,72 @@
+package envelope
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/remind101/empire/pkg/awsutil"
+)
+
+// KMSKeyProvider is a KeyProvider that generates data keys with AWS KMS, using
+// a customer master key.
+type KMSKeyProvider struct {
+	// The ID, ARN or alias of the customer master key.
+	KeyID string
+
+	client *awsutil.JSONClient
+}
+
+// NewKMSKeyProvider returns a new KMSKeyProvider that uses the given customer
+// master key.
+func NewKMSKeyProvider(keyID string, p client.ConfigProvider, cfgs ...*aws.Config) *KMSKeyProvider {
+	return &KMSKeyProvider{
+		KeyID:  keyID,
+		client: awsutil.NewJSONClient(p, "kms", "2014-11-01", "TrentService", cfgs...),
+	}
+}
+
+type kmsGenerateDataKeyInput struct {
+	_ struct{} `type:"structure"`
+
+	KeyId   *string `type:"string" required:"true"`
+	KeySpec *string `type:"string"`
+}
+
+type kmsGenerateDataKeyOutput struct {
+	_ struct{} `type:"structure"`
+
+	CiphertextBlob []byte `type:"blob"`
+	KeyId          *string
+	Plaintext      []byte `type:"blob"`
+}
+
+type kmsDecryptInput struct {
+	_ struct{} `type:"structure"`
+
+	CiphertextBlob []byte `type:"blob" required:"true"`
+}
+
+type kmsDecryptOutput struct {
+	_ struct{} `type:"structure"`
+
+	KeyId     *string
+	Plaintext []byte `type:"blob"`
+}
+
+// GenerateDataKey implements the KeyProvider interface.
+func (p *KMSKeyProvider) GenerateDataKey() ([]byte, []byte, error) {
+	out := new(kmsGenerateDataKeyOutput)
+	err := p.client.Send("GenerateDataKey", &kmsGenerateDataKeyInput{
+		KeyId:   aws.String(p.KeyID),
+		KeySpec: aws.String("AES_256"),
+	}, out)
+	return out.Plaintext, out.CiphertextBlob, err
+}
+
+// DecryptDataKey implements the KeyProvider interface.
+func (p *KMSKeyProvider) DecryptDataKey(ciphertext []byte) ([]byte, error) {
+	out := new(kmsDecryptOutput)
+	err := p.client.Send("Decrypt", &kmsDecryptInput{
+		CiphertextBlob: ciphertext,
+	}, out)
+	return out.Plaintext, err
+}
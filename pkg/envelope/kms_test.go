@@ -0,0 +1,56 @@
+package envelope
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/remind101/empire/pkg/awsutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKMSKeyProvider(t *testing.T) {
+	h := awsutil.NewHandler([]awsutil.Cycle{
+		{
+			Request: awsutil.Request{
+				RequestURI: "/",
+				Operation:  "TrentService.GenerateDataKey",
+				Body:       `{"KeyId":"alias/empire","KeySpec":"AES_256"}`,
+			},
+			Response: awsutil.Response{
+				StatusCode: 200,
+				Body:       `{"CiphertextBlob":"Y2lwaGVydGV4dA==","KeyId":"alias/empire","Plaintext":"cGxhaW50ZXh0"}`,
+			},
+		},
+		{
+			Request: awsutil.Request{
+				RequestURI: "/",
+				Operation:  "TrentService.Decrypt",
+				Body:       `{"CiphertextBlob":"Y2lwaGVydGV4dA=="}`,
+			},
+			Response: awsutil.Response{
+				StatusCode: 200,
+				Body:       `{"KeyId":"alias/empire","Plaintext":"cGxhaW50ZXh0"}`,
+			},
+		},
+	})
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	p := NewKMSKeyProvider("alias/empire", session.New(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(" ", " ", " "),
+		Endpoint:    aws.String(s.URL),
+		Region:      aws.String("localhost"),
+	}))
+
+	plaintext, ciphertext, err := p.GenerateDataKey()
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("plaintext"), plaintext)
+	assert.Equal(t, []byte("ciphertext"), ciphertext)
+
+	plaintext, err = p.DecryptDataKey(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("plaintext"), plaintext)
+}
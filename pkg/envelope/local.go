@@ -0,0 +1,73 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// LocalKeyProvider is a KeyProvider that encrypts data keys with a master key
+// that's held in memory. It's meant for development and tests, where KMS isn't
+// available.
+type LocalKeyProvider struct {
+	key []byte
+}
+
+// NewLocalKeyProvider returns a new LocalKeyProvider that uses the given 256
+// bit master key.
+func NewLocalKeyProvider(key []byte) (*LocalKeyProvider, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("envelope: master key must be 32 bytes, got %d", len(key))
+	}
+	return &LocalKeyProvider{key: key}, nil
+}
+
+// LoadKeyFile returns a new LocalKeyProvider using the base64 encoded master
+// key in the file at path. A key can be generated with:
+//
+//	openssl rand -base64 32
+func LoadKeyFile(path string) (*LocalKeyProvider, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(raw)))
+	if err != nil {
+		return nil, fmt.Errorf("envelope: key file %s isn't base64 encoded: %v", path, err)
+	}
+
+	return NewLocalKeyProvider(key)
+}
+
+// GenerateDataKey implements the KeyProvider interface.
+func (p *LocalKeyProvider) GenerateDataKey() ([]byte, []byte, error) {
+	plaintext := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		return nil, nil, err
+	}
+
+	gcm, err := newGCM(p.key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ciphertext, err := seal(gcm, plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return plaintext, ciphertext, nil
+}
+
+// DecryptDataKey implements the KeyProvider interface.
+func (p *LocalKeyProvider) DecryptDataKey(ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(p.key)
+	if err != nil {
+		return nil, err
+	}
+	return open(gcm, ciphertext)
+}
@@ -279,7 +279,7 @@ func TestAppUpdateSuccess(t *testing.T) {
 	ts, handler, c := newTestServerAndClient(t, appUpdateRequest)
 	defer ts.Close()
 
-	app, err := c.AppUpdate("example", &appUpdateRequestOptions)
+	app, err := c.AppUpdate("example", &appUpdateRequestOptions, "")
 	if err != nil {
 		t.Fatal(err)
 	}
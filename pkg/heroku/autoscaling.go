@@ -0,0 +1,54 @@
+package heroku
+
+// Autoscaling settings for a process type. Autoscaled processes are scaled
+// between a minimum and maximum quantity, by tracking a target value for a
+// metric.
+type Autoscaling struct {
+	// type of process that's autoscaled
+	Process string `json:"process"`
+
+	// the minimum and maximum quantity
+	Min int `json:"min"`
+	Max int `json:"max"`
+
+	// the metric to track (one of "cpu", "memory" or "requests")
+	Metric string `json:"metric"`
+
+	// the value of the metric to maintain
+	Target int `json:"target"`
+}
+
+// AutoscalingUpdateOpts are the options for autoscaling a process type.
+type AutoscalingUpdateOpts struct {
+	Min    int    `json:"min"`
+	Max    int    `json:"max"`
+	Metric string `json:"metric"`
+	Target int    `json:"target"`
+}
+
+// List the autoscaling settings for an app's process types.
+//
+// appIdentity is the unique identifier of the App.
+func (c *Client) AutoscalingList(appIdentity string) ([]Autoscaling, error) {
+	var autoscalingRes []Autoscaling
+	return autoscalingRes, c.Get(&autoscalingRes, "/apps/"+appIdentity+"/autoscaling")
+}
+
+// Autoscale a process type.
+//
+// appIdentity is the unique identifier of the App. process is the process
+// type to autoscale.
+func (c *Client) AutoscalingUpdate(appIdentity, process string, options AutoscalingUpdateOpts, message string) (*Autoscaling, error) {
+	rh := RequestHeaders{CommitMessage: message}
+	var autoscalingRes Autoscaling
+	return &autoscalingRes, c.PutWithHeaders(&autoscalingRes, "/apps/"+appIdentity+"/autoscaling/"+process, options, rh.Headers())
+}
+
+// Disable autoscaling for a process type.
+//
+// appIdentity is the unique identifier of the App. process is the process
+// type to stop autoscaling.
+func (c *Client) AutoscalingDelete(appIdentity, process, message string) error {
+	rh := RequestHeaders{CommitMessage: message}
+	return c.DeleteWithHeaders("/apps/"+appIdentity+"/autoscaling/"+process, rh.Headers())
+}
@@ -0,0 +1,63 @@
+package heroku
+
+import "time"
+
+// A canary is a new release of an app that runs alongside the stable release,
+// and receives a share of the requests to the app.
+type Canary struct {
+	// unique identifier of canary
+	Id string `json:"id"`
+
+	// the app that the canary belongs to
+	App struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"app"`
+
+	// the version of the release that's running as a canary
+	Release int `json:"release"`
+
+	// the version of the release that receives the rest of the requests
+	StableRelease int `json:"stable_release"`
+
+	// the percentage of requests that are sent to the canary at each step
+	Steps []int `json:"steps"`
+
+	// the percentage of requests that are currently sent to the canary
+	Weight int `json:"weight"`
+
+	// one of running, promoted or aborted
+	Status string `json:"status"`
+
+	// if the canary was aborted automatically, the reason why
+	Reason *string `json:"reason"`
+
+	// name of the user that deployed the canary
+	User string `json:"user"`
+
+	// when the canary was created
+	CreatedAt time.Time `json:"created_at"`
+
+	// when the canary was last changed
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Promote the running canary of an app, which releases it to all of the app's
+// processes.
+//
+// appIdentity is the unique identifier of the Canary's App.
+func (c *Client) CanaryPromote(appIdentity, message string) (*Canary, error) {
+	rh := RequestHeaders{CommitMessage: message}
+	var canaryRes Canary
+	return &canaryRes, c.PostWithHeaders(&canaryRes, "/apps/"+appIdentity+"/canary/promote", nil, rh.Headers())
+}
+
+// Abort the running canary of an app, which rolls the app back to the stable
+// release.
+//
+// appIdentity is the unique identifier of the Canary's App.
+func (c *Client) CanaryAbort(appIdentity, message string) (*Canary, error) {
+	rh := RequestHeaders{CommitMessage: message}
+	var canaryRes Canary
+	return &canaryRes, c.PostWithHeaders(&canaryRes, "/apps/"+appIdentity+"/canary/abort", nil, rh.Headers())
+}
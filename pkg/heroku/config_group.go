@@ -0,0 +1,78 @@
+package heroku
+
+import "time"
+
+// A config group is a named set of config-vars that's shared by multiple apps.
+type ConfigGroup struct {
+	// unique name of config group
+	Name string `json:"name"`
+
+	// names of the apps that the config group is attached to
+	Apps []string `json:"apps"`
+
+	// when the config group was created
+	CreatedAt time.Time `json:"created_at"`
+
+	// when the config group was last changed
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// List existing config groups.
+func (c *Client) ConfigGroupList() ([]ConfigGroup, error) {
+	var configGroupRes []ConfigGroup
+	return configGroupRes, c.Get(&configGroupRes, "/config-groups")
+}
+
+// Get config-vars for a config group. If reveal is true, values are returned
+// in full, rather than masked.
+//
+// configGroupIdentity is the unique name of the ConfigGroup.
+func (c *Client) ConfigGroupVarInfo(configGroupIdentity string, reveal bool) (map[string]string, error) {
+	path := "/config-groups/" + configGroupIdentity + "/config-vars"
+	if reveal {
+		path = path + "?reveal=true"
+	}
+
+	var configVar map[string]string
+	return configVar, c.Get(&configVar, path)
+}
+
+// Update config-vars for a config group, creating it if it doesn't exist.
+// Config-vars are removed by setting them to nil.
+//
+// configGroupIdentity is the unique name of the ConfigGroup. options is the
+// hash of config changes.
+func (c *Client) ConfigGroupVarUpdate(configGroupIdentity string, options map[string]*string, message string) (map[string]string, error) {
+	rh := RequestHeaders{CommitMessage: message}
+	var configVarRes map[string]string
+	return configVarRes, c.PatchWithHeaders(&configVarRes, "/config-groups/"+configGroupIdentity+"/config-vars", options, rh.Headers())
+}
+
+// Delete a config group that isn't attached to any apps.
+//
+// configGroupIdentity is the unique name of the ConfigGroup.
+func (c *Client) ConfigGroupDelete(configGroupIdentity, message string) error {
+	rh := RequestHeaders{CommitMessage: message}
+	return c.DeleteWithHeaders("/config-groups/"+configGroupIdentity, rh.Headers())
+}
+
+// Attach a config group to an app.
+//
+// appIdentity is the unique identifier of the App. configGroupIdentity is the
+// unique name of the ConfigGroup.
+func (c *Client) ConfigGroupAttach(appIdentity, configGroupIdentity, message string) error {
+	rh := RequestHeaders{CommitMessage: message}
+	params := struct {
+		Name string `json:"name"`
+	}{Name: configGroupIdentity}
+	return c.PostWithHeaders(nil, "/apps/"+appIdentity+"/config-groups", params, rh.Headers())
+}
+
+// Detach a config group from an app.
+//
+// appIdentity is the unique identifier of the App. configGroupIdentity is the
+// unique name of the ConfigGroup.
+func (c *Client) ConfigGroupDetach(appIdentity, configGroupIdentity, message string) error {
+	rh := RequestHeaders{CommitMessage: message}
+	return c.DeleteWithHeaders("/apps/"+appIdentity+"/config-groups/"+configGroupIdentity, rh.Headers())
+}
@@ -0,0 +1,29 @@
+package heroku
+
+// Get config-vars for app, with the values of sensitive config-vars in full,
+// rather than masked.
+//
+// appIdentity is the unique identifier of the ConfigVar's App.
+func (c *Client) ConfigVarReveal(appIdentity string) (map[string]string, error) {
+	var configVar map[string]string
+	return configVar, c.Get(&configVar, "/apps/"+appIdentity+"/config-vars?reveal=true")
+}
+
+// Get the sensitivity markers for config-vars on an app. Only config-vars
+// that aren't sensitive are included.
+//
+// appIdentity is the unique identifier of the ConfigVar's App.
+func (c *Client) ConfigVarSensitivityInfo(appIdentity string) (map[string]bool, error) {
+	var sensitivity map[string]bool
+	return sensitivity, c.Get(&sensitivity, "/apps/"+appIdentity+"/config-vars/sensitivity")
+}
+
+// Mark config-vars on an app as sensitive (true), or not sensitive (false).
+//
+// appIdentity is the unique identifier of the ConfigVar's App. options is the
+// hash of config-var names to whether they're sensitive.
+func (c *Client) ConfigVarSensitivityUpdate(appIdentity string, options map[string]bool, message string) (map[string]bool, error) {
+	rh := RequestHeaders{CommitMessage: message}
+	var sensitivity map[string]bool
+	return sensitivity, c.PatchWithHeaders(&sensitivity, "/apps/"+appIdentity+"/config-vars/sensitivity", options, rh.Headers())
+}
@@ -0,0 +1,52 @@
+package heroku
+
+import "time"
+
+// A deployment is a deployment of an image that was queued, and is performed
+// by Empire in the background.
+type Deployment struct {
+	// unique identifier of deployment
+	Id string `json:"id"`
+
+	// the app that's being deployed to
+	App struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"app"`
+
+	// the image that's being deployed
+	Image string `json:"image"`
+
+	// one of pending, running, succeeded or failed
+	Status string `json:"status"`
+
+	// the version of the release that was created, once there is one
+	Release *int `json:"release"`
+
+	// if the deployment failed, the reason why
+	Error *string `json:"error"`
+
+	// name of the user that queued the deployment
+	User string `json:"user"`
+
+	// when the deployment was queued
+	CreatedAt time.Time `json:"created_at"`
+
+	// when the deployment started
+	StartedAt *time.Time `json:"started_at"`
+
+	// when the deployment finished
+	FinishedAt *time.Time `json:"finished_at"`
+
+	// the output of the deployment so far, as a stream of jsonmessages
+	Output string `json:"output"`
+}
+
+// Info for existing deployment.
+//
+// appIdentity is the unique identifier of the Deployment's App.
+// deploymentIdentity is the unique identifier of the Deployment.
+func (c *Client) DeploymentInfo(appIdentity string, deploymentIdentity string) (*Deployment, error) {
+	var deployment Deployment
+	return &deployment, c.Get(&deployment, "/apps/"+appIdentity+"/deployments/"+deploymentIdentity)
+}
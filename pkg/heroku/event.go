@@ -0,0 +1,91 @@
+package heroku
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// An event is an entry in Empire's audit log, which records actions that
+// users have performed.
+type Event struct {
+	// unique identifier of event
+	Id string `json:"id"`
+
+	// type of event (e.g. "deploy")
+	Type string `json:"type"`
+
+	// name of the user that triggered the event
+	User string `json:"user"`
+
+	// app that the event relates to
+	App *struct {
+		Id   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"app"`
+
+	// human readable description of the event
+	Description string `json:"description"`
+
+	// the raw event
+	Data json.RawMessage `json:"data"`
+
+	// when the event happened
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EventListOpts holds the optional parameters for EventList
+type EventListOpts struct {
+	// only return events triggered by this user
+	User *string `json:"user,omitempty"`
+	// only return events of this type
+	Type *string `json:"type,omitempty"`
+	// only return events that happened at or after this time
+	Since *time.Time `json:"since,omitempty"`
+	// only return events that happened before this time
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// List events in the audit log.
+//
+// appIdentity is the unique identifier of the App to list events for. If
+// empty, events for all apps are returned. options is the struct of optional
+// parameters for this action. lr is an optional ListRange that sets the Range
+// options for the paginated list of results.
+func (c *Client) EventList(appIdentity string, options *EventListOpts, lr *ListRange) ([]Event, error) {
+	path := "/events"
+	if appIdentity != "" {
+		path = "/apps/" + appIdentity + "/events"
+	}
+
+	if options != nil {
+		v := url.Values{}
+		if options.User != nil {
+			v.Set("user", *options.User)
+		}
+		if options.Type != nil {
+			v.Set("type", *options.Type)
+		}
+		if options.Since != nil {
+			v.Set("since", options.Since.Format(time.RFC3339))
+		}
+		if options.Until != nil {
+			v.Set("until", options.Until.Format(time.RFC3339))
+		}
+		if len(v) > 0 {
+			path = path + "?" + v.Encode()
+		}
+	}
+
+	req, err := c.NewRequest("GET", path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if lr != nil {
+		lr.SetHeader(req)
+	}
+
+	var eventsRes []Event
+	return eventsRes, c.DoReq(req, &eventsRes)
+}
@@ -29,6 +29,9 @@ type OAuthAuthorization struct {
 	// when OAuth authorization was created
 	CreatedAt time.Time `json:"created_at"`
 
+	// human-friendly description of this OAuth authorization
+	Description string `json:"description"`
+
 	// this authorization's grant
 	Grant *struct {
 		Code      string `json:"code"`
@@ -51,6 +54,12 @@ type OAuthAuthorization struct {
 
 	// when OAuth authorization was updated
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// when the authorization was last used to authenticate
+	LastUsedAt *time.Time `json:"last_used_at"`
+
+	// when the authorization was revoked
+	RevokedAt *time.Time `json:"revoked_at"`
 }
 
 // Create a new OAuth authorization.
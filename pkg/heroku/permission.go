@@ -0,0 +1,78 @@
+package heroku
+
+import (
+	"net/url"
+	"time"
+)
+
+// A permission allows a principal to perform an action on an app. Once a
+// permission exists for an action on an app, only the principals that have
+// been granted it can perform the action.
+type Permission struct {
+	// unique identifier of permission
+	Id string `json:"id"`
+
+	// name of the app, or "*" for all apps
+	App string `json:"app"`
+
+	// who the permission is granted to ("user:<name>", "team:<id>" or "*")
+	Principal string `json:"principal"`
+
+	// the action that's allowed (e.g. "deploy"), or "*" for all actions
+	Action string `json:"action"`
+
+	// when the permission was granted
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// List permissions.
+//
+// app is the name of an app to list permissions for. If empty, all
+// permissions are returned. lr is an optional ListRange that sets the Range
+// options for the paginated list of results.
+func (c *Client) PermissionList(app string, lr *ListRange) ([]Permission, error) {
+	path := "/permissions"
+	if app != "" {
+		path = path + "?" + url.Values{"app": []string{app}}.Encode()
+	}
+
+	req, err := c.NewRequest("GET", path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if lr != nil {
+		lr.SetHeader(req)
+	}
+
+	var permissionsRes []Permission
+	return permissionsRes, c.DoReq(req, &permissionsRes)
+}
+
+// Grant a new permission.
+//
+// app is the name of the app, or "*" for all apps. principal is who the
+// permission is granted to. action is the action to allow, or "*" for all
+// actions.
+func (c *Client) PermissionCreate(app, principal, action, message string) (*Permission, error) {
+	params := struct {
+		App       string `json:"app"`
+		Principal string `json:"principal"`
+		Action    string `json:"action"`
+	}{
+		App:       app,
+		Principal: principal,
+		Action:    action,
+	}
+	rh := RequestHeaders{CommitMessage: message}
+	var permissionRes Permission
+	return &permissionRes, c.PostWithHeaders(&permissionRes, "/permissions", params, rh.Headers())
+}
+
+// Revoke an existing permission.
+//
+// permissionIdentity is the unique identifier of the Permission.
+func (c *Client) PermissionDelete(permissionIdentity, message string) error {
+	rh := RequestHeaders{CommitMessage: message}
+	return c.DeleteWithHeaders("/permissions/"+permissionIdentity, rh.Headers())
+}
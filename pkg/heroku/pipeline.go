@@ -0,0 +1,47 @@
+package heroku
+
+import "time"
+
+// A pipeline links apps in order, so that releases can be promoted from one
+// app to the next.
+type Pipeline struct {
+	// unique name of pipeline
+	Name string `json:"name"`
+
+	// names of the apps in the pipeline, in the order that releases are
+	// promoted through them
+	Apps []string `json:"apps"`
+
+	// when the pipeline was created
+	CreatedAt time.Time `json:"created_at"`
+
+	// when the pipeline was last changed
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// List existing pipelines.
+func (c *Client) PipelineList() ([]Pipeline, error) {
+	var pipelineRes []Pipeline
+	return pipelineRes, c.Get(&pipelineRes, "/pipelines")
+}
+
+// Create a pipeline, or replace the apps in an existing one.
+//
+// pipelineIdentity is the unique name of the Pipeline. apps are the names of
+// the apps in the pipeline, in order.
+func (c *Client) PipelineSet(pipelineIdentity string, apps []string, message string) (*Pipeline, error) {
+	rh := RequestHeaders{CommitMessage: message}
+	params := struct {
+		Apps []string `json:"apps"`
+	}{Apps: apps}
+	var pipelineRes Pipeline
+	return &pipelineRes, c.PutWithHeaders(&pipelineRes, "/pipelines/"+pipelineIdentity, params, rh.Headers())
+}
+
+// Delete a pipeline.
+//
+// pipelineIdentity is the unique name of the Pipeline.
+func (c *Client) PipelineDelete(pipelineIdentity, message string) error {
+	rh := RequestHeaders{CommitMessage: message}
+	return c.DeleteWithHeaders("/pipelines/"+pipelineIdentity, rh.Headers())
+}
@@ -20,6 +20,9 @@ type Release struct {
 	// unique identifier of release
 	Id string `json:"id"`
 
+	// current status of the release ("succeeded" or "failed")
+	Status string `json:"status"`
+
 	// when release was updated
 	UpdatedAt time.Time `json:"updated_at"`
 
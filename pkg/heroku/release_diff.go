@@ -0,0 +1,64 @@
+package heroku
+
+// A release diff describes what changed between two releases of an app.
+type ReleaseDiff struct {
+	// version of the release that's being compared against
+	From int `json:"from"`
+
+	// version of the release that's being compared
+	To int `json:"to"`
+
+	// the change to the Docker image, if it changed
+	Image *ReleaseDiffField `json:"image"`
+
+	// config vars that were added, removed or changed
+	Config []ReleaseDiffConfigVar `json:"config"`
+
+	// processes that were added, removed or changed
+	Processes []ReleaseDiffProcess `json:"processes"`
+}
+
+// ReleaseDiffField is a value that changed between two releases.
+type ReleaseDiffField struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// ReleaseDiffConfigVar is a config var that changed between two releases.
+type ReleaseDiffConfigVar struct {
+	Name string `json:"name"`
+
+	// one of "added", "removed" or "changed"
+	Change string `json:"change"`
+
+	// the old and new values, only present when values were requested
+	From *string `json:"from"`
+	To   *string `json:"to"`
+}
+
+// ReleaseDiffProcess is a process that changed between two releases.
+type ReleaseDiffProcess struct {
+	Name string `json:"name"`
+
+	// one of "added", "removed" or "changed"
+	Change string `json:"change"`
+
+	// the attributes of the process that changed
+	Fields []ReleaseDiffField `json:"fields"`
+}
+
+// Info for what changed between two releases.
+//
+// appIdentity is the unique identifier of the Release's App. from and to are
+// the versions of the releases to compare. If values is true, config var
+// values are included in the result.
+func (c *Client) ReleaseDiffInfo(appIdentity string, from, to string, values bool) (*ReleaseDiff, error) {
+	path := "/apps/" + appIdentity + "/releases/" + from + "..." + to + "/diff"
+	if values {
+		path = path + "?values=true"
+	}
+
+	var releaseDiff ReleaseDiff
+	return &releaseDiff, c.Get(&releaseDiff, path)
+}
@@ -0,0 +1,76 @@
+package heroku
+
+import "time"
+
+// A scale schedule scales a process type to a quantity, and optionally a new
+// size, whenever a cron expression triggers.
+type ScaleSchedule struct {
+	// unique identifier of scale schedule
+	Id string `json:"id"`
+
+	// type of process to scale
+	Process string `json:"process"`
+
+	// 5 field cron expression, evaluated in UTC
+	Cron string `json:"cron"`
+
+	// number of processes to scale to
+	Quantity int `json:"quantity"`
+
+	// size to scale to, if it's changed
+	Size string `json:"size"`
+
+	// name of the user that created the schedule
+	User string `json:"user"`
+
+	// when the schedule will run next
+	NextRunAt time.Time `json:"next_run_at"`
+
+	// when the schedule last ran
+	LastRunAt *time.Time `json:"last_run_at"`
+
+	// when the schedule was created
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ScaleScheduleCreateOpts are the options for scheduling a process type to
+// be scaled.
+type ScaleScheduleCreateOpts struct {
+	// type of process to scale
+	Process string `json:"process"`
+
+	// 5 field cron expression, evaluated in UTC
+	Cron string `json:"cron"`
+
+	// number of processes to scale to
+	Quantity int `json:"quantity"`
+
+	// size to scale to
+	Size *string `json:"size,omitempty"`
+}
+
+// List the scale schedules for an app.
+//
+// appIdentity is the unique identifier of the App.
+func (c *Client) ScaleScheduleList(appIdentity string) ([]ScaleSchedule, error) {
+	var scaleScheduleRes []ScaleSchedule
+	return scaleScheduleRes, c.Get(&scaleScheduleRes, "/apps/"+appIdentity+"/scale-schedules")
+}
+
+// Schedule a process type to be scaled.
+//
+// appIdentity is the unique identifier of the App.
+func (c *Client) ScaleScheduleCreate(appIdentity string, options ScaleScheduleCreateOpts, message string) (*ScaleSchedule, error) {
+	rh := RequestHeaders{CommitMessage: message}
+	var scaleScheduleRes ScaleSchedule
+	return &scaleScheduleRes, c.PostWithHeaders(&scaleScheduleRes, "/apps/"+appIdentity+"/scale-schedules", options, rh.Headers())
+}
+
+// Remove a scale schedule.
+//
+// appIdentity is the unique identifier of the App. scaleScheduleIdentity is
+// the unique identifier of the ScaleSchedule.
+func (c *Client) ScaleScheduleDelete(appIdentity, scaleScheduleIdentity, message string) error {
+	rh := RequestHeaders{CommitMessage: message}
+	return c.DeleteWithHeaders("/apps/"+appIdentity+"/scale-schedules/"+scaleScheduleIdentity, rh.Headers())
+}
@@ -0,0 +1,118 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/remind101/empire/pkg/awsutil"
+	"golang.org/x/net/context"
+)
+
+// SSMResolver is a Resolver that resolves ssm:// references to parameters in
+// SSM Parameter Store. SecureString parameters are decrypted.
+type SSMResolver struct {
+	client *awsutil.JSONClient
+}
+
+// NewSSMResolver returns a new SSMResolver.
+func NewSSMResolver(p client.ConfigProvider, cfgs ...*aws.Config) *SSMResolver {
+	return &SSMResolver{
+		client: awsutil.NewJSONClient(p, "ssm", "2014-11-06", "AmazonSSM", cfgs...),
+	}
+}
+
+type ssmGetParameterInput struct {
+	_ struct{} `type:"structure"`
+
+	Name           *string `type:"string" required:"true"`
+	WithDecryption *bool   `type:"boolean"`
+}
+
+type ssmGetParameterOutput struct {
+	_ struct{} `type:"structure"`
+
+	Parameter *struct {
+		_ struct{} `type:"structure"`
+
+		Name  *string
+		Value *string
+	} `type:"structure"`
+}
+
+// Resolve implements the Resolver interface.
+func (r *SSMResolver) Resolve(ctx context.Context, ref *Reference) (string, error) {
+	out := new(ssmGetParameterOutput)
+	if err := r.client.Send("GetParameter", &ssmGetParameterInput{
+		Name:           aws.String(ref.Name),
+		WithDecryption: aws.Bool(true),
+	}, out); err != nil {
+		return "", err
+	}
+
+	if out.Parameter == nil {
+		return "", fmt.Errorf("secret not found: %s", ref)
+	}
+
+	return aws.StringValue(out.Parameter.Value), nil
+}
+
+// SecretsManagerResolver is a Resolver that resolves secretsmanager://
+// references to secrets in AWS Secrets Manager.
+type SecretsManagerResolver struct {
+	client *awsutil.JSONClient
+}
+
+// NewSecretsManagerResolver returns a new SecretsManagerResolver.
+func NewSecretsManagerResolver(p client.ConfigProvider, cfgs ...*aws.Config) *SecretsManagerResolver {
+	return &SecretsManagerResolver{
+		client: awsutil.NewJSONClient(p, "secretsmanager", "2017-10-17", "secretsmanager", cfgs...),
+	}
+}
+
+type secretsManagerGetSecretValueInput struct {
+	_ struct{} `type:"structure"`
+
+	SecretId *string `type:"string" required:"true"`
+}
+
+type secretsManagerGetSecretValueOutput struct {
+	_ struct{} `type:"structure"`
+
+	Name         *string
+	SecretString *string
+}
+
+// Resolve implements the Resolver interface.
+func (r *SecretsManagerResolver) Resolve(ctx context.Context, ref *Reference) (string, error) {
+	out := new(secretsManagerGetSecretValueOutput)
+	if err := r.client.Send("GetSecretValue", &secretsManagerGetSecretValueInput{
+		SecretId: aws.String(ref.Name),
+	}, out); err != nil {
+		return "", err
+	}
+
+	if out.SecretString == nil {
+		return "", fmt.Errorf("%s doesn't have a string value", ref)
+	}
+
+	if ref.Key == "" {
+		return *out.SecretString, nil
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(*out.SecretString), &values); err != nil {
+		return "", fmt.Errorf("%s isn't a JSON object", ref)
+	}
+
+	v, ok := values[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret not found: %s", ref)
+	}
+
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprintf("%v", v), nil
+}
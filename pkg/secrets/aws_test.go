@@ -0,0 +1,98 @@
+package secrets
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/remind101/empire/pkg/awsutil"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestSSMResolver(t *testing.T) {
+	h := awsutil.NewHandler([]awsutil.Cycle{
+		{
+			Request: awsutil.Request{
+				RequestURI: "/",
+				Operation:  "AmazonSSM.GetParameter",
+				Body:       `{"Name":"/prod/api/db_password","WithDecryption":true}`,
+			},
+			Response: awsutil.Response{
+				StatusCode: 200,
+				Body:       `{"Parameter":{"Name":"/prod/api/db_password","Type":"SecureString","Value":"hunter2"}}`,
+			},
+		},
+	})
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	r := NewSSMResolver(newTestSession(s))
+	v, err := r.Resolve(context.Background(), &Reference{Scheme: "ssm", Name: "/prod/api/db_password"})
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", v)
+}
+
+func TestSecretsManagerResolver(t *testing.T) {
+	h := awsutil.NewHandler([]awsutil.Cycle{
+		{
+			Request: awsutil.Request{
+				RequestURI: "/",
+				Operation:  "secretsmanager.GetSecretValue",
+				Body:       `{"SecretId":"prod/api/db"}`,
+			},
+			Response: awsutil.Response{
+				StatusCode: 200,
+				Body:       `{"Name":"prod/api/db","SecretString":"{\"username\":\"api\",\"password\":\"hunter2\",\"port\":5432}"}`,
+			},
+		},
+		{
+			Request: awsutil.Request{
+				RequestURI: "/",
+				Operation:  "secretsmanager.GetSecretValue",
+				Body:       `{"SecretId":"prod/api/db"}`,
+			},
+			Response: awsutil.Response{
+				StatusCode: 200,
+				Body:       `{"Name":"prod/api/db","SecretString":"{\"username\":\"api\",\"password\":\"hunter2\",\"port\":5432}"}`,
+			},
+		},
+		{
+			Request: awsutil.Request{
+				RequestURI: "/",
+				Operation:  "secretsmanager.GetSecretValue",
+				Body:       `{"SecretId":"prod/api/token"}`,
+			},
+			Response: awsutil.Response{
+				StatusCode: 200,
+				Body:       `{"Name":"prod/api/token","SecretString":"abcd"}`,
+			},
+		},
+	})
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	r := NewSecretsManagerResolver(newTestSession(s))
+
+	v, err := r.Resolve(context.Background(), &Reference{Scheme: "secretsmanager", Name: "prod/api/db", Key: "password"})
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", v)
+
+	v, err = r.Resolve(context.Background(), &Reference{Scheme: "secretsmanager", Name: "prod/api/db", Key: "port"})
+	assert.NoError(t, err)
+	assert.Equal(t, "5432", v)
+
+	v, err = r.Resolve(context.Background(), &Reference{Scheme: "secretsmanager", Name: "prod/api/token"})
+	assert.NoError(t, err)
+	assert.Equal(t, "abcd", v)
+}
+
+func newTestSession(s *httptest.Server) *session.Session {
+	return session.New(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(" ", " ", " "),
+		Endpoint:    aws.String(s.URL),
+		Region:      aws.String("localhost"),
+	})
+}
@@ -0,0 +1,118 @@
+// Package secrets resolves references to secrets that are stored outside of
+// Empire, like parameters in SSM Parameter Store or secrets in AWS Secrets
+// Manager.
+//
+// A reference is a URL-like string, with a scheme that identifies where the
+// secret is stored:
+//
+//	ssm:///prod/api/db_password
+//	secretsmanager://prod/api/db
+//	secretsmanager://prod/api/db#password
+//
+// For Secrets Manager, an optional fragment selects a single key from a secret
+// that's stored as a JSON object.
+package secrets
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// Schemes that identify where a secret is stored.
+const (
+	SchemeSSM            = "ssm"
+	SchemeSecretsManager = "secretsmanager"
+)
+
+// Schemes are the schemes of supported references.
+var Schemes = []string{SchemeSSM, SchemeSecretsManager}
+
+// Reference is a parsed reference to a secret.
+type Reference struct {
+	// Where the secret is stored (e.g. "ssm").
+	Scheme string
+
+	// The name of the secret (e.g. "/prod/api/db_password").
+	Name string
+
+	// If provided, the key to extract from a secret that's stored as a
+	// JSON object.
+	Key string
+}
+
+// IsReference returns true if the value looks like a reference to a secret.
+func IsReference(value string) bool {
+	for _, scheme := range Schemes {
+		if strings.HasPrefix(value, scheme+"://") {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseReference parses a reference to a secret.
+func ParseReference(value string) (*Reference, error) {
+	if !IsReference(value) {
+		return nil, fmt.Errorf("%q is not a secret reference", value)
+	}
+
+	i := strings.Index(value, "://")
+	ref := &Reference{Scheme: value[:i], Name: value[i+3:]}
+
+	if ref.Scheme == SchemeSecretsManager {
+		if j := strings.LastIndex(ref.Name, "#"); j != -1 {
+			ref.Name, ref.Key = ref.Name[:j], ref.Name[j+1:]
+			if ref.Key == "" {
+				return nil, fmt.Errorf("%q has an empty key", value)
+			}
+		}
+	}
+
+	if ref.Name == "" || ref.Name == "/" {
+		return nil, fmt.Errorf("%q is missing the name of the secret", value)
+	}
+
+	return ref, nil
+}
+
+// String returns the string representation of the reference.
+func (r *Reference) String() string {
+	s := r.Scheme + "://" + r.Name
+	if r.Key != "" {
+		s += "#" + r.Key
+	}
+	return s
+}
+
+// Resolver resolves a reference to the value of the secret.
+type Resolver interface {
+	Resolve(context.Context, *Reference) (string, error)
+}
+
+// MultiResolver is a Resolver that routes references to another Resolver based
+// on their scheme.
+type MultiResolver map[string]Resolver
+
+// Resolve implements the Resolver interface.
+func (r MultiResolver) Resolve(ctx context.Context, ref *Reference) (string, error) {
+	resolver, ok := r[ref.Scheme]
+	if !ok {
+		return "", fmt.Errorf("no resolver for %s:// references", ref.Scheme)
+	}
+	return resolver.Resolve(ctx, ref)
+}
+
+// MemoryResolver is a Resolver backed by an in memory map of references to
+// values, which is useful in tests.
+type MemoryResolver map[string]string
+
+// Resolve implements the Resolver interface.
+func (r MemoryResolver) Resolve(ctx context.Context, ref *Reference) (string, error) {
+	v, ok := r[ref.String()]
+	if !ok {
+		return "", fmt.Errorf("secret not found: %s", ref)
+	}
+	return v, nil
+}
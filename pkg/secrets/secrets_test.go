@@ -0,0 +1,59 @@
+package secrets
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		in  string
+		ref *Reference
+		err error
+	}{
+		{"ssm:///prod/api/db_password", &Reference{Scheme: "ssm", Name: "/prod/api/db_password"}, nil},
+		{"ssm://db_password", &Reference{Scheme: "ssm", Name: "db_password"}, nil},
+		{"ssm://a#b", &Reference{Scheme: "ssm", Name: "a#b"}, nil},
+		{"secretsmanager://prod/api/db", &Reference{Scheme: "secretsmanager", Name: "prod/api/db"}, nil},
+		{"secretsmanager://prod/api/db#password", &Reference{Scheme: "secretsmanager", Name: "prod/api/db", Key: "password"}, nil},
+		{"secretsmanager://prod/api/db#", nil, errors.New(`"secretsmanager://prod/api/db#" has an empty key`)},
+		{"ssm://", nil, errors.New(`"ssm://" is missing the name of the secret`)},
+		{"ssm:///", nil, errors.New(`"ssm:///" is missing the name of the secret`)},
+		{"postgres://localhost", nil, errors.New(`"postgres://localhost" is not a secret reference`)},
+	}
+
+	for _, tt := range tests {
+		ref, err := ParseReference(tt.in)
+		assert.Equal(t, tt.err, err, tt.in)
+		assert.Equal(t, tt.ref, ref, tt.in)
+		if ref != nil {
+			assert.Equal(t, tt.in, ref.String())
+		}
+	}
+}
+
+func TestIsReference(t *testing.T) {
+	assert.True(t, IsReference("ssm:///prod/api/db_password"))
+	assert.True(t, IsReference("secretsmanager://prod/api/db"))
+	assert.False(t, IsReference("postgres://localhost"))
+	assert.False(t, IsReference("hunter2"))
+}
+
+func TestMultiResolver(t *testing.T) {
+	r := MultiResolver{
+		SchemeSSM: MemoryResolver{"ssm:///prod/api/db_password": "hunter2"},
+	}
+
+	v, err := r.Resolve(context.Background(), &Reference{Scheme: "ssm", Name: "/prod/api/db_password"})
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", v)
+
+	_, err = r.Resolve(context.Background(), &Reference{Scheme: "ssm", Name: "/prod/api/other"})
+	assert.EqualError(t, err, "secret not found: ssm:///prod/api/other")
+
+	_, err = r.Resolve(context.Background(), &Reference{Scheme: "secretsmanager", Name: "prod/api/db"})
+	assert.EqualError(t, err, "no resolver for secretsmanager:// references")
+}
@@ -90,6 +90,208 @@ type Process struct {
 	// A cron expression. If provided, the process will be run as a
 	// scheduled task.
 	Cron *string `json:"cron,omitempty"`
+
+	// If provided, how this process is exposed through a load balancer.
+	// When nil, only the `web` process is exposed, using the app's
+	// exposure settings.
+	Exposure *ProcessExposure `json:"Exposure,omitempty"`
+
+	// If provided, how the load balancer checks the health of this
+	// process. Only used when the process is exposed.
+	HealthCheck *HealthCheck `json:"HealthCheck,omitempty"`
+
+	// If provided, the process is scaled automatically between a minimum
+	// and maximum quantity.
+	Autoscaling *Autoscaling `json:"Autoscaling,omitempty"`
+}
+
+// Metrics that an autoscaled process can track.
+const (
+	// The average CPU utilization of the process, as a percentage.
+	AutoscalingMetricCPU = "cpu"
+
+	// The average memory utilization of the process, as a percentage.
+	AutoscalingMetricMemory = "memory"
+
+	// The number of requests to the process's application load balancer,
+	// per instance, per minute.
+	AutoscalingMetricRequests = "requests"
+)
+
+// Autoscaling configures how a process is scaled automatically.
+type Autoscaling struct {
+	// The minimum and maximum quantity that the process can be scaled to.
+	Min int `json:"Min"`
+	Max int `json:"Max"`
+
+	// The metric to track (e.g. "cpu", "memory" or "requests").
+	Metric string `json:"Metric"`
+
+	// The value of the metric to maintain. Instances are added when the
+	// metric is above the target, and removed when it's below.
+	Target int `json:"Target"`
+}
+
+// IsValid returns nil if the Autoscaling configuration is valid.
+func (a *Autoscaling) IsValid() error {
+	if a.Min < 0 {
+		return errors.New("autoscaling min must be at least 0")
+	}
+
+	if a.Max < 1 || a.Max < a.Min {
+		return errors.New("autoscaling max must be at least 1, and not less than min")
+	}
+
+	switch a.Metric {
+	case AutoscalingMetricCPU, AutoscalingMetricMemory:
+		if a.Target < 1 || a.Target > 100 {
+			return fmt.Errorf("autoscaling target for %s must be a percentage between 1 and 100", a.Metric)
+		}
+	case AutoscalingMetricRequests:
+		if a.Target < 1 {
+			return errors.New("autoscaling target for requests must be at least 1")
+		}
+	default:
+		return fmt.Errorf("unknown autoscaling metric: %s", a.Metric)
+	}
+
+	return nil
+}
+
+// Clamp returns the quantity, limited to the minimum and maximum.
+func (a *Autoscaling) Clamp(quantity int) int {
+	if quantity < a.Min {
+		return a.Min
+	}
+	if quantity > a.Max {
+		return a.Max
+	}
+	return quantity
+}
+
+// String returns a human readable representation of the Autoscaling
+// configuration. For example, "2-10 cpu=50".
+func (a *Autoscaling) String() string {
+	return fmt.Sprintf("%d-%d %s=%d", a.Min, a.Max, a.Metric, a.Target)
+}
+
+// Default settings for health checks, in seconds.
+const (
+	DefaultHealthCheckInterval            = 30
+	DefaultHealthCheckTimeout             = 5
+	DefaultHealthCheckHealthyThreshold    = 5
+	DefaultHealthCheckUnhealthyThreshold  = 2
+	DefaultHealthCheckDeregistrationDelay = 30
+)
+
+// HealthCheck configures how the load balancer checks the health of a process.
+// Times are in seconds.
+type HealthCheck struct {
+	// If provided, the path to make an HTTP GET request to. Otherwise, a
+	// TCP connection is opened.
+	Path string `json:"Path,omitempty"`
+
+	// The time between health checks.
+	Interval int `json:"Interval,omitempty"`
+
+	// The time to wait for a response before the check fails.
+	Timeout int `json:"Timeout,omitempty"`
+
+	// The number of consecutive checks that must pass before an instance
+	// is considered healthy.
+	HealthyThreshold int `json:"HealthyThreshold,omitempty"`
+
+	// The number of consecutive checks that must fail before an instance
+	// is considered unhealthy.
+	UnhealthyThreshold int `json:"UnhealthyThreshold,omitempty"`
+
+	// The time to let in flight requests complete before an instance is
+	// removed from the load balancer.
+	DeregistrationDelay int `json:"DeregistrationDelay,omitempty"`
+}
+
+// IsValid returns nil if the HealthCheck is within the limits that AWS load
+// balancers allow.
+func (h *HealthCheck) IsValid() error {
+	if h.Path != "" && !strings.HasPrefix(h.Path, "/") {
+		return errors.New("health check path must start with /")
+	}
+
+	if h.Interval < 5 || h.Interval > 300 {
+		return errors.New("health check interval must be between 5 and 300 seconds")
+	}
+
+	if h.Timeout < 2 || h.Timeout > 60 {
+		return errors.New("health check timeout must be between 2 and 60 seconds")
+	}
+
+	if h.Timeout >= h.Interval {
+		return errors.New("health check timeout must be less than the interval")
+	}
+
+	if h.HealthyThreshold < 2 || h.HealthyThreshold > 10 {
+		return errors.New("health check healthy threshold must be between 2 and 10")
+	}
+
+	if h.UnhealthyThreshold < 2 || h.UnhealthyThreshold > 10 {
+		return errors.New("health check unhealthy threshold must be between 2 and 10")
+	}
+
+	if h.DeregistrationDelay < 1 || h.DeregistrationDelay > 3600 {
+		return errors.New("health check deregistration delay must be between 1 and 3600 seconds")
+	}
+
+	return nil
+}
+
+// Protocols that a process can be exposed with.
+const (
+	exposeHTTP  = "http"
+	exposeHTTPS = "https"
+	exposeTCP   = "tcp"
+	exposeSSL   = "ssl"
+)
+
+// ProcessExposure holds the exposure settings for a process, as declared in the
+// Procfile.
+type ProcessExposure struct {
+	// When true, the process is exposed to the internet instead of only
+	// internally.
+	External bool `json:"External,omitempty"`
+
+	// The protocol that the process serves (e.g. "http", "https", "tcp" or
+	// "ssl").
+	Protocol string `json:"Protocol,omitempty"`
+
+	// The SSL certificate to attach when the protocol is "https" or "ssl".
+	Cert string `json:"Cert,omitempty"`
+
+	// The port that the load balancer listens on when the protocol is
+	// "tcp" or "ssl".
+	Port int64 `json:"Port,omitempty"`
+}
+
+// IsValid returns nil if the ProcessExposure is valid.
+func (e *ProcessExposure) IsValid() error {
+	switch e.Protocol {
+	case exposeHTTP:
+	case exposeHTTPS:
+		if e.Cert == "" {
+			return errors.New("a cert is required to expose https")
+		}
+	case exposeTCP, exposeSSL:
+		if e.Port < 1 || e.Port > 65535 {
+			return fmt.Errorf("a port between 1 and 65535 is required to expose %s", e.Protocol)
+		}
+
+		if e.Protocol == exposeSSL && e.Cert == "" {
+			return errors.New("a cert is required to expose ssl")
+		}
+	default:
+		return fmt.Errorf("unknown protocol: %s", e.Protocol)
+	}
+
+	return nil
 }
 
 // IsValid returns nil if the Process is valid.
@@ -99,6 +301,32 @@ func (p *Process) IsValid() error {
 		if p.Quantity != 0 {
 			return errors.New("non-service processes cannot be scaled up")
 		}
+
+		if p.Exposure != nil {
+			return errors.New("non-service processes cannot be exposed")
+		}
+
+		if p.Autoscaling != nil {
+			return errors.New("non-service processes cannot be autoscaled")
+		}
+	}
+
+	if p.Exposure != nil {
+		if err := p.Exposure.IsValid(); err != nil {
+			return err
+		}
+	}
+
+	if p.HealthCheck != nil {
+		if err := p.HealthCheck.IsValid(); err != nil {
+			return err
+		}
+	}
+
+	if p.Autoscaling != nil {
+		if err := p.Autoscaling.IsValid(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -165,8 +393,8 @@ func (f Formation) Value() (driver.Value, error) {
 	return driver.Value(raw), nil
 }
 
-// Merge merges in the existing quantity and constraints from the old Formation
-// into this Formation.
+// Merge merges in the existing quantity, constraints and autoscaling settings
+// from the old Formation into this Formation.
 func (f Formation) Merge(other Formation) Formation {
 	new := make(Formation)
 
@@ -177,6 +405,7 @@ func (f Formation) Merge(other Formation) Formation {
 			// instance count.
 			p.Quantity = existing.Quantity
 			p.SetConstraints(existing.Constraints())
+			p.Autoscaling = existing.Autoscaling
 		} else {
 			p.Quantity = DefaultQuantities[name]
 			p.SetConstraints(DefaultConstraints)
@@ -72,6 +72,35 @@ func TestNewFormation(t *testing.T) {
 			},
 		},
 
+		// Check that autoscaling settings are carried over.
+		{
+			f: Formation{
+				"web": Process{
+					Command: Command{"./bin/web"},
+				},
+			},
+			other: Formation{
+				"web": Process{
+					Command:     Command{"./bin/web"},
+					Quantity:    4,
+					Memory:      NamedConstraints["1X"].Memory,
+					CPUShare:    NamedConstraints["1X"].CPUShare,
+					Nproc:       NamedConstraints["1X"].Nproc,
+					Autoscaling: &Autoscaling{Min: 2, Max: 10, Metric: "cpu", Target: 50},
+				},
+			},
+			expected: Formation{
+				"web": Process{
+					Quantity:    4,
+					Command:     Command{"./bin/web"},
+					Memory:      NamedConstraints["1X"].Memory,
+					CPUShare:    NamedConstraints["1X"].CPUShare,
+					Nproc:       NamedConstraints["1X"].Nproc,
+					Autoscaling: &Autoscaling{Min: 2, Max: 10, Metric: "cpu", Target: 50},
+				},
+			},
+		},
+
 		// Check that removed processes are ignored.
 		{
 			f: Formation{
@@ -106,6 +135,38 @@ func TestNewFormation(t *testing.T) {
 	}
 }
 
+func TestAutoscaling_IsValid(t *testing.T) {
+	tests := []struct {
+		autoscaling Autoscaling
+		err         string
+	}{
+		{Autoscaling{Min: 2, Max: 10, Metric: "cpu", Target: 50}, ""},
+		{Autoscaling{Min: 0, Max: 1, Metric: "memory", Target: 80}, ""},
+		{Autoscaling{Min: 1, Max: 5, Metric: "requests", Target: 1000}, ""},
+		{Autoscaling{Min: -1, Max: 5, Metric: "cpu", Target: 50}, "autoscaling min must be at least 0"},
+		{Autoscaling{Min: 5, Max: 2, Metric: "cpu", Target: 50}, "autoscaling max must be at least 1, and not less than min"},
+		{Autoscaling{Min: 1, Max: 2, Metric: "cpu", Target: 150}, "autoscaling target for cpu must be a percentage between 1 and 100"},
+		{Autoscaling{Min: 1, Max: 2, Metric: "requests", Target: 0}, "autoscaling target for requests must be at least 1"},
+		{Autoscaling{Min: 1, Max: 2, Metric: "disk", Target: 50}, "unknown autoscaling metric: disk"},
+	}
+
+	for _, tt := range tests {
+		err := tt.autoscaling.IsValid()
+		if tt.err == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tt.err)
+		}
+	}
+}
+
+func TestAutoscaling_Clamp(t *testing.T) {
+	a := &Autoscaling{Min: 2, Max: 10}
+	assert.Equal(t, 2, a.Clamp(0))
+	assert.Equal(t, 5, a.Clamp(5))
+	assert.Equal(t, 10, a.Clamp(20))
+}
+
 func ExampleCommand() {
 	cmd := Command{"/bin/ls", "-h"}
 	fmt.Println(cmd)
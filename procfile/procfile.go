@@ -22,9 +22,38 @@ func (e ExtendedProcfile) version() string {
 }
 
 type Process struct {
-	Command   interface{} `yaml:"command"`
-	Cron      *string     `yaml:"cron,omitempty"`
-	NoService bool        `yaml:"noservice,omitempty"`
+	Command     interface{}  `yaml:"command"`
+	Cron        *string      `yaml:"cron,omitempty"`
+	NoService   bool         `yaml:"noservice,omitempty"`
+	Expose      *Expose      `yaml:"expose,omitempty"`
+	HealthCheck *HealthCheck `yaml:"healthcheck,omitempty"`
+}
+
+// Expose configures how a process is exposed through a load balancer.
+type Expose struct {
+	// When true, the process is exposed to the internet.
+	External bool `yaml:"external,omitempty"`
+
+	// The protocol to serve, either http, https, tcp or ssl.
+	Protocol string `yaml:"protocol,omitempty"`
+
+	// The SSL certificate to use when the protocol is https or ssl.
+	Cert string `yaml:"cert,omitempty"`
+
+	// The port that the load balancer listens on when the protocol is tcp
+	// or ssl.
+	Port int64 `yaml:"port,omitempty"`
+}
+
+// HealthCheck configures how the load balancer checks the health of an exposed
+// process. Times are in seconds.
+type HealthCheck struct {
+	Path                string `yaml:"path,omitempty"`
+	Interval            int    `yaml:"interval,omitempty"`
+	Timeout             int    `yaml:"timeout,omitempty"`
+	HealthyThreshold    int    `yaml:"healthy_threshold,omitempty"`
+	UnhealthyThreshold  int    `yaml:"unhealthy_threshold,omitempty"`
+	DeregistrationDelay int    `yaml:"deregistration_delay,omitempty"`
 }
 
 // StandardProcfile represents a standard Procfile.
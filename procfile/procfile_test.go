@@ -51,6 +51,37 @@ web:
 			},
 		},
 	},
+
+	// Extended Procfile with multiple exposed processes.
+	{
+		strings.NewReader(`---
+web:
+  command: ./bin/web
+  expose:
+    external: true
+    protocol: https
+    cert: AcmeIncDotCom
+admin:
+  command: ./bin/admin
+  expose:
+    protocol: http`),
+		ExtendedProcfile{
+			"web": Process{
+				Command: "./bin/web",
+				Expose: &Expose{
+					External: true,
+					Protocol: "https",
+					Cert:     "AcmeIncDotCom",
+				},
+			},
+			"admin": Process{
+				Command: "./bin/admin",
+				Expose: &Expose{
+					Protocol: "http",
+				},
+			},
+		},
+	},
 }
 
 func TestParse(t *testing.T) {
@@ -0,0 +1,270 @@
+package empire
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Change describes how something changed between two releases.
+type Change string
+
+const (
+	ChangeAdded   Change = "added"
+	ChangeRemoved Change = "removed"
+	ChangeChanged Change = "changed"
+)
+
+// ReleaseDiff describes what changed between two releases of an app.
+type ReleaseDiff struct {
+	// The release that's being compared against.
+	From *Release
+
+	// The release that's being compared.
+	To *Release
+
+	// If the Docker image changed, the change.
+	Image *FieldDiff
+
+	// The config vars that were added, removed or changed, sorted by name.
+	Config []*ConfigDiff
+
+	// The processes that were added, removed or changed, sorted by name.
+	Processes []*ProcessDiff
+}
+
+// FieldDiff represents a value that changed between two releases.
+type FieldDiff struct {
+	Field string
+	From  string
+	To    string
+}
+
+// ConfigDiff represents a config var that changed between two releases.
+type ConfigDiff struct {
+	Name   Variable
+	Change Change
+
+	// The old and new values. These are nil when the variable was added or
+	// removed respectively.
+	From *string
+	To   *string
+}
+
+// ProcessDiff represents a process in the Formation that changed between two
+// releases.
+type ProcessDiff struct {
+	Name   string
+	Change Change
+
+	// The individual attributes of the process that changed, like the
+	// command, quantity or constraints.
+	Fields []*FieldDiff
+}
+
+// ReleaseDiffOpts are options provided when comparing two releases.
+type ReleaseDiffOpts struct {
+	// The app that the releases belong to.
+	App *App
+
+	// The version of the release to compare against.
+	From int
+
+	// The version of the release to compare.
+	To int
+
+	// The user comparing the releases. Only required when Reveal is true.
+	User *User
+
+	// When true, the values of sensitive config vars are returned in full,
+	// rather than masked.
+	Reveal bool
+}
+
+func (opts ReleaseDiffOpts) Validate(e *Empire) error {
+	if !opts.Reveal {
+		return nil
+	}
+
+	return e.authorize(opts.User, ActionReveal, opts.App)
+}
+
+// releasesDiff returns what changed between two releases. Config vars are
+// decrypted before they're compared, since the same value is encrypted
+// differently in each release, and vars from config groups are included.
+func (e *Empire) releasesDiff(db *gorm.DB, opts ReleaseDiffOpts) (*ReleaseDiff, error) {
+	from, err := releasesFind(db, ReleasesQuery{App: opts.App, Version: &opts.From})
+	if err != nil {
+		return nil, err
+	}
+
+	if from, err = e.decryptRelease(from); err != nil {
+		return nil, err
+	}
+
+	to, err := releasesFind(db, ReleasesQuery{App: opts.App, Version: &opts.To})
+	if err != nil {
+		return nil, err
+	}
+
+	if to, err = e.decryptRelease(to); err != nil {
+		return nil, err
+	}
+
+	from.Config, to.Config = flattenConfig(from.Config), flattenConfig(to.Config)
+
+	d := diffReleases(from, to)
+
+	if !opts.Reveal {
+		insensitive, err := insensitiveConfigVars(db, opts.App.ID)
+		if err != nil {
+			return nil, err
+		}
+		maskConfigDiffs(d.Config, insensitive)
+	}
+
+	return d, nil
+}
+
+// maskConfigDiffs masks the old and new values of sensitive config vars.
+func maskConfigDiffs(diffs []*ConfigDiff, insensitive map[Variable]bool) {
+	for _, d := range diffs {
+		if insensitive[d.Name] {
+			continue
+		}
+		d.From, d.To = maskVar(d.From), maskVar(d.To)
+	}
+}
+
+// diffReleases compares two releases.
+func diffReleases(from, to *Release) *ReleaseDiff {
+	d := &ReleaseDiff{
+		From:      from,
+		To:        to,
+		Config:    diffVars(from.Config.Vars, to.Config.Vars),
+		Processes: diffFormations(from.Formation, to.Formation),
+	}
+
+	if fromImage, toImage := from.Slug.Image.String(), to.Slug.Image.String(); fromImage != toImage {
+		d.Image = &FieldDiff{Field: "image", From: fromImage, To: toImage}
+	}
+
+	return d
+}
+
+// diffVars compares two sets of config vars.
+func diffVars(from, to Vars) []*ConfigDiff {
+	var diffs []*ConfigDiff
+
+	for _, name := range varNames(from, to) {
+		o, n := from[name], to[name]
+		switch {
+		case o == nil && n != nil:
+			diffs = append(diffs, &ConfigDiff{Name: name, Change: ChangeAdded, To: n})
+		case o != nil && n == nil:
+			diffs = append(diffs, &ConfigDiff{Name: name, Change: ChangeRemoved, From: o})
+		case o != nil && n != nil && *o != *n:
+			diffs = append(diffs, &ConfigDiff{Name: name, Change: ChangeChanged, From: o, To: n})
+		}
+	}
+
+	return diffs
+}
+
+// diffFormations compares two formations.
+func diffFormations(from, to Formation) []*ProcessDiff {
+	var diffs []*ProcessDiff
+
+	for _, name := range processNames(from, to) {
+		o, oldOk := from[name]
+		n, newOk := to[name]
+
+		d := &ProcessDiff{Name: name}
+		switch {
+		case !oldOk:
+			d.Change = ChangeAdded
+		case !newOk:
+			d.Change = ChangeRemoved
+		default:
+			d.Change = ChangeChanged
+		}
+
+		oldFields, newFields := processFields(o, oldOk), processFields(n, newOk)
+		for _, f := range processFieldNames {
+			if oldFields[f] != newFields[f] {
+				d.Fields = append(d.Fields, &FieldDiff{Field: f, From: oldFields[f], To: newFields[f]})
+			}
+		}
+
+		if d.Change != ChangeChanged || len(d.Fields) > 0 {
+			diffs = append(diffs, d)
+		}
+	}
+
+	return diffs
+}
+
+// processFieldNames are the attributes of a Process that are compared, in the
+// order that they're shown.
+var processFieldNames = []string{"command", "quantity", "constraints", "cron"}
+
+// processFields returns the string representation of the attributes of a
+// Process that are compared. If the process doesn't exist, all of the
+// attributes are empty.
+func processFields(p Process, ok bool) map[string]string {
+	fields := make(map[string]string)
+	if !ok {
+		return fields
+	}
+
+	fields["command"] = p.Command.String()
+	fields["quantity"] = strconv.Itoa(p.Quantity)
+	fields["constraints"] = p.Constraints().String()
+	if p.Cron != nil {
+		fields["cron"] = *p.Cron
+	}
+
+	return fields
+}
+
+// varNames returns the sorted, unique names of the variables in a and b.
+func varNames(a, b Vars) []Variable {
+	seen := make(map[Variable]bool)
+	for k := range a {
+		seen[k] = true
+	}
+	for k := range b {
+		seen[k] = true
+	}
+
+	var names []string
+	for k := range seen {
+		names = append(names, string(k))
+	}
+	sort.Strings(names)
+
+	vars := make([]Variable, len(names))
+	for i, n := range names {
+		vars[i] = Variable(n)
+	}
+	return vars
+}
+
+// processNames returns the sorted, unique process names in a and b.
+func processNames(a, b Formation) []string {
+	seen := make(map[string]bool)
+	for k := range a {
+		seen[k] = true
+	}
+	for k := range b {
+		seen[k] = true
+	}
+
+	var names []string
+	for k := range seen {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,104 @@
+package empire
+
+import (
+	"testing"
+
+	"github.com/remind101/empire/pkg/image"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffReleases(t *testing.T) {
+	var (
+		foo = "foo"
+		bar = "bar"
+		baz = "baz"
+		a   = "* * * * *"
+	)
+
+	from := &Release{
+		Version: 1,
+		Slug:    &Slug{Image: image.Image{Repository: "remind101/acme-inc", Tag: "a"}},
+		Config: &Config{Vars: Vars{
+			"REMOVED": &foo,
+			"CHANGED": &foo,
+			"SAME":    &foo,
+		}},
+		Formation: Formation{
+			"web":    Process{Command: Command{"./bin/web"}, Quantity: 1, Memory: Constraints1X.Memory, CPUShare: Constraints1X.CPUShare, Nproc: Constraints1X.Nproc},
+			"worker": Process{Command: Command{"./bin/worker"}, Quantity: 1},
+			"same":   Process{Command: Command{"./bin/same"}},
+		},
+	}
+
+	to := &Release{
+		Version: 2,
+		Slug:    &Slug{Image: image.Image{Repository: "remind101/acme-inc", Tag: "b"}},
+		Config: &Config{Vars: Vars{
+			"ADDED":   &baz,
+			"CHANGED": &bar,
+			"SAME":    &foo,
+		}},
+		Formation: Formation{
+			"web":  Process{Command: Command{"./bin/web"}, Quantity: 2, Memory: Constraints2X.Memory, CPUShare: Constraints2X.CPUShare, Nproc: Constraints2X.Nproc},
+			"cron": Process{Command: Command{"./bin/cron"}, Cron: &a},
+			"same": Process{Command: Command{"./bin/same"}},
+		},
+	}
+
+	d := diffReleases(from, to)
+	assert.Equal(t, &FieldDiff{Field: "image", From: "remind101/acme-inc:a", To: "remind101/acme-inc:b"}, d.Image)
+	assert.Equal(t, []*ConfigDiff{
+		{Name: "ADDED", Change: ChangeAdded, To: &baz},
+		{Name: "CHANGED", Change: ChangeChanged, From: &foo, To: &bar},
+		{Name: "REMOVED", Change: ChangeRemoved, From: &foo},
+	}, d.Config)
+	assert.Equal(t, []*ProcessDiff{
+		{Name: "cron", Change: ChangeAdded, Fields: []*FieldDiff{
+			{Field: "command", To: "./bin/cron"},
+			{Field: "quantity", To: "0"},
+			{Field: "constraints", To: "0:0"},
+			{Field: "cron", To: "* * * * *"},
+		}},
+		{Name: "web", Change: ChangeChanged, Fields: []*FieldDiff{
+			{Field: "quantity", From: "1", To: "2"},
+			{Field: "constraints", From: "1X", To: "2X"},
+		}},
+		{Name: "worker", Change: ChangeRemoved, Fields: []*FieldDiff{
+			{Field: "command", From: "./bin/worker"},
+			{Field: "quantity", From: "1"},
+			{Field: "constraints", From: "0:0"},
+		}},
+	}, d.Processes)
+}
+
+func TestDiffReleases_NoChanges(t *testing.T) {
+	r := &Release{
+		Slug:      &Slug{Image: image.Image{Repository: "remind101/acme-inc", Tag: "a"}},
+		Config:    &Config{Vars: Vars{}},
+		Formation: Formation{"web": Process{Command: Command{"./bin/web"}}},
+	}
+
+	d := diffReleases(r, r)
+	assert.Nil(t, d.Image)
+	assert.Nil(t, d.Config)
+	assert.Nil(t, d.Processes)
+}
+
+func TestMaskConfigDiffs(t *testing.T) {
+	var (
+		old = "postgres://localhost:5432"
+		new = "postgres://localhost:6543"
+		env = "production"
+	)
+
+	diffs := []*ConfigDiff{
+		{Name: "DATABASE_URL", Change: ChangeChanged, From: &old, To: &new},
+		{Name: "RAILS_ENV", Change: ChangeAdded, To: &env},
+	}
+
+	maskConfigDiffs(diffs, map[Variable]bool{"RAILS_ENV": true})
+	assert.Equal(t, "****5432", *diffs[0].From)
+	assert.Equal(t, "****6543", *diffs[0].To)
+	assert.Nil(t, diffs[1].From)
+	assert.Equal(t, "production", *diffs[1].To)
+}
@@ -47,6 +47,10 @@ type Release struct {
 	// the release was created (e.g. deployment, config changes, etc).
 	Description string
 
+	// Set when the release failed to stabilize and was automatically
+	// rolled back.
+	Failed bool
+
 	// The time that this release was created.
 	CreatedAt *time.Time
 }
@@ -121,6 +125,14 @@ func (s *releasesService) Create(ctx context.Context, db *gorm.DB, r *Release) (
 		return r, err
 	}
 
+	// New releases can't be created while a canary is running, since they
+	// would replace it.
+	if c, err := canariesFind(db, runningCanary(r.App)); err == nil {
+		return r, errCanaryRunning(c)
+	} else if err != gorm.RecordNotFound {
+		return r, err
+	}
+
 	// During rollbacks, we can just provide the existing Formation for the
 	// old release. For new releases, we need to create a new formation by
 	// merging the formation from the extracted Procfile, and the Formation
@@ -153,9 +165,25 @@ func (s *releasesService) Rollback(ctx context.Context, db *gorm.DB, opts Rollba
 	}, nil)
 }
 
-// Release submits a release to the scheduler.
+// Release submits a release to the scheduler. If the release is running as a
+// canary, it's submitted alongside the stable release.
 func (s *releasesService) Release(ctx context.Context, release *Release, ss scheduler.StatusStream) error {
-	a := newSchedulerApp(release)
+	c, err := canariesFind(s.db, runningCanary(release.App))
+	if err == nil && c.Version == release.Version {
+		return s.canaries.submitRelease(ctx, c, release, ss)
+	} else if err != nil && err != gorm.RecordNotFound {
+		return err
+	}
+
+	return s.submit(ctx, release, ss)
+}
+
+// submit submits a release to the scheduler, replacing any canary.
+func (s *releasesService) submit(ctx context.Context, release *Release, ss scheduler.StatusStream) error {
+	a, err := s.schedulerApp(ctx, s.db, release)
+	if err != nil {
+		return err
+	}
 	return s.Scheduler.Submit(ctx, a, ss)
 }
 
@@ -175,10 +203,35 @@ func (s *releasesService) Restart(ctx context.Context, db *gorm.DB, app *App) er
 		return nil
 	}
 
-	a := newSchedulerApp(release)
+	a, err := s.schedulerApp(ctx, db, release)
+	if err != nil {
+		return err
+	}
 	return s.Scheduler.Restart(ctx, a, nil)
 }
 
+// schedulerApp returns the scheduler.App for the release, including the app's
+// domains, so that the scheduler can route them to the app.
+func (s *releasesService) schedulerApp(ctx context.Context, db *gorm.DB, release *Release) (*scheduler.App, error) {
+	release, err := s.resolveRelease(ctx, release)
+	if err != nil {
+		return nil, err
+	}
+
+	a := newSchedulerApp(release)
+
+	ds, err := domains(db, composedScope{DomainsQuery{App: release.App}, order("hostname")})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range ds {
+		a.Domains = append(a.Domains, d.Hostname)
+	}
+
+	return a, nil
+}
+
 // These associations are always available on a Release.
 var releasesPreload = preload("App", "Config", "Slug")
 
@@ -205,6 +258,12 @@ func releasesUpdate(db *gorm.DB, release *Release) error {
 	return db.Save(release).Error
 }
 
+// releasesMarkFailed marks the release as having failed to deploy.
+func releasesMarkFailed(db *gorm.DB, release *Release) error {
+	release.Failed = true
+	return db.Exec(`UPDATE releases SET failed = true WHERE id = ?`, release.ID).Error
+}
+
 func buildFormation(db *gorm.DB, release *Release) error {
 	var existing Formation
 
@@ -325,7 +384,9 @@ func newSchedulerProcess(release *Release, name string, p Process) *scheduler.Pr
 		MemoryLimit: uint(p.Memory),
 		CPUShares:   uint(p.CPUShare),
 		Nproc:       uint(p.Nproc),
-		Exposure:    processExposure(release.App, name),
+		Exposure:    processExposure(release.App, name, p),
+		HealthCheck: processHealthCheck(p),
+		Autoscaling: processAutoscaling(p),
 		Schedule:    processSchedule(name, p),
 	}
 }
@@ -341,8 +402,36 @@ func environment(vars Vars) map[string]string {
 	return env
 }
 
-func processExposure(app *App, process string) *scheduler.Exposure {
-	// For now, only the `web` process can be exposed.
+func processExposure(app *App, process string, p Process) *scheduler.Exposure {
+	// Exposure settings from the Procfile take precedence.
+	if e := p.Exposure; e != nil {
+		exposure := &scheduler.Exposure{
+			External: e.External,
+		}
+
+		switch e.Protocol {
+		case exposeHTTPS:
+			exposure.Type = &scheduler.HTTPSExposure{
+				Cert: e.Cert,
+			}
+		case exposeTCP:
+			exposure.Type = &scheduler.TCPExposure{
+				Port: e.Port,
+			}
+		case exposeSSL:
+			exposure.Type = &scheduler.SSLExposure{
+				Port: e.Port,
+				Cert: e.Cert,
+			}
+		default:
+			exposure.Type = &scheduler.HTTPExposure{}
+		}
+
+		return exposure
+	}
+
+	// Otherwise, only the `web` process is exposed, using the exposure
+	// settings of the app.
 	if process != webProcessType {
 		return nil
 	}
@@ -363,6 +452,36 @@ func processExposure(app *App, process string) *scheduler.Exposure {
 	return exposure
 }
 
+func processHealthCheck(p Process) *scheduler.HealthCheck {
+	h := p.HealthCheck
+	if h == nil {
+		return nil
+	}
+
+	return &scheduler.HealthCheck{
+		Path:                h.Path,
+		Interval:            time.Duration(h.Interval) * time.Second,
+		Timeout:             time.Duration(h.Timeout) * time.Second,
+		HealthyThreshold:    uint(h.HealthyThreshold),
+		UnhealthyThreshold:  uint(h.UnhealthyThreshold),
+		DeregistrationDelay: time.Duration(h.DeregistrationDelay) * time.Second,
+	}
+}
+
+func processAutoscaling(p Process) *scheduler.Autoscaling {
+	a := p.Autoscaling
+	if a == nil {
+		return nil
+	}
+
+	return &scheduler.Autoscaling{
+		Min:    uint(a.Min),
+		Max:    uint(a.Max),
+		Metric: a.Metric,
+		Target: float64(a.Target),
+	}
+}
+
 func processSchedule(name string, p Process) scheduler.Schedule {
 	if p.Cron != nil {
 		return scheduler.CRONSchedule(*p.Cron)
@@ -2,8 +2,11 @@ package empire
 
 import (
 	"testing"
+	"time"
 
 	"github.com/remind101/empire/pkg/headerutil"
+	"github.com/remind101/empire/scheduler"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestReleasesQuery(t *testing.T) {
@@ -31,3 +34,61 @@ func TestReleasesQuery(t *testing.T) {
 
 	tests.Run(t)
 }
+
+func TestProcessHealthCheck(t *testing.T) {
+	assert.Nil(t, processHealthCheck(Process{}))
+	assert.Equal(t, &scheduler.HealthCheck{
+		Path:                "/health",
+		Interval:            30 * time.Second,
+		Timeout:             5 * time.Second,
+		HealthyThreshold:    2,
+		UnhealthyThreshold:  10,
+		DeregistrationDelay: 2 * time.Minute,
+	}, processHealthCheck(Process{HealthCheck: &HealthCheck{
+		Path:                "/health",
+		Interval:            30,
+		Timeout:             5,
+		HealthyThreshold:    2,
+		UnhealthyThreshold:  10,
+		DeregistrationDelay: 120,
+	}}))
+}
+
+func TestProcessAutoscaling(t *testing.T) {
+	assert.Nil(t, processAutoscaling(Process{}))
+	assert.Equal(t, &scheduler.Autoscaling{
+		Min:    2,
+		Max:    10,
+		Metric: "cpu",
+		Target: 50,
+	}, processAutoscaling(Process{Autoscaling: &Autoscaling{
+		Min:    2,
+		Max:    10,
+		Metric: "cpu",
+		Target: 50,
+	}}))
+}
+
+func TestProcessExposure(t *testing.T) {
+	app := &App{Exposure: exposePublic, Cert: "AppCert"}
+
+	tests := []struct {
+		process  string
+		p        Process
+		exposure *scheduler.Exposure
+	}{
+		// The web process falls back to the app's exposure settings.
+		{"web", Process{}, &scheduler.Exposure{External: true, Type: &scheduler.HTTPSExposure{Cert: "AppCert"}}},
+		{"worker", Process{}, nil},
+
+		// Exposure settings from the Procfile.
+		{"admin", Process{Exposure: &ProcessExposure{Protocol: "http"}}, &scheduler.Exposure{Type: &scheduler.HTTPExposure{}}},
+		{"web", Process{Exposure: &ProcessExposure{External: true, Protocol: "https", Cert: "WebCert"}}, &scheduler.Exposure{External: true, Type: &scheduler.HTTPSExposure{Cert: "WebCert"}}},
+		{"redis", Process{Exposure: &ProcessExposure{Protocol: "tcp", Port: 6379}}, &scheduler.Exposure{Type: &scheduler.TCPExposure{Port: 6379}}},
+		{"smtp", Process{Exposure: &ProcessExposure{Protocol: "ssl", Port: 465, Cert: "SMTPCert"}}, &scheduler.Exposure{Type: &scheduler.SSLExposure{Port: 465, Cert: "SMTPCert"}}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.exposure, processExposure(app, tt.process, tt.p))
+	}
+}
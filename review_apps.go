@@ -0,0 +1,364 @@
+package empire
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire/pkg/image"
+	"github.com/remind101/pkg/reporter"
+	"github.com/remind101/pkg/timex"
+	"golang.org/x/net/context"
+)
+
+// DefaultReviewAppExpiryInterval is the default amount of time to wait between
+// checking for review apps that have expired.
+const DefaultReviewAppExpiryInterval = 5 * time.Minute
+
+// ErrTooManyReviewApps is returned when creating a review app would exceed
+// MaxReviewApps.
+var ErrTooManyReviewApps = &ValidationError{
+	errors.New("The maximum number of review apps already exist. Close a pull request, or destroy a review app, and try again."),
+}
+
+// ReviewApp is an app that was created from a template app to review a pull
+// request. Review apps are destroyed when the pull request is closed, or when
+// they haven't been deployed to for a while.
+type ReviewApp struct {
+	// The id of the app.
+	AppID string `gorm:"primary_key"`
+
+	// The app.
+	App *App
+
+	// The full name of the repository (e.g. remind101/acme-inc).
+	Repo string
+
+	// The pull request number.
+	PullRequest int
+
+	// The user that last deployed the review app.
+	UserName string
+
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+}
+
+// BeforeCreate sets created_at and updated_at before inserting.
+func (a *ReviewApp) BeforeCreate() error {
+	t := timex.Now()
+	a.CreatedAt = &t
+	a.UpdatedAt = &t
+	return nil
+}
+
+// ReviewAppsQuery is a scope implementation for common things to filter review
+// apps by.
+type ReviewAppsQuery struct {
+	// If provided, finds the review app for the pull request in this
+	// repository.
+	Repo        *string
+	PullRequest *int
+
+	// If provided, finds review apps that haven't been deployed to since
+	// this time.
+	UpdatedBefore *time.Time
+}
+
+// scope implements the scope interface.
+func (q ReviewAppsQuery) scope(db *gorm.DB) *gorm.DB {
+	var scope composedScope
+
+	if q.Repo != nil {
+		scope = append(scope, fieldEquals("repo", *q.Repo))
+	}
+
+	if q.PullRequest != nil {
+		scope = append(scope, fieldEquals("pull_request", *q.PullRequest))
+	}
+
+	if q.UpdatedBefore != nil {
+		scope = append(scope, scopeFunc(func(db *gorm.DB) *gorm.DB {
+			return db.Where("updated_at < ?", *q.UpdatedBefore)
+		}))
+	}
+
+	scope = append(scope, order("created_at"))
+
+	return scope.scope(db)
+}
+
+// DeployReviewAppOpts are options provided when deploying a pull request to its
+// review app.
+type DeployReviewAppOpts struct {
+	// User that's triggering the deployment.
+	User *User
+
+	// The app that review apps are created from. When the review app is
+	// created, it gets a copy of the template's config vars, config groups
+	// and formation.
+	Template *App
+
+	// The full name of the repository (e.g. remind101/acme-inc).
+	Repo string
+
+	// The pull request number.
+	PullRequest int
+
+	// Image is the image that's being deployed.
+	Image image.Image
+
+	// Output is a DeploymentStream where deployment output and events will
+	// be streamed in jsonmessage format.
+	Output *DeploymentStream
+
+	// Commit message
+	Message string
+
+	// Stream boolean for whether or not a status stream should be created.
+	Stream bool
+}
+
+// Name returns the name of the review app (e.g. acme-inc-pr-123).
+func (opts DeployReviewAppOpts) Name() string {
+	return fmt.Sprintf("%s-pr-%d", opts.Template.Name, opts.PullRequest)
+}
+
+func (opts DeployReviewAppOpts) Event() DeployEvent {
+	return DeployEvent{
+		User:    opts.User.Name,
+		App:     opts.Name(),
+		Image:   opts.Image.String(),
+		Message: opts.Message,
+	}
+}
+
+func (opts DeployReviewAppOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	app := &App{Name: opts.Name()}
+	if !NamePattern.Match([]byte(app.Name)) {
+		return ErrInvalidName
+	}
+
+	if err := e.authorize(opts.User, ActionCreate, app); err != nil {
+		return err
+	}
+
+	return e.authorize(opts.User, ActionDeploy, app)
+}
+
+// reviewAppsFind returns the first matching review app.
+func reviewAppsFind(db *gorm.DB, scope scope) (*ReviewApp, error) {
+	var app ReviewApp
+	scope = composedScope{preload("App"), scope}
+	return &app, first(db, scope, &app)
+}
+
+// reviewApps returns all review apps matching the scope.
+func reviewApps(db *gorm.DB, scope scope) ([]*ReviewApp, error) {
+	var apps []*ReviewApp
+	scope = composedScope{preload("App"), scope}
+	return apps, find(db, scope, &apps)
+}
+
+func reviewAppsCreate(db *gorm.DB, app *ReviewApp) (*ReviewApp, error) {
+	return app, db.Create(app).Error
+}
+
+// reviewAppsTouch records that the review app was deployed to by user.
+func reviewAppsTouch(db *gorm.DB, app *ReviewApp, user *User) error {
+	t := timex.Now()
+	app.UpdatedAt = &t
+	app.UserName = user.Name
+	return db.Exec(`UPDATE review_apps SET updated_at = ?, user_name = ? WHERE app_id = ?`, app.UpdatedAt, app.UserName, app.AppID).Error
+}
+
+// reviewAppsLockKey is the advisory lock key that's held while a review app is
+// created, so that concurrent pull requests can't exceed MaxReviewApps.
+var reviewAppsLockKey = crc32.ChecksumIEEE([]byte("review_apps"))
+
+// reviewAppsCount returns the number of review apps that exist.
+func reviewAppsCount(db *gorm.DB) (int, error) {
+	var count int
+	return count, db.Table("review_apps").Count(&count).Error
+}
+
+// createReviewApp creates the review app as a copy of the template app. db
+// should be a transaction.
+func (s *deployerService) createReviewApp(ctx context.Context, db *gorm.DB, opts DeployReviewAppOpts) (*ReviewApp, error) {
+	if s.MaxReviewApps > 0 {
+		// The lock is held until the transaction ends, so the count
+		// includes review apps that are being created concurrently.
+		if err := db.Exec(`SELECT pg_advisory_xact_lock(?)`, reviewAppsLockKey).Error; err != nil {
+			return nil, err
+		}
+
+		n, err := reviewAppsCount(db)
+		if err != nil {
+			return nil, err
+		}
+		if n >= s.MaxReviewApps {
+			return nil, ErrTooManyReviewApps
+		}
+	}
+
+	app, err := appsCreate(db, &App{Name: opts.Name()})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordEvent(db, opts.User, app, CreateEvent{
+		User:    opts.User.Name,
+		Name:    app.Name,
+		Message: opts.Message,
+	}); err != nil {
+		return nil, err
+	}
+
+	// Attach the same config groups, in the same order.
+	if err := db.Exec(`INSERT INTO app_config_groups (app_id, config_group_id, created_at)
+SELECT ?, config_group_id, created_at FROM app_config_groups WHERE app_id = ?`, app.ID, opts.Template.ID).Error; err != nil {
+		return nil, err
+	}
+
+	template, err := s.configs.Config(db, opts.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err = s.decryptConfig(template)
+	if err != nil {
+		return nil, err
+	}
+
+	groupVars, err := s.configGroupVars(db, app)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.configs.release(ctx, db, app, &Config{
+		AppID:     app.ID,
+		Vars:      template.Vars,
+		GroupVars: groupVars,
+	}, ""); err != nil {
+		return nil, err
+	}
+
+	ra, err := reviewAppsCreate(db, &ReviewApp{
+		AppID:       app.ID,
+		Repo:        opts.Repo,
+		PullRequest: opts.PullRequest,
+		UserName:    opts.User.Name,
+	})
+	if err != nil {
+		return ra, err
+	}
+	ra.App = app
+
+	return ra, nil
+}
+
+// DestroyExpiredReviewApps destroys review apps that haven't been deployed to
+// within ttl. The destroy event is attributed to the user that last deployed
+// the review app.
+func (e *Empire) DestroyExpiredReviewApps(ctx context.Context, ttl time.Duration) error {
+	before := timex.Now().Add(-ttl)
+	apps, err := reviewApps(e.db, ReviewAppsQuery{UpdatedBefore: &before})
+	if err != nil {
+		return err
+	}
+
+	for _, ra := range apps {
+		if err := e.destroyExpiredReviewApp(ctx, ra, ttl); err != nil {
+			reporter.Report(ctx, fmt.Errorf("error destroying expired review app %s: %v", ra.App.Name, err))
+		}
+	}
+
+	return nil
+}
+
+func (e *Empire) destroyExpiredReviewApp(ctx context.Context, ra *ReviewApp, ttl time.Duration) error {
+	event := DestroyEvent{
+		User:    ra.UserName,
+		App:     ra.App.Name,
+		Message: fmt.Sprintf("Review app wasn't deployed to for %v", ttl),
+	}
+
+	tx := e.db.Begin()
+
+	if err := e.apps.Destroy(ctx, tx, ra.App); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := recordEvent(tx, &User{Name: ra.UserName}, ra.App, event); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	return e.PublishEvent(event)
+}
+
+// ReviewAppExpiryWorker periodically destroys review apps that haven't been
+// deployed to within TTL.
+type ReviewAppExpiryWorker struct {
+	// Context used when destroying review apps.
+	Context context.Context
+
+	// How often to check for expired review apps. The default is
+	// DefaultReviewAppExpiryInterval.
+	Interval time.Duration
+
+	// How long a review app can go without being deployed to before it's
+	// destroyed.
+	TTL time.Duration
+
+	empire  *Empire
+	stopped chan struct{}
+}
+
+// NewReviewAppExpiryWorker returns a new ReviewAppExpiryWorker.
+func NewReviewAppExpiryWorker(e *Empire) *ReviewAppExpiryWorker {
+	return &ReviewAppExpiryWorker{
+		Context: context.Background(),
+		empire:  e,
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start starts checking for expired review apps. It blocks until Stop is
+// called.
+func (w *ReviewAppExpiryWorker) Start() {
+	interval := w.Interval
+	if interval == 0 {
+		interval = DefaultReviewAppExpiryInterval
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-w.stopped:
+			return
+		case <-t.C:
+			if err := w.empire.DestroyExpiredReviewApps(w.Context, w.TTL); err != nil {
+				reporter.Report(w.Context, err)
+			}
+		}
+	}
+}
+
+// Stop stops the worker.
+func (w *ReviewAppExpiryWorker) Stop() {
+	close(w.stopped)
+}
@@ -86,7 +86,12 @@ func (r *runnerService) Run(ctx context.Context, opts RunOpts) error {
 	}
 	proc.SetConstraints(constraints)
 
-	a := newSchedulerApp(release)
+	dr, err := r.resolveRelease(ctx, release)
+	if err != nil {
+		return err
+	}
+
+	a := newSchedulerApp(dr)
 	p := newSchedulerProcess(release, procName, proc)
 	p.Labels["empire.user"] = opts.User.Name
 
@@ -0,0 +1,394 @@
+package empire
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire/pkg/cron"
+	"github.com/remind101/pkg/reporter"
+	"github.com/remind101/pkg/timex"
+	"golang.org/x/net/context"
+)
+
+// DefaultScaleSchedulePollInterval is the default amount of time to wait
+// between checking for scale schedules that are due.
+const DefaultScaleSchedulePollInterval = 30 * time.Second
+
+// ScaleSchedule scales a process to a quantity, and optionally new
+// constraints, whenever a cron expression triggers.
+type ScaleSchedule struct {
+	// A unique uuid that identifies the schedule.
+	ID string
+
+	// The app that the process belongs to.
+	AppID string
+	App   *App
+
+	// The process to scale.
+	Process string
+
+	// A 5 field cron expression (e.g. "0 20 * * 1-5"), evaluated in UTC.
+	Cron string
+
+	// The quantity to scale the process to.
+	Quantity int
+
+	// If provided, the constraints to scale the process to (e.g. "2X").
+	Size string
+
+	// The name of the user that created the schedule. The process is
+	// scaled on behalf of this user.
+	UserName string
+
+	// The next time that the schedule will run.
+	NextRunAt time.Time
+
+	// The last time that the schedule ran.
+	LastRunAt *time.Time
+
+	CreatedAt *time.Time
+}
+
+// BeforeCreate sets created_at before inserting.
+func (s *ScaleSchedule) BeforeCreate() error {
+	t := timex.Now()
+	s.CreatedAt = &t
+	return nil
+}
+
+// Constraints returns the constraints that the process is scaled to, or nil
+// if they're left unchanged.
+func (s *ScaleSchedule) Constraints() (*Constraints, error) {
+	return parseConstraints(s.Size)
+}
+
+// ScaleSchedulesQuery is a scope implementation for common things to filter
+// scale schedules by.
+type ScaleSchedulesQuery struct {
+	// If provided, finds the schedule with the given ID.
+	ID *string
+
+	// If provided, finds schedules for the given app.
+	App *App
+}
+
+// scope implements the scope interface.
+func (q ScaleSchedulesQuery) scope(db *gorm.DB) *gorm.DB {
+	var scope composedScope
+
+	if q.ID != nil {
+		scope = append(scope, idEquals(*q.ID))
+	}
+
+	if q.App != nil {
+		scope = append(scope, forApp(q.App))
+	}
+
+	scope = append(scope, order("process, created_at"))
+
+	return scope.scope(db)
+}
+
+// CreateScaleScheduleOpts are options provided when scheduling a process to be
+// scaled.
+type CreateScaleScheduleOpts struct {
+	// User performing the action.
+	User *User
+
+	// The associated app.
+	App *App
+
+	// The process to scale.
+	Process string
+
+	// A 5 field cron expression.
+	Cron string
+
+	// The quantity to scale the process to.
+	Quantity int
+
+	// If provided, new memory and CPU constraints for the process.
+	Constraints *Constraints
+
+	// Commit message
+	Message string
+}
+
+func (opts CreateScaleScheduleOpts) Event() ScaleScheduleEvent {
+	return ScaleScheduleEvent{
+		User:     opts.User.Name,
+		App:      opts.App.Name,
+		Process:  opts.Process,
+		Cron:     opts.Cron,
+		Quantity: opts.Quantity,
+		Message:  opts.Message,
+		app:      opts.App,
+	}
+}
+
+func (opts CreateScaleScheduleOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	if _, err := cron.Parse(opts.Cron); err != nil {
+		return &ValidationError{Err: err}
+	}
+
+	if opts.Quantity < 0 {
+		return &ValidationError{Err: fmt.Errorf("quantity must be at least 0")}
+	}
+
+	return e.authorize(opts.User, ActionScale, opts.App)
+}
+
+// DestroyScaleScheduleOpts are options provided when removing a scale
+// schedule.
+type DestroyScaleScheduleOpts struct {
+	// User performing the action.
+	User *User
+
+	// The schedule to remove.
+	Schedule *ScaleSchedule
+
+	// Commit message
+	Message string
+}
+
+func (opts DestroyScaleScheduleOpts) Event() ScaleScheduleEvent {
+	return ScaleScheduleEvent{
+		User:     opts.User.Name,
+		App:      opts.Schedule.App.Name,
+		Process:  opts.Schedule.Process,
+		Cron:     opts.Schedule.Cron,
+		Quantity: opts.Schedule.Quantity,
+		Removed:  true,
+		Message:  opts.Message,
+		app:      opts.Schedule.App,
+	}
+}
+
+func (opts DestroyScaleScheduleOpts) Validate(e *Empire) error {
+	if err := e.requireMessages(opts.Message); err != nil {
+		return err
+	}
+
+	return e.authorize(opts.User, ActionScale, opts.Schedule.App)
+}
+
+// scaleSchedulesFind returns the first matching scale schedule.
+func scaleSchedulesFind(db *gorm.DB, scope scope) (*ScaleSchedule, error) {
+	var schedule ScaleSchedule
+	return &schedule, first(db, composedScope{preload("App"), scope}, &schedule)
+}
+
+// scaleSchedules returns all scale schedules matching the scope.
+func scaleSchedules(db *gorm.DB, scope scope) ([]*ScaleSchedule, error) {
+	var schedules []*ScaleSchedule
+	return schedules, find(db, composedScope{preload("App"), scope}, &schedules)
+}
+
+func scaleSchedulesCreate(db *gorm.DB, schedule *ScaleSchedule) (*ScaleSchedule, error) {
+	return schedule, db.Create(schedule).Error
+}
+
+// scaleSchedulesMarkRun records that the schedule ran, and when it'll run
+// next.
+func scaleSchedulesMarkRun(db *gorm.DB, schedule *ScaleSchedule, ranAt, next time.Time) error {
+	schedule.LastRunAt = &ranAt
+	schedule.NextRunAt = next
+	return db.Exec(`UPDATE scale_schedules SET last_run_at = ?, next_run_at = ? WHERE id = ?`, ranAt, next, schedule.ID).Error
+}
+
+func scaleSchedulesDestroy(db *gorm.DB, schedule *ScaleSchedule) error {
+	return db.Delete(schedule).Error
+}
+
+type scaleSchedulesService struct {
+	*Empire
+}
+
+func (s *scaleSchedulesService) Create(ctx context.Context, db *gorm.DB, opts CreateScaleScheduleOpts) (*ScaleSchedule, error) {
+	release, err := releasesFind(db, ReleasesQuery{App: opts.App})
+	if err != nil {
+		return nil, err
+	}
+	if release == nil {
+		return nil, &ValidationError{Err: fmt.Errorf("no releases for %s", opts.App.Name)}
+	}
+
+	if _, ok := release.Formation[opts.Process]; !ok {
+		return nil, &ValidationError{Err: fmt.Errorf("no %s process type in release", opts.Process)}
+	}
+
+	c, err := cron.Parse(opts.Cron)
+	if err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+
+	next := c.Next(timex.Now().UTC())
+	if next.IsZero() {
+		return nil, &ValidationError{Err: fmt.Errorf("cron expression never triggers: %s", opts.Cron)}
+	}
+
+	schedule := &ScaleSchedule{
+		AppID:     opts.App.ID,
+		App:       opts.App,
+		Process:   opts.Process,
+		Cron:      opts.Cron,
+		Quantity:  opts.Quantity,
+		UserName:  opts.User.Name,
+		NextRunAt: next,
+	}
+	if opts.Constraints != nil {
+		schedule.Size = opts.Constraints.String()
+	}
+
+	return scaleSchedulesCreate(db, schedule)
+}
+
+func (s *scaleSchedulesService) Destroy(ctx context.Context, db *gorm.DB, opts DestroyScaleScheduleOpts) error {
+	return scaleSchedulesDestroy(db, opts.Schedule)
+}
+
+// Claim finds the schedules that are due, and moves them to their next run.
+// The rows are locked while they're claimed, so that a schedule only runs once
+// when there are multiple Empire instances.
+func (s *scaleSchedulesService) Claim(db *gorm.DB, now time.Time) ([]*ScaleSchedule, error) {
+	rows, err := db.Raw(`SELECT id FROM scale_schedules WHERE next_run_at <= ? ORDER BY next_run_at FOR UPDATE SKIP LOCKED`, now).Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	schedules, err := scaleSchedules(db, scopeFunc(func(db *gorm.DB) *gorm.DB {
+		return db.Where("id IN (?)", ids)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, schedule := range schedules {
+		c, err := cron.Parse(schedule.Cron)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := scaleSchedulesMarkRun(db, schedule, now, c.Next(now)); err != nil {
+			return nil, err
+		}
+	}
+
+	return schedules, nil
+}
+
+// RunScaleSchedules scales the processes for any scale schedules that are
+// due, using Scale, so that an automatic ScaleEvent is published for each
+// one. A schedule that fails to run isn't retried until it triggers again.
+func (e *Empire) RunScaleSchedules(ctx context.Context) error {
+	tx := e.db.Begin()
+
+	schedules, err := e.scaleSchedules.Claim(tx, timex.Now().UTC())
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	for _, schedule := range schedules {
+		if err := e.runScaleSchedule(ctx, schedule); err != nil {
+			reporter.Report(ctx, fmt.Errorf("error running scale schedule %s for %s: %v", schedule.ID, schedule.App.Name, err))
+		}
+	}
+
+	return nil
+}
+
+func (e *Empire) runScaleSchedule(ctx context.Context, schedule *ScaleSchedule) error {
+	c, err := schedule.Constraints()
+	if err != nil {
+		return err
+	}
+
+	_, err = e.Scale(ctx, ScaleOpts{
+		User: &User{Name: schedule.UserName},
+		App:  schedule.App,
+		Updates: []*ProcessUpdate{
+			{Process: schedule.Process, Quantity: schedule.Quantity, Constraints: c},
+		},
+		Message:   fmt.Sprintf("Scheduled (%s)", schedule.Cron),
+		automatic: true,
+	})
+	return err
+}
+
+// ScaleScheduleWorker periodically runs the scale schedules that are due.
+type ScaleScheduleWorker struct {
+	// Root context.Context to use. If a reporter.Reporter is embedded,
+	// errors generated will be reporter there.
+	Context context.Context
+
+	// The amount of time to wait between checking for schedules that are
+	// due. The zero value is DefaultScaleSchedulePollInterval.
+	PollInterval time.Duration
+
+	empire  *Empire
+	stopped chan struct{}
+}
+
+// NewScaleScheduleWorker returns a new ScaleScheduleWorker.
+func NewScaleScheduleWorker(e *Empire) *ScaleScheduleWorker {
+	return &ScaleScheduleWorker{
+		Context: context.Background(),
+		empire:  e,
+		stopped: make(chan struct{}),
+	}
+}
+
+// Start starts running scale schedules. It blocks until Stop is called.
+func (w *ScaleScheduleWorker) Start() {
+	interval := w.PollInterval
+	if interval == 0 {
+		interval = DefaultScaleSchedulePollInterval
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-w.stopped:
+			return
+		case <-t.C:
+			if err := w.empire.RunScaleSchedules(w.Context); err != nil {
+				reporter.Report(w.Context, err)
+			}
+		}
+	}
+}
+
+// Stop stops running scale schedules.
+func (w *ScaleScheduleWorker) Stop() {
+	close(w.stopped)
+}
@@ -41,6 +41,12 @@ const servicesOutput = "Services"
 
 const deploymentsOutput = "Deployments"
 
+// The name of the output key where processes with a canary are mapped to the
+// canary's target group. This output is a comma delimited list of
+// `process=loadbalancer/targetgroup` values, where each is the full name used
+// for CloudWatch metric dimensions.
+const canaryTargetGroupsOutput = "CanaryTargetGroups"
+
 // Parameter used to trigger a restart of the application.
 const restartParameter = "RestartKey"
 
@@ -144,6 +150,17 @@ type Scheduler struct {
 	// Any additional tags to add to stacks.
 	Tags []*cloudformation.Tag
 
+	// When streaming a Submit, the amount of time to wait for the new ECS
+	// deployments to stabilize. If they don't stabilize in time, Submit
+	// returns a scheduler.UnstableError. The zero value waits forever.
+	StabilizeTimeout time.Duration
+
+	// When streaming a Submit, the number of tasks from a new ECS
+	// deployment that can stop before the deployment is considered to have
+	// failed, and Submit returns a scheduler.UnstableError. The zero value
+	// disables this check.
+	MaxStoppedTasks int
+
 	// CloudFormation client for creating stacks.
 	cloudformation cloudformationClient
 
@@ -153,6 +170,9 @@ type Scheduler struct {
 	// S3 client to upload templates to s3.
 	s3 s3Client
 
+	// CloudWatch client to get metrics for canaries.
+	cloudwatch cloudwatchClient
+
 	db *sql.DB
 
 	after func(time.Duration) <-chan time.Time
@@ -164,6 +184,7 @@ func NewScheduler(db *sql.DB, config client.ConfigProvider) *Scheduler {
 		cloudformation: cloudformation.New(config),
 		ecs:            ecsWithCaching(ecs.New(config)),
 		s3:             s3.New(config),
+		cloudwatch:     newCloudWatch(config),
 		db:             db,
 		after:          time.After,
 	}
@@ -188,12 +209,88 @@ func (s *Scheduler) SubmitWithOptions(ctx context.Context, app *scheduler.App, s
 		return err
 	}
 
-	err = s.submit(ctx, tx, app, ss, opts)
+	stack, err := s.submit(ctx, tx, app, ss, opts)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
-	return tx.Commit()
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	// Only wait for the new deployments to stabilize after the
+	// transaction is committed, since that can take a while.
+	if stack != nil {
+		if err := s.waitUntilStable(ctx, stack, ss); err != nil {
+			if _, ok := err.(*scheduler.UnstableError); ok {
+				return err
+			}
+			logger.Warn(ctx, fmt.Sprintf("error waiting for submit to stabilize: %v", err))
+		}
+	}
+
+	return nil
+}
+
+// SubmitCanary submits the CloudFormation stack for the app, with the canary
+// running as separate ECS services. Requests to processes that are exposed
+// through an application load balancer are split between the app and the
+// canary by the canary's weight.
+func (s *Scheduler) SubmitCanary(ctx context.Context, app *scheduler.App, ss scheduler.StatusStream) error {
+	if app.Canary == nil {
+		return errors.New("app doesn't have a canary")
+	}
+	return s.Submit(ctx, app, ss)
+}
+
+// CanaryErrorRate returns the percentage of requests to the canary's target
+// groups that received a 5xx response from the canary since the given time.
+func (s *Scheduler) CanaryErrorRate(ctx context.Context, appID string, since time.Time) (float64, error) {
+	stackName, err := s.stackName(appID)
+	if err != nil {
+		return 0, err
+	}
+
+	stack, err := s.stack(aws.String(stackName))
+	if err != nil {
+		return 0, fmt.Errorf("error describing stack: %v", err)
+	}
+
+	o := output(stack, canaryTargetGroupsOutput)
+	if o == nil {
+		return 0, fmt.Errorf("stack didn't provide a \"%s\" output key", canaryTargetGroupsOutput)
+	}
+
+	now := time.Now()
+
+	var failed, requests float64
+	for process, label := range extractProcessData(*o.OutputValue) {
+		// app/my-load-balancer/50dc6c495c0c9188/targetgroup/my-target-group/73e2d6bc24d8a067
+		parts := strings.Split(label, "/")
+		if len(parts) != 6 {
+			return 0, fmt.Errorf("invalid canary target group for %s: %s", process, label)
+		}
+		loadBalancer, targetGroup := strings.Join(parts[:3], "/"), strings.Join(parts[3:], "/")
+
+		n, err := metricSum(s.cloudwatch, "HTTPCode_Target_5XX_Count", loadBalancer, targetGroup, since, now)
+		if err != nil {
+			return 0, fmt.Errorf("error getting 5xx count for %s: %v", process, err)
+		}
+		failed += n
+
+		n, err = metricSum(s.cloudwatch, "RequestCount", loadBalancer, targetGroup, since, now)
+		if err != nil {
+			return 0, fmt.Errorf("error getting request count for %s: %v", process, err)
+		}
+		requests += n
+	}
+
+	if requests == 0 {
+		return 0, nil
+	}
+
+	return failed / requests * 100, nil
 }
 
 func (s *Scheduler) Restart(ctx context.Context, app *scheduler.App, ss scheduler.StatusStream) error {
@@ -225,8 +322,10 @@ func (s *Scheduler) Restart(ctx context.Context, app *scheduler.App, ss schedule
 	return nil
 }
 
-// Submit creates (or updates) the CloudFormation stack for the app.
-func (s *Scheduler) submit(ctx context.Context, tx *sql.Tx, app *scheduler.App, ss scheduler.StatusStream, opts SubmitOptions) error {
+// Submit creates (or updates) the CloudFormation stack for the app. If ss is
+// provided, it waits for the stack operation to complete and returns the
+// stack.
+func (s *Scheduler) submit(ctx context.Context, tx *sql.Tx, app *scheduler.App, ss scheduler.StatusStream, opts SubmitOptions) (*cloudformation.Stack, error) {
 	stackName, err := s.stackName(app.ID)
 	if err == errNoStack {
 		t := s.StackNameTemplate
@@ -235,19 +334,19 @@ func (s *Scheduler) submit(ctx context.Context, tx *sql.Tx, app *scheduler.App,
 		}
 		buf := new(bytes.Buffer)
 		if err := t.Execute(buf, app); err != nil {
-			return fmt.Errorf("error generating stack name: %v", err)
+			return nil, fmt.Errorf("error generating stack name: %v", err)
 		}
 		stackName = buf.String()
 		if _, err := tx.Exec(`INSERT INTO stacks (app_id, stack_name) VALUES ($1, $2)`, app.ID, stackName); err != nil {
-			return err
+			return nil, err
 		}
 	} else if err != nil {
-		return err
+		return nil, err
 	}
 
 	t, err := s.createTemplate(ctx, app)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	stats.Histogram(ctx, "scheduler.cloudformation.template_size", float32(t.Size), 1.0, []string{
@@ -287,7 +386,7 @@ func (s *Scheduler) submit(ctx context.Context, tx *sql.Tx, app *scheduler.App,
 			Tags:       tags,
 			Parameters: parameters,
 		}, output, ss); err != nil {
-			return fmt.Errorf("error creating stack: %v", err)
+			return nil, fmt.Errorf("error creating stack: %v", err)
 		}
 	} else if err == nil {
 		if err := s.updateStack(ctx, &updateStackInput{
@@ -297,22 +396,20 @@ func (s *Scheduler) submit(ctx context.Context, tx *sql.Tx, app *scheduler.App,
 			// TODO: Update Go client
 			// Tags:         tags,
 		}, output, ss); err != nil {
-			return err
+			return nil, err
 		}
 	} else {
-		return fmt.Errorf("error describing stack: %v", err)
+		return nil, fmt.Errorf("error describing stack: %v", err)
 	}
 
 	if ss != nil {
 		o := <-output
 		if o.err != nil || o.stack == nil {
-			return o.err
-		}
-		if err := s.waitUntilStable(ctx, o.stack, ss); err != nil {
-			logger.Warn(ctx, fmt.Sprintf("error waiting for submit to stabilize: %v", err))
+			return nil, o.err
 		}
+		return o.stack, nil
 	}
-	return nil
+	return nil, nil
 }
 
 func (s *Scheduler) waitUntilStable(ctx context.Context, stack *cloudformation.Stack, ss scheduler.StatusStream) error {
@@ -321,13 +418,30 @@ func (s *Scheduler) waitUntilStable(ctx context.Context, stack *cloudformation.S
 		return err
 	}
 	deploymentStatuses := s.waitForDeploymentsToStabilize(ctx, deployments)
+
+	var unstable []string
 	for status := range deploymentStatuses {
 		scheduler.Publish(ctx, ss, fmt.Sprintf("Service %s became %s", status.deployment.process, status))
+		if status.failed() {
+			unstable = append(unstable, fmt.Sprintf("%s %s", status.deployment.process, status))
+		}
 	}
-	// TODO publish notification to empire
+
+	if len(unstable) > 0 {
+		return &scheduler.UnstableError{Reason: strings.Join(unstable, ", ")}
+	}
+
 	return nil
 }
 
+// Possible values for deploymentStatus.status.
+const (
+	deploymentStable   = "stable"
+	deploymentInactive = "inactive"
+	deploymentFailed   = "failed"
+	deploymentTimedOut = "timed out"
+)
+
 type deploymentStatus struct {
 	deployment *ecsDeployment
 	status     string
@@ -337,6 +451,13 @@ func (d *deploymentStatus) String() string {
 	return d.status
 }
 
+// failed returns true if the deployment failed to stabilize. An inactive
+// deployment has been superseded by a newer deployment, so it's not
+// considered failed.
+func (d *deploymentStatus) failed() bool {
+	return d.status == deploymentFailed || d.status == deploymentTimedOut
+}
+
 func (s *Scheduler) waitForDeploymentsToStabilize(ctx context.Context, deployments map[string]*ecsDeployment) <-chan *deploymentStatus {
 	ch := make(chan *deploymentStatus)
 
@@ -365,12 +486,19 @@ func (s *Scheduler) waitForDeploymentsToStabilize(ctx context.Context, deploymen
 			}
 
 			if primary && stable {
-				ch <- &deploymentStatus{d, "stable"}
+				ch <- &deploymentStatus{d, deploymentStable}
 				delete(deployments, *service.ServiceArn)
 			} else if primary {
-				// do nothing
+				failed, err := s.deploymentFailed(d)
+				if err != nil {
+					return false, err
+				}
+				if failed {
+					ch <- &deploymentStatus{d, deploymentFailed}
+					return false, nil
+				}
 			} else {
-				ch <- &deploymentStatus{d, "inactive"}
+				ch <- &deploymentStatus{d, deploymentInactive}
 				return false, nil
 			}
 		}
@@ -378,6 +506,11 @@ func (s *Scheduler) waitForDeploymentsToStabilize(ctx context.Context, deploymen
 	}
 
 	go func(deployments map[string]*ecsDeployment) {
+		var timeout <-chan time.Time
+		if s.StabilizeTimeout != 0 {
+			timeout = s.after(s.StabilizeTimeout)
+		}
+
 		keepWaiting := true
 		var err error
 		for keepWaiting && len(deployments) > 0 {
@@ -387,7 +520,14 @@ func (s *Scheduler) waitForDeploymentsToStabilize(ctx context.Context, deploymen
 				break
 			}
 			if keepWaiting {
-				<-s.after(pollServicesWait)
+				select {
+				case <-timeout:
+					for _, d := range deployments {
+						ch <- &deploymentStatus{d, deploymentTimedOut}
+					}
+					keepWaiting = false
+				case <-s.after(pollServicesWait):
+				}
 			}
 		}
 		close(ch)
@@ -396,6 +536,28 @@ func (s *Scheduler) waitForDeploymentsToStabilize(ctx context.Context, deploymen
 	return ch
 }
 
+// deploymentFailed returns true if too many of the tasks started by the ECS
+// deployment have stopped, which usually means that they're crashing.
+func (s *Scheduler) deploymentFailed(d *ecsDeployment) (bool, error) {
+	if s.MaxStoppedTasks == 0 {
+		return false, nil
+	}
+
+	var stopped int
+	if err := s.ecs.ListTasksPages(&ecs.ListTasksInput{
+		Cluster:       aws.String(s.Cluster),
+		StartedBy:     aws.String(d.ID),
+		DesiredStatus: aws.String(ecs.DesiredStatusStopped),
+	}, func(resp *ecs.ListTasksOutput, lastPage bool) bool {
+		stopped += len(resp.TaskArns)
+		return true
+	}); err != nil {
+		return false, fmt.Errorf("error listing stopped tasks for %s: %v", d.process, err)
+	}
+
+	return stopped >= s.MaxStoppedTasks, nil
+}
+
 // createTemplate takes a scheduler.App, and returns a validated cloudformation
 // template.
 func (s *Scheduler) createTemplate(ctx context.Context, app *scheduler.App) (*cloudformationTemplate, error) {
@@ -1378,6 +1378,104 @@ func TestScheduler_Restart(t *testing.T) {
 	x.AssertExpectations(t)
 }
 
+func TestScheduler_WaitUntilStable_StoppedTasks(t *testing.T) {
+	e := new(mockECSClient)
+	s := &Scheduler{
+		Cluster:         "cluster",
+		MaxStoppedTasks: 2,
+		ecs:             e,
+		after:           fakeAfter,
+	}
+
+	e.On("DescribeServices", &ecs.DescribeServicesInput{
+		Cluster:  aws.String("cluster"),
+		Services: []*string{aws.String("arn:aws:ecs:us-east-1:012345678910:service/acme-inc-web")},
+	}).Return(&ecs.DescribeServicesOutput{
+		Services: []*ecs.Service{
+			{
+				ServiceArn: aws.String("arn:aws:ecs:us-east-1:012345678910:service/acme-inc-web"),
+				Deployments: []*ecs.Deployment{
+					&ecs.Deployment{Id: aws.String("ecs-svc/2"), Status: aws.String("PRIMARY")},
+					&ecs.Deployment{Id: aws.String("ecs-svc/1"), Status: aws.String("ACTIVE")},
+				},
+			},
+		},
+	}, nil)
+
+	e.On("ListTasksPages", &ecs.ListTasksInput{
+		Cluster:       aws.String("cluster"),
+		StartedBy:     aws.String("ecs-svc/2"),
+		DesiredStatus: aws.String("STOPPED"),
+	}).Return(&ecs.ListTasksOutput{
+		TaskArns: []*string{aws.String("a"), aws.String("b")},
+	}, nil)
+
+	stream := &storedStatusStream{}
+	err := s.waitUntilStable(context.Background(), &cloudformation.Stack{
+		Outputs: []*cloudformation.Output{
+			{
+				OutputKey:   aws.String("Services"),
+				OutputValue: aws.String("web=arn:aws:ecs:us-east-1:012345678910:service/acme-inc-web"),
+			},
+			{
+				OutputKey:   aws.String("Deployments"),
+				OutputValue: aws.String("web=ecs-svc/2"),
+			},
+		},
+	}, stream)
+	assert.Equal(t, &scheduler.UnstableError{Reason: "web failed"}, err)
+
+	e.AssertExpectations(t)
+}
+
+func TestScheduler_WaitUntilStable_Timeout(t *testing.T) {
+	e := new(mockECSClient)
+	s := &Scheduler{
+		Cluster:          "cluster",
+		StabilizeTimeout: 10 * time.Minute,
+		ecs:              e,
+		after: func(d time.Duration) <-chan time.Time {
+			ch := make(chan time.Time)
+			if d == 10*time.Minute {
+				close(ch)
+			}
+			return ch
+		},
+	}
+
+	e.On("DescribeServices", &ecs.DescribeServicesInput{
+		Cluster:  aws.String("cluster"),
+		Services: []*string{aws.String("arn:aws:ecs:us-east-1:012345678910:service/acme-inc-web")},
+	}).Return(&ecs.DescribeServicesOutput{
+		Services: []*ecs.Service{
+			{
+				ServiceArn: aws.String("arn:aws:ecs:us-east-1:012345678910:service/acme-inc-web"),
+				Deployments: []*ecs.Deployment{
+					&ecs.Deployment{Id: aws.String("ecs-svc/2"), Status: aws.String("PRIMARY")},
+					&ecs.Deployment{Id: aws.String("ecs-svc/1"), Status: aws.String("ACTIVE")},
+				},
+			},
+		},
+	}, nil)
+
+	stream := &storedStatusStream{}
+	err := s.waitUntilStable(context.Background(), &cloudformation.Stack{
+		Outputs: []*cloudformation.Output{
+			{
+				OutputKey:   aws.String("Services"),
+				OutputValue: aws.String("web=arn:aws:ecs:us-east-1:012345678910:service/acme-inc-web"),
+			},
+			{
+				OutputKey:   aws.String("Deployments"),
+				OutputValue: aws.String("web=ecs-svc/2"),
+			},
+		},
+	}, stream)
+	assert.Equal(t, &scheduler.UnstableError{Reason: "web timed out"}, err)
+
+	e.AssertExpectations(t)
+}
+
 func newDB(t testing.TB) *sql.DB {
 	db, err := sql.Open("postgres", "postgres://localhost/empire?sslmode=disable")
 	if err != nil {
@@ -0,0 +1,127 @@
+package cloudformation
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/signer/v4"
+	"github.com/aws/aws-sdk-go/private/protocol/query"
+)
+
+// cloudwatchClient duck types the CloudWatch API that we use.
+type cloudwatchClient interface {
+	GetMetricStatistics(*getMetricStatisticsInput) (*getMetricStatisticsOutput, error)
+}
+
+// The version of aws-sdk-go that we use doesn't include a CloudWatch client,
+// so this is a minimal client for the operations that we need, built on the
+// same query protocol handlers as the generated clients.
+type cloudwatch struct {
+	*client.Client
+}
+
+// newCloudWatch returns a new cloudwatch client.
+func newCloudWatch(p client.ConfigProvider) *cloudwatch {
+	c := p.ClientConfig("monitoring")
+	svc := &cloudwatch{
+		Client: client.New(
+			*c.Config,
+			metadata.ClientInfo{
+				ServiceName:   "monitoring",
+				SigningRegion: c.SigningRegion,
+				Endpoint:      c.Endpoint,
+				APIVersion:    "2010-08-01",
+			},
+			c.Handlers,
+		),
+	}
+
+	svc.Handlers.Sign.PushBackNamed(v4.SignRequestHandler)
+	svc.Handlers.Build.PushBackNamed(query.BuildHandler)
+	svc.Handlers.Unmarshal.PushBackNamed(query.UnmarshalHandler)
+	svc.Handlers.UnmarshalMeta.PushBackNamed(query.UnmarshalMetaHandler)
+	svc.Handlers.UnmarshalError.PushBackNamed(query.UnmarshalErrorHandler)
+
+	return svc
+}
+
+type getMetricStatisticsInput struct {
+	_ struct{} `type:"structure"`
+
+	Namespace  *string      `type:"string"`
+	MetricName *string      `type:"string"`
+	Dimensions []*dimension `type:"list"`
+	StartTime  *time.Time   `type:"timestamp" timestampFormat:"iso8601"`
+	EndTime    *time.Time   `type:"timestamp" timestampFormat:"iso8601"`
+	Period     *int64       `type:"integer"`
+	Statistics []*string    `type:"list"`
+}
+
+type dimension struct {
+	_ struct{} `type:"structure"`
+
+	Name  *string `type:"string"`
+	Value *string `type:"string"`
+}
+
+type getMetricStatisticsOutput struct {
+	_ struct{} `type:"structure"`
+
+	Datapoints []*datapoint `type:"list"`
+}
+
+type datapoint struct {
+	_ struct{} `type:"structure"`
+
+	Timestamp *time.Time `type:"timestamp" timestampFormat:"iso8601"`
+	Sum       *float64   `type:"double"`
+}
+
+// GetMetricStatistics gets statistics for the specified metric.
+func (c *cloudwatch) GetMetricStatistics(input *getMetricStatisticsInput) (*getMetricStatisticsOutput, error) {
+	op := &request.Operation{
+		Name:       "GetMetricStatistics",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+
+	output := &getMetricStatisticsOutput{}
+	req := c.NewRequest(op, input, output)
+	req.Data = output
+	return output, req.Send()
+}
+
+// metricSum returns the sum of a metric for an application load balancer's
+// target group, between start and end.
+func metricSum(c cloudwatchClient, metric, loadBalancer, targetGroup string, start, end time.Time) (float64, error) {
+	period := int64(end.Sub(start) / time.Second)
+	// The period must be a multiple of 60 seconds.
+	period = (period/60 + 1) * 60
+
+	resp, err := c.GetMetricStatistics(&getMetricStatisticsInput{
+		Namespace:  aws.String("AWS/ApplicationELB"),
+		MetricName: aws.String(metric),
+		Dimensions: []*dimension{
+			{Name: aws.String("LoadBalancer"), Value: aws.String(loadBalancer)},
+			{Name: aws.String("TargetGroup"), Value: aws.String(targetGroup)},
+		},
+		StartTime:  aws.Time(start),
+		EndTime:    aws.Time(end),
+		Period:     aws.Int64(period),
+		Statistics: []*string{aws.String("Sum")},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var sum float64
+	for _, d := range resp.Datapoints {
+		if d.Sum != nil {
+			sum += *d.Sum
+		}
+	}
+	return sum, nil
+}
@@ -32,6 +32,8 @@ var (
 var (
 	classicLoadBalancer     = "elb"
 	applicationLoadBalancer = "alb"
+	networkLoadBalancer     = "nlb"
+	sharedLoadBalancer      = "shared"
 )
 
 const (
@@ -47,10 +49,16 @@ const (
 	defaultConnectionDrainingTimeout int64 = 30
 	defaultCNAMETTL                        = 60
 
+	// The maximum number of hosts that can be matched by a single ALB
+	// listener rule.
+	maxHostsPerListenerRule = 5
+
 	runTaskFunction = "RunTaskFunction"
 
 	appEnvironment = "AppEnvironment"
 
+	canaryAppEnvironment = "CanaryAppEnvironment"
+
 	restartLabel = "cloudformation.restart-key"
 )
 
@@ -95,6 +103,57 @@ type EmpireTemplate struct {
 
 	// Any extra outputs to attach to the template.
 	ExtraOutputs map[string]troposphere.Output
+
+	// If provided, apps with a LOAD_BALANCER_TYPE of "shared" will be
+	// attached to these Application Load Balancers, with host based
+	// routing, instead of getting load balancers of their own.
+	InternalSharedLoadBalancer *SharedLoadBalancer
+	ExternalSharedLoadBalancer *SharedLoadBalancer
+}
+
+// SharedLoadBalancer represents an Application Load Balancer, managed outside of
+// app stacks, that's shared by many apps using host based routing.
+type SharedLoadBalancer struct {
+	// The DNS name of the load balancer, which CNAME's will point to.
+	DNSName string
+
+	// The ARN of the HTTP listener.
+	HTTPListenerArn string
+
+	// The ARN of the HTTPS listener, if there is one. Certificates for
+	// processes with an HTTPS exposure are added to this listener.
+	HTTPSListenerArn string
+}
+
+// Validate checks that all of the expected values are provided.
+func (lb *SharedLoadBalancer) Validate() error {
+	if lb.DNSName == "" {
+		return errors.New("DNSName is required")
+	}
+	if lb.HTTPListenerArn == "" {
+		return errors.New("HTTPListenerArn is required")
+	}
+	if _, err := arn.Parse(lb.HTTPListenerArn); err != nil {
+		return fmt.Errorf("HTTPListenerArn: %v", err)
+	}
+	return nil
+}
+
+// fullName returns the full name of the load balancer (e.g.
+// app/my-load-balancer/50dc6c495c0c9188), which is the resource portion of
+// its ARN, extracted from the ARN of its HTTP listener.
+func (lb *SharedLoadBalancer) fullName() string {
+	a, err := arn.Parse(lb.HTTPListenerArn)
+	if err != nil {
+		return ""
+	}
+
+	// listener/app/my-load-balancer/50dc6c495c0c9188/f2f7dc8efc522ab2
+	parts := strings.Split(a.Resource, "/")
+	if len(parts) < 4 {
+		return ""
+	}
+	return strings.Join(parts[1:4], "/")
 }
 
 // Validate checks that all of the expected values are provided.
@@ -130,6 +189,16 @@ func (t *EmpireTemplate) Validate() error {
 	if t.CustomResourcesTopic == "" {
 		return r("CustomResourcesTopic")
 	}
+	if t.InternalSharedLoadBalancer != nil {
+		if err := t.InternalSharedLoadBalancer.Validate(); err != nil {
+			return fmt.Errorf("InternalSharedLoadBalancer: %v", err)
+		}
+	}
+	if t.ExternalSharedLoadBalancer != nil {
+		if err := t.ExternalSharedLoadBalancer.Validate(); err != nil {
+			return fmt.Errorf("ExternalSharedLoadBalancer: %v", err)
+		}
+	}
 
 	return nil
 }
@@ -180,6 +249,8 @@ func (t *EmpireTemplate) Build(app *scheduler.App) (*troposphere.Template, error
 	deploymentMappings := []interface{}{}
 	scheduledProcesses := map[string]string{}
 
+	canaryTargetGroups := []interface{}{}
+
 	if taskDefinitionResourceType(app) == "Custom::ECSTaskDefinition" {
 		tmpl.Resources[appEnvironment] = troposphere.Resource{
 			Type: "Custom::ECSEnvironment",
@@ -190,6 +261,16 @@ func (t *EmpireTemplate) Build(app *scheduler.App) (*troposphere.Template, error
 		}
 	}
 
+	if app.Canary != nil && taskDefinitionResourceType(app.Canary.App) == "Custom::ECSTaskDefinition" {
+		tmpl.Resources[canaryAppEnvironment] = troposphere.Resource{
+			Type: "Custom::ECSEnvironment",
+			Properties: map[string]interface{}{
+				"ServiceToken": t.CustomResourcesTopic,
+				"Environment":  sortedEnvironment(app.Canary.App.Env),
+			},
+		}
+	}
+
 	for _, p := range app.Processes {
 		if p.Env == nil {
 			p.Env = make(map[string]string)
@@ -208,9 +289,19 @@ func (t *EmpireTemplate) Build(app *scheduler.App) (*troposphere.Template, error
 				scheduledProcesses[p.Type] = taskDefinition.Name
 			}
 		default:
-			service := t.addService(tmpl, app, p)
-			serviceMappings = append(serviceMappings, Join("=", p.Type, Ref(service)))
-			deploymentMappings = append(deploymentMappings, Join("=", p.Type, GetAtt(service, "DeploymentId")))
+			service, err := t.addService(tmpl, app, p)
+			if err != nil {
+				return tmpl, err
+			}
+			serviceMappings = append(serviceMappings, Join("=", p.Type, Ref(service.Name)))
+			deploymentMappings = append(deploymentMappings, Join("=", p.Type, GetAtt(service.Name, "DeploymentId")))
+
+			if service.Canary != "" {
+				canaryProcess := fmt.Sprintf("%s-canary", p.Type)
+				serviceMappings = append(serviceMappings, Join("=", canaryProcess, Ref(service.Canary)))
+				deploymentMappings = append(deploymentMappings, Join("=", canaryProcess, GetAtt(service.Canary, "DeploymentId")))
+				canaryTargetGroups = append(canaryTargetGroups, Join("=", p.Type, service.CanaryTargetGroupLabel))
+			}
 		}
 	}
 
@@ -222,11 +313,21 @@ func (t *EmpireTemplate) Build(app *scheduler.App) (*troposphere.Template, error
 	tmpl.Outputs[servicesOutput] = troposphere.Output{Value: Join(",", serviceMappings...)}
 	tmpl.Outputs[deploymentsOutput] = troposphere.Output{Value: Join(",", deploymentMappings...)}
 
+	if len(canaryTargetGroups) > 0 {
+		tmpl.Outputs[canaryTargetGroupsOutput] = troposphere.Output{Value: Join(",", canaryTargetGroups...)}
+	}
+
 	return tmpl, nil
 }
 
 func (t *EmpireTemplate) addTaskDefinition(tmpl *troposphere.Template, app *scheduler.App, p *scheduler.Process) (troposphere.NamedResource, *ContainerDefinitionProperties) {
-	key := processResourceName(p.Type)
+	return t.addNamedTaskDefinition(tmpl, processResourceName(p.Type), appEnvironment, app, p)
+}
+
+// addNamedTaskDefinition adds a task definition for the process, using key as
+// the prefix for resource names. When a Custom::ECSTaskDefinition is used, the
+// app's environment is referenced from the environment resource.
+func (t *EmpireTemplate) addNamedTaskDefinition(tmpl *troposphere.Template, key, environment string, app *scheduler.App, p *scheduler.Process) (troposphere.NamedResource, *ContainerDefinitionProperties) {
 	// The task definition that will be used to run the ECS task.
 	taskDefinition := troposphere.NamedResource{
 		Name: fmt.Sprintf("%sTaskDefinition", key),
@@ -250,7 +351,7 @@ func (t *EmpireTemplate) addTaskDefinition(tmpl *troposphere.Template, app *sche
 		}
 
 		containerDefinition.Environment = []interface{}{
-			Ref(appEnvironment),
+			Ref(environment),
 			Ref(processEnvironment),
 		}
 		taskDefinitionProperties = &CustomTaskDefinitionProperties{
@@ -318,7 +419,21 @@ func (t *EmpireTemplate) addScheduledTask(tmpl *troposphere.Template, app *sched
 	return taskDefinition
 }
 
-func (t *EmpireTemplate) addService(tmpl *troposphere.Template, app *scheduler.App, p *scheduler.Process) (serviceName string) {
+// processService identifies the ECS services that were added for a process.
+type processService struct {
+	// The name of the ECS service resource.
+	Name string
+
+	// If the app has a canary that includes the process, the name of the
+	// ECS service resource that runs the canary.
+	Canary string
+
+	// Identifies the canary's target group, as
+	// <load balancer full name>/<target group full name>.
+	CanaryTargetGroupLabel interface{}
+}
+
+func (t *EmpireTemplate) addService(tmpl *troposphere.Template, app *scheduler.App, p *scheduler.Process) (*processService, error) {
 	key := processResourceName(p.Type)
 
 	// The standard AWS::ECS::Service resource's default behavior is to wait
@@ -336,6 +451,15 @@ func (t *EmpireTemplate) addService(tmpl *troposphere.Template, app *scheduler.A
 
 	var serviceDependencies []string
 	loadBalancers := []map[string]interface{}{}
+
+	// When the process is attached to an application load balancer, this
+	// identifies its target group, for tracking the request count.
+	var requestCountResourceLabel interface{}
+
+	// When the app has a canary that includes the process, the target
+	// group that the canary's tasks are registered with.
+	var canaryTargetGroup string
+	var loadBalancerFullName interface{}
 	if p.Exposure != nil {
 		scheme := schemeInternal
 		sg := t.InternalSecurityGroupID
@@ -354,8 +478,110 @@ func (t *EmpireTemplate) addService(tmpl *troposphere.Template, app *scheduler.A
 			loadBalancerType = v
 		}
 
+		// If there's no shared load balancer for this exposure, fall
+		// back to a dedicated application load balancer.
+		shared := t.sharedLoadBalancer(p.Exposure.External)
+		if loadBalancerType == sharedLoadBalancer && shared == nil {
+			loadBalancerType = applicationLoadBalancer
+		}
+
+		// Application load balancers only support HTTP and HTTPS, so
+		// TCP and SSL processes get a network load balancer instead.
+		if (loadBalancerType == applicationLoadBalancer || loadBalancerType == sharedLoadBalancer) && !isHTTP(p.Exposure.Type) {
+			loadBalancerType = networkLoadBalancer
+		}
+
 		var loadBalancer string
+		var dnsName interface{}
 		switch loadBalancerType {
+		case sharedLoadBalancer:
+			dnsName = shared.DNSName
+
+			targetGroup := fmt.Sprintf("%sTargetGroup", key)
+			targetGroupProperties := map[string]interface{}{
+				"Port":     65535, // Not used. ECS sets a port override when registering targets.
+				"Protocol": "HTTP",
+				"VpcId":    t.VpcId,
+			}
+			if p.HealthCheck != nil {
+				addTargetGroupHealthCheck(targetGroupProperties, p.HealthCheck, false)
+			}
+			tmpl.Resources[targetGroup] = troposphere.Resource{
+				Type:       "AWS::ElasticLoadBalancingV2::TargetGroup",
+				Properties: targetGroupProperties,
+			}
+			canaryTargetGroup = t.addCanaryTargetGroup(tmpl, app, p, targetGroupProperties)
+
+			listeners := []string{shared.HTTPListenerArn}
+			if e, ok := p.Exposure.Type.(*scheduler.HTTPSExposure); ok && shared.HTTPSListenerArn != "" {
+				listeners = append(listeners, shared.HTTPSListenerArn)
+
+				// Add the certificate to the HTTPS listener, where
+				// it'll be selected with SNI.
+				tmpl.Resources[fmt.Sprintf("%sListenerCertificate", key)] = troposphere.Resource{
+					Type: "AWS::ElasticLoadBalancingV2::ListenerCertificate",
+					Properties: map[string]interface{}{
+						"ListenerArn": shared.HTTPSListenerArn,
+						"Certificates": []interface{}{
+							map[string]interface{}{
+								"CertificateArn": certificateArn(e.Cert),
+							},
+						},
+					},
+				}
+			}
+
+			// Rules can only match a limited number of hosts, so
+			// the hosts are split across multiple rules. Each rule
+			// gets a priority allocated by Empire, which is shared
+			// between the HTTP and HTTPS listeners.
+			for i, hosts := range chunkHosts(t.processHosts(app, p), maxHostsPerListenerRule) {
+				priority := fmt.Sprintf("%sListenerRule%dPriority", key, i)
+				tmpl.Resources[priority] = troposphere.Resource{
+					Type: "Custom::ListenerRulePriority",
+					Properties: map[string]interface{}{
+						"ServiceToken": t.CustomResourcesTopic,
+					},
+				}
+
+				for j, listener := range listeners {
+					rule := fmt.Sprintf("%sListenerRule%d", key, i)
+					if j > 0 {
+						rule = fmt.Sprintf("%sHTTPSListenerRule%d", key, i)
+					}
+					tmpl.Resources[rule] = troposphere.Resource{
+						Type: "AWS::ElasticLoadBalancingV2::ListenerRule",
+						Properties: map[string]interface{}{
+							"ListenerArn": listener,
+							"Priority":    GetAtt(priority, "Priority"),
+							"Conditions": []interface{}{
+								map[string]interface{}{
+									"Field": "host-header",
+									"HostHeaderConfig": map[string]interface{}{
+										"Values": hosts,
+									},
+								},
+							},
+							"Actions": []interface{}{
+								forwardAction(app, targetGroup, canaryTargetGroup),
+							},
+						},
+					}
+					serviceDependencies = append(serviceDependencies, rule)
+				}
+			}
+			loadBalancerFullName = shared.fullName()
+			requestCountResourceLabel = Join("/", loadBalancerFullName, GetAtt(targetGroup, "TargetGroupFullName"))
+
+			loadBalancers = append(loadBalancers, map[string]interface{}{
+				"ContainerName":  p.Type,
+				"ContainerPort":  ContainerPort,
+				"TargetGroupArn": Ref(targetGroup),
+			})
+			portMappings = append(portMappings, &PortMappingProperties{
+				ContainerPort: ContainerPort,
+				HostPort:      0,
+			})
 		case applicationLoadBalancer:
 			loadBalancer = fmt.Sprintf("%sApplicationLoadBalancer", key)
 			tmpl.Resources[loadBalancer] = troposphere.Resource{
@@ -374,14 +600,19 @@ func (t *EmpireTemplate) addService(tmpl *troposphere.Template, app *scheduler.A
 			}
 
 			targetGroup := fmt.Sprintf("%sTargetGroup", key)
+			targetGroupProperties := map[string]interface{}{
+				"Port":     65535, // Not used. ECS sets a port override when registering targets.
+				"Protocol": "HTTP",
+				"VpcId":    t.VpcId,
+			}
+			if p.HealthCheck != nil {
+				addTargetGroupHealthCheck(targetGroupProperties, p.HealthCheck, false)
+			}
 			tmpl.Resources[targetGroup] = troposphere.Resource{
-				Type: "AWS::ElasticLoadBalancingV2::TargetGroup",
-				Properties: map[string]interface{}{
-					"Port":     65535, // Not used. ECS sets a port override when registering targets.
-					"Protocol": "HTTP",
-					"VpcId":    t.VpcId,
-				},
+				Type:       "AWS::ElasticLoadBalancingV2::TargetGroup",
+				Properties: targetGroupProperties,
 			}
+			canaryTargetGroup = t.addCanaryTargetGroup(tmpl, app, p, targetGroupProperties)
 
 			httpListener := fmt.Sprintf("%sPort%dListener", loadBalancer, 80)
 			tmpl.Resources[httpListener] = troposphere.Resource{
@@ -391,45 +622,102 @@ func (t *EmpireTemplate) addService(tmpl *troposphere.Template, app *scheduler.A
 					"Port":            80,
 					"Protocol":        "HTTP",
 					"DefaultActions": []interface{}{
-						map[string]interface{}{
-							"TargetGroupArn": Ref(targetGroup),
-							"Type":           "forward",
-						},
+						forwardAction(app, targetGroup, canaryTargetGroup),
 					},
 				},
 			}
 			serviceDependencies = append(serviceDependencies, httpListener)
 
 			if e, ok := p.Exposure.Type.(*scheduler.HTTPSExposure); ok {
-				var cert interface{}
-				if _, err := arn.Parse(e.Cert); err == nil {
-					cert = e.Cert
-				} else {
-					cert = Join("", "arn:aws:iam::", Ref("AWS::AccountId"), ":server-certificate/", e.Cert)
-				}
-
 				httpsListener := fmt.Sprintf("%sPort%dListener", loadBalancer, 443)
 				tmpl.Resources[httpsListener] = troposphere.Resource{
 					Type: "AWS::ElasticLoadBalancingV2::Listener",
 					Properties: map[string]interface{}{
 						"Certificates": []interface{}{
 							map[string]interface{}{
-								"CertificateArn": cert,
+								"CertificateArn": certificateArn(e.Cert),
 							},
 						},
 						"LoadBalancerArn": GetAtt(loadBalancer, "Arn"),
 						"Port":            443,
 						"Protocol":        "HTTPS",
 						"DefaultActions": []interface{}{
-							map[string]interface{}{
-								"TargetGroupArn": Ref(targetGroup),
-								"Type":           "forward",
-							},
+							forwardAction(app, targetGroup, canaryTargetGroup),
 						},
 					},
 				}
 				serviceDependencies = append(serviceDependencies, httpsListener)
 			}
+			loadBalancerFullName = GetAtt(loadBalancer, "LoadBalancerFullName")
+			requestCountResourceLabel = Join("/", loadBalancerFullName, GetAtt(targetGroup, "TargetGroupFullName"))
+
+			loadBalancers = append(loadBalancers, map[string]interface{}{
+				"ContainerName":  p.Type,
+				"ContainerPort":  ContainerPort,
+				"TargetGroupArn": Ref(targetGroup),
+			})
+			portMappings = append(portMappings, &PortMappingProperties{
+				ContainerPort: ContainerPort,
+				HostPort:      0,
+			})
+		case networkLoadBalancer:
+			loadBalancer = fmt.Sprintf("%sNetworkLoadBalancer", key)
+			tmpl.Resources[loadBalancer] = troposphere.Resource{
+				Type: "AWS::ElasticLoadBalancingV2::LoadBalancer",
+				Properties: map[string]interface{}{
+					"Type":    "network",
+					"Scheme":  scheme,
+					"Subnets": subnets,
+					"Tags": []map[string]string{
+						map[string]string{
+							"Key":   "empire.app.process",
+							"Value": p.Type,
+						},
+					},
+				},
+			}
+
+			targetGroup := fmt.Sprintf("%sTargetGroup", key)
+			targetGroupProperties := map[string]interface{}{
+				"Port":     65535, // Not used. ECS sets a port override when registering targets.
+				"Protocol": "TCP",
+				"VpcId":    t.VpcId,
+			}
+			if p.HealthCheck != nil {
+				addTargetGroupHealthCheck(targetGroupProperties, p.HealthCheck, true)
+			}
+			tmpl.Resources[targetGroup] = troposphere.Resource{
+				Type:       "AWS::ElasticLoadBalancingV2::TargetGroup",
+				Properties: targetGroupProperties,
+			}
+
+			port := exposurePort(p.Exposure.Type)
+			listenerProperties := map[string]interface{}{
+				"LoadBalancerArn": Ref(loadBalancer),
+				"Port":            port,
+				"Protocol":        "TCP",
+				"DefaultActions": []interface{}{
+					map[string]interface{}{
+						"TargetGroupArn": Ref(targetGroup),
+						"Type":           "forward",
+					},
+				},
+			}
+			if e, ok := p.Exposure.Type.(*scheduler.SSLExposure); ok {
+				listenerProperties["Protocol"] = "TLS"
+				listenerProperties["Certificates"] = []interface{}{
+					map[string]interface{}{
+						"CertificateArn": certificateArn(e.Cert),
+					},
+				}
+			}
+
+			listener := fmt.Sprintf("%sPort%dListener", loadBalancer, port)
+			tmpl.Resources[listener] = troposphere.Resource{
+				Type:       "AWS::ElasticLoadBalancingV2::Listener",
+				Properties: listenerProperties,
+			}
+			serviceDependencies = append(serviceDependencies, listener)
 
 			loadBalancers = append(loadBalancers, map[string]interface{}{
 				"ContainerName":  p.Type,
@@ -452,51 +740,82 @@ func (t *EmpireTemplate) addService(tmpl *troposphere.Template, app *scheduler.A
 				},
 			}
 
-			listeners := []map[string]interface{}{
-				map[string]interface{}{
+			var listeners []map[string]interface{}
+			switch e := p.Exposure.Type.(type) {
+			case *scheduler.TCPExposure:
+				listeners = append(listeners, map[string]interface{}{
+					"LoadBalancerPort": e.Port,
+					"Protocol":         "tcp",
+					"InstancePort":     GetAtt(instancePort, "InstancePort"),
+					"InstanceProtocol": "tcp",
+				})
+			case *scheduler.SSLExposure:
+				listeners = append(listeners, map[string]interface{}{
+					"LoadBalancerPort": e.Port,
+					"Protocol":         "ssl",
+					"InstancePort":     GetAtt(instancePort, "InstancePort"),
+					"SSLCertificateId": certificateArn(e.Cert),
+					"InstanceProtocol": "tcp",
+				})
+			default:
+				listeners = append(listeners, map[string]interface{}{
 					"LoadBalancerPort": 80,
 					"Protocol":         "http",
 					"InstancePort":     GetAtt(instancePort, "InstancePort"),
 					"InstanceProtocol": "http",
+				})
+
+				if e, ok := p.Exposure.Type.(*scheduler.HTTPSExposure); ok {
+					listeners = append(listeners, map[string]interface{}{
+						"LoadBalancerPort": 443,
+						"Protocol":         "https",
+						"InstancePort":     GetAtt(instancePort, "InstancePort"),
+						"SSLCertificateId": certificateArn(e.Cert),
+						"InstanceProtocol": "http",
+					})
+				}
+			}
+
+			loadBalancerProperties := map[string]interface{}{
+				"Scheme":         scheme,
+				"SecurityGroups": []string{sg},
+				"Subnets":        subnets,
+				"Listeners":      listeners,
+				"CrossZone":      true,
+				"Tags": []map[string]string{
+					map[string]string{
+						"Key":   "empire.app.process",
+						"Value": p.Type,
+					},
+				},
+				"ConnectionDrainingPolicy": map[string]interface{}{
+					"Enabled": true,
+					"Timeout": defaultConnectionDrainingTimeout,
 				},
 			}
 
-			if e, ok := p.Exposure.Type.(*scheduler.HTTPSExposure); ok {
-				var cert interface{}
-				if _, err := arn.Parse(e.Cert); err == nil {
-					cert = e.Cert
-				} else {
-					cert = Join("", "arn:aws:iam::", Ref("AWS::AccountId"), ":server-certificate/", e.Cert)
+			if h := p.HealthCheck; h != nil {
+				target := Join("", "TCP:", GetAtt(instancePort, "InstancePort"))
+				if h.Path != "" {
+					target = Join("", "HTTP:", GetAtt(instancePort, "InstancePort"), h.Path)
 				}
 
-				listeners = append(listeners, map[string]interface{}{
-					"LoadBalancerPort": 443,
-					"Protocol":         "https",
-					"InstancePort":     GetAtt(instancePort, "InstancePort"),
-					"SSLCertificateId": cert,
-					"InstanceProtocol": "http",
-				})
+				loadBalancerProperties["HealthCheck"] = map[string]interface{}{
+					"Target":             target,
+					"Interval":           seconds(h.Interval),
+					"Timeout":            seconds(h.Timeout),
+					"HealthyThreshold":   fmt.Sprintf("%d", h.HealthyThreshold),
+					"UnhealthyThreshold": fmt.Sprintf("%d", h.UnhealthyThreshold),
+				}
+				loadBalancerProperties["ConnectionDrainingPolicy"] = map[string]interface{}{
+					"Enabled": true,
+					"Timeout": int64(h.DeregistrationDelay / time.Second),
+				}
 			}
 
 			tmpl.Resources[loadBalancer] = troposphere.Resource{
-				Type: "AWS::ElasticLoadBalancing::LoadBalancer",
-				Properties: map[string]interface{}{
-					"Scheme":         scheme,
-					"SecurityGroups": []string{sg},
-					"Subnets":        subnets,
-					"Listeners":      listeners,
-					"CrossZone":      true,
-					"Tags": []map[string]string{
-						map[string]string{
-							"Key":   "empire.app.process",
-							"Value": p.Type,
-						},
-					},
-					"ConnectionDrainingPolicy": map[string]interface{}{
-						"Enabled": true,
-						"Timeout": defaultConnectionDrainingTimeout,
-					},
-				},
+				Type:       "AWS::ElasticLoadBalancing::LoadBalancer",
+				Properties: loadBalancerProperties,
 			}
 
 			loadBalancers = append(loadBalancers, map[string]interface{}{
@@ -510,18 +829,26 @@ func (t *EmpireTemplate) addService(tmpl *troposphere.Template, app *scheduler.A
 			})
 		}
 
+		if dnsName == nil {
+			dnsName = GetAtt(loadBalancer, "DNSName")
+		}
+
+		// The web process gets <app>.<zone>, and other exposed
+		// processes get <process>.<app>.<zone>.
+		cname, name := fmt.Sprintf("%sCNAME", key), t.processCNAME(app, p)
 		if p.Type == "web" {
-			tmpl.Resources["CNAME"] = troposphere.Resource{
-				Type:      "AWS::Route53::RecordSet",
-				Condition: "DNSCondition",
-				Properties: map[string]interface{}{
-					"HostedZoneId":    *t.HostedZone.Id,
-					"Name":            fmt.Sprintf("%s.%s", app.Name, *t.HostedZone.Name),
-					"Type":            "CNAME",
-					"TTL":             defaultCNAMETTL,
-					"ResourceRecords": []interface{}{GetAtt(loadBalancer, "DNSName")},
-				},
-			}
+			cname = "CNAME"
+		}
+		tmpl.Resources[cname] = troposphere.Resource{
+			Type:      "AWS::Route53::RecordSet",
+			Condition: "DNSCondition",
+			Properties: map[string]interface{}{
+				"HostedZoneId":    *t.HostedZone.Id,
+				"Name":            name,
+				"Type":            "CNAME",
+				"TTL":             defaultCNAMETTL,
+				"ResourceRecords": []interface{}{dnsName},
+			},
 		}
 	}
 
@@ -552,9 +879,183 @@ func (t *EmpireTemplate) addService(tmpl *troposphere.Template, app *scheduler.A
 		service.Resource.DependsOn = serviceDependencies
 	}
 	tmpl.AddResource(service)
+
+	ps := &processService{Name: service.Name}
+
+	if p.Autoscaling != nil {
+		if err := t.addAutoscaling(tmpl, app, p, service.Name, requestCountResourceLabel); err != nil {
+			return ps, err
+		}
+	}
+
+	if canaryTargetGroup != "" {
+		ps.Canary = t.addCanaryService(tmpl, app, p, canaryTargetGroup, serviceDependencies)
+		ps.CanaryTargetGroupLabel = Join("/", loadBalancerFullName, GetAtt(canaryTargetGroup, "TargetGroupFullName"))
+	}
+
+	return ps, nil
+}
+
+// canaryProcess returns the process from the app's canary that replaces p, or
+// nil if the app doesn't have a canary, or the canary doesn't include the
+// process.
+func canaryProcess(app *scheduler.App, p *scheduler.Process) *scheduler.Process {
+	if app.Canary == nil {
+		return nil
+	}
+
+	for _, cp := range app.Canary.App.Processes {
+		if cp.Type == p.Type && cp.Schedule == nil {
+			return cp
+		}
+	}
+
+	return nil
+}
+
+// addCanaryTargetGroup adds a target group for the canary of the process, if
+// there is one, and returns its name.
+func (t *EmpireTemplate) addCanaryTargetGroup(tmpl *troposphere.Template, app *scheduler.App, p *scheduler.Process, properties map[string]interface{}) string {
+	if canaryProcess(app, p) == nil {
+		return ""
+	}
+
+	targetGroup := fmt.Sprintf("%sCanaryTargetGroup", processResourceName(p.Type))
+	tmpl.Resources[targetGroup] = troposphere.Resource{
+		Type:       "AWS::ElasticLoadBalancingV2::TargetGroup",
+		Properties: properties,
+	}
+	return targetGroup
+}
+
+// addCanaryService adds an ECS service that runs the canary's version of the
+// process, with its tasks registered in the canary's target group.
+func (t *EmpireTemplate) addCanaryService(tmpl *troposphere.Template, app *scheduler.App, p *scheduler.Process, targetGroup string, dependsOn []string) string {
+	key := fmt.Sprintf("%sCanary", processResourceName(p.Type))
+
+	cp := canaryProcess(app, p)
+	if cp.Env == nil {
+		cp.Env = make(map[string]string)
+	}
+	cp.Env["PORT"] = fmt.Sprintf("%d", ContainerPort)
+
+	taskDefinition, containerDefinition := t.addNamedTaskDefinition(tmpl, key, canaryAppEnvironment, app.Canary.App, cp)
+	containerDefinition.DockerLabels[restartLabel] = Ref(restartParameter)
+	containerDefinition.PortMappings = []*PortMappingProperties{
+		{
+			ContainerPort: ContainerPort,
+			HostPort:      0,
+		},
+	}
+
+	service := troposphere.NamedResource{
+		Name: fmt.Sprintf("%sService", key),
+		Resource: troposphere.Resource{
+			Type: "Custom::ECSService",
+			Properties: map[string]interface{}{
+				"Cluster":      t.Cluster,
+				"DesiredCount": cp.Instances,
+				"LoadBalancers": []map[string]interface{}{
+					{
+						"ContainerName":  cp.Type,
+						"ContainerPort":  ContainerPort,
+						"TargetGroupArn": Ref(targetGroup),
+					},
+				},
+				"TaskDefinition": Ref(taskDefinition),
+				"ServiceName":    fmt.Sprintf("%s-%s-canary", app.Name, p.Type),
+				"ServiceToken":   t.CustomResourcesTopic,
+				"Role":           t.ServiceRole,
+			},
+			DependsOn: dependsOn,
+		},
+	}
+	tmpl.AddResource(service)
+
 	return service.Name
 }
 
+// forwardAction returns a listener action that forwards requests to the target
+// group. When the process has a canary, requests are split between the target
+// group and the canary's target group, by the canary's weight.
+func forwardAction(app *scheduler.App, targetGroup, canaryTargetGroup string) map[string]interface{} {
+	if canaryTargetGroup == "" {
+		return map[string]interface{}{
+			"TargetGroupArn": Ref(targetGroup),
+			"Type":           "forward",
+		}
+	}
+
+	weight := app.Canary.Weight
+	return map[string]interface{}{
+		"Type": "forward",
+		"ForwardConfig": map[string]interface{}{
+			"TargetGroups": []interface{}{
+				map[string]interface{}{
+					"TargetGroupArn": Ref(targetGroup),
+					"Weight":         100 - weight,
+				},
+				map[string]interface{}{
+					"TargetGroupArn": Ref(canaryTargetGroup),
+					"Weight":         weight,
+				},
+			},
+		},
+	}
+}
+
+// addAutoscaling adds an Application Auto Scaling target for the ECS service,
+// with a target tracking policy for the process's metric.
+func (t *EmpireTemplate) addAutoscaling(tmpl *troposphere.Template, app *scheduler.App, p *scheduler.Process, service string, requestCountResourceLabel interface{}) error {
+	key := processResourceName(p.Type)
+	a := p.Autoscaling
+
+	metric := map[string]interface{}{}
+	switch a.Metric {
+	case "cpu":
+		metric["PredefinedMetricType"] = "ECSServiceAverageCPUUtilization"
+	case "memory":
+		metric["PredefinedMetricType"] = "ECSServiceAverageMemoryUtilization"
+	case "requests":
+		if requestCountResourceLabel == nil {
+			return fmt.Errorf("process %s must be exposed with an application load balancer to be autoscaled on requests", p.Type)
+		}
+		metric["PredefinedMetricType"] = "ALBRequestCountPerTarget"
+		metric["ResourceLabel"] = requestCountResourceLabel
+	default:
+		return fmt.Errorf("unknown autoscaling metric for process %s: %s", p.Type, a.Metric)
+	}
+
+	// The role is omitted, so that the service linked role for
+	// Application Auto Scaling is used.
+	scalableTarget := fmt.Sprintf("%sScalableTarget", key)
+	tmpl.Resources[scalableTarget] = troposphere.Resource{
+		Type: "AWS::ApplicationAutoScaling::ScalableTarget",
+		Properties: map[string]interface{}{
+			"MinCapacity":       a.Min,
+			"MaxCapacity":       a.Max,
+			"ResourceId":        Join("/", "service", t.Cluster, GetAtt(service, "Name")),
+			"ScalableDimension": "ecs:service:DesiredCount",
+			"ServiceNamespace":  "ecs",
+		},
+	}
+
+	tmpl.Resources[fmt.Sprintf("%sScalingPolicy", key)] = troposphere.Resource{
+		Type: "AWS::ApplicationAutoScaling::ScalingPolicy",
+		Properties: map[string]interface{}{
+			"PolicyName":      fmt.Sprintf("%s-%s-%s", app.Name, p.Type, a.Metric),
+			"PolicyType":      "TargetTrackingScaling",
+			"ScalingTargetId": Ref(scalableTarget),
+			"TargetTrackingScalingPolicyConfiguration": map[string]interface{}{
+				"TargetValue":                   a.Target,
+				"PredefinedMetricSpecification": metric,
+			},
+		},
+	}
+
+	return nil
+}
+
 // If the ServiceRole option is not an ARN, it will return a CloudFormation
 // expression that expands the ServiceRole to an ARN.
 func (t *EmpireTemplate) serviceRoleArn() interface{} {
@@ -637,6 +1138,104 @@ var resourceRegex = regexp.MustCompile("[^a-zA-Z0-9]")
 
 // processResourceName returns a string that can be used as a resource name in a
 // CloudFormation stack for a process.
+// sharedLoadBalancer returns the shared load balancer for processes with the
+// given exposure, or nil if there isn't one.
+func (t *EmpireTemplate) sharedLoadBalancer(external bool) *SharedLoadBalancer {
+	if external {
+		return t.ExternalSharedLoadBalancer
+	}
+	return t.InternalSharedLoadBalancer
+}
+
+// processCNAME returns the name of the CNAME record for an exposed process.
+func (t *EmpireTemplate) processCNAME(app *scheduler.App, p *scheduler.Process) string {
+	if p.Type == "web" {
+		return fmt.Sprintf("%s.%s", app.Name, *t.HostedZone.Name)
+	}
+	return fmt.Sprintf("%s.%s.%s", p.Type, app.Name, *t.HostedZone.Name)
+}
+
+// processHosts returns the hosts that should be routed to a process on a shared
+// load balancer. This is the process's CNAME, and for the web process, the
+// app's domains.
+func (t *EmpireTemplate) processHosts(app *scheduler.App, p *scheduler.Process) []string {
+	hosts := []string{strings.TrimSuffix(t.processCNAME(app, p), ".")}
+	if p.Type == "web" {
+		hosts = append(hosts, app.Domains...)
+	}
+	return hosts
+}
+
+// chunkHosts splits hosts into groups of at most n hosts.
+func chunkHosts(hosts []string, n int) [][]string {
+	var chunks [][]string
+	for len(hosts) > n {
+		chunks = append(chunks, hosts[:n])
+		hosts = hosts[n:]
+	}
+	return append(chunks, hosts)
+}
+
+// certificateArn returns the ARN of the certificate. Certificates that aren't
+// an ARN are expanded to the ARN of an IAM server certificate.
+func certificateArn(cert string) interface{} {
+	if _, err := arn.Parse(cert); err == nil {
+		return cert
+	}
+	return Join("", "arn:aws:iam::", Ref("AWS::AccountId"), ":server-certificate/", cert)
+}
+
+// addTargetGroupHealthCheck adds the health check settings to the properties
+// of an AWS::ElasticLoadBalancingV2::TargetGroup. Network load balancers don't
+// support custom timeouts, so the timeout is only set for application load
+// balancers.
+func addTargetGroupHealthCheck(properties map[string]interface{}, h *scheduler.HealthCheck, network bool) {
+	if h.Path != "" {
+		properties["HealthCheckProtocol"] = "HTTP"
+		properties["HealthCheckPath"] = h.Path
+	}
+	properties["HealthCheckIntervalSeconds"] = int64(h.Interval / time.Second)
+	if !network {
+		properties["HealthCheckTimeoutSeconds"] = int64(h.Timeout / time.Second)
+	}
+	properties["HealthyThresholdCount"] = h.HealthyThreshold
+	properties["UnhealthyThresholdCount"] = h.UnhealthyThreshold
+	properties["TargetGroupAttributes"] = []map[string]string{
+		{
+			"Key":   "deregistration_delay.timeout_seconds",
+			"Value": seconds(h.DeregistrationDelay),
+		},
+	}
+}
+
+// seconds formats a duration as a whole number of seconds.
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%d", int64(d/time.Second))
+}
+
+// isHTTP returns true if the exposure is HTTP or HTTPS.
+func isHTTP(e scheduler.ExposureType) bool {
+	switch e.(type) {
+	case *scheduler.TCPExposure, *scheduler.SSLExposure:
+		return false
+	default:
+		return true
+	}
+}
+
+// exposurePort returns the port that the load balancer listens on for TCP and
+// SSL exposures.
+func exposurePort(e scheduler.ExposureType) int64 {
+	switch e := e.(type) {
+	case *scheduler.TCPExposure:
+		return e.Port
+	case *scheduler.SSLExposure:
+		return e.Port
+	default:
+		return 0
+	}
+}
+
 func processResourceName(process string) string {
 	return resourceRegex.ReplaceAllString(process, "")
 }
@@ -172,6 +172,260 @@ func TestEmpireTemplate(t *testing.T) {
 			},
 		},
 
+		{
+			"tcp.json",
+			&scheduler.App{
+				ID:      "1234",
+				Release: "v1",
+				Name:    "acme-inc",
+				Processes: []*scheduler.Process{
+					{
+						Type:    "redis",
+						Command: []string{"./bin/redis"},
+						Exposure: &scheduler.Exposure{
+							Type: &scheduler.TCPExposure{
+								Port: 6379,
+							},
+						},
+					},
+					{
+						Type:    "smtp",
+						Command: []string{"./bin/smtp"},
+						Exposure: &scheduler.Exposure{
+							External: true,
+							Type: &scheduler.SSLExposure{
+								Port: 465,
+								Cert: "AcmeIncDotCom",
+							},
+						},
+					},
+				},
+			},
+		},
+
+		{
+			"tcp-nlb.json",
+			&scheduler.App{
+				ID:      "1234",
+				Release: "v1",
+				Name:    "acme-inc",
+				Env: map[string]string{
+					"LOAD_BALANCER_TYPE": "alb",
+				},
+				Processes: []*scheduler.Process{
+					{
+						Type:    "redis",
+						Command: []string{"./bin/redis"},
+						Exposure: &scheduler.Exposure{
+							Type: &scheduler.TCPExposure{
+								Port: 6379,
+							},
+						},
+					},
+					{
+						Type:    "smtp",
+						Command: []string{"./bin/smtp"},
+						Exposure: &scheduler.Exposure{
+							External: true,
+							Type: &scheduler.SSLExposure{
+								Port: 465,
+								Cert: "AcmeIncDotCom",
+							},
+						},
+					},
+				},
+			},
+		},
+
+		{
+			"healthcheck.json",
+			&scheduler.App{
+				ID:      "1234",
+				Release: "v1",
+				Name:    "acme-inc",
+				Processes: []*scheduler.Process{
+					{
+						Type:    "web",
+						Command: []string{"./bin/web"},
+						Exposure: &scheduler.Exposure{
+							Type: &scheduler.HTTPExposure{},
+						},
+						HealthCheck: &scheduler.HealthCheck{
+							Path:                "/health",
+							Interval:            30 * time.Second,
+							Timeout:             10 * time.Second,
+							HealthyThreshold:    2,
+							UnhealthyThreshold:  10,
+							DeregistrationDelay: 2 * time.Minute,
+						},
+					},
+				},
+			},
+		},
+
+		{
+			"healthcheck-alb.json",
+			&scheduler.App{
+				ID:      "1234",
+				Release: "v1",
+				Name:    "acme-inc",
+				Env: map[string]string{
+					"LOAD_BALANCER_TYPE": "alb",
+				},
+				Processes: []*scheduler.Process{
+					{
+						Type:    "web",
+						Command: []string{"./bin/web"},
+						Exposure: &scheduler.Exposure{
+							Type: &scheduler.HTTPExposure{},
+						},
+						HealthCheck: &scheduler.HealthCheck{
+							Path:                "/health",
+							Interval:            30 * time.Second,
+							Timeout:             10 * time.Second,
+							HealthyThreshold:    2,
+							UnhealthyThreshold:  10,
+							DeregistrationDelay: 2 * time.Minute,
+						},
+					},
+				},
+			},
+		},
+
+		{
+			"canary-alb.json",
+			&scheduler.App{
+				ID:      "1234",
+				Release: "v1",
+				Name:    "acme-inc",
+				Env: map[string]string{
+					"LOAD_BALANCER_TYPE": "alb",
+				},
+				Processes: []*scheduler.Process{
+					{
+						Type:    "web",
+						Image:   image.Image{Repository: "remind101/acme-inc", Tag: "v1"},
+						Command: []string{"./bin/web"},
+						Exposure: &scheduler.Exposure{
+							Type: &scheduler.HTTPExposure{},
+						},
+						Instances: 2,
+					},
+					{
+						Type:    "worker",
+						Image:   image.Image{Repository: "remind101/acme-inc", Tag: "v1"},
+						Command: []string{"./bin/worker"},
+					},
+				},
+				Canary: &scheduler.Canary{
+					App: &scheduler.App{
+						ID:      "1234",
+						Release: "v2",
+						Name:    "acme-inc",
+						Env: map[string]string{
+							"LOAD_BALANCER_TYPE": "alb",
+						},
+						Processes: []*scheduler.Process{
+							{
+								Type:    "web",
+								Image:   image.Image{Repository: "remind101/acme-inc", Tag: "v2"},
+								Command: []string{"./bin/web"},
+								Exposure: &scheduler.Exposure{
+									Type: &scheduler.HTTPExposure{},
+								},
+								Instances: 2,
+							},
+							{
+								Type:    "worker",
+								Image:   image.Image{Repository: "remind101/acme-inc", Tag: "v2"},
+								Command: []string{"./bin/worker"},
+							},
+						},
+					},
+					Weight: 25,
+				},
+			},
+		},
+
+		{
+			"shared-alb.json",
+			&scheduler.App{
+				ID:      "1234",
+				Release: "v1",
+				Name:    "acme-inc",
+				Env: map[string]string{
+					"LOAD_BALANCER_TYPE": "shared",
+				},
+				Domains: []string{"acme.example.com"},
+				Processes: []*scheduler.Process{
+					{
+						Type:    "web",
+						Command: []string{"./bin/web"},
+						Exposure: &scheduler.Exposure{
+							External: true,
+							Type: &scheduler.HTTPSExposure{
+								Cert: "arn:aws:iam::012345678901:server-certificate/AcmeIncDotCom",
+							},
+						},
+						HealthCheck: &scheduler.HealthCheck{
+							Path:                "/health",
+							Interval:            30 * time.Second,
+							Timeout:             5 * time.Second,
+							HealthyThreshold:    2,
+							UnhealthyThreshold:  5,
+							DeregistrationDelay: 30 * time.Second,
+						},
+					},
+					{
+						Type:    "api",
+						Command: []string{"./bin/api"},
+						Exposure: &scheduler.Exposure{
+							Type: &scheduler.HTTPExposure{},
+						},
+					},
+				},
+			},
+		},
+
+		{
+			"autoscaling.json",
+			&scheduler.App{
+				ID:      "1234",
+				Release: "v1",
+				Name:    "acme-inc",
+				Env: map[string]string{
+					"LOAD_BALANCER_TYPE": "alb",
+				},
+				Processes: []*scheduler.Process{
+					{
+						Type:      "web",
+						Command:   []string{"./bin/web"},
+						Instances: 2,
+						Exposure: &scheduler.Exposure{
+							Type: &scheduler.HTTPExposure{},
+						},
+						Autoscaling: &scheduler.Autoscaling{
+							Min:    2,
+							Max:    10,
+							Metric: "requests",
+							Target: 1000,
+						},
+					},
+					{
+						Type:      "worker",
+						Command:   []string{"./bin/worker"},
+						Instances: 1,
+						Autoscaling: &scheduler.Autoscaling{
+							Min:    1,
+							Max:    5,
+							Metric: "cpu",
+							Target: 50,
+						},
+					},
+				},
+			},
+		},
+
 		{
 			"custom.json",
 			&scheduler.App{
@@ -274,6 +528,30 @@ func TestEmpireTemplate(t *testing.T) {
 	}
 }
 
+func TestEmpireTemplate_AutoscalingRequestsWithoutLoadBalancer(t *testing.T) {
+	tmpl := newTemplate()
+	app := &scheduler.App{
+		ID:      "1234",
+		Release: "v1",
+		Name:    "acme-inc",
+		Processes: []*scheduler.Process{
+			{
+				Type:    "worker",
+				Command: []string{"./bin/worker"},
+				Autoscaling: &scheduler.Autoscaling{
+					Min:    1,
+					Max:    5,
+					Metric: "requests",
+					Target: 1000,
+				},
+			},
+		},
+	}
+
+	err := tmpl.Execute(new(bytes.Buffer), app)
+	assert.EqualError(t, err, "process worker must be exposed with an application load balancer to be autoscaled on requests")
+}
+
 func TestEmpireTemplate_Large(t *testing.T) {
 	labels := make(map[string]string)
 	env := make(map[string]string)
@@ -341,5 +619,14 @@ func newTemplate() *EmpireTemplate {
 				Value: "x.x.x",
 			},
 		},
+		InternalSharedLoadBalancer: &SharedLoadBalancer{
+			DNSName:         "internal-shared-1234.us-east-1.elb.amazonaws.com",
+			HTTPListenerArn: "arn:aws:elasticloadbalancing:us-east-1:012345678901:listener/app/internal-shared/1234/http",
+		},
+		ExternalSharedLoadBalancer: &SharedLoadBalancer{
+			DNSName:          "shared-5678.us-east-1.elb.amazonaws.com",
+			HTTPListenerArn:  "arn:aws:elasticloadbalancing:us-east-1:012345678901:listener/app/shared/5678/http",
+			HTTPSListenerArn: "arn:aws:elasticloadbalancing:us-east-1:012345678901:listener/app/shared/5678/https",
+		},
 	}
 }
@@ -3,16 +3,20 @@ package docker
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"strconv"
 	"strings"
+	"time"
 
 	"code.google.com/p/go-uuid/uuid"
 
 	"github.com/fsouza/go-dockerclient"
 	"github.com/remind101/empire/pkg/dockerutil"
+	"github.com/remind101/empire/pkg/image"
 	"github.com/remind101/empire/scheduler"
+	"github.com/remind101/pkg/logger"
 	"golang.org/x/net/context"
 )
 
@@ -41,8 +45,21 @@ const (
 
 	// Label that determines what the name of the process is.
 	processLabel = "empire.app.process"
+
+	// Label that determines what release a container for a long running
+	// process was started from.
+	releaseLabel = "empire.app.release"
+
+	// Label that determines the instance number of a container for a long
+	// running process.
+	instanceLabel = "empire.app.process.instance"
 )
 
+// For exposed processes, this is the port that the process within the
+// container should bind to. This value is also exposed to the container
+// through the PORT environment variable.
+const ContainerPort = 8080
+
 // Values for `runLabel`.
 const (
 	Attached = "attached"
@@ -84,6 +101,12 @@ func (s *AttachedScheduler) Run(ctx context.Context, app *scheduler.App, process
 	}
 }
 
+// RunsAttached implements the scheduler.AttachedRunner interface. Attached
+// processes are always run with Docker.
+func (s *AttachedScheduler) RunsAttached() bool {
+	return true
+}
+
 // Instances returns a combination of instances from the wrapped scheduler, as
 // well as instances from attached runs.
 func (s *AttachedScheduler) Instances(ctx context.Context, app string) ([]*scheduler.Instance, error) {
@@ -133,8 +156,31 @@ func (s *AttachedScheduler) Stop(ctx context.Context, maybeContainerID string) e
 	return err
 }
 
+// SubmitCanary delegates to the wrapped Scheduler, if it supports canaries.
+func (s *AttachedScheduler) SubmitCanary(ctx context.Context, app *scheduler.App, ss scheduler.StatusStream) error {
+	cs, ok := s.Scheduler.(scheduler.CanaryScheduler)
+	if !ok {
+		return scheduler.ErrCanaryNotSupported
+	}
+	return cs.SubmitCanary(ctx, app, ss)
+}
+
+// CanaryErrorRate delegates to the wrapped Scheduler, if it supports canaries.
+func (s *AttachedScheduler) CanaryErrorRate(ctx context.Context, app string, since time.Time) (float64, error) {
+	cs, ok := s.Scheduler.(scheduler.CanaryScheduler)
+	if !ok {
+		return 0, scheduler.ErrCanaryNotSupported
+	}
+	return cs.CanaryErrorRate(ctx, app, since)
+}
+
 // Scheduler provides an implementation of the scheduler.Scheduler interface
 // backed by Docker.
+//
+// Long running processes are run as containers with an "always" restart
+// policy, one container per instance. Containers are labeled with the app,
+// process and release that they belong to, which is how the scheduler finds
+// them again. Scheduled processes are not supported and are ignored.
 type Scheduler struct {
 	docker dockerClient
 }
@@ -147,45 +193,187 @@ func NewScheduler(client *dockerutil.Client) *Scheduler {
 	}
 }
 
+// Submit creates containers for each instance of each process in the app.
+// Containers from a previous release are replaced, and containers for
+// processes that no longer exist are removed.
+func (s *Scheduler) Submit(ctx context.Context, app *scheduler.App, ss scheduler.StatusStream) error {
+	return s.submit(ctx, app, ss, false)
+}
+
+// Restart replaces all of the containers for the app with new ones.
+func (s *Scheduler) Restart(ctx context.Context, app *scheduler.App, ss scheduler.StatusStream) error {
+	return s.submit(ctx, app, ss, true)
+}
+
+func (s *Scheduler) submit(ctx context.Context, app *scheduler.App, ss scheduler.StatusStream, force bool) error {
+	existing, err := s.serviceContainers(ctx, app.ID)
+	if err != nil {
+		return err
+	}
+
+	processes := make(map[string]bool)
+	for _, p := range app.Processes {
+		if p.Schedule != nil {
+			logger.Warn(ctx, fmt.Sprintf("%s: scheduled processes are not supported by the Docker scheduler", p.Type))
+			continue
+		}
+
+		processes[p.Type] = true
+
+		if err := s.pullImage(ctx, p.Image, nil); err != nil {
+			return err
+		}
+
+		if err := s.updateProcess(ctx, app, p, existing[p.Type], ss, force); err != nil {
+			return err
+		}
+	}
+
+	// Remove containers for processes that have been removed from the
+	// app.
+	for process, containers := range existing {
+		if processes[process] {
+			continue
+		}
+
+		scheduler.Publish(ctx, ss, fmt.Sprintf("Removing %s process", process))
+		for _, c := range containers {
+			if err := s.removeContainer(ctx, c.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// updateProcess converges the containers for a process to the desired state.
+// Containers that can be kept are those that belong to the current release
+// and have an instance number below the desired instance count. Any missing
+// instances are started before the old containers are removed.
+func (s *Scheduler) updateProcess(ctx context.Context, app *scheduler.App, p *scheduler.Process, existing []docker.APIContainers, ss scheduler.StatusStream, force bool) error {
+	var stale []docker.APIContainers
+	keep := make(map[int]bool)
+
+	for _, c := range existing {
+		instance, err := strconv.Atoi(c.Labels[instanceLabel])
+		if force || err != nil || keep[instance] || c.Labels[releaseLabel] != app.Release || uint(instance) >= p.Instances || !isRunning(c) {
+			stale = append(stale, c)
+			continue
+		}
+		keep[instance] = true
+	}
+
+	for i := 0; uint(i) < p.Instances; i++ {
+		if keep[i] {
+			continue
+		}
+
+		scheduler.Publish(ctx, ss, fmt.Sprintf("Starting %s.%d", p.Type, i))
+		if _, err := s.startService(ctx, app, p, i); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range stale {
+		scheduler.Publish(ctx, ss, fmt.Sprintf("Stopping %s.%s", p.Type, c.Labels[instanceLabel]))
+		if err := s.removeContainer(ctx, c.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// startService creates and starts a container for a single instance of a
+// long running process.
+func (s *Scheduler) startService(ctx context.Context, app *scheduler.App, p *scheduler.Process, instance int) (*docker.Container, error) {
+	config := containerConfig(app, p)
+	config.Labels[releaseLabel] = app.Release
+	config.Labels[instanceLabel] = strconv.Itoa(instance)
+
+	hostConfig := hostConfig(p)
+	hostConfig.RestartPolicy = docker.AlwaysRestart()
+
+	// If the process is exposed, expose the container port on a random
+	// port on the host.
+	if p.Exposure != nil {
+		port := docker.Port(fmt.Sprintf("%d/tcp", ContainerPort))
+		config.ExposedPorts = map[docker.Port]struct{}{port: struct{}{}}
+		config.Env = append(config.Env, fmt.Sprintf("PORT=%d", ContainerPort))
+		hostConfig.PublishAllPorts = true
+	}
+
+	return s.createAndStart(ctx, docker.CreateContainerOptions{
+		Name:       fmt.Sprintf("%s.%s.%d.%s", app.Name, p.Type, instance, uuid.New()[:8]),
+		Config:     config,
+		HostConfig: hostConfig,
+	})
+}
+
+// createAndStart creates a new container and starts it.
+func (s *Scheduler) createAndStart(ctx context.Context, opts docker.CreateContainerOptions) (*docker.Container, error) {
+	container, err := s.docker.CreateContainer(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error creating container: %v", err)
+	}
+
+	if err := s.docker.StartContainer(ctx, container.ID, nil); err != nil {
+		return nil, fmt.Errorf("error starting container: %v", err)
+	}
+
+	return container, nil
+}
+
+// Remove removes all of the containers for the app.
+func (s *Scheduler) Remove(ctx context.Context, appID string) error {
+	containers, err := s.docker.ListContainers(docker.ListContainersOptions{
+		All: true,
+		Filters: map[string][]string{
+			"label": []string{fmt.Sprintf("%s=%s", appLabel, appID)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error listing containers: %v", err)
+	}
+
+	for _, c := range containers {
+		if err := s.removeContainer(ctx, c.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunsAttached implements the scheduler.AttachedRunner interface.
+func (s *Scheduler) RunsAttached() bool {
+	return true
+}
+
 func (s *Scheduler) Run(ctx context.Context, app *scheduler.App, p *scheduler.Process, in io.Reader, out io.Writer) error {
 	attached := out != nil || in != nil
 
 	if !attached {
-		return errors.New("cannot run detached processes with Docker scheduler")
+		return s.runDetached(ctx, app, p)
 	}
 
-	labels := scheduler.Labels(app, p)
-	labels[runLabel] = Attached
-
-	if err := s.docker.PullImage(ctx, docker.PullImageOptions{
-		Registry:     p.Image.Registry,
-		Repository:   p.Image.Repository,
-		Tag:          p.Image.Tag,
-		OutputStream: replaceNL(out),
-	}); err != nil {
-		return fmt.Errorf("error pulling image: %v", err)
+	if err := s.pullImage(ctx, p.Image, replaceNL(out)); err != nil {
+		return err
 	}
 
+	config := containerConfig(app, p)
+	config.Labels[runLabel] = Attached
+	config.Tty = true
+	config.AttachStdin = true
+	config.AttachStdout = true
+	config.AttachStderr = true
+	config.OpenStdin = true
+
 	container, err := s.docker.CreateContainer(ctx, docker.CreateContainerOptions{
-		Name: uuid.New(),
-		Config: &docker.Config{
-			Tty:          true,
-			AttachStdin:  true,
-			AttachStdout: true,
-			AttachStderr: true,
-			OpenStdin:    true,
-			Memory:       int64(p.MemoryLimit),
-			CPUShares:    int64(p.CPUShares),
-			Image:        p.Image.String(),
-			Cmd:          p.Command,
-			Env:          envKeys(scheduler.Env(app, p)),
-			Labels:       labels,
-		},
-		HostConfig: &docker.HostConfig{
-			LogConfig: docker.LogConfig{
-				Type: "json-file",
-			},
-		},
+		Name:       uuid.New(),
+		Config:     config,
+		HostConfig: hostConfig(p),
 	})
 	if err != nil {
 		return fmt.Errorf("error creating container: %v", err)
@@ -216,11 +404,61 @@ func (s *Scheduler) Run(ctx context.Context, app *scheduler.App, p *scheduler.Pr
 		return fmt.Errorf("error attaching to container: %v", err)
 	}
 
+	// The attach returns once the container has exited, so we can check
+	// the exit code.
+	c, err := s.docker.InspectContainer(container.ID)
+	if err != nil {
+		return fmt.Errorf("error inspecting container: %v", err)
+	}
+
+	if code := c.State.ExitCode; code != 0 {
+		return &scheduler.ExitError{Code: code}
+	}
+
 	return nil
 }
 
+// runDetached starts a container for a one-off process without waiting for
+// it to exit. The container is left around after it exits, so that its logs
+// can be inspected, and is cleaned up when the app is removed.
+func (s *Scheduler) runDetached(ctx context.Context, app *scheduler.App, p *scheduler.Process) error {
+	if err := s.pullImage(ctx, p.Image, nil); err != nil {
+		return err
+	}
+
+	config := containerConfig(app, p)
+	config.Labels[runLabel] = Detached
+
+	_, err := s.createAndStart(ctx, docker.CreateContainerOptions{
+		Name:       uuid.New(),
+		Config:     config,
+		HostConfig: hostConfig(p),
+	})
+	return err
+}
+
+// pullImage pulls the image, optionally writing the progress to w.
+func (s *Scheduler) pullImage(ctx context.Context, img image.Image, w io.Writer) error {
+	if w == nil {
+		w = ioutil.Discard
+	}
+
+	if err := s.docker.PullImage(ctx, docker.PullImageOptions{
+		Registry:     img.Registry,
+		Repository:   img.Repository,
+		Tag:          img.Tag,
+		OutputStream: w,
+	}); err != nil {
+		return fmt.Errorf("error pulling image: %v", err)
+	}
+
+	return nil
+}
+
+// Instances returns all of the containers for the app, including containers
+// from attached and detached runs.
 func (s *Scheduler) Instances(ctx context.Context, app string) ([]*scheduler.Instance, error) {
-	return s.InstancesFromAttachedRuns(ctx, app)
+	return s.instances(ctx, app)
 }
 
 // InstancesFromAttachedRuns returns Instances that were started from attached
@@ -272,7 +510,33 @@ func (s *Scheduler) instances(ctx context.Context, app string, labels ...string)
 	return instances, nil
 }
 
-// Stop stops the given container.
+// serviceContainers returns the containers for the long running processes of
+// the app, grouped by process type.
+func (s *Scheduler) serviceContainers(ctx context.Context, app string) (map[string][]docker.APIContainers, error) {
+	containers, err := s.docker.ListContainers(docker.ListContainersOptions{
+		All: true,
+		Filters: map[string][]string{
+			"label": []string{
+				fmt.Sprintf("%s=%s", appLabel, app),
+				instanceLabel,
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing containers: %v", err)
+	}
+
+	processes := make(map[string][]docker.APIContainers)
+	for _, c := range containers {
+		p := c.Labels[processLabel]
+		processes[p] = append(processes[p], c)
+	}
+
+	return processes, nil
+}
+
+// Stop stops the given container. If the container belongs to a long running
+// process, it's replaced with a new container.
 func (s *Scheduler) Stop(ctx context.Context, containerID string) error {
 	container, err := s.docker.InspectContainer(containerID)
 	if err != nil {
@@ -282,7 +546,9 @@ func (s *Scheduler) Stop(ctx context.Context, containerID string) error {
 	// Some extra protection around stopping containers. We don't want to
 	// allow users to stop containers that may have been started outside of
 	// Empire.
-	if _, ok := container.Config.Labels[runLabel]; !ok {
+	_, run := container.Config.Labels[runLabel]
+	_, service := container.Config.Labels[instanceLabel]
+	if !run && !service {
 		return &docker.NoSuchContainer{
 			ID: containerID,
 		}
@@ -292,9 +558,89 @@ func (s *Scheduler) Stop(ctx context.Context, containerID string) error {
 		return err
 	}
 
+	if !service {
+		return nil
+	}
+
+	// Docker won't restart a container that was explicitly stopped, so we
+	// replace it with an identical one.
+	if err := s.docker.RemoveContainer(ctx, docker.RemoveContainerOptions{
+		ID:            container.ID,
+		RemoveVolumes: true,
+	}); err != nil {
+		return fmt.Errorf("error removing container: %v", err)
+	}
+
+	_, err = s.createAndStart(ctx, docker.CreateContainerOptions{
+		Name:       strings.TrimPrefix(container.Name, "/"),
+		Config:     container.Config,
+		HostConfig: container.HostConfig,
+	})
+	return err
+}
+
+// removeContainer stops and removes the container.
+func (s *Scheduler) removeContainer(ctx context.Context, containerID string) error {
+	if err := s.docker.StopContainer(ctx, containerID, stopContainerTimeout); err != nil {
+		switch err.(type) {
+		case *docker.NoSuchContainer, *docker.ContainerNotRunning:
+		default:
+			return fmt.Errorf("error stopping container: %v", err)
+		}
+	}
+
+	if err := s.docker.RemoveContainer(ctx, docker.RemoveContainerOptions{
+		ID:            containerID,
+		RemoveVolumes: true,
+		Force:         true,
+	}); err != nil {
+		if _, ok := err.(*docker.NoSuchContainer); !ok {
+			return fmt.Errorf("error removing container: %v", err)
+		}
+	}
+
 	return nil
 }
 
+// containerConfig returns the base container configuration for the process.
+func containerConfig(app *scheduler.App, p *scheduler.Process) *docker.Config {
+	labels := scheduler.Labels(app, p)
+	labels[appLabel] = app.ID
+	labels[processLabel] = p.Type
+
+	return &docker.Config{
+		Memory:    int64(p.MemoryLimit),
+		CPUShares: int64(p.CPUShares),
+		Image:     p.Image.String(),
+		Cmd:       p.Command,
+		Env:       envKeys(scheduler.Env(app, p)),
+		Labels:    labels,
+	}
+}
+
+// hostConfig returns the base host configuration for the process.
+func hostConfig(p *scheduler.Process) *docker.HostConfig {
+	var ulimits []docker.ULimit
+	if p.Nproc != 0 {
+		ulimits = []docker.ULimit{
+			{Name: "nproc", Soft: int64(p.Nproc), Hard: int64(p.Nproc)},
+		}
+	}
+
+	return &docker.HostConfig{
+		Memory:    int64(p.MemoryLimit),
+		CPUShares: int64(p.CPUShares),
+		Ulimits:   ulimits,
+		LogConfig: docker.LogConfig{
+			Type: "json-file",
+		},
+	}
+}
+
+func isRunning(c docker.APIContainers) bool {
+	return c.State == "" || c.State == "running" || c.State == "restarting"
+}
+
 func parseEnv(env []string) map[string]string {
 	m := make(map[string]string)
 	for _, e := range env {
@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"io/ioutil"
 	"testing"
 	"time"
 
@@ -8,6 +9,7 @@ import (
 
 	"github.com/fsouza/go-dockerclient"
 	"github.com/remind101/empire/pkg/bytesize"
+	"github.com/remind101/empire/pkg/image"
 	"github.com/remind101/empire/scheduler"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -74,6 +76,152 @@ func TestScheduler_InstancesFromAttachedRuns(t *testing.T) {
 	d.AssertExpectations(t)
 }
 
+func TestScheduler_Submit(t *testing.T) {
+	d := new(mockDockerClient)
+	s := Scheduler{
+		docker: d,
+	}
+
+	app := &scheduler.App{
+		ID:      "2cdc4941-e36d-4855-a0ec-51525db4a500",
+		Name:    "acme-inc",
+		Release: "v2",
+		Processes: []*scheduler.Process{
+			{
+				Type:        "web",
+				Image:       image.Image{Repository: "remind101/acme-inc", Tag: "latest"},
+				Command:     []string{"./bin/web"},
+				Instances:   2,
+				MemoryLimit: 128 * bytesize.MB,
+				CPUShares:   256,
+				Nproc:       512,
+				Exposure: &scheduler.Exposure{
+					Type: &scheduler.HTTPExposure{},
+				},
+			},
+		},
+	}
+
+	d.On("ListContainers", docker.ListContainersOptions{
+		All: true,
+		Filters: map[string][]string{
+			"label": []string{
+				"empire.app.id=2cdc4941-e36d-4855-a0ec-51525db4a500",
+				"empire.app.process.instance",
+			},
+		},
+	}).Return([]docker.APIContainers{
+		// Instance 0 from the current release should be kept.
+		{ID: "a", State: "running", Labels: map[string]string{"empire.app.process": "web", "empire.app.release": "v2", "empire.app.process.instance": "0"}},
+		// Instance 1 from an old release should be replaced.
+		{ID: "b", State: "running", Labels: map[string]string{"empire.app.process": "web", "empire.app.release": "v1", "empire.app.process.instance": "1"}},
+		// The worker process was removed.
+		{ID: "c", State: "running", Labels: map[string]string{"empire.app.process": "worker", "empire.app.release": "v1", "empire.app.process.instance": "0"}},
+	}, nil)
+
+	d.On("PullImage", docker.PullImageOptions{
+		Repository:   "remind101/acme-inc",
+		Tag:          "latest",
+		OutputStream: ioutil.Discard,
+	}).Return(nil)
+
+	var created docker.CreateContainerOptions
+	d.On("CreateContainer", mock.AnythingOfType("docker.CreateContainerOptions")).Return(&docker.Container{ID: "d"}, nil).Run(func(args mock.Arguments) {
+		created = args.Get(0).(docker.CreateContainerOptions)
+	}).Once()
+	d.On("StartContainer", "d").Return(nil)
+
+	d.On("StopContainer", "b", uint(10)).Return(nil)
+	d.On("RemoveContainer", docker.RemoveContainerOptions{ID: "b", RemoveVolumes: true, Force: true}).Return(nil)
+	d.On("StopContainer", "c", uint(10)).Return(nil)
+	d.On("RemoveContainer", docker.RemoveContainerOptions{ID: "c", RemoveVolumes: true, Force: true}).Return(nil)
+
+	err := s.Submit(ctx, app, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"empire.app.id":               "2cdc4941-e36d-4855-a0ec-51525db4a500",
+		"empire.app.process":          "web",
+		"empire.app.release":          "v2",
+		"empire.app.process.instance": "1",
+	}, created.Config.Labels)
+	assert.Equal(t, []string{"PORT=8080"}, created.Config.Env)
+	assert.Equal(t, docker.AlwaysRestart(), created.HostConfig.RestartPolicy)
+	assert.Equal(t, int64(128*bytesize.MB), created.HostConfig.Memory)
+	assert.Equal(t, int64(256), created.HostConfig.CPUShares)
+	assert.Equal(t, []docker.ULimit{{Name: "nproc", Soft: 512, Hard: 512}}, created.HostConfig.Ulimits)
+	assert.True(t, created.HostConfig.PublishAllPorts)
+
+	d.AssertExpectations(t)
+}
+
+func TestScheduler_Run_Detached(t *testing.T) {
+	d := new(mockDockerClient)
+	s := Scheduler{
+		docker: d,
+	}
+
+	app := &scheduler.App{
+		ID:   "2cdc4941-e36d-4855-a0ec-51525db4a500",
+		Name: "acme-inc",
+	}
+	p := &scheduler.Process{
+		Type:    "run",
+		Image:   image.Image{Repository: "remind101/acme-inc", Tag: "latest"},
+		Command: []string{"rake", "db:migrate"},
+	}
+
+	d.On("PullImage", docker.PullImageOptions{
+		Repository:   "remind101/acme-inc",
+		Tag:          "latest",
+		OutputStream: ioutil.Discard,
+	}).Return(nil)
+
+	var created docker.CreateContainerOptions
+	d.On("CreateContainer", mock.AnythingOfType("docker.CreateContainerOptions")).Return(&docker.Container{ID: "a"}, nil).Run(func(args mock.Arguments) {
+		created = args.Get(0).(docker.CreateContainerOptions)
+	})
+	d.On("StartContainer", "a").Return(nil)
+
+	err := s.Run(ctx, app, p, nil, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "detached", created.Config.Labels["run"])
+	assert.Equal(t, []string{"rake", "db:migrate"}, created.Config.Cmd)
+	assert.Equal(t, docker.RestartPolicy{}, created.HostConfig.RestartPolicy)
+
+	d.AssertExpectations(t)
+}
+
+func TestScheduler_Remove(t *testing.T) {
+	d := new(mockDockerClient)
+	s := Scheduler{
+		docker: d,
+	}
+
+	d.On("ListContainers", docker.ListContainersOptions{
+		All: true,
+		Filters: map[string][]string{
+			"label": []string{
+				"empire.app.id=2cdc4941-e36d-4855-a0ec-51525db4a500",
+			},
+		},
+	}).Return([]docker.APIContainers{
+		{ID: "a"},
+		{ID: "b"},
+	}, nil)
+
+	d.On("StopContainer", "a", uint(10)).Return(nil)
+	d.On("RemoveContainer", docker.RemoveContainerOptions{ID: "a", RemoveVolumes: true, Force: true}).Return(nil)
+	d.On("StopContainer", "b", uint(10)).Return(&docker.ContainerNotRunning{ID: "b"})
+	d.On("RemoveContainer", docker.RemoveContainerOptions{ID: "b", RemoveVolumes: true, Force: true}).Return(nil)
+
+	err := s.Remove(ctx, "2cdc4941-e36d-4855-a0ec-51525db4a500")
+	assert.NoError(t, err)
+
+	d.AssertExpectations(t)
+}
+
 func TestScheduler_Stop(t *testing.T) {
 	d := new(mockDockerClient)
 	s := Scheduler{
@@ -97,6 +245,44 @@ func TestScheduler_Stop(t *testing.T) {
 	d.AssertExpectations(t)
 }
 
+func TestScheduler_Stop_Service(t *testing.T) {
+	d := new(mockDockerClient)
+	s := Scheduler{
+		docker: d,
+	}
+
+	config := &docker.Config{
+		Labels: map[string]string{
+			"empire.app.process":          "web",
+			"empire.app.process.instance": "0",
+		},
+	}
+	hostConfig := &docker.HostConfig{
+		RestartPolicy: docker.AlwaysRestart(),
+	}
+
+	d.On("InspectContainer", "container_id").Return(&docker.Container{
+		ID:         "container_id",
+		Name:       "/acme-inc.web.0.a1b2c3d4",
+		Config:     config,
+		HostConfig: hostConfig,
+	}, nil)
+
+	d.On("StopContainer", "container_id", uint(10)).Return(nil)
+	d.On("RemoveContainer", docker.RemoveContainerOptions{ID: "container_id", RemoveVolumes: true}).Return(nil)
+	d.On("CreateContainer", docker.CreateContainerOptions{
+		Name:       "acme-inc.web.0.a1b2c3d4",
+		Config:     config,
+		HostConfig: hostConfig,
+	}).Return(&docker.Container{ID: "new_container_id"}, nil)
+	d.On("StartContainer", "new_container_id").Return(nil)
+
+	err := s.Stop(ctx, "container_id")
+	assert.NoError(t, err)
+
+	d.AssertExpectations(t)
+}
+
 func TestScheduler_Stop_ContainerNotStartedByEmpire(t *testing.T) {
 	d := new(mockDockerClient)
 	s := Scheduler{
@@ -181,6 +367,30 @@ func (m *mockDockerClient) StopContainer(ctx context.Context, id string, timeout
 	return args.Error(0)
 }
 
+func (m *mockDockerClient) PullImage(ctx context.Context, opts docker.PullImageOptions) error {
+	args := m.Called(opts)
+	return args.Error(0)
+}
+
+func (m *mockDockerClient) CreateContainer(ctx context.Context, opts docker.CreateContainerOptions) (*docker.Container, error) {
+	args := m.Called(opts)
+	var container *docker.Container
+	if v := args.Get(0); v != nil {
+		container = v.(*docker.Container)
+	}
+	return container, args.Error(1)
+}
+
+func (m *mockDockerClient) StartContainer(ctx context.Context, id string, config *docker.HostConfig) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *mockDockerClient) RemoveContainer(ctx context.Context, opts docker.RemoveContainerOptions) error {
+	args := m.Called(opts)
+	return args.Error(0)
+}
+
 type mockScheduler struct {
 	scheduler.Scheduler
 	mock.Mock
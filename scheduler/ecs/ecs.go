@@ -32,6 +32,7 @@ var DefaultDelimiter = "-"
 type lbManager interface {
 	lb.Manager
 	RemoveCNAMEs(context.Context, map[string]string) error
+	RenameCNAME(context.Context, *lb.LoadBalancer, string) error
 }
 
 // Scheduler is an implementation of the ServiceManager interface that
@@ -524,7 +525,7 @@ func (m *Scheduler) loadBalancer(ctx context.Context, app *scheduler.App, p *sch
 	// the app and re-create it with the proper exposure.
 	if l != nil {
 		var opts *lb.UpdateLoadBalancerOpts
-		opts, err = updateOpts(p, l)
+		opts, err = updateOpts(app, p, l)
 		if err != nil {
 			return nil, err
 		}
@@ -533,6 +534,14 @@ func (m *Scheduler) loadBalancer(ctx context.Context, app *scheduler.App, p *sch
 			if err = m.lb.UpdateLoadBalancer(ctx, *opts); err != nil {
 				return nil, err
 			}
+
+			// The app was renamed, so the CNAME needs to follow it.
+			if name, ok := opts.Tags[lb.AppTag]; ok {
+				if err = m.lb.RenameCNAME(ctx, l, name); err != nil {
+					return nil, err
+				}
+				l.Tags[lb.AppTag] = name
+			}
 		}
 	}
 
@@ -548,7 +557,17 @@ func (m *Scheduler) loadBalancer(ctx context.Context, app *scheduler.App, p *sch
 			Tags:     tags,
 		}
 
-		if e, ok := p.Exposure.Type.(*scheduler.HTTPSExposure); ok {
+		opts.HealthCheck = lbHealthCheck(p.HealthCheck)
+
+		switch e := p.Exposure.Type.(type) {
+		case *scheduler.HTTPSExposure:
+			opts.SSLCert = e.Cert
+		case *scheduler.TCPExposure:
+			opts.Protocol = e.Protocol()
+			opts.Port = e.Port
+		case *scheduler.SSLExposure:
+			opts.Protocol = e.Protocol()
+			opts.Port = e.Port
 			opts.SSLCert = e.Cert
 		}
 
@@ -803,7 +822,7 @@ func canUpdate(p *scheduler.Process, lb *lb.LoadBalancer) error {
 	return nil
 }
 
-func updateOpts(p *scheduler.Process, b *lb.LoadBalancer) (*lb.UpdateLoadBalancerOpts, error) {
+func updateOpts(app *scheduler.App, p *scheduler.Process, b *lb.LoadBalancer) (*lb.UpdateLoadBalancerOpts, error) {
 	// This load balancer can't be updated to make it work for the process.
 	// Return an error.
 	if err := canUpdate(p, b); err != nil {
@@ -815,16 +834,47 @@ func updateOpts(p *scheduler.Process, b *lb.LoadBalancer) (*lb.UpdateLoadBalance
 	}
 
 	// Requires an update to the Cert.
-	if e, ok := p.Exposure.Type.(*scheduler.HTTPSExposure); ok {
+	switch e := p.Exposure.Type.(type) {
+	case *scheduler.HTTPSExposure:
 		if e.Cert != b.SSLCert {
 			opts.SSLCert = &e.Cert
 		}
+	case *scheduler.SSLExposure:
+		if e.Cert != b.SSLCert {
+			opts.SSLCert = &e.Cert
+			opts.SSLPort = e.Port
+		}
+	}
+
+	// The load balancer was tagged with an old app name.
+	if n, ok := b.Tags[lb.AppTag]; ok && n != app.Name {
+		opts.Tags = map[string]string{lb.AppTag: app.Name}
 	}
 
+	// We don't know the current health check of the load balancer, so
+	// it's reconfigured on every update.
+	opts.HealthCheck = lbHealthCheck(p.HealthCheck)
+
 	// Load balancer doesn't require an update.
-	if opts.SSLCert == nil {
+	if opts.SSLCert == nil && opts.Tags == nil && opts.HealthCheck == nil {
 		return nil, nil
 	}
 
 	return &opts, nil
 }
+
+// lbHealthCheck converts a scheduler.HealthCheck into an lb.HealthCheck.
+func lbHealthCheck(h *scheduler.HealthCheck) *lb.HealthCheck {
+	if h == nil {
+		return nil
+	}
+
+	return &lb.HealthCheck{
+		Path:                h.Path,
+		Interval:            h.Interval,
+		Timeout:             h.Timeout,
+		HealthyThreshold:    int64(h.HealthyThreshold),
+		UnhealthyThreshold:  int64(h.UnhealthyThreshold),
+		DeregistrationDelay: h.DeregistrationDelay,
+	}
+}
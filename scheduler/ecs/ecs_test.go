@@ -718,6 +718,39 @@ func TestScheduler_LoadBalancer_ExistingLoadBalancer_NewCert(t *testing.T) {
 	l.AssertExpectations(t)
 }
 
+func TestScheduler_LoadBalancer_ExistingLoadBalancer_Renamed(t *testing.T) {
+	l := new(mockLBManager)
+	s := &Scheduler{
+		lb: l,
+	}
+
+	app := &scheduler.App{
+		ID:   "appid",
+		Name: "newname",
+	}
+	process := &scheduler.Process{
+		Type: "web",
+		Exposure: &scheduler.Exposure{
+			External: true,
+			Type:     &scheduler.HTTPExposure{},
+		},
+	}
+
+	existing := &lb.LoadBalancer{Name: "lbname", External: true, InstancePort: 8080, Tags: map[string]string{lb.AppTag: "appname"}}
+	l.On("LoadBalancers", map[string]string{"AppID": "appid", "ProcessType": "web"}).Return([]*lb.LoadBalancer{existing}, nil)
+	l.On("UpdateLoadBalancer", lb.UpdateLoadBalancerOpts{
+		Name: "lbname",
+		Tags: map[string]string{lb.AppTag: "newname"},
+	}).Return(nil)
+	l.On("RenameCNAME", existing, "newname").Return(nil)
+
+	loadBalancer, err := s.loadBalancer(context.Background(), app, process)
+	assert.NoError(t, err)
+	assert.Equal(t, "newname", loadBalancer.Tags[lb.AppTag])
+
+	l.AssertExpectations(t)
+}
+
 type mockLBManager struct {
 	mock.Mock
 }
@@ -747,6 +780,11 @@ func (m *mockLBManager) RemoveCNAMEs(ctx context.Context, tags map[string]string
 	return args.Error(0)
 }
 
+func (m *mockLBManager) RenameCNAME(ctx context.Context, lb *lb.LoadBalancer, name string) error {
+	args := m.Called(lb, name)
+	return args.Error(0)
+}
+
 // fake app for testing.
 var fakeApp = &scheduler.App{
 	ID: "1234",
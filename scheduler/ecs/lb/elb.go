@@ -1,7 +1,9 @@
 package lb
 
 import (
+	"fmt"
 	"strings"
+	"time"
 
 	"code.google.com/p/go-uuid/uuid"
 	"github.com/aws/aws-sdk-go/aws"
@@ -27,6 +29,8 @@ type elbClient interface {
 	DeleteLoadBalancer(input *elb.DeleteLoadBalancerInput) (*elb.DeleteLoadBalancerOutput, error)
 	DescribeLoadBalancers(input *elb.DescribeLoadBalancersInput) (*elb.DescribeLoadBalancersOutput, error)
 	DescribeTags(input *elb.DescribeTagsInput) (*elb.DescribeTagsOutput, error)
+	AddTags(input *elb.AddTagsInput) (*elb.AddTagsOutput, error)
+	ConfigureHealthCheck(input *elb.ConfigureHealthCheckInput) (*elb.ConfigureHealthCheckOutput, error)
 }
 
 // ELBManager is an implementation of the Manager interface that creates Elastic
@@ -83,7 +87,7 @@ func (m *ELBManager) CreateLoadBalancer(ctx context.Context, o CreateLoadBalance
 	}
 
 	input := &elb.CreateLoadBalancerInput{
-		Listeners:        elbListeners(port, o.SSLCert),
+		Listeners:        elbListeners(port, o),
 		LoadBalancerName: aws.String(m.newName()),
 		Scheme:           aws.String(scheme),
 		SecurityGroups:   []*string{aws.String(sg)},
@@ -97,12 +101,17 @@ func (m *ELBManager) CreateLoadBalancer(ctx context.Context, o CreateLoadBalance
 		return nil, err
 	}
 
+	drainingTimeout := defaultConnectionDrainingTimeout
+	if o.HealthCheck != nil {
+		drainingTimeout = int64(o.HealthCheck.DeregistrationDelay / time.Second)
+	}
+
 	// Add connection draining to the LoadBalancer.
 	if _, err := m.elb.ModifyLoadBalancerAttributes(&elb.ModifyLoadBalancerAttributesInput{
 		LoadBalancerAttributes: &elb.LoadBalancerAttributes{
 			ConnectionDraining: &elb.ConnectionDraining{
 				Enabled: aws.Bool(true),
-				Timeout: aws.Int64(defaultConnectionDrainingTimeout),
+				Timeout: aws.Int64(drainingTimeout),
 			},
 			CrossZoneLoadBalancing: &elb.CrossZoneLoadBalancing{
 				Enabled: aws.Bool(true),
@@ -113,6 +122,12 @@ func (m *ELBManager) CreateLoadBalancer(ctx context.Context, o CreateLoadBalance
 		return nil, err
 	}
 
+	if o.HealthCheck != nil {
+		if err := m.configureHealthCheck(ctx, *input.LoadBalancerName, port, o.HealthCheck); err != nil {
+			return nil, err
+		}
+	}
+
 	return &LoadBalancer{
 		Name:         *input.LoadBalancerName,
 		DNSName:      *out.DNSName,
@@ -124,7 +139,27 @@ func (m *ELBManager) CreateLoadBalancer(ctx context.Context, o CreateLoadBalance
 
 func (m *ELBManager) UpdateLoadBalancer(ctx context.Context, opts UpdateLoadBalancerOpts) error {
 	if opts.SSLCert != nil {
-		if err := m.updateSSLCert(ctx, opts.Name, *opts.SSLCert); err != nil {
+		sslPort := opts.SSLPort
+		if sslPort == 0 {
+			sslPort = 443
+		}
+
+		if err := m.updateSSLCert(ctx, opts.Name, sslPort, *opts.SSLCert); err != nil {
+			return err
+		}
+	}
+
+	if len(opts.Tags) > 0 {
+		if _, err := m.elb.AddTags(&elb.AddTagsInput{
+			LoadBalancerNames: []*string{aws.String(opts.Name)},
+			Tags:              elbTags(opts.Tags),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if opts.HealthCheck != nil {
+		if err := m.updateHealthCheck(ctx, opts.Name, opts.HealthCheck); err != nil {
 			return err
 		}
 	}
@@ -132,10 +167,58 @@ func (m *ELBManager) UpdateLoadBalancer(ctx context.Context, opts UpdateLoadBala
 	return nil
 }
 
-func (m *ELBManager) updateSSLCert(ctx context.Context, name, certID string) error {
+// updateHealthCheck updates the health check and connection draining timeout
+// of an existing load balancer.
+func (m *ELBManager) updateHealthCheck(ctx context.Context, name string, h *HealthCheck) error {
+	resp, err := m.elb.DescribeLoadBalancers(&elb.DescribeLoadBalancersInput{
+		LoadBalancerNames: []*string{aws.String(name)},
+	})
+	if err != nil {
+		return err
+	}
+
+	lb := loadBalancerFromDescription(resp.LoadBalancerDescriptions[0])
+	if err := m.configureHealthCheck(ctx, name, lb.InstancePort, h); err != nil {
+		return err
+	}
+
+	_, err = m.elb.ModifyLoadBalancerAttributes(&elb.ModifyLoadBalancerAttributesInput{
+		LoadBalancerAttributes: &elb.LoadBalancerAttributes{
+			ConnectionDraining: &elb.ConnectionDraining{
+				Enabled: aws.Bool(true),
+				Timeout: aws.Int64(int64(h.DeregistrationDelay / time.Second)),
+			},
+		},
+		LoadBalancerName: aws.String(name),
+	})
+	return err
+}
+
+// configureHealthCheck sets the health check of the load balancer, which checks
+// the given instance port.
+func (m *ELBManager) configureHealthCheck(ctx context.Context, name string, port int64, h *HealthCheck) error {
+	target := fmt.Sprintf("TCP:%d", port)
+	if h.Path != "" {
+		target = fmt.Sprintf("HTTP:%d%s", port, h.Path)
+	}
+
+	_, err := m.elb.ConfigureHealthCheck(&elb.ConfigureHealthCheckInput{
+		LoadBalancerName: aws.String(name),
+		HealthCheck: &elb.HealthCheck{
+			Target:             aws.String(target),
+			Interval:           aws.Int64(int64(h.Interval / time.Second)),
+			Timeout:            aws.Int64(int64(h.Timeout / time.Second)),
+			HealthyThreshold:   aws.Int64(h.HealthyThreshold),
+			UnhealthyThreshold: aws.Int64(h.UnhealthyThreshold),
+		},
+	})
+	return err
+}
+
+func (m *ELBManager) updateSSLCert(ctx context.Context, name string, port int64, certID string) error {
 	_, err := m.elb.SetLoadBalancerListenerSSLCertificate(&elb.SetLoadBalancerListenerSSLCertificateInput{
 		LoadBalancerName: aws.String(name),
-		LoadBalancerPort: aws.Int64(443),
+		LoadBalancerPort: aws.Int64(port),
 		SSLCertificateId: aws.String(certID),
 	})
 	return err
@@ -277,7 +360,33 @@ func newName() string {
 // elbListeners returns a suitable list of listeners. We listen on post 80 by default.
 // If certID is not empty an SSL listener will be added to the list. certID should be
 // the Amazon Resource Name (ARN) of the server certificate.
-func elbListeners(port int64, certID string) []*elb.Listener {
+//
+// For the tcp and ssl protocols, a single listener is returned on the port
+// from the options, which forwards tcp to the instance.
+func elbListeners(port int64, o CreateLoadBalancerOpts) []*elb.Listener {
+	switch o.Protocol {
+	case "tcp":
+		return []*elb.Listener{
+			{
+				InstancePort:     aws.Int64(port),
+				LoadBalancerPort: aws.Int64(o.Port),
+				Protocol:         aws.String("tcp"),
+				InstanceProtocol: aws.String("tcp"),
+			},
+		}
+	case "ssl":
+		return []*elb.Listener{
+			{
+				InstancePort:     aws.Int64(port),
+				LoadBalancerPort: aws.Int64(o.Port),
+				SSLCertificateId: aws.String(o.SSLCert),
+				Protocol:         aws.String("ssl"),
+				InstanceProtocol: aws.String("tcp"),
+			},
+		}
+	}
+
+	certID := o.SSLCert
 	listeners := []*elb.Listener{
 		{
 			InstancePort:     aws.Int64(port),
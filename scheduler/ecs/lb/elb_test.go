@@ -2,6 +2,7 @@ package lb
 
 import (
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/elb"
@@ -54,6 +55,112 @@ func TestELB_CreateLoadBalancer(t *testing.T) {
 	c.AssertExpectations(t)
 }
 
+func TestELB_CreateLoadBalancer_HealthCheck(t *testing.T) {
+	c := new(mockELBClient)
+	m := newTestELBManager()
+	m.elb = c
+
+	c.On("CreateLoadBalancer", mock.Anything).Return(&elb.CreateLoadBalancerOutput{
+		DNSName: aws.String("acme-inc.us-east-1.elb.amazonaws.com"),
+	}, nil)
+
+	c.On("ModifyLoadBalancerAttributes", &elb.ModifyLoadBalancerAttributesInput{
+		LoadBalancerName: aws.String("acme-inc"),
+		LoadBalancerAttributes: &elb.LoadBalancerAttributes{
+			ConnectionDraining: &elb.ConnectionDraining{
+				Enabled: aws.Bool(true),
+				Timeout: aws.Int64(120),
+			},
+			CrossZoneLoadBalancing: &elb.CrossZoneLoadBalancing{
+				Enabled: aws.Bool(true),
+			},
+		},
+	}).Return(&elb.ModifyLoadBalancerAttributesOutput{}, nil)
+
+	c.On("ConfigureHealthCheck", &elb.ConfigureHealthCheckInput{
+		LoadBalancerName: aws.String("acme-inc"),
+		HealthCheck: &elb.HealthCheck{
+			Target:             aws.String("HTTP:9000/health"),
+			Interval:           aws.Int64(30),
+			Timeout:            aws.Int64(5),
+			HealthyThreshold:   aws.Int64(3),
+			UnhealthyThreshold: aws.Int64(10),
+		},
+	}).Return(&elb.ConfigureHealthCheckOutput{}, nil)
+
+	_, err := m.CreateLoadBalancer(context.Background(), CreateLoadBalancerOpts{
+		HealthCheck: &HealthCheck{
+			Path:                "/health",
+			Interval:            30 * time.Second,
+			Timeout:             5 * time.Second,
+			HealthyThreshold:    3,
+			UnhealthyThreshold:  10,
+			DeregistrationDelay: 2 * time.Minute,
+		},
+	})
+	assert.NoError(t, err)
+
+	c.AssertExpectations(t)
+}
+
+func TestELBListeners(t *testing.T) {
+	tests := []struct {
+		opts      CreateLoadBalancerOpts
+		listeners []*elb.Listener
+	}{
+		{
+			CreateLoadBalancerOpts{},
+			[]*elb.Listener{
+				{InstancePort: aws.Int64(9000), LoadBalancerPort: aws.Int64(80), Protocol: aws.String("http"), InstanceProtocol: aws.String("http")},
+			},
+		},
+		{
+			CreateLoadBalancerOpts{SSLCert: "cert"},
+			[]*elb.Listener{
+				{InstancePort: aws.Int64(9000), LoadBalancerPort: aws.Int64(80), Protocol: aws.String("http"), InstanceProtocol: aws.String("http")},
+				{InstancePort: aws.Int64(9000), LoadBalancerPort: aws.Int64(443), SSLCertificateId: aws.String("cert"), Protocol: aws.String("https"), InstanceProtocol: aws.String("http")},
+			},
+		},
+		{
+			CreateLoadBalancerOpts{Protocol: "tcp", Port: 6379},
+			[]*elb.Listener{
+				{InstancePort: aws.Int64(9000), LoadBalancerPort: aws.Int64(6379), Protocol: aws.String("tcp"), InstanceProtocol: aws.String("tcp")},
+			},
+		},
+		{
+			CreateLoadBalancerOpts{Protocol: "ssl", Port: 465, SSLCert: "cert"},
+			[]*elb.Listener{
+				{InstancePort: aws.Int64(9000), LoadBalancerPort: aws.Int64(465), SSLCertificateId: aws.String("cert"), Protocol: aws.String("ssl"), InstanceProtocol: aws.String("tcp")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.listeners, elbListeners(9000, tt.opts))
+	}
+}
+
+func TestELB_UpdateLoadBalancer_SSLPort(t *testing.T) {
+	c := new(mockELBClient)
+	m := newTestELBManager()
+	m.elb = c
+
+	c.On("SetLoadBalancerListenerSSLCertificate", &elb.SetLoadBalancerListenerSSLCertificateInput{
+		LoadBalancerName: aws.String("acme-inc"),
+		LoadBalancerPort: aws.Int64(465),
+		SSLCertificateId: aws.String("newcert"),
+	}).Return(&elb.SetLoadBalancerListenerSSLCertificateOutput{}, nil)
+
+	err := m.UpdateLoadBalancer(context.Background(), UpdateLoadBalancerOpts{
+		Name:    "acme-inc",
+		SSLCert: aws.String("newcert"),
+		SSLPort: 465,
+	})
+	assert.NoError(t, err)
+
+	c.AssertExpectations(t)
+}
+
 func TestELB_UpdateLoadBalancer(t *testing.T) {
 	c := new(mockELBClient)
 	m := newTestELBManager()
@@ -74,6 +181,41 @@ func TestELB_UpdateLoadBalancer(t *testing.T) {
 	c.AssertExpectations(t)
 }
 
+func TestELB_UpdateLoadBalancer_Tags(t *testing.T) {
+	c := new(mockELBClient)
+	m := newTestELBManager()
+	m.elb = c
+
+	c.On("AddTags", &elb.AddTagsInput{
+		LoadBalancerNames: []*string{aws.String("acme-inc")},
+		Tags: []*elb.Tag{
+			{Key: aws.String(AppTag), Value: aws.String("acme-corp")},
+		},
+	}).Return(&elb.AddTagsOutput{}, nil)
+
+	err := m.UpdateLoadBalancer(context.Background(), UpdateLoadBalancerOpts{
+		Name: "acme-inc",
+		Tags: map[string]string{AppTag: "acme-corp"},
+	})
+	assert.NoError(t, err)
+
+	c.AssertExpectations(t)
+}
+
+func TestCNAMEManager_RenameCNAME(t *testing.T) {
+	ns := newTestNameserver("FAKEZONE")
+	m := WithCNAME(newTestELBManager(), ns)
+
+	err := m.RenameCNAME(context.Background(), &LoadBalancer{
+		Name:    "acme-inc",
+		DNSName: "acme-inc.us-east-1.elb.amazonaws.com",
+		Tags:    map[string]string{AppTag: "acme-inc"},
+	}, "acme-corp")
+	assert.NoError(t, err)
+	assert.True(t, ns.CNAMECalled)
+	assert.True(t, ns.DeleteCNAMECalled)
+}
+
 func TestELB_DestroyLoadBalancer(t *testing.T) {
 	c := new(mockELBClient)
 	m := newTestELBManager()
@@ -335,6 +477,11 @@ func (m *mockELBClient) SetLoadBalancerListenerSSLCertificate(input *elb.SetLoad
 	return args.Get(0).(*elb.SetLoadBalancerListenerSSLCertificateOutput), args.Error(1)
 }
 
+func (m *mockELBClient) AddTags(input *elb.AddTagsInput) (*elb.AddTagsOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*elb.AddTagsOutput), args.Error(1)
+}
+
 func (m *mockELBClient) DescribeLoadBalancers(input *elb.DescribeLoadBalancersInput) (*elb.DescribeLoadBalancersOutput, error) {
 	args := m.Called(input)
 	return args.Get(0).(*elb.DescribeLoadBalancersOutput), args.Error(1)
@@ -345,6 +492,11 @@ func (m *mockELBClient) DeleteLoadBalancer(input *elb.DeleteLoadBalancerInput) (
 	return args.Get(0).(*elb.DeleteLoadBalancerOutput), args.Error(1)
 }
 
+func (m *mockELBClient) ConfigureHealthCheck(input *elb.ConfigureHealthCheckInput) (*elb.ConfigureHealthCheckOutput, error) {
+	args := m.Called(input)
+	return args.Get(0).(*elb.ConfigureHealthCheckOutput), args.Error(1)
+}
+
 func (m *mockELBClient) DescribeTags(input *elb.DescribeTagsInput) (*elb.DescribeTagsOutput, error) {
 	args := m.Called(input)
 	return args.Get(0).(*elb.DescribeTagsOutput), args.Error(1)
@@ -1,7 +1,11 @@
 // package lb provides an abstraction around creating load balancers.
 package lb
 
-import "golang.org/x/net/context"
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
 
 const AppTag = "App"
 
@@ -16,6 +20,18 @@ type CreateLoadBalancerOpts struct {
 
 	// The SSL Certificate
 	SSLCert string
+
+	// The protocol that the load balancer listens with. Can be "tcp" or
+	// "ssl". When empty, the load balancer listens for http on port 80,
+	// and https on port 443 if an SSLCert is provided.
+	Protocol string
+
+	// For the tcp and ssl protocols, the port that the load balancer
+	// listens on.
+	Port int64
+
+	// If provided, a custom health check for the load balancer.
+	HealthCheck *HealthCheck
 }
 
 // UpdateLoadBalancerOpts are options that can be provided when updating an
@@ -26,6 +42,43 @@ type UpdateLoadBalancerOpts struct {
 
 	// The SSL Certificate
 	SSLCert *string
+
+	// The port of the listener that SSLCert is attached to. Defaults to
+	// 443.
+	SSLPort int64
+
+	// If provided, these tags will be added to the load balancer, replacing
+	// the value of any existing tags with the same key.
+	Tags map[string]string
+
+	// If provided, the health check will be updated.
+	HealthCheck *HealthCheck
+}
+
+// HealthCheck configures how a load balancer checks the health of the instances
+// behind it.
+type HealthCheck struct {
+	// If provided, an HTTP GET request is made to this path. Otherwise, a
+	// TCP connection is opened.
+	Path string
+
+	// The time between health checks.
+	Interval time.Duration
+
+	// The time to wait for a response before the check fails.
+	Timeout time.Duration
+
+	// The number of consecutive successful checks before an instance is
+	// healthy.
+	HealthyThreshold int64
+
+	// The number of consecutive failed checks before an instance is
+	// unhealthy.
+	UnhealthyThreshold int64
+
+	// The time to let in flight requests complete when an instance is
+	// deregistered.
+	DeregistrationDelay time.Duration
 }
 
 // LoadBalancer represents a load balancer.
@@ -118,6 +171,20 @@ func (m *CNAMEManager) RemoveCNAME(ctx context.Context, lb *LoadBalancer) error
 	return nil
 }
 
+// RenameCNAME points a CNAME record for name at the LoadBalancers DNSName,
+// then removes the CNAME record that was created for the old `App` tag.
+func (m *CNAMEManager) RenameCNAME(ctx context.Context, lb *LoadBalancer, name string) error {
+	if err := m.CreateCNAME(name, lb.DNSName); err != nil {
+		return err
+	}
+
+	if n, ok := lb.Tags[AppTag]; ok && n != name {
+		return m.DeleteCNAME(n, lb.DNSName)
+	}
+
+	return nil
+}
+
 func (m *CNAMEManager) RemoveCNAMEs(ctx context.Context, tags map[string]string) error {
 	lbs, err := m.LoadBalancers(ctx, tags)
 	if err != nil {
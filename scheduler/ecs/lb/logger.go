@@ -32,6 +32,8 @@ func (m *LoggedManager) CreateLoadBalancer(ctx context.Context, o CreateLoadBala
 		"external", o.External,
 		"dns-name", dnsName,
 		"cert", o.SSLCert,
+		"protocol", o.Protocol,
+		"port", o.Port,
 	)
 	return lb, err
 }
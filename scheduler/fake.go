@@ -76,6 +76,10 @@ func (m *FakeScheduler) Stop(ctx context.Context, instanceID string) error {
 	return nil
 }
 
+func (m *FakeScheduler) RunsAttached() bool {
+	return true
+}
+
 func (m *FakeScheduler) Run(ctx context.Context, app *App, p *Process, in io.Reader, out io.Writer) error {
 	if out != nil {
 		fmt.Fprintf(out, "Fake output for `%s` on %s\n", p.Command, app.Name)
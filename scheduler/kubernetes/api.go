@@ -0,0 +1,188 @@
+package kubernetes
+
+import "time"
+
+// This file contains the subset of the Kubernetes API objects that the
+// scheduler interacts with. Only the fields that Empire reads or writes are
+// defined, which keeps us from having to vendor the full Kubernetes client
+// libraries.
+
+// TypeMeta describes the kind and API version of an object.
+type TypeMeta struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+}
+
+// ObjectMeta is metadata that all persisted resources must have.
+type ObjectMeta struct {
+	Name              string            `json:"name,omitempty"`
+	Namespace         string            `json:"namespace,omitempty"`
+	ResourceVersion   string            `json:"resourceVersion,omitempty"`
+	Generation        int64             `json:"generation,omitempty"`
+	CreationTimestamp *time.Time        `json:"creationTimestamp,omitempty"`
+	Labels            map[string]string `json:"labels,omitempty"`
+	Annotations       map[string]string `json:"annotations,omitempty"`
+}
+
+// LabelSelector selects resources by their labels.
+type LabelSelector struct {
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+}
+
+// EnvVar represents an environment variable present in a Container.
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// ContainerPortSpec represents a network port in a single container.
+type ContainerPortSpec struct {
+	ContainerPort int32  `json:"containerPort"`
+	Protocol      string `json:"protocol,omitempty"`
+}
+
+// ResourceRequirements describes the compute resource requirements of a
+// Container. Quantities are encoded as strings (e.g. "512Mi", "250m").
+type ResourceRequirements struct {
+	Limits   map[string]string `json:"limits,omitempty"`
+	Requests map[string]string `json:"requests,omitempty"`
+}
+
+// Container is a single application container that runs within a Pod.
+type Container struct {
+	Name      string               `json:"name"`
+	Image     string               `json:"image"`
+	Command   []string             `json:"command,omitempty"`
+	Env       []EnvVar             `json:"env,omitempty"`
+	Ports     []ContainerPortSpec  `json:"ports,omitempty"`
+	Resources ResourceRequirements `json:"resources,omitempty"`
+}
+
+// PodSpec is a description of a Pod.
+type PodSpec struct {
+	Containers    []Container `json:"containers"`
+	RestartPolicy string      `json:"restartPolicy,omitempty"`
+	NodeName      string      `json:"nodeName,omitempty"`
+}
+
+// PodTemplateSpec describes the data a Pod should have when created from a
+// template.
+type PodTemplateSpec struct {
+	ObjectMeta `json:"metadata,omitempty"`
+	Spec       PodSpec `json:"spec"`
+}
+
+// PodStatus represents information about the status of a Pod.
+type PodStatus struct {
+	Phase     string     `json:"phase,omitempty"`
+	HostIP    string     `json:"hostIP,omitempty"`
+	StartTime *time.Time `json:"startTime,omitempty"`
+}
+
+// Pod is a collection of containers that run on a host.
+type Pod struct {
+	TypeMeta
+	ObjectMeta `json:"metadata,omitempty"`
+	Spec       PodSpec   `json:"spec"`
+	Status     PodStatus `json:"status,omitempty"`
+}
+
+// DeploymentSpec is the specification of the desired behavior of a
+// Deployment.
+type DeploymentSpec struct {
+	Replicas *int32          `json:"replicas,omitempty"`
+	Selector *LabelSelector  `json:"selector,omitempty"`
+	Template PodTemplateSpec `json:"template"`
+}
+
+// DeploymentStatus is the most recently observed status of a Deployment.
+type DeploymentStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	Replicas           int32 `json:"replicas,omitempty"`
+	UpdatedReplicas    int32 `json:"updatedReplicas,omitempty"`
+	AvailableReplicas  int32 `json:"availableReplicas,omitempty"`
+
+	Conditions []DeploymentCondition `json:"conditions,omitempty"`
+}
+
+// DeploymentCondition describes the state of a Deployment at a certain point.
+type DeploymentCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Deployment enables declarative updates for Pods.
+type Deployment struct {
+	TypeMeta
+	ObjectMeta `json:"metadata,omitempty"`
+	Spec       DeploymentSpec   `json:"spec"`
+	Status     DeploymentStatus `json:"status,omitempty"`
+}
+
+// ServicePort contains information on a Service's port.
+type ServicePort struct {
+	Name       string `json:"name,omitempty"`
+	Protocol   string `json:"protocol,omitempty"`
+	Port       int32  `json:"port"`
+	TargetPort int32  `json:"targetPort,omitempty"`
+}
+
+// ServiceSpec describes the attributes that a user creates on a Service.
+type ServiceSpec struct {
+	Type      string            `json:"type,omitempty"`
+	ClusterIP string            `json:"clusterIP,omitempty"`
+	Selector  map[string]string `json:"selector,omitempty"`
+	Ports     []ServicePort     `json:"ports,omitempty"`
+}
+
+// Service is a named abstraction of a software service, exposed through a
+// port and a selector of Pods.
+type Service struct {
+	TypeMeta
+	ObjectMeta `json:"metadata,omitempty"`
+	Spec       ServiceSpec `json:"spec"`
+}
+
+// JobSpec describes how a Job execution will look.
+type JobSpec struct {
+	BackoffLimit *int32          `json:"backoffLimit,omitempty"`
+	Template     PodTemplateSpec `json:"template"`
+}
+
+// Job represents the configuration of a single job.
+type Job struct {
+	TypeMeta
+	ObjectMeta `json:"metadata,omitempty"`
+	Spec       JobSpec `json:"spec"`
+}
+
+// JobTemplateSpec describes the data a Job should have when created from a
+// template.
+type JobTemplateSpec struct {
+	ObjectMeta `json:"metadata,omitempty"`
+	Spec       JobSpec `json:"spec"`
+}
+
+// CronJobSpec describes how a job execution will look and when it will
+// actually run.
+type CronJobSpec struct {
+	Schedule          string          `json:"schedule"`
+	ConcurrencyPolicy string          `json:"concurrencyPolicy,omitempty"`
+	JobTemplate       JobTemplateSpec `json:"jobTemplate"`
+}
+
+// CronJob represents the configuration of a single cron job.
+type CronJob struct {
+	TypeMeta
+	ObjectMeta `json:"metadata,omitempty"`
+	Spec       CronJobSpec `json:"spec"`
+}
+
+// Status is the object returned by the API server when an operation fails.
+type Status struct {
+	Message string `json:"message,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Code    int    `json:"code,omitempty"`
+}
@@ -0,0 +1,315 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+// Client defines the subset of the Kubernetes API that the Scheduler uses.
+// Implementations should return an error that satisfies IsNotFound when the
+// requested resource does not exist.
+type Client interface {
+	ListDeployments(ctx context.Context, namespace string, selector map[string]string) ([]*Deployment, error)
+	GetDeployment(ctx context.Context, namespace, name string) (*Deployment, error)
+	CreateDeployment(ctx context.Context, namespace string, d *Deployment) (*Deployment, error)
+	UpdateDeployment(ctx context.Context, namespace string, d *Deployment) (*Deployment, error)
+	DeleteDeployment(ctx context.Context, namespace, name string) error
+
+	ListServices(ctx context.Context, namespace string, selector map[string]string) ([]*Service, error)
+	GetService(ctx context.Context, namespace, name string) (*Service, error)
+	CreateService(ctx context.Context, namespace string, s *Service) (*Service, error)
+	UpdateService(ctx context.Context, namespace string, s *Service) (*Service, error)
+	DeleteService(ctx context.Context, namespace, name string) error
+
+	ListCronJobs(ctx context.Context, namespace string, selector map[string]string) ([]*CronJob, error)
+	GetCronJob(ctx context.Context, namespace, name string) (*CronJob, error)
+	CreateCronJob(ctx context.Context, namespace string, j *CronJob) (*CronJob, error)
+	UpdateCronJob(ctx context.Context, namespace string, j *CronJob) (*CronJob, error)
+	DeleteCronJob(ctx context.Context, namespace, name string) error
+
+	ListJobs(ctx context.Context, namespace string, selector map[string]string) ([]*Job, error)
+	CreateJob(ctx context.Context, namespace string, j *Job) (*Job, error)
+	DeleteJob(ctx context.Context, namespace, name string) error
+
+	ListPods(ctx context.Context, namespace string, selector map[string]string) ([]*Pod, error)
+	GetPod(ctx context.Context, namespace, name string) (*Pod, error)
+	DeletePod(ctx context.Context, namespace, name string) error
+}
+
+// StatusError is returned when the Kubernetes API responds with a non 2xx
+// status code.
+type StatusError struct {
+	Status
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("kubernetes: %s (%d)", e.Message, e.Code)
+}
+
+// IsNotFound returns true if the error indicates that the resource does not
+// exist.
+func IsNotFound(err error) bool {
+	if err, ok := err.(*StatusError); ok {
+		return err.Code == http.StatusNotFound
+	}
+	return false
+}
+
+// API group paths for the resources that we use.
+const (
+	coreV1  = "/api/v1"
+	appsV1  = "/apis/apps/v1"
+	batchV1 = "/apis/batch/v1"
+)
+
+// HTTPClient is a Client implementation that talks directly to the
+// Kubernetes REST API.
+type HTTPClient struct {
+	// The base URL of the Kubernetes API server (e.g.
+	// https://kubernetes.default.svc).
+	URL string
+
+	// If provided, this bearer token will be used to authenticate requests.
+	Token string
+
+	// The http.Client to use to make requests. Defaults to
+	// http.DefaultClient.
+	Client *http.Client
+}
+
+// NewClient returns a new HTTPClient instance.
+func NewClient(url, token string) *HTTPClient {
+	return &HTTPClient{
+		URL:   url,
+		Token: token,
+	}
+}
+
+func (c *HTTPClient) ListDeployments(ctx context.Context, namespace string, selector map[string]string) ([]*Deployment, error) {
+	var list struct {
+		Items []*Deployment `json:"items"`
+	}
+	err := c.list(ctx, resourcePath(appsV1, namespace, "deployments", ""), selector, &list)
+	return list.Items, err
+}
+
+func (c *HTTPClient) GetDeployment(ctx context.Context, namespace, name string) (*Deployment, error) {
+	var d Deployment
+	err := c.do(ctx, "GET", resourcePath(appsV1, namespace, "deployments", name), nil, &d)
+	return &d, err
+}
+
+func (c *HTTPClient) CreateDeployment(ctx context.Context, namespace string, d *Deployment) (*Deployment, error) {
+	d.TypeMeta = TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"}
+	var resp Deployment
+	err := c.do(ctx, "POST", resourcePath(appsV1, namespace, "deployments", ""), d, &resp)
+	return &resp, err
+}
+
+func (c *HTTPClient) UpdateDeployment(ctx context.Context, namespace string, d *Deployment) (*Deployment, error) {
+	d.TypeMeta = TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"}
+	var resp Deployment
+	err := c.do(ctx, "PUT", resourcePath(appsV1, namespace, "deployments", d.Name), d, &resp)
+	return &resp, err
+}
+
+func (c *HTTPClient) DeleteDeployment(ctx context.Context, namespace, name string) error {
+	return c.delete(ctx, resourcePath(appsV1, namespace, "deployments", name))
+}
+
+func (c *HTTPClient) ListServices(ctx context.Context, namespace string, selector map[string]string) ([]*Service, error) {
+	var list struct {
+		Items []*Service `json:"items"`
+	}
+	err := c.list(ctx, resourcePath(coreV1, namespace, "services", ""), selector, &list)
+	return list.Items, err
+}
+
+func (c *HTTPClient) GetService(ctx context.Context, namespace, name string) (*Service, error) {
+	var s Service
+	err := c.do(ctx, "GET", resourcePath(coreV1, namespace, "services", name), nil, &s)
+	return &s, err
+}
+
+func (c *HTTPClient) CreateService(ctx context.Context, namespace string, s *Service) (*Service, error) {
+	s.TypeMeta = TypeMeta{APIVersion: "v1", Kind: "Service"}
+	var resp Service
+	err := c.do(ctx, "POST", resourcePath(coreV1, namespace, "services", ""), s, &resp)
+	return &resp, err
+}
+
+func (c *HTTPClient) UpdateService(ctx context.Context, namespace string, s *Service) (*Service, error) {
+	s.TypeMeta = TypeMeta{APIVersion: "v1", Kind: "Service"}
+	var resp Service
+	err := c.do(ctx, "PUT", resourcePath(coreV1, namespace, "services", s.Name), s, &resp)
+	return &resp, err
+}
+
+func (c *HTTPClient) DeleteService(ctx context.Context, namespace, name string) error {
+	return c.delete(ctx, resourcePath(coreV1, namespace, "services", name))
+}
+
+func (c *HTTPClient) ListCronJobs(ctx context.Context, namespace string, selector map[string]string) ([]*CronJob, error) {
+	var list struct {
+		Items []*CronJob `json:"items"`
+	}
+	err := c.list(ctx, resourcePath(batchV1, namespace, "cronjobs", ""), selector, &list)
+	return list.Items, err
+}
+
+func (c *HTTPClient) GetCronJob(ctx context.Context, namespace, name string) (*CronJob, error) {
+	var j CronJob
+	err := c.do(ctx, "GET", resourcePath(batchV1, namespace, "cronjobs", name), nil, &j)
+	return &j, err
+}
+
+func (c *HTTPClient) CreateCronJob(ctx context.Context, namespace string, j *CronJob) (*CronJob, error) {
+	j.TypeMeta = TypeMeta{APIVersion: "batch/v1", Kind: "CronJob"}
+	var resp CronJob
+	err := c.do(ctx, "POST", resourcePath(batchV1, namespace, "cronjobs", ""), j, &resp)
+	return &resp, err
+}
+
+func (c *HTTPClient) UpdateCronJob(ctx context.Context, namespace string, j *CronJob) (*CronJob, error) {
+	j.TypeMeta = TypeMeta{APIVersion: "batch/v1", Kind: "CronJob"}
+	var resp CronJob
+	err := c.do(ctx, "PUT", resourcePath(batchV1, namespace, "cronjobs", j.Name), j, &resp)
+	return &resp, err
+}
+
+func (c *HTTPClient) DeleteCronJob(ctx context.Context, namespace, name string) error {
+	return c.delete(ctx, resourcePath(batchV1, namespace, "cronjobs", name))
+}
+
+func (c *HTTPClient) ListJobs(ctx context.Context, namespace string, selector map[string]string) ([]*Job, error) {
+	var list struct {
+		Items []*Job `json:"items"`
+	}
+	err := c.list(ctx, resourcePath(batchV1, namespace, "jobs", ""), selector, &list)
+	return list.Items, err
+}
+
+func (c *HTTPClient) CreateJob(ctx context.Context, namespace string, j *Job) (*Job, error) {
+	j.TypeMeta = TypeMeta{APIVersion: "batch/v1", Kind: "Job"}
+	var resp Job
+	err := c.do(ctx, "POST", resourcePath(batchV1, namespace, "jobs", ""), j, &resp)
+	return &resp, err
+}
+
+func (c *HTTPClient) DeleteJob(ctx context.Context, namespace, name string) error {
+	return c.delete(ctx, resourcePath(batchV1, namespace, "jobs", name))
+}
+
+func (c *HTTPClient) ListPods(ctx context.Context, namespace string, selector map[string]string) ([]*Pod, error) {
+	var list struct {
+		Items []*Pod `json:"items"`
+	}
+	err := c.list(ctx, resourcePath(coreV1, namespace, "pods", ""), selector, &list)
+	return list.Items, err
+}
+
+func (c *HTTPClient) GetPod(ctx context.Context, namespace, name string) (*Pod, error) {
+	var p Pod
+	err := c.do(ctx, "GET", resourcePath(coreV1, namespace, "pods", name), nil, &p)
+	return &p, err
+}
+
+func (c *HTTPClient) DeletePod(ctx context.Context, namespace, name string) error {
+	return c.delete(ctx, resourcePath(coreV1, namespace, "pods", name))
+}
+
+func (c *HTTPClient) list(ctx context.Context, path string, selector map[string]string, v interface{}) error {
+	if len(selector) > 0 {
+		path = path + "?" + url.Values{"labelSelector": {labelSelector(selector)}}.Encode()
+	}
+	return c.do(ctx, "GET", path, nil, v)
+}
+
+// delete deletes the resource at path, cascading the deletion to any
+// dependent objects (e.g. the ReplicaSets and Pods owned by a Deployment).
+func (c *HTTPClient) delete(ctx context.Context, path string) error {
+	opts := map[string]string{
+		"kind":              "DeleteOptions",
+		"apiVersion":        "v1",
+		"propagationPolicy": "Background",
+	}
+	return c.do(ctx, "DELETE", path, opts, nil)
+}
+
+func (c *HTTPClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(c.URL, "/")+path, &body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		raw, _ := ioutil.ReadAll(resp.Body)
+		e := &StatusError{}
+		if err := json.Unmarshal(raw, &e.Status); err != nil || e.Message == "" {
+			e.Message = strings.TrimSpace(string(raw))
+		}
+		e.Code = resp.StatusCode
+		return e
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// resourcePath returns the path to a namespaced resource. If name is empty,
+// the path to the collection is returned.
+func resourcePath(group, namespace, resource, name string) string {
+	p := fmt.Sprintf("%s/namespaces/%s/%s", group, namespace, resource)
+	if name != "" {
+		p = p + "/" + name
+	}
+	return p
+}
+
+// labelSelector encodes the labels as an equality based label selector.
+func labelSelector(labels map[string]string) string {
+	var selectors []string
+	for k, v := range labels {
+		selectors = append(selectors, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(selectors)
+	return strings.Join(selectors, ",")
+}
@@ -0,0 +1,69 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPClient_ListDeployments(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "GET", r.Method)
+		assert.Equal(t, "/apis/apps/v1/namespaces/default/deployments", r.URL.Path)
+		assert.Equal(t, "empire.app.id=1234,empire.app.process=web", r.URL.Query().Get("labelSelector"))
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"items":[{"metadata":{"name":"acme-inc-web","labels":{"empire.app.id":"1234"}},"spec":{"replicas":2}}]}`))
+	}))
+	defer s.Close()
+
+	c := NewClient(s.URL, "token")
+	deployments, err := c.ListDeployments(ctx, "default", map[string]string{
+		"empire.app.id":      "1234",
+		"empire.app.process": "web",
+	})
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(deployments)) {
+		assert.Equal(t, "acme-inc-web", deployments[0].Name)
+		assert.Equal(t, int32(2), *deployments[0].Spec.Replicas)
+	}
+}
+
+func TestHTTPClient_CreateService(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "/api/v1/namespaces/default/services", r.URL.Path)
+
+		var svc Service
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&svc))
+		assert.Equal(t, "v1", svc.APIVersion)
+		assert.Equal(t, "Service", svc.Kind)
+		assert.Equal(t, "acme-inc-web", svc.Name)
+
+		svc.ResourceVersion = "1"
+		json.NewEncoder(w).Encode(svc)
+	}))
+	defer s.Close()
+
+	c := NewClient(s.URL, "")
+	svc, err := c.CreateService(ctx, "default", &Service{
+		ObjectMeta: ObjectMeta{Name: "acme-inc-web"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", svc.ResourceVersion)
+}
+
+func TestHTTPClient_NotFound(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"kind":"Status","message":"deployments.apps \"acme-inc-web\" not found","reason":"NotFound","code":404}`))
+	}))
+	defer s.Close()
+
+	c := NewClient(s.URL, "")
+	_, err := c.GetDeployment(ctx, "default", "acme-inc-web")
+	assert.True(t, IsNotFound(err))
+	assert.EqualError(t, err, `kubernetes: deployments.apps "acme-inc-web" not found (404)`)
+}
@@ -0,0 +1,310 @@
+package kubernetes
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// FakeClient is an in memory implementation of the Client interface, which
+// can be used in tests. Like the real API, objects returned from the client
+// are copies of what's stored.
+type FakeClient struct {
+	sync.Mutex
+
+	Deployments map[string]*Deployment
+	Services    map[string]*Service
+	CronJobs    map[string]*CronJob
+	Jobs        map[string]*Job
+	Pods        map[string]*Pod
+
+	version int
+}
+
+// NewFakeClient returns a new FakeClient with no resources.
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		Deployments: make(map[string]*Deployment),
+		Services:    make(map[string]*Service),
+		CronJobs:    make(map[string]*CronJob),
+		Jobs:        make(map[string]*Job),
+		Pods:        make(map[string]*Pod),
+	}
+}
+
+func (c *FakeClient) ListDeployments(ctx context.Context, namespace string, selector map[string]string) ([]*Deployment, error) {
+	c.Lock()
+	defer c.Unlock()
+	var items []*Deployment
+	for _, d := range c.Deployments {
+		if d.Namespace == namespace && matches(d.Labels, selector) {
+			cp := *d
+			items = append(items, &cp)
+		}
+	}
+	return items, nil
+}
+
+func (c *FakeClient) GetDeployment(ctx context.Context, namespace, name string) (*Deployment, error) {
+	c.Lock()
+	defer c.Unlock()
+	d, ok := c.Deployments[key(namespace, name)]
+	if !ok {
+		return nil, notFound("deployments", name)
+	}
+	cp := *d
+	return &cp, nil
+}
+
+func (c *FakeClient) CreateDeployment(ctx context.Context, namespace string, d *Deployment) (*Deployment, error) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.Deployments[key(namespace, d.Name)]; ok {
+		return nil, alreadyExists("deployments", d.Name)
+	}
+	c.stamp(&d.ObjectMeta, namespace)
+	c.Deployments[key(namespace, d.Name)] = d
+	return d, nil
+}
+
+func (c *FakeClient) UpdateDeployment(ctx context.Context, namespace string, d *Deployment) (*Deployment, error) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.Deployments[key(namespace, d.Name)]; !ok {
+		return nil, notFound("deployments", d.Name)
+	}
+	c.stamp(&d.ObjectMeta, namespace)
+	c.Deployments[key(namespace, d.Name)] = d
+	return d, nil
+}
+
+func (c *FakeClient) DeleteDeployment(ctx context.Context, namespace, name string) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.Deployments[key(namespace, name)]; !ok {
+		return notFound("deployments", name)
+	}
+	delete(c.Deployments, key(namespace, name))
+	return nil
+}
+
+func (c *FakeClient) ListServices(ctx context.Context, namespace string, selector map[string]string) ([]*Service, error) {
+	c.Lock()
+	defer c.Unlock()
+	var items []*Service
+	for _, s := range c.Services {
+		if s.Namespace == namespace && matches(s.Labels, selector) {
+			cp := *s
+			items = append(items, &cp)
+		}
+	}
+	return items, nil
+}
+
+func (c *FakeClient) GetService(ctx context.Context, namespace, name string) (*Service, error) {
+	c.Lock()
+	defer c.Unlock()
+	s, ok := c.Services[key(namespace, name)]
+	if !ok {
+		return nil, notFound("services", name)
+	}
+	cp := *s
+	return &cp, nil
+}
+
+func (c *FakeClient) CreateService(ctx context.Context, namespace string, s *Service) (*Service, error) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.Services[key(namespace, s.Name)]; ok {
+		return nil, alreadyExists("services", s.Name)
+	}
+	c.stamp(&s.ObjectMeta, namespace)
+	c.Services[key(namespace, s.Name)] = s
+	return s, nil
+}
+
+func (c *FakeClient) UpdateService(ctx context.Context, namespace string, s *Service) (*Service, error) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.Services[key(namespace, s.Name)]; !ok {
+		return nil, notFound("services", s.Name)
+	}
+	c.stamp(&s.ObjectMeta, namespace)
+	c.Services[key(namespace, s.Name)] = s
+	return s, nil
+}
+
+func (c *FakeClient) DeleteService(ctx context.Context, namespace, name string) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.Services[key(namespace, name)]; !ok {
+		return notFound("services", name)
+	}
+	delete(c.Services, key(namespace, name))
+	return nil
+}
+
+func (c *FakeClient) ListCronJobs(ctx context.Context, namespace string, selector map[string]string) ([]*CronJob, error) {
+	c.Lock()
+	defer c.Unlock()
+	var items []*CronJob
+	for _, j := range c.CronJobs {
+		if j.Namespace == namespace && matches(j.Labels, selector) {
+			cp := *j
+			items = append(items, &cp)
+		}
+	}
+	return items, nil
+}
+
+func (c *FakeClient) GetCronJob(ctx context.Context, namespace, name string) (*CronJob, error) {
+	c.Lock()
+	defer c.Unlock()
+	j, ok := c.CronJobs[key(namespace, name)]
+	if !ok {
+		return nil, notFound("cronjobs", name)
+	}
+	cp := *j
+	return &cp, nil
+}
+
+func (c *FakeClient) CreateCronJob(ctx context.Context, namespace string, j *CronJob) (*CronJob, error) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.CronJobs[key(namespace, j.Name)]; ok {
+		return nil, alreadyExists("cronjobs", j.Name)
+	}
+	c.stamp(&j.ObjectMeta, namespace)
+	c.CronJobs[key(namespace, j.Name)] = j
+	return j, nil
+}
+
+func (c *FakeClient) UpdateCronJob(ctx context.Context, namespace string, j *CronJob) (*CronJob, error) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.CronJobs[key(namespace, j.Name)]; !ok {
+		return nil, notFound("cronjobs", j.Name)
+	}
+	c.stamp(&j.ObjectMeta, namespace)
+	c.CronJobs[key(namespace, j.Name)] = j
+	return j, nil
+}
+
+func (c *FakeClient) DeleteCronJob(ctx context.Context, namespace, name string) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.CronJobs[key(namespace, name)]; !ok {
+		return notFound("cronjobs", name)
+	}
+	delete(c.CronJobs, key(namespace, name))
+	return nil
+}
+
+func (c *FakeClient) ListJobs(ctx context.Context, namespace string, selector map[string]string) ([]*Job, error) {
+	c.Lock()
+	defer c.Unlock()
+	var items []*Job
+	for _, j := range c.Jobs {
+		if j.Namespace == namespace && matches(j.Labels, selector) {
+			cp := *j
+			items = append(items, &cp)
+		}
+	}
+	return items, nil
+}
+
+func (c *FakeClient) CreateJob(ctx context.Context, namespace string, j *Job) (*Job, error) {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.Jobs[key(namespace, j.Name)]; ok {
+		return nil, alreadyExists("jobs", j.Name)
+	}
+	c.stamp(&j.ObjectMeta, namespace)
+	c.Jobs[key(namespace, j.Name)] = j
+	return j, nil
+}
+
+func (c *FakeClient) DeleteJob(ctx context.Context, namespace, name string) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.Jobs[key(namespace, name)]; !ok {
+		return notFound("jobs", name)
+	}
+	delete(c.Jobs, key(namespace, name))
+	return nil
+}
+
+func (c *FakeClient) ListPods(ctx context.Context, namespace string, selector map[string]string) ([]*Pod, error) {
+	c.Lock()
+	defer c.Unlock()
+	var items []*Pod
+	for _, p := range c.Pods {
+		if p.Namespace == namespace && matches(p.Labels, selector) {
+			cp := *p
+			items = append(items, &cp)
+		}
+	}
+	return items, nil
+}
+
+func (c *FakeClient) GetPod(ctx context.Context, namespace, name string) (*Pod, error) {
+	c.Lock()
+	defer c.Unlock()
+	p, ok := c.Pods[key(namespace, name)]
+	if !ok {
+		return nil, notFound("pods", name)
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (c *FakeClient) DeletePod(ctx context.Context, namespace, name string) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.Pods[key(namespace, name)]; !ok {
+		return notFound("pods", name)
+	}
+	delete(c.Pods, key(namespace, name))
+	return nil
+}
+
+// stamp sets the server populated metadata on the object.
+func (c *FakeClient) stamp(m *ObjectMeta, namespace string) {
+	c.version++
+	m.Namespace = namespace
+	m.ResourceVersion = fmt.Sprintf("%d", c.version)
+	m.Generation++
+}
+
+func key(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// matches returns true if labels contains all of the key/value pairs in
+// selector.
+func matches(labels, selector map[string]string) bool {
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func notFound(resource, name string) error {
+	return &StatusError{Status{
+		Message: fmt.Sprintf("%s %q not found", resource, name),
+		Reason:  "NotFound",
+		Code:    http.StatusNotFound,
+	}}
+}
+
+func alreadyExists(resource, name string) error {
+	return &StatusError{Status{
+		Message: fmt.Sprintf("%s %q already exists", resource, name),
+		Reason:  "AlreadyExists",
+		Code:    http.StatusConflict,
+	}}
+}
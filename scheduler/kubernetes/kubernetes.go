@@ -1,4 +1,781 @@
 // Package kubernetes implements the Scheduler interface backed by Kubernetes.
 //
-// TODO: Implement this.
+// Each process in an app is mapped onto Kubernetes resources as follows:
+//
+//   - Long running processes are created as a Deployment.
+//   - Processes with an Exposure get a Service of type LoadBalancer in front of
+//     the Deployment.
+//   - Processes with a CRONSchedule are created as a CronJob.
+//   - Detached runs are created as a Job.
+//
+// All resources are labeled with the app id and process type, which is how
+// the scheduler finds them again.
+//
+// Kubernetes has no equivalent of the nproc ulimit, so Process.Nproc is
+// ignored. Attached runs are not supported, and should be handled by wrapping
+// this scheduler with docker.RunAttachedWithDocker.
 package kubernetes
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"code.google.com/p/go-uuid/uuid"
+	. "github.com/remind101/empire/pkg/bytesize"
+	"github.com/remind101/empire/scheduler"
+	"github.com/remind101/pkg/timex"
+	"golang.org/x/net/context"
+)
+
+// For exposed processes, this is the port that the process within the
+// container should bind to. This value is also exposed to the container
+// through the PORT environment variable.
+const ContainerPort = 8080
+
+// DefaultNamespace is the namespace that resources are created in when no
+// Namespace is provided.
+const DefaultNamespace = "default"
+
+const (
+	// Label that determines what app the resource relates to.
+	appLabel = "empire.app.id"
+
+	// Label that determines what the name of the process is.
+	processLabel = "empire.app.process"
+
+	// Label that determines whether a Job is from a one-off run.
+	runLabel = "run"
+
+	// Pod template annotation that is updated to trigger a rolling restart
+	// of a Deployment.
+	restartedAtAnnotation = "empire.restartedAt"
+)
+
+// Annotations used to configure the AWS cloud provider when provisioning
+// load balancers for Services.
+const (
+	awsInternalAnnotation        = "service.beta.kubernetes.io/aws-load-balancer-internal"
+	awsSSLCertAnnotation         = "service.beta.kubernetes.io/aws-load-balancer-ssl-cert"
+	awsSSLPortsAnnotation        = "service.beta.kubernetes.io/aws-load-balancer-ssl-ports"
+	awsBackendProtocolAnnotation = "service.beta.kubernetes.io/aws-load-balancer-backend-protocol"
+)
+
+// The amount of time to wait between polls when waiting for deployments to
+// roll out.
+var defaultPollInterval = 5 * time.Second
+
+// DefaultRolloutTimeout is the default amount of time to wait for a deployment
+// to roll out, before it's considered to have failed.
+const DefaultRolloutTimeout = 10 * time.Minute
+
+// The reason given by the Deployment controller when a deployment hasn't made
+// progress within its progress deadline.
+const progressDeadlineExceeded = "ProgressDeadlineExceeded"
+
+// Scheduler is an implementation of the scheduler.Scheduler interface backed
+// by Kubernetes.
+type Scheduler struct {
+	// The namespace to create resources in. Defaults to DefaultNamespace.
+	Namespace string
+
+	// The amount of time to wait between polls when waiting for a
+	// deployment to roll out.
+	PollInterval time.Duration
+
+	// The maximum amount of time to wait for a deployment to roll out.
+	// Defaults to DefaultRolloutTimeout.
+	RolloutTimeout time.Duration
+
+	client Client
+}
+
+// NewScheduler returns a new Scheduler instance that uses the given client to
+// interact with Kubernetes.
+func NewScheduler(client Client) *Scheduler {
+	return &Scheduler{
+		Namespace:      DefaultNamespace,
+		PollInterval:   defaultPollInterval,
+		RolloutTimeout: DefaultRolloutTimeout,
+		client:         client,
+	}
+}
+
+// Submit creates or updates the Deployments, Services and CronJobs for each
+// process in the app. Resources for processes that no longer exist in the
+// app are removed. If a StatusStream is provided, Submit waits for all of
+// the Deployments to finish rolling out.
+func (s *Scheduler) Submit(ctx context.Context, app *scheduler.App, ss scheduler.StatusStream) error {
+	var deployments []*Deployment
+
+	for _, p := range app.Processes {
+		if p.Schedule != nil {
+			if err := s.upsertCronJob(ctx, app, p); err != nil {
+				return err
+			}
+			continue
+		}
+
+		d, err := s.upsertDeployment(ctx, app, p)
+		if err != nil {
+			return err
+		}
+		deployments = append(deployments, d)
+
+		if err := s.upsertService(ctx, app, p); err != nil {
+			return err
+		}
+	}
+
+	if err := s.removeStale(ctx, app); err != nil {
+		return err
+	}
+
+	if ss != nil {
+		return s.waitForRollout(ctx, deployments, ss)
+	}
+
+	return nil
+}
+
+// Restart triggers a rolling restart of all of the Deployments for the app.
+func (s *Scheduler) Restart(ctx context.Context, app *scheduler.App, ss scheduler.StatusStream) error {
+	deployments, err := s.client.ListDeployments(ctx, s.namespace(), appSelector(app.ID))
+	if err != nil {
+		return fmt.Errorf("error listing deployments: %v", err)
+	}
+
+	var updated []*Deployment
+	for _, d := range deployments {
+		if d.Spec.Template.Annotations == nil {
+			d.Spec.Template.Annotations = make(map[string]string)
+		}
+		d.Spec.Template.Annotations[restartedAtAnnotation] = timex.Now().UTC().Format(time.RFC3339)
+
+		u, err := s.client.UpdateDeployment(ctx, s.namespace(), d)
+		if err != nil {
+			return fmt.Errorf("error restarting deployment %s: %v", d.Name, err)
+		}
+		updated = append(updated, u)
+	}
+
+	if ss != nil {
+		return s.waitForRollout(ctx, updated, ss)
+	}
+
+	return nil
+}
+
+// Remove removes all of the Kubernetes resources for the app.
+func (s *Scheduler) Remove(ctx context.Context, appID string) error {
+	return s.remove(ctx, appID, func(kind, name, process string) bool { return false })
+}
+
+// Instances returns the Pods for the app.
+func (s *Scheduler) Instances(ctx context.Context, appID string) ([]*scheduler.Instance, error) {
+	var instances []*scheduler.Instance
+
+	pods, err := s.client.ListPods(ctx, s.namespace(), appSelector(appID))
+	if err != nil {
+		return instances, fmt.Errorf("error listing pods: %v", err)
+	}
+
+	for _, pod := range pods {
+		instances = append(instances, podToInstance(pod))
+	}
+
+	return instances, nil
+}
+
+// Stop deletes the given Pod. If the Pod belongs to a Deployment, Kubernetes
+// will start a new one to replace it.
+func (s *Scheduler) Stop(ctx context.Context, instanceID string) error {
+	pod, err := s.client.GetPod(ctx, s.namespace(), instanceID)
+	if err != nil {
+		return err
+	}
+
+	// Some extra protection around stopping pods. We don't want to allow
+	// users to stop pods that may have been started outside of Empire.
+	if _, ok := pod.Labels[appLabel]; !ok {
+		return fmt.Errorf("pod %s was not started by Empire", instanceID)
+	}
+
+	return s.client.DeletePod(ctx, s.namespace(), instanceID)
+}
+
+// Run runs a detached process as a Job. Attached processes are not
+// supported; they should be run with the Docker scheduler instead.
+func (s *Scheduler) Run(ctx context.Context, app *scheduler.App, p *scheduler.Process, in io.Reader, out io.Writer) error {
+	if out != nil || in != nil {
+		return errors.New("running an attached process is not implemented by the Kubernetes scheduler")
+	}
+
+	labels := scheduler.Labels(app, p)
+	labels[appLabel] = app.ID
+	labels[processLabel] = p.Type
+	labels[runLabel] = "detached"
+
+	template := podTemplate(app, p)
+	template.Spec.RestartPolicy = "Never"
+	template.Labels = labels
+
+	backoffLimit := int32(0)
+	_, err := s.client.CreateJob(ctx, s.namespace(), &Job{
+		ObjectMeta: ObjectMeta{
+			Name:   resourceName(app.Name, "run", uuid.New()[:8]),
+			Labels: labels,
+		},
+		Spec: JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template:     template,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error creating job: %v", err)
+	}
+
+	return nil
+}
+
+// upsertDeployment creates the Deployment for the process, or updates it if
+// it already exists.
+func (s *Scheduler) upsertDeployment(ctx context.Context, app *scheduler.App, p *scheduler.Process) (*Deployment, error) {
+	d := newDeployment(app, p)
+
+	existing, err := s.client.GetDeployment(ctx, s.namespace(), d.Name)
+	if IsNotFound(err) {
+		created, err := s.client.CreateDeployment(ctx, s.namespace(), d)
+		if err != nil {
+			return nil, fmt.Errorf("error creating deployment %s: %v", d.Name, err)
+		}
+		return created, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	d.ResourceVersion = existing.ResourceVersion
+
+	// Preserve any restart annotation so that a deploy doesn't trigger an
+	// extra rollout.
+	if v, ok := existing.Spec.Template.Annotations[restartedAtAnnotation]; ok {
+		if d.Spec.Template.Annotations == nil {
+			d.Spec.Template.Annotations = make(map[string]string)
+		}
+		d.Spec.Template.Annotations[restartedAtAnnotation] = v
+	}
+
+	updated, err := s.client.UpdateDeployment(ctx, s.namespace(), d)
+	if err != nil {
+		return nil, fmt.Errorf("error updating deployment %s: %v", d.Name, err)
+	}
+	return updated, nil
+}
+
+// upsertService creates or updates the Service for the process, if it's
+// exposed. If the process is not exposed, any existing Service is removed.
+func (s *Scheduler) upsertService(ctx context.Context, app *scheduler.App, p *scheduler.Process) error {
+	name := resourceName(app.Name, p.Type)
+
+	existing, err := s.client.GetService(ctx, s.namespace(), name)
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+	if IsNotFound(err) {
+		existing = nil
+	}
+
+	if p.Exposure == nil {
+		if existing != nil {
+			return s.client.DeleteService(ctx, s.namespace(), name)
+		}
+		return nil
+	}
+
+	svc := newService(app, p)
+
+	if existing == nil {
+		if _, err := s.client.CreateService(ctx, s.namespace(), svc); err != nil {
+			return fmt.Errorf("error creating service %s: %v", name, err)
+		}
+		return nil
+	}
+
+	// The cluster IP of a Service is immutable.
+	svc.ResourceVersion = existing.ResourceVersion
+	svc.Spec.ClusterIP = existing.Spec.ClusterIP
+
+	if _, err := s.client.UpdateService(ctx, s.namespace(), svc); err != nil {
+		return fmt.Errorf("error updating service %s: %v", name, err)
+	}
+	return nil
+}
+
+// upsertCronJob creates the CronJob for the process, or updates it if it
+// already exists.
+func (s *Scheduler) upsertCronJob(ctx context.Context, app *scheduler.App, p *scheduler.Process) error {
+	j, err := newCronJob(app, p)
+	if err != nil {
+		return err
+	}
+
+	existing, err := s.client.GetCronJob(ctx, s.namespace(), j.Name)
+	if IsNotFound(err) {
+		if _, err := s.client.CreateCronJob(ctx, s.namespace(), j); err != nil {
+			return fmt.Errorf("error creating cronjob %s: %v", j.Name, err)
+		}
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	j.ResourceVersion = existing.ResourceVersion
+	if _, err := s.client.UpdateCronJob(ctx, s.namespace(), j); err != nil {
+		return fmt.Errorf("error updating cronjob %s: %v", j.Name, err)
+	}
+	return nil
+}
+
+// removeStale removes the resources for any processes that are no longer
+// defined in the app, or that have changed from a long running process to a
+// scheduled one (or vice versa). Jobs from one-off runs are left alone.
+func (s *Scheduler) removeStale(ctx context.Context, app *scheduler.App) error {
+	services := make(map[string]bool)
+	scheduled := make(map[string]bool)
+	for _, p := range app.Processes {
+		if p.Schedule != nil {
+			scheduled[p.Type] = true
+		} else {
+			services[p.Type] = true
+		}
+	}
+
+	return s.remove(ctx, app.ID, func(kind, name, process string) bool {
+		// Resources are named after the app, so anything with a
+		// different name was created before the app was renamed.
+		current := name == resourceName(app.Name, process)
+
+		switch kind {
+		case "cronjob":
+			return scheduled[process] && current
+		case "job":
+			return true
+		default:
+			return services[process] && current
+		}
+	})
+}
+
+// remove removes all of the resources for the app, except for those that
+// keep returns true for.
+func (s *Scheduler) remove(ctx context.Context, appID string, keep func(kind, name, process string) bool) error {
+	selector := appSelector(appID)
+
+	deployments, err := s.client.ListDeployments(ctx, s.namespace(), selector)
+	if err != nil {
+		return fmt.Errorf("error listing deployments: %v", err)
+	}
+	for _, d := range deployments {
+		if !keep("deployment", d.Name, d.Labels[processLabel]) {
+			if err := s.client.DeleteDeployment(ctx, s.namespace(), d.Name); err != nil && !IsNotFound(err) {
+				return fmt.Errorf("error removing deployment %s: %v", d.Name, err)
+			}
+		}
+	}
+
+	services, err := s.client.ListServices(ctx, s.namespace(), selector)
+	if err != nil {
+		return fmt.Errorf("error listing services: %v", err)
+	}
+	for _, svc := range services {
+		if !keep("service", svc.Name, svc.Labels[processLabel]) {
+			if err := s.client.DeleteService(ctx, s.namespace(), svc.Name); err != nil && !IsNotFound(err) {
+				return fmt.Errorf("error removing service %s: %v", svc.Name, err)
+			}
+		}
+	}
+
+	cronJobs, err := s.client.ListCronJobs(ctx, s.namespace(), selector)
+	if err != nil {
+		return fmt.Errorf("error listing cronjobs: %v", err)
+	}
+	for _, j := range cronJobs {
+		if !keep("cronjob", j.Name, j.Labels[processLabel]) {
+			if err := s.client.DeleteCronJob(ctx, s.namespace(), j.Name); err != nil && !IsNotFound(err) {
+				return fmt.Errorf("error removing cronjob %s: %v", j.Name, err)
+			}
+		}
+	}
+
+	jobs, err := s.client.ListJobs(ctx, s.namespace(), selector)
+	if err != nil {
+		return fmt.Errorf("error listing jobs: %v", err)
+	}
+	for _, j := range jobs {
+		if !keep("job", j.Name, j.Labels[processLabel]) {
+			if err := s.client.DeleteJob(ctx, s.namespace(), j.Name); err != nil && !IsNotFound(err) {
+				return fmt.Errorf("error removing job %s: %v", j.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// waitForRollout polls the given deployments until all of their replicas
+// have been updated and are available. If a deployment exceeds its progress
+// deadline, or doesn't roll out within the RolloutTimeout, a
+// scheduler.UnstableError is returned.
+func (s *Scheduler) waitForRollout(ctx context.Context, deployments []*Deployment, ss scheduler.StatusStream) error {
+	timeout := s.RolloutTimeout
+	if timeout == 0 {
+		timeout = DefaultRolloutTimeout
+	}
+	deadline := time.After(timeout)
+
+	for _, d := range deployments {
+		scheduler.Publish(ctx, ss, fmt.Sprintf("Waiting for deployment %s to roll out", d.Name))
+
+		for {
+			current, err := s.client.GetDeployment(ctx, s.namespace(), d.Name)
+			if err != nil {
+				return err
+			}
+
+			if rolledOut(current) {
+				break
+			}
+
+			if c := progressCondition(current); c != nil && c.Reason == progressDeadlineExceeded {
+				return &scheduler.UnstableError{Reason: fmt.Sprintf("deployment %s exceeded its progress deadline: %s", d.Name, c.Message)}
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-deadline:
+				return &scheduler.UnstableError{Reason: fmt.Sprintf("deployment %s didn't roll out within %v", d.Name, timeout)}
+			case <-time.After(s.PollInterval):
+			}
+		}
+
+		scheduler.Publish(ctx, ss, fmt.Sprintf("Deployment %s rolled out", d.Name))
+	}
+
+	return nil
+}
+
+func (s *Scheduler) namespace() string {
+	if s.Namespace == "" {
+		return DefaultNamespace
+	}
+	return s.Namespace
+}
+
+// rolledOut returns true if the Deployment controller has observed the latest
+// generation, and all replicas have been updated and are available.
+func rolledOut(d *Deployment) bool {
+	var replicas int32 = 1
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+
+	return d.Status.ObservedGeneration >= d.Generation &&
+		d.Status.UpdatedReplicas == replicas &&
+		d.Status.Replicas == replicas &&
+		d.Status.AvailableReplicas == replicas
+}
+
+// progressCondition returns the Progressing condition of the Deployment, if it
+// has one.
+func progressCondition(d *Deployment) *DeploymentCondition {
+	for _, c := range d.Status.Conditions {
+		if c.Type == "Progressing" {
+			return &c
+		}
+	}
+	return nil
+}
+
+// newDeployment returns the Deployment for a long running process.
+func newDeployment(app *scheduler.App, p *scheduler.Process) *Deployment {
+	selector := processSelector(app.ID, p.Type)
+	replicas := int32(p.Instances)
+
+	template := podTemplate(app, p)
+	template.Spec.RestartPolicy = "Always"
+
+	return &Deployment{
+		ObjectMeta: ObjectMeta{
+			Name:   resourceName(app.Name, p.Type),
+			Labels: template.Labels,
+		},
+		Spec: DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &LabelSelector{MatchLabels: selector},
+			Template: template,
+		},
+	}
+}
+
+// newService returns the Service that exposes the process.
+func newService(app *scheduler.App, p *scheduler.Process) *Service {
+	annotations := make(map[string]string)
+	if !p.Exposure.External {
+		annotations[awsInternalAnnotation] = "0.0.0.0/0"
+	}
+
+	port := ServicePort{
+		Name:       p.Exposure.Type.Protocol(),
+		Protocol:   "TCP",
+		TargetPort: ContainerPort,
+	}
+
+	switch e := p.Exposure.Type.(type) {
+	case *scheduler.HTTPSExposure:
+		port.Port = 443
+		annotations[awsBackendProtocolAnnotation] = "http"
+		if e.Cert != "" {
+			annotations[awsSSLCertAnnotation] = e.Cert
+			annotations[awsSSLPortsAnnotation] = "443"
+		}
+	case *scheduler.TCPExposure:
+		port.Port = int32(e.Port)
+		annotations[awsBackendProtocolAnnotation] = "tcp"
+	case *scheduler.SSLExposure:
+		port.Port = int32(e.Port)
+		annotations[awsBackendProtocolAnnotation] = "tcp"
+		annotations[awsSSLCertAnnotation] = e.Cert
+		annotations[awsSSLPortsAnnotation] = fmt.Sprintf("%d", e.Port)
+	default:
+		port.Port = 80
+		annotations[awsBackendProtocolAnnotation] = "http"
+	}
+
+	return &Service{
+		ObjectMeta: ObjectMeta{
+			Name:        resourceName(app.Name, p.Type),
+			Labels:      processSelector(app.ID, p.Type),
+			Annotations: annotations,
+		},
+		Spec: ServiceSpec{
+			Type:     "LoadBalancer",
+			Selector: processSelector(app.ID, p.Type),
+			Ports:    []ServicePort{port},
+		},
+	}
+}
+
+// newCronJob returns the CronJob for a scheduled process.
+func newCronJob(app *scheduler.App, p *scheduler.Process) (*CronJob, error) {
+	schedule, err := cronSchedule(p.Schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	template := podTemplate(app, p)
+	template.Spec.RestartPolicy = "Never"
+
+	return &CronJob{
+		ObjectMeta: ObjectMeta{
+			Name:   resourceName(app.Name, p.Type),
+			Labels: template.Labels,
+		},
+		Spec: CronJobSpec{
+			Schedule:          schedule,
+			ConcurrencyPolicy: "Forbid",
+			JobTemplate: JobTemplateSpec{
+				ObjectMeta: ObjectMeta{
+					Labels: template.Labels,
+				},
+				Spec: JobSpec{
+					Template: template,
+				},
+			},
+		},
+	}, nil
+}
+
+// podTemplate returns the PodTemplateSpec for the process.
+func podTemplate(app *scheduler.App, p *scheduler.Process) PodTemplateSpec {
+	labels := scheduler.Labels(app, p)
+	labels[appLabel] = app.ID
+	labels[processLabel] = p.Type
+
+	env := scheduler.Env(app, p)
+
+	var ports []ContainerPortSpec
+	if p.Exposure != nil {
+		ports = append(ports, ContainerPortSpec{
+			ContainerPort: ContainerPort,
+			Protocol:      "TCP",
+		})
+		env["PORT"] = fmt.Sprintf("%d", ContainerPort)
+	}
+
+	resources := ResourceRequirements{
+		Limits:   make(map[string]string),
+		Requests: make(map[string]string),
+	}
+	if p.MemoryLimit != 0 {
+		resources.Limits["memory"] = fmt.Sprintf("%dMi", p.MemoryLimit/MB)
+		resources.Requests["memory"] = fmt.Sprintf("%dMi", p.MemoryLimit/MB)
+	}
+	if p.CPUShares != 0 {
+		// CPU shares are out of 1024, which maps to 1 cpu, or 1000
+		// millicpus.
+		resources.Requests["cpu"] = fmt.Sprintf("%dm", p.CPUShares*1000/1024)
+	}
+
+	return PodTemplateSpec{
+		ObjectMeta: ObjectMeta{
+			Labels: labels,
+		},
+		Spec: PodSpec{
+			Containers: []Container{
+				{
+					Name:      resourceName(p.Type),
+					Image:     p.Image.String(),
+					Command:   p.Command,
+					Env:       envVars(env),
+					Ports:     ports,
+					Resources: resources,
+				},
+			},
+		},
+	}
+}
+
+// podToInstance converts a Pod to a scheduler.Instance.
+func podToInstance(pod *Pod) *scheduler.Instance {
+	var (
+		command []string
+		env     = make(map[string]string)
+		memory  uint
+		cpu     uint
+	)
+
+	if len(pod.Spec.Containers) > 0 {
+		c := pod.Spec.Containers[0]
+		command = c.Command
+		for _, e := range c.Env {
+			env[e.Name] = e.Value
+		}
+		memory = parseMemory(c.Resources.Limits["memory"])
+		cpu = parseCPU(c.Resources.Requests["cpu"])
+	}
+
+	var updatedAt time.Time
+	if pod.Status.StartTime != nil {
+		updatedAt = *pod.Status.StartTime
+	} else if pod.CreationTimestamp != nil {
+		updatedAt = *pod.CreationTimestamp
+	}
+
+	return &scheduler.Instance{
+		ID:        pod.Name,
+		Host:      scheduler.Host{ID: pod.Spec.NodeName},
+		State:     strings.ToUpper(pod.Status.Phase),
+		UpdatedAt: updatedAt,
+		Process: &scheduler.Process{
+			Type:        pod.Labels[processLabel],
+			Command:     command,
+			Env:         env,
+			MemoryLimit: memory,
+			CPUShares:   cpu,
+		},
+	}
+}
+
+// cronSchedule converts a scheduler.Schedule into a schedule that Kubernetes
+// understands. Schedules in the AWS six field format (with a year field and
+// `?` wildcards) are converted to the standard five field format.
+func cronSchedule(schedule scheduler.Schedule) (string, error) {
+	switch s := schedule.(type) {
+	case scheduler.CRONSchedule:
+		fields := strings.Fields(string(s))
+		switch len(fields) {
+		case 5:
+		case 6:
+			fields = fields[:5]
+		default:
+			return "", fmt.Errorf("invalid cron expression: %q", s)
+		}
+		return strings.Replace(strings.Join(fields, " "), "?", "*", -1), nil
+	default:
+		return "", fmt.Errorf("unknown schedule type: %T", schedule)
+	}
+}
+
+// appSelector returns the label selector for all resources of an app.
+func appSelector(appID string) map[string]string {
+	return map[string]string{
+		appLabel: appID,
+	}
+}
+
+// processSelector returns the label selector for the resources of a single
+// process within an app.
+func processSelector(appID, process string) map[string]string {
+	return map[string]string{
+		appLabel:     appID,
+		processLabel: process,
+	}
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// The maximum length of a DNS-1123 label.
+const maxNameLength = 63
+
+// resourceName joins the parts into a valid DNS-1123 label that can be used
+// as the name of a Kubernetes resource. Names that are too long are truncated,
+// and suffixed with a hash of the full name, so that different names don't
+// collide.
+func resourceName(parts ...string) string {
+	name := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(strings.Join(parts, "-")), "-"), "-")
+	if len(name) <= maxNameLength {
+		return name
+	}
+
+	suffix := fmt.Sprintf("%08x", crc32.ChecksumIEEE([]byte(name)))
+	prefix := strings.TrimRight(name[:maxNameLength-len(suffix)-1], "-")
+	return prefix + "-" + suffix
+}
+
+// envVars converts the environment into a list of EnvVar's, sorted by name
+// so that the generated pod templates are stable between deploys.
+func envVars(env map[string]string) []EnvVar {
+	var keys []string
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var vars []EnvVar
+	for _, k := range keys {
+		vars = append(vars, EnvVar{Name: k, Value: env[k]})
+	}
+	return vars
+}
+
+func parseMemory(q string) uint {
+	var n uint
+	if _, err := fmt.Sscanf(q, "%dMi", &n); err != nil {
+		return 0
+	}
+	return n * MB
+}
+
+func parseCPU(q string) uint {
+	var n uint
+	if _, err := fmt.Sscanf(q, "%dm", &n); err != nil {
+		return 0
+	}
+	return n * 1024 / 1000
+}
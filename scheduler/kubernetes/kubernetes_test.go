@@ -0,0 +1,477 @@
+package kubernetes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remind101/empire/pkg/bytesize"
+	"github.com/remind101/empire/pkg/image"
+	"github.com/remind101/empire/scheduler"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+var ctx = context.Background()
+
+func TestScheduler_Submit(t *testing.T) {
+	c := NewFakeClient()
+	s := NewScheduler(c)
+
+	err := s.Submit(ctx, fakeApp(), nil)
+	assert.NoError(t, err)
+
+	d := c.Deployments["default/acme-inc-web"]
+	if assert.NotNil(t, d) {
+		assert.Equal(t, int32(2), *d.Spec.Replicas)
+		assert.Equal(t, map[string]string{
+			"empire.app.id":      "2cdc4941-e36d-4855-a0ec-51525db4a500",
+			"empire.app.process": "web",
+		}, d.Spec.Selector.MatchLabels)
+		assert.Equal(t, "Always", d.Spec.Template.Spec.RestartPolicy)
+
+		container := d.Spec.Template.Spec.Containers[0]
+		assert.Equal(t, "remind101/acme-inc:latest", container.Image)
+		assert.Equal(t, []string{"./bin/web"}, container.Command)
+		assert.Equal(t, []EnvVar{
+			{Name: "EMPIRE_PROCESS", Value: "web"},
+			{Name: "FOO", Value: "bar"},
+			{Name: "PORT", Value: "8080"},
+		}, container.Env)
+		assert.Equal(t, []ContainerPortSpec{{ContainerPort: 8080, Protocol: "TCP"}}, container.Ports)
+		assert.Equal(t, "128Mi", container.Resources.Limits["memory"])
+		assert.Equal(t, "500m", container.Resources.Requests["cpu"])
+	}
+
+	svc := c.Services["default/acme-inc-web"]
+	if assert.NotNil(t, svc) {
+		assert.Equal(t, "LoadBalancer", svc.Spec.Type)
+		assert.Equal(t, []ServicePort{
+			{Name: "https", Protocol: "TCP", Port: 443, TargetPort: 8080},
+		}, svc.Spec.Ports)
+		assert.Equal(t, "arn:aws:iam::123456789012:server-certificate/AcmeIncDotCom", svc.Annotations[awsSSLCertAnnotation])
+		assert.False(t, hasKey(svc.Annotations, awsInternalAnnotation))
+	}
+
+	assert.False(t, hasKey(c.Deployments, "default/acme-inc-scheduled"))
+	assert.False(t, hasKey(c.Services, "default/acme-inc-worker"))
+
+	j := c.CronJobs["default/acme-inc-scheduled"]
+	if assert.NotNil(t, j) {
+		assert.Equal(t, "0 12 * * *", j.Spec.Schedule)
+		assert.Equal(t, "Never", j.Spec.JobTemplate.Spec.Template.Spec.RestartPolicy)
+	}
+
+	assert.NotNil(t, c.Deployments["default/acme-inc-worker"])
+}
+
+func TestScheduler_Submit_Update(t *testing.T) {
+	c := NewFakeClient()
+	s := NewScheduler(c)
+
+	app := fakeApp()
+	err := s.Submit(ctx, app, nil)
+	assert.NoError(t, err)
+
+	clusterIP := "10.0.0.1"
+	c.Services["default/acme-inc-web"].Spec.ClusterIP = clusterIP
+
+	// Remove the worker process and stop exposing the web process.
+	app.Processes[0].Instances = 5
+	app.Processes = app.Processes[:1]
+	err = s.Submit(ctx, app, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, int32(5), *c.Deployments["default/acme-inc-web"].Spec.Replicas)
+	assert.Equal(t, clusterIP, c.Services["default/acme-inc-web"].Spec.ClusterIP)
+	assert.False(t, hasKey(c.Deployments, "default/acme-inc-worker"))
+	assert.False(t, hasKey(c.CronJobs, "default/acme-inc-scheduled"))
+
+	app.Processes[0].Exposure = nil
+	err = s.Submit(ctx, app, nil)
+	assert.NoError(t, err)
+
+	assert.False(t, hasKey(c.Services, "default/acme-inc-web"))
+}
+
+func TestScheduler_Submit_Renamed(t *testing.T) {
+	c := NewFakeClient()
+	s := NewScheduler(c)
+
+	app := fakeApp()
+	err := s.Submit(ctx, app, nil)
+	assert.NoError(t, err)
+
+	app.Name = "acme-corp"
+	err = s.Submit(ctx, app, nil)
+	assert.NoError(t, err)
+
+	assert.NotNil(t, c.Deployments["default/acme-corp-web"])
+	assert.NotNil(t, c.Services["default/acme-corp-web"])
+	assert.NotNil(t, c.CronJobs["default/acme-corp-scheduled"])
+	assert.False(t, hasKey(c.Deployments, "default/acme-inc-web"))
+	assert.False(t, hasKey(c.Services, "default/acme-inc-web"))
+	assert.False(t, hasKey(c.CronJobs, "default/acme-inc-scheduled"))
+}
+
+func TestScheduler_Submit_StatusStream(t *testing.T) {
+	c := NewFakeClient()
+	s := NewScheduler(c)
+	s.PollInterval = time.Millisecond
+
+	app := fakeApp()
+	app.Processes = app.Processes[:1]
+
+	// Simulate the deployment controller rolling out the deployment.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			c.Lock()
+			d, ok := c.Deployments["default/acme-inc-web"]
+			if ok {
+				d.Status = DeploymentStatus{
+					ObservedGeneration: d.Generation,
+					Replicas:           *d.Spec.Replicas,
+					UpdatedReplicas:    *d.Spec.Replicas,
+					AvailableReplicas:  *d.Spec.Replicas,
+				}
+			}
+			c.Unlock()
+			if ok {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	var messages []string
+	err := s.Submit(ctx, app, scheduler.StatusStreamFunc(func(status scheduler.Status) error {
+		messages = append(messages, status.Message)
+		return nil
+	}))
+	assert.NoError(t, err)
+	<-done
+
+	assert.Equal(t, []string{
+		"Waiting for deployment acme-inc-web to roll out",
+		"Deployment acme-inc-web rolled out",
+	}, messages)
+}
+
+func TestScheduler_Restart(t *testing.T) {
+	c := NewFakeClient()
+	s := NewScheduler(c)
+
+	app := fakeApp()
+	err := s.Submit(ctx, app, nil)
+	assert.NoError(t, err)
+
+	err = s.Restart(ctx, app, nil)
+	assert.NoError(t, err)
+
+	d := c.Deployments["default/acme-inc-web"]
+	assert.True(t, hasKey(d.Spec.Template.Annotations, restartedAtAnnotation))
+
+	// A subsequent deploy should not trigger another restart.
+	restartedAt := d.Spec.Template.Annotations[restartedAtAnnotation]
+	err = s.Submit(ctx, app, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, restartedAt, c.Deployments["default/acme-inc-web"].Spec.Template.Annotations[restartedAtAnnotation])
+}
+
+func TestScheduler_Remove(t *testing.T) {
+	c := NewFakeClient()
+	s := NewScheduler(c)
+
+	app := fakeApp()
+	err := s.Submit(ctx, app, nil)
+	assert.NoError(t, err)
+
+	err = s.Run(ctx, app, app.Processes[0], nil, nil)
+	assert.NoError(t, err)
+
+	// Resources from another app should be left alone.
+	other := fakeApp()
+	other.ID = "1e7cd1a9-a1c2-4b4b-a0a0-3c3d7f6e2d11"
+	other.Name = "other"
+	err = s.Submit(ctx, other, nil)
+	assert.NoError(t, err)
+
+	err = s.Remove(ctx, app.ID)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, len(c.Deployments))
+	assert.Equal(t, 1, len(c.Services))
+	assert.Equal(t, 1, len(c.CronJobs))
+	assert.Equal(t, 0, len(c.Jobs))
+}
+
+func TestScheduler_Run(t *testing.T) {
+	c := NewFakeClient()
+	s := NewScheduler(c)
+
+	app := fakeApp()
+	p := &scheduler.Process{
+		Type:    "run",
+		Image:   image.Image{Repository: "remind101/acme-inc", Tag: "latest"},
+		Command: []string{"rake", "db:migrate"},
+	}
+
+	err := s.Run(ctx, app, p, nil, nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 1, len(c.Jobs))
+	for _, j := range c.Jobs {
+		assert.Equal(t, "detached", j.Labels[runLabel])
+		assert.Equal(t, "run", j.Labels[processLabel])
+		assert.Equal(t, int32(0), *j.Spec.BackoffLimit)
+		assert.Equal(t, "Never", j.Spec.Template.Spec.RestartPolicy)
+		assert.Equal(t, []string{"rake", "db:migrate"}, j.Spec.Template.Spec.Containers[0].Command)
+	}
+}
+
+func TestScheduler_Run_Attached(t *testing.T) {
+	s := NewScheduler(NewFakeClient())
+
+	app := fakeApp()
+	err := s.Run(ctx, app, app.Processes[0], nil, new(nopWriter))
+	assert.Error(t, err)
+}
+
+func TestScheduler_Instances(t *testing.T) {
+	c := NewFakeClient()
+	s := NewScheduler(c)
+
+	startedAt := time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+	c.Pods["default/acme-inc-web-1234"] = &Pod{
+		ObjectMeta: ObjectMeta{
+			Name:      "acme-inc-web-1234",
+			Namespace: "default",
+			Labels: map[string]string{
+				"empire.app.id":      "2cdc4941-e36d-4855-a0ec-51525db4a500",
+				"empire.app.process": "web",
+			},
+		},
+		Spec: PodSpec{
+			NodeName: "ip-10-0-0-1",
+			Containers: []Container{
+				{
+					Name:    "web",
+					Command: []string{"./bin/web"},
+					Env:     []EnvVar{{Name: "FOO", Value: "bar"}},
+					Resources: ResourceRequirements{
+						Limits:   map[string]string{"memory": "128Mi"},
+						Requests: map[string]string{"cpu": "500m"},
+					},
+				},
+			},
+		},
+		Status: PodStatus{
+			Phase:     "Running",
+			StartTime: &startedAt,
+		},
+	}
+	c.Pods["default/other-web-1234"] = &Pod{
+		ObjectMeta: ObjectMeta{
+			Name:      "other-web-1234",
+			Namespace: "default",
+			Labels: map[string]string{
+				"empire.app.id": "1e7cd1a9-a1c2-4b4b-a0a0-3c3d7f6e2d11",
+			},
+		},
+	}
+
+	instances, err := s.Instances(ctx, "2cdc4941-e36d-4855-a0ec-51525db4a500")
+	assert.NoError(t, err)
+	assert.Equal(t, []*scheduler.Instance{
+		{
+			ID:        "acme-inc-web-1234",
+			Host:      scheduler.Host{ID: "ip-10-0-0-1"},
+			State:     "RUNNING",
+			UpdatedAt: startedAt,
+			Process: &scheduler.Process{
+				Type:        "web",
+				Command:     []string{"./bin/web"},
+				Env:         map[string]string{"FOO": "bar"},
+				MemoryLimit: 128 * bytesize.MB,
+				CPUShares:   512,
+			},
+		},
+	}, instances)
+}
+
+func TestScheduler_Stop(t *testing.T) {
+	c := NewFakeClient()
+	s := NewScheduler(c)
+
+	c.Pods["default/acme-inc-web-1234"] = &Pod{
+		ObjectMeta: ObjectMeta{
+			Name:      "acme-inc-web-1234",
+			Namespace: "default",
+			Labels: map[string]string{
+				"empire.app.id": "2cdc4941-e36d-4855-a0ec-51525db4a500",
+			},
+		},
+	}
+
+	err := s.Stop(ctx, "acme-inc-web-1234")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(c.Pods))
+}
+
+func TestScheduler_Stop_PodNotStartedByEmpire(t *testing.T) {
+	c := NewFakeClient()
+	s := NewScheduler(c)
+
+	c.Pods["default/kube-dns"] = &Pod{
+		ObjectMeta: ObjectMeta{
+			Name:      "kube-dns",
+			Namespace: "default",
+		},
+	}
+
+	err := s.Stop(ctx, "kube-dns")
+	assert.Error(t, err)
+	assert.Equal(t, 1, len(c.Pods))
+}
+
+func TestCronSchedule(t *testing.T) {
+	tests := []struct {
+		in  scheduler.Schedule
+		out string
+		err bool
+	}{
+		{scheduler.CRONSchedule("* * * * *"), "* * * * *", false},
+		{scheduler.CRONSchedule("0 12 * * ? *"), "0 12 * * *", false},
+		{scheduler.CRONSchedule("0 12"), "", true},
+	}
+
+	for _, tt := range tests {
+		out, err := cronSchedule(tt.in)
+		assert.Equal(t, tt.out, out)
+		assert.Equal(t, tt.err, err != nil)
+	}
+}
+
+func TestScheduler_Submit_ProgressDeadlineExceeded(t *testing.T) {
+	c := NewFakeClient()
+	s := NewScheduler(c)
+	s.PollInterval = time.Millisecond
+
+	app := fakeApp()
+	app.Processes = app.Processes[:1]
+
+	// Simulate the deployment controller giving up on the deployment.
+	go func() {
+		for {
+			c.Lock()
+			d, ok := c.Deployments["default/acme-inc-web"]
+			if ok {
+				d.Status.Conditions = []DeploymentCondition{
+					{Type: "Progressing", Status: "False", Reason: "ProgressDeadlineExceeded", Message: "ReplicaSet has timed out progressing."},
+				}
+			}
+			c.Unlock()
+			if ok {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	err := s.Submit(ctx, app, scheduler.StatusStreamFunc(func(status scheduler.Status) error { return nil }))
+	assert.EqualError(t, err, "release failed to stabilize: deployment acme-inc-web exceeded its progress deadline: ReplicaSet has timed out progressing.")
+}
+
+func TestScheduler_Submit_RolloutTimeout(t *testing.T) {
+	c := NewFakeClient()
+	s := NewScheduler(c)
+	s.PollInterval = time.Millisecond
+	s.RolloutTimeout = 10 * time.Millisecond
+
+	app := fakeApp()
+	app.Processes = app.Processes[:1]
+
+	err := s.Submit(ctx, app, scheduler.StatusStreamFunc(func(status scheduler.Status) error { return nil }))
+	assert.IsType(t, &scheduler.UnstableError{}, err)
+}
+
+func TestResourceName(t *testing.T) {
+	tests := []struct {
+		in  []string
+		out string
+	}{
+		{[]string{"acme-inc", "web"}, "acme-inc-web"},
+		{[]string{"acme-inc", "worker_1"}, "acme-inc-worker-1"},
+		{[]string{"acme-inc", "Web.Admin"}, "acme-inc-web-admin"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.out, resourceName(tt.in...))
+	}
+
+	// Long names are truncated, but don't collide.
+	long := strings.Repeat("a", 60)
+	a, b := resourceName("acme-inc", long+"-web"), resourceName("acme-inc", long+"-worker")
+	assert.Len(t, a, 63)
+	assert.Len(t, b, 63)
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, a, resourceName("acme-inc", long+"-web"))
+}
+
+func fakeApp() *scheduler.App {
+	img := image.Image{Repository: "remind101/acme-inc", Tag: "latest"}
+	return &scheduler.App{
+		ID:      "2cdc4941-e36d-4855-a0ec-51525db4a500",
+		Name:    "acme-inc",
+		Release: "v1",
+		Env: map[string]string{
+			"FOO": "bar",
+		},
+		Labels: map[string]string{
+			"empire.app.release": "v1",
+		},
+		Processes: []*scheduler.Process{
+			{
+				Type:        "web",
+				Image:       img,
+				Command:     []string{"./bin/web"},
+				Env:         map[string]string{"EMPIRE_PROCESS": "web"},
+				Instances:   2,
+				MemoryLimit: 128 * bytesize.MB,
+				CPUShares:   512,
+				Exposure: &scheduler.Exposure{
+					External: true,
+					Type: &scheduler.HTTPSExposure{
+						Cert: "arn:aws:iam::123456789012:server-certificate/AcmeIncDotCom",
+					},
+				},
+			},
+			{
+				Type:      "worker",
+				Image:     img,
+				Command:   []string{"./bin/worker"},
+				Instances: 1,
+			},
+			{
+				Type:     "scheduled",
+				Image:    img,
+				Command:  []string{"./bin/scheduled"},
+				Schedule: scheduler.CRONSchedule("0 12 * * ? *"),
+			},
+		},
+	}
+}
+
+type nopWriter struct{}
+
+func (w *nopWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// hasKey returns true if the map contains the key.
+func hasKey(m interface{}, key string) bool {
+	return reflect.ValueOf(m).MapIndex(reflect.ValueOf(key)).IsValid()
+}
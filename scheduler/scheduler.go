@@ -3,6 +3,7 @@
 package scheduler
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -31,6 +32,25 @@ type App struct {
 
 	// Process that belong to this app.
 	Processes []*Process
+
+	// Custom domains that route to the app's web process.
+	Domains []string
+
+	// If provided, a new release of the app that runs alongside this one,
+	// and receives a share of the requests to its processes. Only used by
+	// CanaryScheduler.SubmitCanary.
+	Canary *Canary
+}
+
+// Canary is a new release of an app, that runs as separate processes alongside
+// the current release, and receives a share of the requests to the processes
+// that are exposed through an application load balancer.
+type Canary struct {
+	// The new release of the app.
+	App *App
+
+	// The percentage of requests, from 0 to 100, to send to the canary.
+	Weight int
 }
 
 type Process struct {
@@ -65,6 +85,14 @@ type Process struct {
 	// Exposure is the level of exposure for this process.
 	Exposure *Exposure
 
+	// If provided, how the load balancer checks the health of the
+	// instances of an exposed process.
+	HealthCheck *HealthCheck
+
+	// If provided, the process is scaled automatically, and Instances is
+	// only used as the initial quantity.
+	Autoscaling *Autoscaling
+
 	// Can be used to setup a CRON schedule to run this task periodically.
 	Schedule Schedule
 }
@@ -75,6 +103,48 @@ type Schedule interface{}
 // CRONSchedule is a Schedule implementation that represents a CRON expression.
 type CRONSchedule string
 
+// Autoscaling controls how a process is scaled automatically, by tracking a
+// target value for a metric.
+type Autoscaling struct {
+	// The minimum and maximum number of instances.
+	Min uint
+	Max uint
+
+	// The metric to track. One of "cpu" or "memory", which are average
+	// utilization percentages, or "requests", which is the number of
+	// requests per instance, per minute, to an application load balancer.
+	Metric string
+
+	// The value of the metric to maintain.
+	Target float64
+}
+
+// HealthCheck controls how a load balancer checks the health of the instances
+// of a process.
+type HealthCheck struct {
+	// If provided, the health check makes an HTTP GET request to this path.
+	// Otherwise, the health check only opens a TCP connection.
+	Path string
+
+	// The time between health checks.
+	Interval time.Duration
+
+	// The time to wait for a response before the check fails.
+	Timeout time.Duration
+
+	// The number of consecutive successful checks before an instance is
+	// considered healthy.
+	HealthyThreshold uint
+
+	// The number of consecutive failed checks before an instance is
+	// considered unhealthy.
+	UnhealthyThreshold uint
+
+	// The time to wait for in flight requests to complete before an
+	// instance is removed from the load balancer.
+	DeregistrationDelay time.Duration
+}
+
 // Exposure controls the exposure settings for a process.
 type Exposure struct {
 	// External means that this process will be exposed to internet facing
@@ -106,6 +176,26 @@ type HTTPSExposure struct {
 
 func (e *HTTPSExposure) Protocol() string { return "https" }
 
+// TCPExposure represents a TCP exposure, for services that don't speak HTTP.
+type TCPExposure struct {
+	// The port that the load balancer listens on.
+	Port int64
+}
+
+func (e *TCPExposure) Protocol() string { return "tcp" }
+
+// SSLExposure represents an SSL exposure. SSL is terminated at the load
+// balancer, and traffic is forwarded to the process over TCP.
+type SSLExposure struct {
+	// The port that the load balancer listens on.
+	Port int64
+
+	// The certificate to attach to the process.
+	Cert string
+}
+
+func (e *SSLExposure) Protocol() string { return "ssl" }
+
 // Host represents the host of an instance
 type Host struct {
 	// The host ID.
@@ -130,10 +220,35 @@ type Instance struct {
 }
 
 type Runner interface {
-	// Run runs a process.
+	// Run runs a process. If out is provided, Run should block until the
+	// process exits, and return an ExitError if it exited with a non-zero
+	// status.
 	Run(ctx context.Context, app *App, process *Process, in io.Reader, out io.Writer) error
 }
 
+// ExitError is returned from Run when an attached process exits with a
+// non-zero status.
+type ExitError struct {
+	Code int
+}
+
+// Error implements the error interface.
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("process exited with status %d", e.Code)
+}
+
+// UnstableError can be returned from Submit when streaming, if the new version
+// of the app failed to become stable. For example, when the new processes
+// keep crashing.
+type UnstableError struct {
+	Reason string
+}
+
+// Error implements the error interface.
+func (e *UnstableError) Error() string {
+	return fmt.Sprintf("release failed to stabilize: %s", e.Reason)
+}
+
 // Scheduler is an interface for interfacing with Services.
 type Scheduler interface {
 	Runner
@@ -159,6 +274,39 @@ type Scheduler interface {
 	Restart(context.Context, *App, StatusStream) error
 }
 
+// ErrCanaryNotSupported is returned by CanaryScheduler implementations that
+// wrap a Scheduler which doesn't support canaries.
+var ErrCanaryNotSupported = errors.New("canary deployments aren't supported by the scheduler")
+
+// CanaryScheduler is implemented by schedulers that can run a new release of an
+// app as a canary, alongside the current release.
+type CanaryScheduler interface {
+	// SubmitCanary submits the app, and runs App.Canary alongside it,
+	// creating or updating the canary as necessary. Submitting the app
+	// with Submit removes the canary.
+	SubmitCanary(context.Context, *App, StatusStream) error
+
+	// CanaryErrorRate returns the percentage of requests to the canary of
+	// the app that received a 5xx response since the given time. If there
+	// were no requests, the rate is 0.
+	CanaryErrorRate(ctx context.Context, app string, since time.Time) (float64, error)
+}
+
+// AttachedRunner is implemented by schedulers that can run attached processes,
+// where Run streams the output of the process and blocks until it exits.
+type AttachedRunner interface {
+	// RunsAttached returns true if attached processes can be run.
+	RunsAttached() bool
+}
+
+// RunsAttached returns true if the Runner can run attached processes.
+func RunsAttached(r Runner) bool {
+	if a, ok := r.(AttachedRunner); ok {
+		return a.RunsAttached()
+	}
+	return false
+}
+
 // Env merges the App environment with any environment variables provided
 // in the process.
 func Env(app *App, process *Process) map[string]string {
@@ -121,3 +121,25 @@ func (a *TeamAuthorizer) Authorize(user *empire.User) error {
 
 	return nil
 }
+
+// Teams is an implementation of the empire.GroupMembership interface
+// that maps groups to GitHub team ids, so permissions can be granted to
+// "team:<id>" principals.
+type Teams struct {
+	client interface {
+		IsTeamMember(teamID, token string) (bool, error)
+	}
+}
+
+// NewTeams returns a new Teams instance.
+func NewTeams(c *Client) *Teams {
+	return &Teams{client: c}
+}
+
+func (m *Teams) IsMember(user *empire.User, teamID string) (bool, error) {
+	if user.GitHubToken == "" {
+		return false, nil
+	}
+
+	return m.client.IsTeamMember(teamID, user.GitHubToken)
+}
@@ -122,6 +122,35 @@ func TestTeamAuthorizer_Unauthorized(t *testing.T) {
 	assert.EqualError(t, err, `ejholmes is not a member of team 123.`)
 }
 
+func TestTeams(t *testing.T) {
+	c := new(mockClient)
+	m := &Teams{
+		client: c,
+	}
+
+	c.On("IsTeamMember", "123", "access_token").Return(true, nil)
+	c.On("IsTeamMember", "456", "access_token").Return(false, nil)
+
+	user := &empire.User{
+		Name:        "ejholmes",
+		GitHubToken: "access_token",
+	}
+
+	ok, err := m.IsMember(user, "123")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = m.IsMember(user, "456")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// Users that didn't authenticate with GitHub aren't members of any
+	// team.
+	ok, err = m.IsMember(&empire.User{Name: "fake"}, "123")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
 type mockClient struct {
 	mock.Mock
 }
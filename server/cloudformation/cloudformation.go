@@ -58,13 +58,16 @@ func NewCustomResourceProvisioner(empire *empire.Empire, config client.ConfigPro
 	p.add("Custom::InstancePort", &InstancePortsProvisioner{
 		ports: lb.NewDBPortAllocator(db),
 	})
+	p.add("Custom::ListenerRulePriority", &ListenerRulePrioritiesProvisioner{
+		priorities: &dbPriorityAllocator{db},
+	})
 
 	ecs := newECSClient(config)
 	p.add("Custom::ECSService", &ECSServiceResource{
 		ecs: ecs,
 	})
 
-	store := &dbEnvironmentStore{db}
+	store := &dbEnvironmentStore{db: db, encrypter: empire.Encrypter}
 	p.add("Custom::ECSEnvironment", newECSEnvironmentProvisioner(&ECSEnvironmentResource{
 		environmentStore: store,
 	}))
@@ -16,7 +16,9 @@ import (
 	"github.com/aws/aws-sdk-go/aws/client"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/mitchellh/hashstructure"
+	"github.com/remind101/empire"
 	"github.com/remind101/empire/pkg/cloudformation/customresources"
+	"github.com/remind101/empire/pkg/envelope"
 	"github.com/remind101/pkg/reporter"
 )
 
@@ -80,6 +82,7 @@ func (p *ECSServiceResource) Provision(ctx context.Context, req customresources.
 		id, deploymentId, err := p.create(ctx, req.Hash(), properties)
 		if err == nil {
 			data["DeploymentId"] = deploymentId
+			data["Name"] = serviceName(id)
 		}
 		return id, data, err
 	case customresources.Delete:
@@ -99,6 +102,7 @@ func (p *ECSServiceResource) Provision(ctx context.Context, req customresources.
 				return oldId, nil, err
 			}
 			data["DeploymentId"] = deploymentId
+			data["Name"] = serviceName(id)
 
 			// There's no need to delete the old service here, since
 			// CloudFormation will send us a DELETE request for the old
@@ -107,13 +111,22 @@ func (p *ECSServiceResource) Provision(ctx context.Context, req customresources.
 			return id, data, err
 		}
 
+		// Only change the desired count when it's changed in the
+		// template, so that changes made by Application Auto Scaling
+		// aren't reverted when the service is updated.
+		var desiredCount *int64
+		if !reflect.DeepEqual(properties.DesiredCount, oldProperties.DesiredCount) {
+			desiredCount = properties.DesiredCount.Value()
+		}
+
 		resp, err := p.ecs.UpdateService(&ecs.UpdateServiceInput{
 			Service:        aws.String(id),
 			Cluster:        properties.Cluster,
-			DesiredCount:   properties.DesiredCount.Value(),
+			DesiredCount:   desiredCount,
 			TaskDefinition: properties.TaskDefinition,
 		})
 		if err == nil {
+			data["Name"] = serviceName(id)
 			d := primaryDeployment(resp.Service)
 			if d != nil {
 				data["DeploymentId"] = *d.Id
@@ -449,20 +462,140 @@ func (v ecsKeyValuePair) Value() (driver.Value, error) {
 // dbEnvironmentStore implements environmentStore on top of a sql.DB.
 type dbEnvironmentStore struct {
 	db *sql.DB
+
+	// If provided, values are encrypted before they're stored.
+	encrypter *envelope.Encrypter
 }
 
 func (s *dbEnvironmentStore) store(env []*ecs.KeyValuePair) (string, error) {
+	env, err := encryptEnvironment(s.encrypter, env)
+	if err != nil {
+		return "", err
+	}
+
 	sql := `INSERT INTO ecs_environment (environment) VALUES ($1) RETURNING id`
 	var id string
-	err := s.db.QueryRow(sql, ecsKeyValuePair(env)).Scan(&id)
+	err = s.db.QueryRow(sql, ecsKeyValuePair(env)).Scan(&id)
 	return id, err
 }
 
 func (s *dbEnvironmentStore) fetch(id string) ([]*ecs.KeyValuePair, error) {
 	sql := `SELECT environment FROM ecs_environment WHERE id = $1 LIMIT 1`
 	var env ecsKeyValuePair
-	err := s.db.QueryRow(sql, id).Scan(&env)
-	return env, err
+	if err := s.db.QueryRow(sql, id).Scan(&env); err != nil {
+		return nil, err
+	}
+	return decryptEnvironment(s.encrypter, env)
+}
+
+// EncryptEnvironments encrypts the values in any environments that were
+// stored before encryption was enabled, returning the number of environments
+// that were encrypted.
+func EncryptEnvironments(db *sql.DB, encrypter *envelope.Encrypter) (int, error) {
+	rows, err := db.Query(`SELECT id, environment FROM ecs_environment`)
+	if err != nil {
+		return 0, err
+	}
+
+	envs := make(map[string]ecsKeyValuePair)
+	for rows.Next() {
+		var id string
+		var env ecsKeyValuePair
+		if err := rows.Scan(&id, &env); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		envs[id] = env
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var n int
+	for id, env := range envs {
+		if !hasPlaintextValues(env) {
+			continue
+		}
+
+		decrypted, err := decryptEnvironment(encrypter, env)
+		if err != nil {
+			return n, err
+		}
+
+		encrypted, err := encryptEnvironment(encrypter, decrypted)
+		if err != nil {
+			return n, err
+		}
+
+		if _, err := db.Exec(`UPDATE ecs_environment SET environment = $1 WHERE id = $2`, ecsKeyValuePair(encrypted), id); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// encryptEnvironment returns a copy of env with the values encrypted. If
+// encrypter is nil, env is returned as is.
+func encryptEnvironment(encrypter *envelope.Encrypter, env []*ecs.KeyValuePair) ([]*ecs.KeyValuePair, error) {
+	if encrypter == nil || len(env) == 0 {
+		return env, nil
+	}
+
+	values := make([]string, len(env))
+	for i, kv := range env {
+		values[i] = aws.StringValue(kv.Value)
+	}
+
+	encrypted, err := encrypter.Encrypt(values...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ecs.KeyValuePair, len(env))
+	for i, kv := range env {
+		result[i] = &ecs.KeyValuePair{
+			Name:  kv.Name,
+			Value: aws.String(encrypted[i]),
+		}
+	}
+	return result, nil
+}
+
+// decryptEnvironment returns a copy of env with the values decrypted.
+func decryptEnvironment(encrypter *envelope.Encrypter, env []*ecs.KeyValuePair) ([]*ecs.KeyValuePair, error) {
+	result := make([]*ecs.KeyValuePair, len(env))
+	for i, kv := range env {
+		v := aws.StringValue(kv.Value)
+		if envelope.IsEncrypted(v) {
+			if encrypter == nil {
+				return nil, empire.ErrNoEncrypter
+			}
+
+			var err error
+			if v, err = encrypter.Decrypt(v); err != nil {
+				return nil, fmt.Errorf("error decrypting %s: %v", aws.StringValue(kv.Name), err)
+			}
+		}
+
+		result[i] = &ecs.KeyValuePair{
+			Name:  kv.Name,
+			Value: aws.String(v),
+		}
+	}
+	return result, nil
+}
+
+// hasPlaintextValues returns true if any of the values aren't encrypted.
+func hasPlaintextValues(env []*ecs.KeyValuePair) bool {
+	for _, kv := range env {
+		if !envelope.IsEncrypted(aws.StringValue(kv.Value)) {
+			return true
+		}
+	}
+	return false
 }
 
 // Certain parameters cannot be updated on existing services, so we need to
@@ -489,6 +622,11 @@ func serviceRequiresReplacement(new, old *ECSServiceProperties) bool {
 	return false
 }
 
+// serviceName returns the name of an ECS service from its ARN.
+func serviceName(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
+
 func primaryDeployment(service *ecs.Service) *ecs.Deployment {
 	for _, d := range service.Deployments {
 		if d.Status != nil && *d.Status == "PRIMARY" {
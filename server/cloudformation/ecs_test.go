@@ -12,7 +12,9 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/remind101/empire"
 	"github.com/remind101/empire/pkg/cloudformation/customresources"
+	"github.com/remind101/empire/pkg/envelope"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -84,7 +86,7 @@ func TestECSServiceResource_Create(t *testing.T) {
 	})
 	assert.NoError(t, err)
 	assert.Equal(t, "arn:aws:ecs:us-east-1:012345678901:service/acme-inc-web-dxRU5tYsnzt", id)
-	assert.Equal(t, data, map[string]string{"DeploymentId": "New"})
+	assert.Equal(t, data, map[string]string{"DeploymentId": "New", "Name": "acme-inc-web-dxRU5tYsnzt"})
 
 	e.AssertExpectations(t)
 }
@@ -176,7 +178,53 @@ func TestECSServiceResource_Update(t *testing.T) {
 	})
 	assert.NoError(t, err)
 	assert.Equal(t, "arn:aws:ecs:us-east-1:012345678901:service/acme-inc-web", id)
-	assert.Equal(t, data, map[string]string{"DeploymentId": "New"})
+	assert.Equal(t, data, map[string]string{"DeploymentId": "New", "Name": "acme-inc-web"})
+
+	e.AssertExpectations(t)
+}
+
+func TestECSServiceResource_Update_SameDesiredCount(t *testing.T) {
+	e := new(mockECS)
+	p := &ECSServiceResource{
+		ecs: e,
+	}
+
+	// The desired count isn't changed, so that it doesn't revert changes
+	// made by autoscaling.
+	e.On("UpdateService", &ecs.UpdateServiceInput{
+		Service:        aws.String("arn:aws:ecs:us-east-1:012345678901:service/acme-inc-web"),
+		Cluster:        aws.String("cluster"),
+		TaskDefinition: aws.String("arn:aws:ecs:us-east-1:012345678910:task-definition/acme-inc:2"),
+	}).Return(
+		&ecs.UpdateServiceOutput{
+			Service: &ecs.Service{
+				Deployments: []*ecs.Deployment{
+					&ecs.Deployment{Id: aws.String("New"), Status: aws.String("PRIMARY")},
+				},
+			},
+		},
+		nil,
+	)
+
+	_, _, err := p.Provision(ctx, customresources.Request{
+		StackId:            "arn:aws:cloudformation:us-east-1:012345678901:stack/acme-inc/bc66fd60-32be-11e6-902b-50d501eb4c17",
+		RequestId:          "411f3f38-565f-4216-a711-aeafd5ba635e",
+		RequestType:        customresources.Update,
+		PhysicalResourceId: "arn:aws:ecs:us-east-1:012345678901:service/acme-inc-web",
+		ResourceProperties: &ECSServiceProperties{
+			Cluster:        aws.String("cluster"),
+			ServiceName:    aws.String("acme-inc-web"),
+			DesiredCount:   customresources.Int(2),
+			TaskDefinition: aws.String("arn:aws:ecs:us-east-1:012345678910:task-definition/acme-inc:2"),
+		},
+		OldResourceProperties: &ECSServiceProperties{
+			Cluster:        aws.String("cluster"),
+			ServiceName:    aws.String("acme-inc-web"),
+			DesiredCount:   customresources.Int(2),
+			TaskDefinition: aws.String("arn:aws:ecs:us-east-1:012345678910:task-definition/acme-inc:1"),
+		},
+	})
+	assert.NoError(t, err)
 
 	e.AssertExpectations(t)
 }
@@ -225,7 +273,7 @@ func TestECSServiceResource_Update_RequiresReplacement(t *testing.T) {
 	})
 	assert.NoError(t, err)
 	assert.Equal(t, "arn:aws:ecs:us-east-1:012345678901:service/acme-inc-web-dxRU5tYsnzt", id)
-	assert.Equal(t, data, map[string]string{"DeploymentId": "New"})
+	assert.Equal(t, data, map[string]string{"DeploymentId": "New", "Name": "acme-inc-web-dxRU5tYsnzt"})
 
 	e.AssertExpectations(t)
 }
@@ -578,6 +626,30 @@ func TestServiceRequiresReplacement(t *testing.T) {
 	}
 }
 
+func TestEncryptEnvironment(t *testing.T) {
+	p, err := envelope.NewLocalKeyProvider([]byte("0123456789abcdef0123456789abcdef"))
+	assert.NoError(t, err)
+	encrypter := envelope.NewEncrypter(p)
+
+	env := []*ecs.KeyValuePair{
+		{Name: aws.String("FOO"), Value: aws.String("bar")},
+	}
+
+	encrypted, err := encryptEnvironment(encrypter, env)
+	assert.NoError(t, err)
+	assert.Equal(t, "FOO", *encrypted[0].Name)
+	assert.True(t, envelope.IsEncrypted(*encrypted[0].Value))
+	assert.False(t, hasPlaintextValues(encrypted))
+	assert.True(t, hasPlaintextValues(env))
+
+	decrypted, err := decryptEnvironment(encrypter, encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, env, decrypted)
+
+	_, err = decryptEnvironment(nil, encrypted)
+	assert.Equal(t, empire.ErrNoEncrypter, err)
+}
+
 type mockECS struct {
 	ecsClient
 	mock.Mock
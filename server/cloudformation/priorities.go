@@ -0,0 +1,81 @@
+package cloudformation
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/remind101/empire/pkg/cloudformation/customresources"
+
+	"golang.org/x/net/context"
+)
+
+type priorityAllocator interface {
+	Get() (int64, error)
+	Put(priority int64) error
+}
+
+// ListenerRulePrioritiesProvisioner is a Provisioner that allocates priorities
+// for listener rules on shared Application Load Balancers.
+type ListenerRulePrioritiesProvisioner struct {
+	priorities priorityAllocator
+}
+
+func (p *ListenerRulePrioritiesProvisioner) Properties() interface{} {
+	return nil
+}
+
+func (p *ListenerRulePrioritiesProvisioner) Provision(_ context.Context, req customresources.Request) (id string, data interface{}, err error) {
+	switch req.RequestType {
+	case customresources.Create:
+		var priority int64
+		priority, err = p.priorities.Get()
+		if err != nil {
+			return
+		}
+		id = fmt.Sprintf("%d", priority)
+		data = map[string]int64{"Priority": priority}
+	case customresources.Update:
+		// The priority doesn't change when the resource is updated.
+		priority, err2 := strconv.Atoi(req.PhysicalResourceId)
+		if err2 != nil {
+			err = fmt.Errorf("physical resource id should have been a priority: %v", err2)
+			return
+		}
+		id = req.PhysicalResourceId
+		data = map[string]int64{"Priority": int64(priority)}
+	case customresources.Delete:
+		priority, err2 := strconv.Atoi(req.PhysicalResourceId)
+		if err2 != nil {
+			err = fmt.Errorf("physical resource id should have been a priority: %v", err2)
+			return
+		}
+		id = req.PhysicalResourceId
+		err = p.priorities.Put(int64(priority))
+	default:
+		err = fmt.Errorf("%s is not supported", req.RequestType)
+	}
+
+	return
+}
+
+// dbPriorityAllocator allocates listener rule priorities from the
+// `listener_rule_priorities` table.
+type dbPriorityAllocator struct {
+	db *sql.DB
+}
+
+// Get takes the lowest priority that isn't taken.
+func (a *dbPriorityAllocator) Get() (int64, error) {
+	sql := `UPDATE listener_rule_priorities SET taken = true WHERE priority = (SELECT priority FROM listener_rule_priorities WHERE taken IS NULL ORDER BY priority ASC LIMIT 1) RETURNING priority`
+	var priority int64
+	err := a.db.QueryRow(sql).Scan(&priority)
+	return priority, err
+}
+
+// Put releases the priority, returning it back to the pool.
+func (a *dbPriorityAllocator) Put(priority int64) error {
+	sql := `UPDATE listener_rule_priorities SET taken = NULL WHERE priority = $1`
+	_, err := a.db.Exec(sql, priority)
+	return err
+}
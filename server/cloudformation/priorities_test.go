@@ -0,0 +1,71 @@
+package cloudformation
+
+import (
+	"testing"
+
+	"github.com/remind101/empire/pkg/cloudformation/customresources"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/net/context"
+)
+
+func TestListenerRulePrioritiesProvisioner_Create(t *testing.T) {
+	a := new(mockPriorityAllocator)
+	p := &ListenerRulePrioritiesProvisioner{priorities: a}
+
+	a.On("Get").Return(int64(1), nil)
+
+	id, data, err := p.Provision(context.Background(), customresources.Request{
+		RequestType: customresources.Create,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", id)
+	assert.Equal(t, map[string]int64{"Priority": 1}, data)
+
+	a.AssertExpectations(t)
+}
+
+func TestListenerRulePrioritiesProvisioner_Update(t *testing.T) {
+	a := new(mockPriorityAllocator)
+	p := &ListenerRulePrioritiesProvisioner{priorities: a}
+
+	id, data, err := p.Provision(context.Background(), customresources.Request{
+		RequestType:        customresources.Update,
+		PhysicalResourceId: "1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", id)
+	assert.Equal(t, map[string]int64{"Priority": 1}, data)
+
+	a.AssertExpectations(t)
+}
+
+func TestListenerRulePrioritiesProvisioner_Delete(t *testing.T) {
+	a := new(mockPriorityAllocator)
+	p := &ListenerRulePrioritiesProvisioner{priorities: a}
+
+	a.On("Put", int64(1)).Return(nil)
+
+	id, _, err := p.Provision(context.Background(), customresources.Request{
+		RequestType:        customresources.Delete,
+		PhysicalResourceId: "1",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1", id)
+
+	a.AssertExpectations(t)
+}
+
+type mockPriorityAllocator struct {
+	mock.Mock
+}
+
+func (m *mockPriorityAllocator) Get() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockPriorityAllocator) Put(priority int64) error {
+	args := m.Called(priority)
+	return args.Error(0)
+}
@@ -1,8 +1,10 @@
 package github
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"text/template"
 	"time"
 
 	"github.com/ejholmes/hookshot/events"
@@ -30,11 +32,11 @@ func (fn DeployerFunc) Deploy(ctx context.Context, event events.Deployment, w io
 
 // empire mocks the Empire interface we use.
 type empireClient interface {
-	Deploy(context.Context, empire.DeployOpts) (*empire.Release, error)
+	QueueDeployAndWait(context.Context, empire.DeployOpts) (*empire.Release, error)
 }
 
-// EmpireDeployer is a deployer implementation that uses the Deploy method in
-// Empire to perform the deployment.
+// EmpireDeployer is a deployer implementation that queues the deployment in
+// Empire, and waits for it to be performed.
 type EmpireDeployer struct {
 	empire empireClient
 	ImageBuilder
@@ -50,9 +52,16 @@ func NewEmpireDeployer(e *empire.Empire) *EmpireDeployer {
 // Deploy builds/determines the docker image to deploy, then deploys it with
 // Empire.
 func (d *EmpireDeployer) Deploy(ctx context.Context, event events.Deployment, w io.Writer) error {
+	_, err := d.DeployRelease(ctx, event, w)
+	return err
+}
+
+// DeployRelease is like Deploy, but also returns the release that was created.
+// If the image can't be built, the error is an *ImageBuildError.
+func (d *EmpireDeployer) DeployRelease(ctx context.Context, event events.Deployment, w io.Writer) (*empire.Release, error) {
 	img, err := d.BuildImage(ctx, w, event)
 	if err != nil {
-		return err
+		return nil, &ImageBuildError{Err: err}
 	}
 
 	// What we write to w should be plain text. `p` will get the jsonmessage
@@ -63,7 +72,7 @@ func (d *EmpireDeployer) Deploy(ctx context.Context, event events.Deployment, w
 	if message == "" {
 		message = fmt.Sprintf("GitHub deployment #%d of %s", event.Deployment.ID, event.Repository.FullName)
 	}
-	_, err = d.empire.Deploy(ctx, empire.DeployOpts{
+	release, err := d.empire.QueueDeployAndWait(ctx, empire.DeployOpts{
 		Image:   img,
 		Output:  empire.NewDeploymentStream(p),
 		User:    &empire.User{Name: event.Deployment.Creator.Login},
@@ -71,10 +80,89 @@ func (d *EmpireDeployer) Deploy(ctx context.Context, event events.Deployment, w
 		Message: message,
 	})
 	if err != nil {
+		return release, err
+	}
+
+	return release, p.Err()
+}
+
+// ImageBuildError is returned when the image for a deployment couldn't be
+// built, before anything was deployed.
+type ImageBuildError struct {
+	Err error
+}
+
+func (e *ImageBuildError) Error() string {
+	return e.Err.Error()
+}
+
+// ReleaseDeployer represents something that can deploy a github deployment,
+// and return the release that was created.
+type ReleaseDeployer interface {
+	DeployRelease(context.Context, events.Deployment, io.Writer) (*empire.Release, error)
+}
+
+// GitHubDeployer is an implementation of the Deployer interface that posts
+// deployment statuses back to GitHub.
+type GitHubDeployer struct {
+	// If provided, a template that's executed with the *empire.Release to
+	// determine the URL that's linked from the deployment status.
+	URL *template.Template
+
+	deployer ReleaseDeployer
+	client   DeploymentsClient
+}
+
+// NotifyGitHub wraps a ReleaseDeployer to post deployment statuses to GitHub.
+func NotifyGitHub(d ReleaseDeployer, c DeploymentsClient) *GitHubDeployer {
+	return &GitHubDeployer{
+		deployer: d,
+		client:   c,
+	}
+}
+
+// Deploy marks the GitHub deployment as pending, performs the deployment, then
+// marks it as a success or failure. If the deployment fails before anything
+// was deployed (e.g. the image couldn't be built), it's marked as an error.
+func (d *GitHubDeployer) Deploy(ctx context.Context, event events.Deployment, w io.Writer) error {
+	repo, id := event.Repository.FullName, int(event.Deployment.ID)
+
+	if err := createDeploymentStatus(d.client, repo, id, StatusPending, "", ""); err != nil {
+		return err
+	}
+
+	release, err := d.deployer.DeployRelease(ctx, event, w)
+	if err != nil {
+		state := StatusFailure
+		if _, ok := err.(*ImageBuildError); ok {
+			state = StatusError
+		}
+
+		// The deployment error is more interesting than an error posting
+		// the status.
+		createDeploymentStatus(d.client, repo, id, state, err.Error(), "")
 		return err
 	}
 
-	return p.Err()
+	url, err := d.url(release)
+	if err != nil {
+		return err
+	}
+
+	return createDeploymentStatus(d.client, repo, id, StatusSuccess, fmt.Sprintf("Deployed v%d to %s", release.Version, release.App.Name), url)
+}
+
+// url returns the URL of the release.
+func (d *GitHubDeployer) url(release *empire.Release) (string, error) {
+	if d.URL == nil {
+		return "", nil
+	}
+
+	buf := new(bytes.Buffer)
+	if err := d.URL.Execute(buf, release); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
 }
 
 // TugboatDeployer is an implementtion of the deployer interface that sends logs
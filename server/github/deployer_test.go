@@ -2,11 +2,16 @@ package github
 
 import (
 	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
 	"testing"
+	"text/template"
 
 	"golang.org/x/net/context"
 
 	"github.com/ejholmes/hookshot/events"
+	githubapi "github.com/google/go-github/github"
 	"github.com/remind101/empire"
 	"github.com/remind101/empire/pkg/dockerutil"
 	"github.com/remind101/empire/pkg/image"
@@ -29,7 +34,7 @@ func TestEmpireDeployer_Deploy(t *testing.T) {
 
 	b := new(bytes.Buffer)
 
-	e.On("Deploy", empire.DeployOpts{
+	e.On("QueueDeployAndWait", empire.DeployOpts{
 		User: &empire.User{Name: "ejholmes"},
 		Image: image.Image{
 			Repository: "remind101/acme-inc",
@@ -50,11 +55,88 @@ Status: Image is up to date for remind101/acme-inc:latest
 `, b.String())
 }
 
+func TestGitHubDeployer_Deploy(t *testing.T) {
+	r := new(mockReleaseDeployer)
+	g := new(mockDeploymentsClient)
+	d := NotifyGitHub(r, g)
+	d.URL = template.Must(template.New("url").Parse("https://empire.example.com/apps/{{ .App.Name }}/releases/{{ .Version }}"))
+
+	event := deploymentEvent()
+
+	g.On("CreateDeploymentStatus", "remind101", "acme-inc", 53252, &githubapi.DeploymentStatusRequest{
+		State: githubapi.String("pending"),
+	}).Return(nil)
+	r.On("DeployRelease", event).Return(&empire.Release{Version: 2, App: &empire.App{Name: "acme-inc"}}, nil)
+	g.On("CreateDeploymentStatus", "remind101", "acme-inc", 53252, &githubapi.DeploymentStatusRequest{
+		State:       githubapi.String("success"),
+		Description: githubapi.String("Deployed v2 to acme-inc"),
+		TargetURL:   githubapi.String("https://empire.example.com/apps/acme-inc/releases/2"),
+	}).Return(nil)
+
+	err := d.Deploy(context.Background(), event, ioutil.Discard)
+	assert.NoError(t, err)
+
+	r.AssertExpectations(t)
+	g.AssertExpectations(t)
+}
+
+func TestGitHubDeployer_Deploy_Failure(t *testing.T) {
+	tests := []struct {
+		err   error
+		state string
+	}{
+		{errors.New("deployment failed to stabilize"), "failure"},
+		{&ImageBuildError{Err: errors.New("image not found")}, "error"},
+	}
+
+	for _, tt := range tests {
+		r := new(mockReleaseDeployer)
+		g := new(mockDeploymentsClient)
+		d := NotifyGitHub(r, g)
+
+		event := deploymentEvent()
+
+		g.On("CreateDeploymentStatus", "remind101", "acme-inc", 53252, &githubapi.DeploymentStatusRequest{
+			State: githubapi.String("pending"),
+		}).Return(nil)
+		r.On("DeployRelease", event).Return(nil, tt.err)
+		g.On("CreateDeploymentStatus", "remind101", "acme-inc", 53252, &githubapi.DeploymentStatusRequest{
+			State:       githubapi.String(tt.state),
+			Description: githubapi.String(tt.err.Error()),
+		}).Return(nil)
+
+		err := d.Deploy(context.Background(), event, ioutil.Discard)
+		assert.Equal(t, tt.err, err)
+
+		r.AssertExpectations(t)
+		g.AssertExpectations(t)
+	}
+}
+
+func deploymentEvent() events.Deployment {
+	var event events.Deployment
+	event.Repository.FullName = "remind101/acme-inc"
+	event.Deployment.Sha = "abcd123"
+	event.Deployment.Creator.Login = "ejholmes"
+	event.Deployment.ID = 53252
+	return event
+}
+
+type mockReleaseDeployer struct {
+	mock.Mock
+}
+
+func (m *mockReleaseDeployer) DeployRelease(ctx context.Context, event events.Deployment, w io.Writer) (*empire.Release, error) {
+	args := m.Called(event)
+	r, _ := args.Get(0).(*empire.Release)
+	return r, args.Error(1)
+}
+
 type mockEmpire struct {
 	mock.Mock
 }
 
-func (m *mockEmpire) Deploy(ctx context.Context, opts empire.DeployOpts) (*empire.Release, error) {
+func (m *mockEmpire) QueueDeployAndWait(ctx context.Context, opts empire.DeployOpts) (*empire.Release, error) {
 	w := opts.Output
 	if err := dockerutil.FakePull(image.Image{Repository: "remind101/acme-inc", Tag: "latest"}, w); err != nil {
 		panic(err)
@@ -1,5 +1,5 @@
 // Package github provides an http.Handler implementation that allows Empire to
-// handle GitHub Deployments.
+// handle GitHub Deployments, and deploy pull requests to review apps.
 package github
 
 import (
@@ -31,6 +31,9 @@ type Options struct {
 	Environments []string
 
 	Deployer Deployer
+
+	// If provided, pull requests are deployed to review apps.
+	ReviewApps *ReviewApps
 }
 
 func New(e *empire.Empire, opts Options) httpx.Handler {
@@ -40,6 +43,10 @@ func New(e *empire.Empire, opts Options) httpx.Handler {
 	r.Handle("deployment", hookshot.Authorize(&DeploymentHandler{Deployer: opts.Deployer, environments: opts.Environments}, secret))
 	r.Handle("ping", hookshot.Authorize(http.HandlerFunc(Ping), secret))
 
+	if opts.ReviewApps != nil {
+		r.Handle("pull_request", hookshot.Authorize(&PullRequestHandler{ReviewApps: opts.ReviewApps}, secret))
+	}
+
 	return r
 }
 
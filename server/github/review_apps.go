@@ -0,0 +1,245 @@
+package github
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"text/template"
+
+	"github.com/ejholmes/hookshot/events"
+	githubapi "github.com/google/go-github/github"
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/pkg/dockerutil"
+	"github.com/remind101/pkg/reporter"
+	"golang.org/x/net/context"
+)
+
+// reviewAppsEmpire mocks the Empire interface we use for review apps.
+type reviewAppsEmpire interface {
+	AppsFind(empire.AppsQuery) (*empire.App, error)
+	ReviewAppsFind(empire.ReviewAppsQuery) (*empire.ReviewApp, error)
+	DeployReviewApp(context.Context, empire.DeployReviewAppOpts) (*empire.Release, error)
+	Destroy(context.Context, empire.DestroyOpts) error
+}
+
+// ReviewApps deploys pull requests to review apps, which are created from a
+// template app, and destroys them when the pull request is closed.
+type ReviewApps struct {
+	// Maps the full name of a repository (e.g. remind101/acme-inc) to the
+	// name of the app that its review apps are created from. Pull requests
+	// for other repositories are ignored.
+	Templates map[string]string
+
+	// If provided, a template that's executed with the *empire.App to
+	// determine the URL of a review app, which is linked from the GitHub
+	// deployment status.
+	URL *template.Template
+
+	// If provided, a GitHub deployment is created for each deployment of a
+	// review app, and its status is updated with the result.
+	GitHub DeploymentsClient
+
+	ImageBuilder
+
+	empire reviewAppsEmpire
+}
+
+// NewReviewApps returns a new ReviewApps instance.
+func NewReviewApps(e *empire.Empire) *ReviewApps {
+	return &ReviewApps{
+		empire: e,
+	}
+}
+
+// Deploy builds the image for the head of the pull request, then deploys it
+// to the pull request's review app, creating the review app if it doesn't
+// exist.
+func (r *ReviewApps) Deploy(ctx context.Context, event events.PullRequest, w io.Writer) error {
+	repo := event.Repository.FullName
+
+	name, ok := r.Templates[repo]
+	if !ok {
+		return nil
+	}
+
+	tmpl, err := r.empire.AppsFind(empire.AppsQuery{Name: &name})
+	if err != nil {
+		return fmt.Errorf("error finding template app %s: %v", name, err)
+	}
+
+	opts := empire.DeployReviewAppOpts{
+		User:        &empire.User{Name: event.Sender.Login},
+		Template:    tmpl,
+		Repo:        repo,
+		PullRequest: event.Number,
+		Message:     fmt.Sprintf("Pull request #%d of %s", event.Number, repo),
+		Stream:      true,
+	}
+
+	d, err := r.createDeployment(event, opts.Name())
+	if err != nil {
+		return err
+	}
+
+	img, err := r.BuildImage(ctx, w, imageEvent(event))
+	if err != nil {
+		return r.updateStatus(event, d, StatusError, err.Error(), "", err)
+	}
+	opts.Image = img
+
+	// What we write to w should be plain text. `p` will get the jsonmessage
+	// stream.
+	p := dockerutil.DecodeJSONMessageStream(w)
+	opts.Output = empire.NewDeploymentStream(p)
+
+	release, err := r.empire.DeployReviewApp(ctx, opts)
+	if err == nil {
+		err = p.Err()
+	}
+	if err != nil {
+		return r.updateStatus(event, d, StatusFailure, err.Error(), "", err)
+	}
+
+	url, err := r.url(release.App)
+	if err != nil {
+		return err
+	}
+
+	return r.updateStatus(event, d, StatusSuccess, fmt.Sprintf("Deployed v%d to %s", release.Version, release.App.Name), url, nil)
+}
+
+// Destroy destroys the pull request's review app, if it has one.
+func (r *ReviewApps) Destroy(ctx context.Context, event events.PullRequest, w io.Writer) error {
+	repo := event.Repository.FullName
+
+	ra, err := r.empire.ReviewAppsFind(empire.ReviewAppsQuery{Repo: &repo, PullRequest: &event.Number})
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			return nil
+		}
+		return err
+	}
+
+	if err := r.empire.Destroy(ctx, empire.DestroyOpts{
+		User:    &empire.User{Name: event.Sender.Login},
+		App:     ra.App,
+		Message: fmt.Sprintf("Pull request #%d of %s was closed", event.Number, repo),
+	}); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "Destroyed review app %s\n", ra.App.Name)
+	return err
+}
+
+// createDeployment creates a GitHub deployment for the head of the pull
+// request, in an environment named after the review app.
+func (r *ReviewApps) createDeployment(event events.PullRequest, environment string) (*githubapi.Deployment, error) {
+	if r.GitHub == nil {
+		return nil, nil
+	}
+
+	owner, repo := splitRepo(event.Repository.FullName)
+	d, _, err := r.GitHub.CreateDeployment(owner, repo, &githubapi.DeploymentRequest{
+		Ref:              githubapi.String(event.PullRequest.Head.Sha),
+		Environment:      githubapi.String(environment),
+		Description:      githubapi.String(fmt.Sprintf("Review app for pull request #%d", event.Number)),
+		AutoMerge:        githubapi.Bool(false),
+		RequiredContexts: &[]string{},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating GitHub deployment: %v", err)
+	}
+
+	return d, createDeploymentStatus(r.GitHub, event.Repository.FullName, *d.ID, StatusPending, "", "")
+}
+
+// updateStatus updates the status of the GitHub deployment, if there is one,
+// and returns deployErr.
+func (r *ReviewApps) updateStatus(event events.PullRequest, d *githubapi.Deployment, state, description, url string, deployErr error) error {
+	if d == nil {
+		return deployErr
+	}
+
+	if err := createDeploymentStatus(r.GitHub, event.Repository.FullName, *d.ID, state, description, url); err != nil && deployErr == nil {
+		return err
+	}
+
+	return deployErr
+}
+
+// url returns the URL of the review app.
+func (r *ReviewApps) url(app *empire.App) (string, error) {
+	if r.URL == nil {
+		return "", nil
+	}
+
+	buf := new(bytes.Buffer)
+	if err := r.URL.Execute(buf, app); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// imageEvent returns a deployment event for the head of the pull request,
+// which is passed to the ImageBuilder.
+func imageEvent(event events.PullRequest) events.Deployment {
+	var d events.Deployment
+	d.Repository.FullName = event.Repository.FullName
+	d.Deployment.Ref = event.PullRequest.Head.Ref
+	d.Deployment.Sha = event.PullRequest.Head.Sha
+	d.Deployment.Creator.Login = event.Sender.Login
+	return d
+}
+
+// PullRequestHandler is an http.Handler for handling the `pull_request` event.
+type PullRequestHandler struct {
+	*ReviewApps
+}
+
+func (h *PullRequestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	panic("expected ServeHTTPContext to be called")
+}
+
+func (h *PullRequestHandler) ServeHTTPContext(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var p events.PullRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	if _, ok := h.Templates[p.Repository.FullName]; !ok {
+		w.WriteHeader(http.StatusNoContent)
+		fmt.Fprintf(w, "Ignore pull request for repository: %s", p.Repository.FullName)
+		return nil
+	}
+
+	var fn func(context.Context, events.PullRequest, io.Writer) error
+	switch p.Action {
+	case "opened", "reopened", "synchronize":
+		fn = h.Deploy
+	case "closed":
+		fn = h.Destroy
+	default:
+		w.WriteHeader(http.StatusNoContent)
+		fmt.Fprintf(w, "Ignore pull request action: %s", p.Action)
+		return nil
+	}
+
+	// Deploy within a go routine so we don't timeout githubs webhook
+	// requests.
+	go func() {
+		if err := fn(ctx, p, os.Stdout); err != nil {
+			reporter.Report(ctx, fmt.Errorf("error handling pull request #%d of %s: %v", p.Number, p.Repository.FullName, err))
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+	io.WriteString(w, "Ok\n")
+	return nil
+}
@@ -0,0 +1,218 @@
+package github
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+
+	"golang.org/x/net/context"
+
+	"github.com/ejholmes/hookshot/events"
+	githubapi "github.com/google/go-github/github"
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/pkg/image"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReviewApps_Deploy(t *testing.T) {
+	e := new(mockReviewAppsEmpire)
+	g := new(mockDeploymentsClient)
+	r := &ReviewApps{
+		Templates:    map[string]string{"remind101/acme-inc": "acme-inc"},
+		URL:          template.Must(template.New("url").Parse("https://{{ .Name }}.example.com")),
+		GitHub:       g,
+		ImageBuilder: ImageFromTemplate(defaultTemplate),
+		empire:       e,
+	}
+
+	tmpl := &empire.App{ID: "abcd", Name: "acme-inc"}
+	name := "acme-inc"
+	e.On("AppsFind", empire.AppsQuery{Name: &name}).Return(tmpl, nil)
+
+	g.On("CreateDeployment", "remind101", "acme-inc", &githubapi.DeploymentRequest{
+		Ref:              githubapi.String("abcd123"),
+		Environment:      githubapi.String("acme-inc-pr-42"),
+		Description:      githubapi.String("Review app for pull request #42"),
+		AutoMerge:        githubapi.Bool(false),
+		RequiredContexts: &[]string{},
+	}).Return(&githubapi.Deployment{ID: githubapi.Int(1234)}, nil)
+	g.On("CreateDeploymentStatus", "remind101", "acme-inc", 1234, &githubapi.DeploymentStatusRequest{
+		State: githubapi.String("pending"),
+	}).Return(nil)
+
+	e.On("DeployReviewApp", empire.DeployReviewAppOpts{
+		User:        &empire.User{Name: "ejholmes"},
+		Template:    tmpl,
+		Repo:        "remind101/acme-inc",
+		PullRequest: 42,
+		Image:       image.Image{Repository: "remind101/acme-inc", Tag: "abcd123"},
+		Message:     "Pull request #42 of remind101/acme-inc",
+		Stream:      true,
+	}).Return(&empire.Release{Version: 1, App: &empire.App{Name: "acme-inc-pr-42"}}, nil)
+
+	g.On("CreateDeploymentStatus", "remind101", "acme-inc", 1234, &githubapi.DeploymentStatusRequest{
+		State:       githubapi.String("success"),
+		Description: githubapi.String("Deployed v1 to acme-inc-pr-42"),
+		TargetURL:   githubapi.String("https://acme-inc-pr-42.example.com"),
+	}).Return(nil)
+
+	err := r.Deploy(context.Background(), pullRequestEvent("opened"), ioutil.Discard)
+	assert.NoError(t, err)
+
+	e.AssertExpectations(t)
+	g.AssertExpectations(t)
+}
+
+func TestReviewApps_Deploy_Failure(t *testing.T) {
+	e := new(mockReviewAppsEmpire)
+	g := new(mockDeploymentsClient)
+	r := &ReviewApps{
+		Templates:    map[string]string{"remind101/acme-inc": "acme-inc"},
+		GitHub:       g,
+		ImageBuilder: ImageFromTemplate(defaultTemplate),
+		empire:       e,
+	}
+
+	tmpl := &empire.App{ID: "abcd", Name: "acme-inc"}
+	name := "acme-inc"
+	e.On("AppsFind", empire.AppsQuery{Name: &name}).Return(tmpl, nil)
+
+	g.On("CreateDeployment", "remind101", "acme-inc", mock.Anything).Return(&githubapi.Deployment{ID: githubapi.Int(1234)}, nil)
+	g.On("CreateDeploymentStatus", "remind101", "acme-inc", 1234, &githubapi.DeploymentStatusRequest{
+		State: githubapi.String("pending"),
+	}).Return(nil)
+
+	errBoom := errors.New("boom")
+	e.On("DeployReviewApp", mock.Anything).Return(nil, errBoom)
+
+	g.On("CreateDeploymentStatus", "remind101", "acme-inc", 1234, &githubapi.DeploymentStatusRequest{
+		State:       githubapi.String("failure"),
+		Description: githubapi.String("boom"),
+	}).Return(nil)
+
+	err := r.Deploy(context.Background(), pullRequestEvent("synchronize"), ioutil.Discard)
+	assert.Equal(t, errBoom, err)
+
+	e.AssertExpectations(t)
+	g.AssertExpectations(t)
+}
+
+func TestReviewApps_Destroy(t *testing.T) {
+	e := new(mockReviewAppsEmpire)
+	r := &ReviewApps{
+		Templates: map[string]string{"remind101/acme-inc": "acme-inc"},
+		empire:    e,
+	}
+
+	repo, number := "remind101/acme-inc", 42
+	app := &empire.App{Name: "acme-inc-pr-42"}
+	e.On("ReviewAppsFind", empire.ReviewAppsQuery{Repo: &repo, PullRequest: &number}).Return(&empire.ReviewApp{App: app}, nil)
+	e.On("Destroy", empire.DestroyOpts{
+		User:    &empire.User{Name: "ejholmes"},
+		App:     app,
+		Message: "Pull request #42 of remind101/acme-inc was closed",
+	}).Return(nil)
+
+	b := new(bytes.Buffer)
+	err := r.Destroy(context.Background(), pullRequestEvent("closed"), b)
+	assert.NoError(t, err)
+	assert.Equal(t, "Destroyed review app acme-inc-pr-42\n", b.String())
+
+	e.AssertExpectations(t)
+}
+
+func TestReviewApps_Destroy_NotFound(t *testing.T) {
+	e := new(mockReviewAppsEmpire)
+	r := &ReviewApps{
+		Templates: map[string]string{"remind101/acme-inc": "acme-inc"},
+		empire:    e,
+	}
+
+	e.On("ReviewAppsFind", mock.Anything).Return(nil, gorm.RecordNotFound)
+
+	err := r.Destroy(context.Background(), pullRequestEvent("closed"), ioutil.Discard)
+	assert.NoError(t, err)
+
+	e.AssertExpectations(t)
+}
+
+func TestPullRequestHandler_Ignored(t *testing.T) {
+	h := &PullRequestHandler{&ReviewApps{
+		Templates: map[string]string{"remind101/acme-inc": "acme-inc"},
+	}}
+
+	tests := []struct {
+		body string
+	}{
+		{`{"action":"opened","number":42,"repository":{"full_name":"remind101/other"}}`},
+		{`{"action":"labeled","number":42,"repository":{"full_name":"remind101/acme-inc"}}`},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest("POST", "/", strings.NewReader(tt.body))
+		resp := httptest.NewRecorder()
+
+		err := h.ServeHTTPContext(context.Background(), resp, req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, resp.Code)
+	}
+}
+
+func pullRequestEvent(action string) events.PullRequest {
+	var event events.PullRequest
+	event.Action = action
+	event.Number = 42
+	event.Repository.FullName = "remind101/acme-inc"
+	event.PullRequest.Head.Ref = "feature"
+	event.PullRequest.Head.Sha = "abcd123"
+	event.Sender.Login = "ejholmes"
+	return event
+}
+
+type mockReviewAppsEmpire struct {
+	mock.Mock
+}
+
+func (m *mockReviewAppsEmpire) AppsFind(q empire.AppsQuery) (*empire.App, error) {
+	args := m.Called(q)
+	return args.Get(0).(*empire.App), args.Error(1)
+}
+
+func (m *mockReviewAppsEmpire) ReviewAppsFind(q empire.ReviewAppsQuery) (*empire.ReviewApp, error) {
+	args := m.Called(q)
+	ra, _ := args.Get(0).(*empire.ReviewApp)
+	return ra, args.Error(1)
+}
+
+func (m *mockReviewAppsEmpire) DeployReviewApp(ctx context.Context, opts empire.DeployReviewAppOpts) (*empire.Release, error) {
+	opts.Output = nil
+	args := m.Called(opts)
+	r, _ := args.Get(0).(*empire.Release)
+	return r, args.Error(1)
+}
+
+func (m *mockReviewAppsEmpire) Destroy(ctx context.Context, opts empire.DestroyOpts) error {
+	args := m.Called(opts)
+	return args.Error(0)
+}
+
+type mockDeploymentsClient struct {
+	mock.Mock
+}
+
+func (m *mockDeploymentsClient) CreateDeployment(owner, repo string, request *githubapi.DeploymentRequest) (*githubapi.Deployment, *githubapi.Response, error) {
+	args := m.Called(owner, repo, request)
+	return args.Get(0).(*githubapi.Deployment), nil, args.Error(1)
+}
+
+func (m *mockDeploymentsClient) CreateDeploymentStatus(owner, repo string, deployment int, request *githubapi.DeploymentStatusRequest) (*githubapi.DeploymentStatus, *githubapi.Response, error) {
+	args := m.Called(owner, repo, deployment, request)
+	return nil, nil, args.Error(0)
+}
@@ -0,0 +1,61 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+
+	githubapi "github.com/google/go-github/github"
+)
+
+// DeploymentsClient is the part of the GitHub API that's used to create
+// deployments and post deployment statuses. It's implemented by
+// RepositoriesService in github.com/google/go-github/github.
+type DeploymentsClient interface {
+	CreateDeployment(owner, repo string, request *githubapi.DeploymentRequest) (*githubapi.Deployment, *githubapi.Response, error)
+	CreateDeploymentStatus(owner, repo string, deployment int, request *githubapi.DeploymentStatusRequest) (*githubapi.DeploymentStatus, *githubapi.Response, error)
+}
+
+// Deployment statuses.
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+	StatusError   = "error"
+)
+
+// maxDescriptionLength is the maximum length of a deployment status
+// description that GitHub accepts.
+const maxDescriptionLength = 140
+
+// createDeploymentStatus posts a status for the GitHub deployment in the
+// repository. The description and url are optional.
+func createDeploymentStatus(c DeploymentsClient, fullName string, deployment int, state, description, url string) error {
+	req := &githubapi.DeploymentStatusRequest{
+		State: githubapi.String(state),
+	}
+	if description != "" {
+		if len(description) > maxDescriptionLength {
+			description = description[:maxDescriptionLength]
+		}
+		req.Description = githubapi.String(description)
+	}
+	if url != "" {
+		req.TargetURL = githubapi.String(url)
+	}
+
+	owner, repo := splitRepo(fullName)
+	if _, _, err := c.CreateDeploymentStatus(owner, repo, deployment, req); err != nil {
+		return fmt.Errorf("error updating GitHub deployment status: %v", err)
+	}
+
+	return nil
+}
+
+// splitRepo splits the full name of a repository into the owner and name.
+func splitRepo(fullName string) (string, string) {
+	parts := strings.SplitN(fullName, "/", 2)
+	if len(parts) != 2 {
+		return fullName, ""
+	}
+	return parts[0], parts[1]
+}
@@ -79,13 +79,12 @@ func (h *Server) DeployApp(ctx context.Context, w http.ResponseWriter, r *http.R
 		return err
 	}
 
-	opts, err := newDeployOpts(ctx, w, r)
-	opts.App = a
+	opts, detach, err := newDeployOpts(ctx, r)
 	if err != nil {
 		return err
 	}
-	h.Deploy(ctx, *opts)
-	return nil
+	opts.App = a
+	return h.deploy(ctx, w, *opts, detach)
 }
 
 type PostAppsForm struct {
@@ -129,6 +128,23 @@ func (h *Server) PatchApp(ctx context.Context, w http.ResponseWriter, r *http.Re
 		return err
 	}
 
+	if form.Name != nil {
+		m, err := findMessage(r)
+		if err != nil {
+			return err
+		}
+
+		a, err = h.Rename(ctx, empire.RenameOpts{
+			User:    UserFromContext(ctx),
+			App:     a,
+			Name:    *form.Name,
+			Message: m,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
 	if form.Cert != nil {
 		if err := h.CertsAttach(ctx, a, *form.Cert); err != nil {
 			return err
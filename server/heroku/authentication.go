@@ -1,8 +1,10 @@
 package heroku
 
 import (
+	"fmt"
 	"net/http"
 
+	"github.com/remind101/empire"
 	"github.com/remind101/empire/server/auth"
 	"github.com/remind101/pkg/httpx"
 	"github.com/remind101/pkg/logger"
@@ -17,14 +19,20 @@ type Authentication struct {
 	// handler is the wrapped httpx.Handler. This handler is called when the
 	// user is authenticated.
 	handler httpx.Handler
+
+	// scope returns the access token scope that's required to make the
+	// request. If nil, any scope is allowed.
+	scope func(*http.Request) empire.Scope
 }
 
 // Authenticat wraps an httpx.Handler in the Authentication middleware to authenticate
-// the request.
-func Authenticate(h httpx.Handler, auth auth.Authenticator) httpx.Handler {
+// the request. scope returns the access token scope that's required for the
+// request.
+func Authenticate(h httpx.Handler, auth auth.Authenticator, scope func(*http.Request) empire.Scope) httpx.Handler {
 	return &Authentication{
 		authenticator: auth,
 		handler:       h,
+		scope:         scope,
 	}
 }
 
@@ -56,6 +64,16 @@ func (h *Authentication) ServeHTTPContext(ctx context.Context, w http.ResponseWr
 		}
 	}
 
+	// Scopes are enforced by Empire for actions on apps, but requests that
+	// don't map to an action (like adding a domain) are checked here.
+	if h.scope != nil && !user.Scopes.Grants(h.scope(r)) {
+		return &ErrorResource{
+			Status:  http.StatusForbidden,
+			ID:      "forbidden",
+			Message: fmt.Sprintf("Access token is limited to %v.", user.Scopes.Strings()),
+		}
+	}
+
 	// Embed the associated user into the context.
 	ctx = WithUser(ctx, user)
 
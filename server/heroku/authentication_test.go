@@ -107,6 +107,63 @@ func TestAuthentication_UnauthorizedError(t *testing.T) {
 	}, err)
 }
 
+func TestAuthentication_ScopedToken(t *testing.T) {
+	a := new(mockAuthenticator)
+	m := &Authentication{
+		authenticator: a,
+		handler:       ensureUserInContext(t),
+		scope:         New(nil).requiredScope,
+	}
+
+	a.On("Authenticate", "", "deploy", "").Return(&empire.User{
+		Scopes: empire.Scopes{empire.ScopeDeploy},
+	}, nil)
+	a.On("Authenticate", "", "read", "").Return(&empire.User{
+		Scopes: empire.Scopes{empire.ScopeRead},
+	}, nil)
+
+	tests := []struct {
+		token        string
+		method, path string
+		err          error
+	}{
+		{"deploy", "GET", "/apps", nil},
+		{"deploy", "POST", "/deploys", nil},
+		{"deploy", "POST", "/apps/acme-inc/deploys", nil},
+		{"deploy", "POST", "/apps/acme-inc/promotions", nil},
+		{"deploy", "POST", "/apps/acme-inc/canary/promote", nil},
+		{"deploy", "POST", "/apps/acme-inc/canary/abort", nil},
+		{"deploy", "POST", "/apps/acme-inc/log-sessions", nil},
+		{"deploy", "POST", "/apps/acme-inc/domains", &ErrorResource{
+			Status:  http.StatusForbidden,
+			ID:      "forbidden",
+			Message: "Access token is limited to [deploy].",
+		}},
+		{"deploy", "DELETE", "/apps/acme-inc", &ErrorResource{
+			Status:  http.StatusForbidden,
+			ID:      "forbidden",
+			Message: "Access token is limited to [deploy].",
+		}},
+		{"read", "GET", "/apps", nil},
+		{"read", "POST", "/apps/acme-inc/log-sessions", nil},
+		{"read", "POST", "/apps/acme-inc/deploys", &ErrorResource{
+			Status:  http.StatusForbidden,
+			ID:      "forbidden",
+			Message: "Access token is limited to [read].",
+		}},
+	}
+
+	for _, tt := range tests {
+		ctx := context.Background()
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest(tt.method, tt.path, nil)
+		req.SetBasicAuth("", tt.token)
+
+		err := m.ServeHTTPContext(ctx, resp, req)
+		assert.Equal(t, tt.err, err, "%s %s %s", tt.token, tt.method, tt.path)
+	}
+}
+
 type mockAuthenticator struct {
 	mock.Mock
 }
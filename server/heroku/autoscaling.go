@@ -0,0 +1,118 @@
+package heroku
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/pkg/heroku"
+	"github.com/remind101/pkg/httpx"
+	"golang.org/x/net/context"
+)
+
+type Autoscaling heroku.Autoscaling
+
+func newAutoscaling(process string, a *empire.Autoscaling) *Autoscaling {
+	return &Autoscaling{
+		Process: process,
+		Min:     a.Min,
+		Max:     a.Max,
+		Metric:  a.Metric,
+		Target:  a.Target,
+	}
+}
+
+// GetAutoscaling returns the autoscaling settings for the app's processes,
+// sorted by process type. Processes that aren't autoscaled are omitted.
+func (h *Server) GetAutoscaling(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	app, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	formation, err := h.ListScale(ctx, app)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range formation {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resp := []*Autoscaling{}
+	for _, name := range names {
+		if a := formation[name].Autoscaling; a != nil {
+			resp = append(resp, newAutoscaling(name, a))
+		}
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, resp)
+}
+
+type PutAutoscalingForm heroku.AutoscalingUpdateOpts
+
+// PutAutoscaling autoscales a process.
+func (h *Server) PutAutoscaling(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var form PutAutoscalingForm
+
+	if err := Decode(r, &form); err != nil {
+		return err
+	}
+
+	app, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	process := httpx.Vars(ctx)["process"]
+	p, err := h.Autoscale(ctx, empire.AutoscaleOpts{
+		User:    UserFromContext(ctx),
+		App:     app,
+		Process: process,
+		Autoscaling: &empire.Autoscaling{
+			Min:    form.Min,
+			Max:    form.Max,
+			Metric: form.Metric,
+			Target: form.Target,
+		},
+		Message: m,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, newAutoscaling(process, p.Autoscaling))
+}
+
+// DeleteAutoscaling disables autoscaling for a process.
+func (h *Server) DeleteAutoscaling(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	app, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := h.Autoscale(ctx, empire.AutoscaleOpts{
+		User:    UserFromContext(ctx),
+		App:     app,
+		Process: httpx.Vars(ctx)["process"],
+		Message: m,
+	}); err != nil {
+		return err
+	}
+
+	return NoContent(w)
+}
@@ -0,0 +1,94 @@
+package heroku
+
+import (
+	"net/http"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/pkg/heroku"
+	"golang.org/x/net/context"
+)
+
+type Canary heroku.Canary
+
+func newCanary(c *empire.Canary) *Canary {
+	canary := &Canary{
+		Id:            c.ID,
+		Release:       c.Version,
+		StableRelease: c.StableVersion,
+		Steps:         c.Steps,
+		Weight:        c.Weight(),
+		Status:        c.Status,
+		Reason:        c.Reason,
+		User:          c.UserName,
+		CreatedAt:     *c.CreatedAt,
+		UpdatedAt:     *c.UpdatedAt,
+	}
+	canary.App.Id = c.App.ID
+	canary.App.Name = c.App.Name
+	return canary
+}
+
+// errNoCanary is returned when the app doesn't have a running canary.
+var errNoCanary = &ErrorResource{
+	Status:  http.StatusNotFound,
+	ID:      "not_found",
+	Message: "This app doesn't have a running canary.",
+}
+
+// PostCanaryPromote promotes the running canary of an app.
+func (h *Server) PostCanaryPromote(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	app, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	c, err := h.PromoteCanary(ctx, empire.PromoteCanaryOpts{
+		User:    UserFromContext(ctx),
+		App:     app,
+		Message: m,
+	})
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			return errNoCanary
+		}
+		return err
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, newCanary(c))
+}
+
+// PostCanaryAbort aborts the running canary of an app, and rolls the app back
+// to the stable release.
+func (h *Server) PostCanaryAbort(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	app, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	c, err := h.AbortCanary(ctx, empire.AbortCanaryOpts{
+		User:    UserFromContext(ctx),
+		App:     app,
+		Message: m,
+	})
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			return errNoCanary
+		}
+		return err
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, newCanary(c))
+}
@@ -0,0 +1,208 @@
+package heroku
+
+import (
+	"net/http"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/pkg/heroku"
+	"github.com/remind101/pkg/httpx"
+	"golang.org/x/net/context"
+)
+
+type ConfigGroup heroku.ConfigGroup
+
+func newConfigGroup(g *empire.ConfigGroup, apps []*empire.App) *ConfigGroup {
+	names := make([]string, len(apps))
+	for i, a := range apps {
+		names[i] = a.Name
+	}
+
+	return &ConfigGroup{
+		Name:      g.Name,
+		Apps:      names,
+		CreatedAt: *g.CreatedAt,
+		UpdatedAt: *g.UpdatedAt,
+	}
+}
+
+// GetConfigGroups returns all of the config groups, and the apps that they're
+// attached to.
+func (h *Server) GetConfigGroups(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	gs, err := h.ConfigGroups(empire.ConfigGroupsQuery{})
+	if err != nil {
+		return err
+	}
+
+	groups := make([]*ConfigGroup, len(gs))
+	for i, g := range gs {
+		apps, err := h.ConfigGroupApps(g)
+		if err != nil {
+			return err
+		}
+		groups[i] = newConfigGroup(g, apps)
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, groups)
+}
+
+// GetConfigGroupVars returns the config vars for a config group. Values are
+// masked, unless the request includes reveal=true.
+func (h *Server) GetConfigGroupVars(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	g, err := findConfigGroup(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	vars, err := h.ReadConfigGroup(ctx, empire.ReadConfigGroupOpts{
+		User:   UserFromContext(ctx),
+		Group:  g,
+		Reveal: r.URL.Query().Get("reveal") == "true",
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, vars)
+}
+
+// PatchConfigGroupVars changes the config vars for a config group, creating it
+// if it doesn't exist.
+func (h *Server) PatchConfigGroupVars(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var configVars empire.Vars
+
+	if err := Decode(r, &configVars); err != nil {
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	g, err := h.SetConfigGroup(ctx, empire.SetConfigGroupOpts{
+		User:    UserFromContext(ctx),
+		Name:    httpx.Vars(ctx)["group"],
+		Vars:    configVars,
+		Message: m,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, empire.MaskVars(g.Vars))
+}
+
+// DeleteConfigGroup destroys a config group.
+func (h *Server) DeleteConfigGroup(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	g, err := findConfigGroup(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	if err := h.DestroyConfigGroup(ctx, empire.DestroyConfigGroupOpts{
+		User:    UserFromContext(ctx),
+		Group:   g,
+		Message: m,
+	}); err != nil {
+		return err
+	}
+
+	return NoContent(w)
+}
+
+type PostAppConfigGroupsForm struct {
+	Name string `json:"name"`
+}
+
+// PostAppConfigGroups attaches a config group to an app.
+func (h *Server) PostAppConfigGroups(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var form PostAppConfigGroupsForm
+
+	if err := Decode(r, &form); err != nil {
+		return err
+	}
+
+	app, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	g, err := configGroupNamed(h, form.Name)
+	if err != nil {
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	if err := h.AttachConfigGroup(ctx, empire.AttachConfigGroupOpts{
+		User:    UserFromContext(ctx),
+		App:     app,
+		Group:   g,
+		Message: m,
+	}); err != nil {
+		return err
+	}
+
+	return NoContent(w)
+}
+
+// DeleteAppConfigGroup detaches a config group from an app.
+func (h *Server) DeleteAppConfigGroup(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	app, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	g, err := findConfigGroup(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	if err := h.DetachConfigGroup(ctx, empire.AttachConfigGroupOpts{
+		User:    UserFromContext(ctx),
+		App:     app,
+		Group:   g,
+		Message: m,
+	}); err != nil {
+		return err
+	}
+
+	return NoContent(w)
+}
+
+// findConfigGroup finds the config group named in the route.
+func findConfigGroup(ctx context.Context, h *Server) (*empire.ConfigGroup, error) {
+	return configGroupNamed(h, httpx.Vars(ctx)["group"])
+}
+
+func configGroupNamed(h *Server, name string) (*empire.ConfigGroup, error) {
+	g, err := h.ConfigGroupsFind(empire.ConfigGroupsQuery{Name: &name})
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			return nil, &ErrorResource{
+				Status:  http.StatusNotFound,
+				ID:      "not_found",
+				Message: "Couldn't find that config group.",
+			}
+		}
+		return nil, err
+	}
+	return g, nil
+}
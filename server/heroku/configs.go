@@ -7,13 +7,19 @@ import (
 	"golang.org/x/net/context"
 )
 
+// GetConfigs returns the config vars for an app. The values of sensitive vars
+// are masked, unless the request includes reveal=true.
 func (h *Server) GetConfigs(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	a, err := findApp(ctx, h)
 	if err != nil {
 		return err
 	}
 
-	c, err := h.Config(a)
+	c, err := h.ReadConfig(ctx, empire.ReadConfigOpts{
+		User:   UserFromContext(ctx),
+		App:    a,
+		Reveal: r.URL.Query().Get("reveal") == "true",
+	})
 	if err != nil {
 		return err
 	}
@@ -50,6 +56,69 @@ func (h *Server) PatchConfigs(ctx context.Context, w http.ResponseWriter, r *htt
 		return err
 	}
 
+	// The new config includes vars that weren't changed, so it's masked
+	// the same as a read.
+	c, err = h.MaskConfig(c)
+	if err != nil {
+		return err
+	}
+
 	w.WriteHeader(200)
 	return Encode(w, c.Vars)
 }
+
+// GetConfigSensitivity returns the sensitivity markers for an app's config
+// vars. Only vars that aren't sensitive are included.
+func (h *Server) GetConfigSensitivity(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	a, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	vars, err := h.InsensitiveConfigVars(a)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, newConfigSensitivity(vars))
+}
+
+func (h *Server) PatchConfigSensitivity(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var sensitivity map[empire.Variable]bool
+
+	if err := Decode(r, &sensitivity); err != nil {
+		return err
+	}
+
+	a, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	vars, err := h.SetConfigSensitivity(ctx, empire.SetConfigSensitivityOpts{
+		User:        UserFromContext(ctx),
+		App:         a,
+		Sensitivity: sensitivity,
+		Message:     m,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, newConfigSensitivity(vars))
+}
+
+func newConfigSensitivity(insensitive []empire.Variable) map[string]bool {
+	m := make(map[string]bool)
+	for _, n := range insensitive {
+		m[string(n)] = false
+	}
+	return m
+}
@@ -1,48 +1,174 @@
 package heroku
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire/pkg/heroku"
 	"github.com/remind101/empire/pkg/image"
 	streamhttp "github.com/remind101/empire/pkg/stream/http"
+	"github.com/remind101/pkg/httpx"
 
 	"github.com/remind101/empire"
 	"golang.org/x/net/context"
 )
 
+type Deployment heroku.Deployment
+
+func newDeployment(d *empire.Deployment) *Deployment {
+	deployment := &Deployment{
+		Id:         d.ID,
+		Image:      d.Image.String(),
+		Status:     d.Status,
+		Release:    d.ReleaseVersion,
+		Error:      d.Error,
+		User:       d.UserName,
+		CreatedAt:  *d.CreatedAt,
+		StartedAt:  d.StartedAt,
+		FinishedAt: d.FinishedAt,
+		Output:     d.Output,
+	}
+	deployment.App.Id = d.App.ID
+	deployment.App.Name = d.App.Name
+	return deployment
+}
+
 // PostDeployForm is the form object that represents the POST body.
 type PostDeployForm struct {
 	Image  image.Image
 	Stream bool
+
+	// If true, the deployment is queued, and the queued deployment is
+	// returned instead of the deployment output.
+	Detach bool
+
+	// If provided, the image is deployed as a canary.
+	Canary *PostDeployCanaryForm
+}
+
+// PostDeployCanaryForm is the part of the POST body that configures a canary
+// deployment. Fields that aren't provided use the defaults.
+type PostDeployCanaryForm struct {
+	Steps     []int
+	Pause     *string
+	Threshold *float64
+}
+
+// canaryOpts returns the empire.CanaryOpts for the form.
+func (f *PostDeployCanaryForm) canaryOpts() (*empire.CanaryOpts, error) {
+	opts := &empire.CanaryOpts{
+		Steps:     f.Steps,
+		Pause:     empire.DefaultCanaryPause,
+		Threshold: empire.DefaultCanaryThreshold,
+	}
+
+	if opts.Steps == nil {
+		opts.Steps = empire.DefaultCanarySteps
+	}
+
+	if f.Pause != nil {
+		pause, err := time.ParseDuration(*f.Pause)
+		if err != nil {
+			return nil, &ErrorResource{
+				Status:  http.StatusBadRequest,
+				ID:      "bad_request",
+				Message: fmt.Sprintf("Invalid canary pause: %v", err),
+			}
+		}
+		opts.Pause = pause
+	}
+
+	if f.Threshold != nil {
+		opts.Threshold = *f.Threshold
+	}
+
+	return opts, nil
 }
 
 // ServeHTTPContext implements the Handler interface.
 func (h *Server) PostDeploys(ctx context.Context, w http.ResponseWriter, req *http.Request) error {
-	opts, err := newDeployOpts(ctx, w, req)
+	opts, detach, err := newDeployOpts(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	return h.deploy(ctx, w, *opts, detach)
+}
+
+// GetDeployment returns a deployment that was queued.
+func (h *Server) GetDeployment(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	app, err := findApp(ctx, h)
 	if err != nil {
 		return err
 	}
 
-	// We ignore errors here since this is a streaming endpoint,
-	// and the error is handled in the response message
-	_, _ = h.Deploy(ctx, *opts)
+	id := httpx.Vars(ctx)["id"]
+	d, err := h.DeploymentsFind(empire.DeploymentsQuery{ID: &id, App: app})
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			return &ErrorResource{
+				Status:  http.StatusNotFound,
+				ID:      "not_found",
+				Message: "Couldn't find that deployment.",
+			}
+		}
+		return err
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, newDeployment(d))
+}
+
+// deploy queues the deployment, and streams its output to w while it's
+// performed. If detach is true, the queued deployment is returned instead.
+func (h *Server) deploy(ctx context.Context, w http.ResponseWriter, opts empire.DeployOpts, detach bool) error {
+	if opts.Canary != nil {
+		// Canary deployments can't be queued, so they're performed
+		// while the request is open.
+		w.Header().Set("Content-Type", "application/json; boundary=NL")
+		opts.Output = empire.NewDeploymentStream(streamhttp.StreamingResponseWriter(w))
+
+		// We ignore errors here since this is a streaming endpoint,
+		// and the error is handled in the response message
+		_, _ = h.Deploy(ctx, opts)
+		return nil
+	}
+
+	d, err := h.QueueDeploy(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	if detach {
+		w.WriteHeader(202)
+		return Encode(w, newDeployment(d))
+	}
+
+	w.Header().Set("Content-Type", "application/json; boundary=NL")
+	output := empire.NewDeploymentStream(streamhttp.StreamingResponseWriter(w))
+
+	// Errors from the deployment itself are part of its output. Anything
+	// else is written to the stream, since the response has started.
+	if _, err := h.FollowDeployment(ctx, d, output); err != nil {
+		output.Error(err)
+	}
 	return nil
 }
 
-func newDeployOpts(ctx context.Context, w http.ResponseWriter, req *http.Request) (*empire.DeployOpts, error) {
+func newDeployOpts(ctx context.Context, req *http.Request) (*empire.DeployOpts, bool, error) {
 	var form PostDeployForm
 
 	if err := Decode(req, &form); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
 	m, err := findMessage(req)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	w.Header().Set("Content-Type", "application/json; boundary=NL")
-
 	if form.Image.Tag == "" && form.Image.Digest == "" {
 		form.Image.Tag = "latest"
 	}
@@ -50,9 +176,16 @@ func newDeployOpts(ctx context.Context, w http.ResponseWriter, req *http.Request
 	opts := empire.DeployOpts{
 		User:    UserFromContext(ctx),
 		Image:   form.Image,
-		Output:  empire.NewDeploymentStream(streamhttp.StreamingResponseWriter(w)),
 		Message: m,
 		Stream:  form.Stream,
 	}
-	return &opts, nil
+
+	if form.Canary != nil {
+		opts.Canary, err = form.Canary.canaryOpts()
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	return &opts, form.Detach, nil
 }
@@ -74,6 +74,8 @@ func newError(err error) *ErrorResource {
 		return ErrMessageRequired
 	case *empire.ValidationError:
 		return ErrBadRequest
+	case *empire.AccessDeniedError:
+		return errForbidden(err)
 	default:
 		return &ErrorResource{
 			Message: err.Error(),
@@ -102,6 +104,14 @@ func errUnauthorized(err *auth.UnauthorizedError) *ErrorResource {
 	}
 }
 
+func errForbidden(err *empire.AccessDeniedError) *ErrorResource {
+	return &ErrorResource{
+		Status:  http.StatusForbidden,
+		ID:      "forbidden",
+		Message: err.Error(),
+	}
+}
+
 // errHandler returns an httpx.Handler that responds with the given error.
 func errHandler(err error) httpx.Handler {
 	return httpx.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
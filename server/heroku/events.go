@@ -0,0 +1,122 @@
+package heroku
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/pkg/heroku"
+	"golang.org/x/net/context"
+)
+
+type Event heroku.Event
+
+func newEvent(e *empire.EventRecord) *Event {
+	event := &Event{
+		Id:          e.ID,
+		Type:        e.Type,
+		User:        e.User,
+		Description: e.Description,
+		Data:        json.RawMessage(e.Data),
+		CreatedAt:   *e.CreatedAt,
+	}
+
+	if e.AppID != nil {
+		event.App = &struct {
+			Id   string `json:"id"`
+			Name string `json:"name"`
+		}{
+			Id:   *e.AppID,
+			Name: e.App,
+		}
+	}
+
+	return event
+}
+
+func newEvents(es []*empire.EventRecord) []*Event {
+	events := make([]*Event, len(es))
+
+	for i := 0; i < len(es); i++ {
+		events[i] = newEvent(es[i])
+	}
+
+	return events
+}
+
+// GetEvents returns the events in the audit log for all apps.
+func (h *Server) GetEvents(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	q, err := eventsQuery(r)
+	if err != nil {
+		return err
+	}
+
+	return h.getEvents(w, q)
+}
+
+// GetAppEvents returns the events in the audit log for a single app.
+func (h *Server) GetAppEvents(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	a, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	q, err := eventsQuery(r)
+	if err != nil {
+		return err
+	}
+	q.App = a
+
+	return h.getEvents(w, q)
+}
+
+func (h *Server) getEvents(w http.ResponseWriter, q empire.EventsQuery) error {
+	events, err := h.Events(q)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, newEvents(events))
+}
+
+// eventsQuery builds an empire.EventsQuery from the query parameters and Range
+// header of the request.
+func eventsQuery(r *http.Request) (empire.EventsQuery, error) {
+	var q empire.EventsQuery
+
+	rangeHeader, err := RangeHeader(r)
+	if err != nil {
+		return q, err
+	}
+	q.Range = rangeHeader
+
+	params := r.URL.Query()
+
+	if user := params.Get("user"); user != "" {
+		q.User = &user
+	}
+
+	if typ := params.Get("type"); typ != "" {
+		q.Type = &typ
+	}
+
+	if since := params.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			return q, ErrBadRequest
+		}
+		q.Since = &t
+	}
+
+	if until := params.Get("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			return q, ErrBadRequest
+		}
+		q.Until = &t
+	}
+
+	return q, nil
+}
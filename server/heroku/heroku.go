@@ -36,6 +36,10 @@ type Server struct {
 	Authenticator auth.Authenticator
 
 	mux *httpx.Router
+
+	// scopes maps each route to the access token scope that's required to
+	// use it.
+	scopes map[*httpx.Route]empire.Scope
 }
 
 // New returns a new Server instance to serve the Heroku compatible API.
@@ -43,16 +47,21 @@ func New(e *empire.Empire) *Server {
 	r := &Server{
 		Empire: e,
 		mux:    httpx.NewRouter(),
+		scopes: make(map[*httpx.Route]empire.Scope),
 	}
 
 	// Apps
-	r.handle("GET", "/apps", r.GetApps)                  // hk apps
-	r.handle("GET", "/apps/{app}", r.GetAppInfo)         // hk info
-	r.handle("DELETE", "/apps/{app}", r.DeleteApp)       // hk destroy
-	r.handle("PATCH", "/apps/{app}", r.PatchApp)         // hk destroy
-	r.handle("POST", "/apps/{app}/deploys", r.DeployApp) // Deploy an image to an app
-	r.handle("POST", "/apps", r.PostApps)                // hk create
-	r.handle("POST", "/organizations/apps", r.PostApps)  // hk create
+	r.handle("GET", "/apps", r.GetApps)                                           // hk apps
+	r.handle("GET", "/apps/{app}", r.GetAppInfo)                                  // hk info
+	r.handle("DELETE", "/apps/{app}", r.DeleteApp)                                // hk destroy
+	r.handle("PATCH", "/apps/{app}", r.PatchApp)                                  // hk destroy
+	r.handleScope(empire.ScopeDeploy, "POST", "/apps/{app}/deploys", r.DeployApp) // Deploy an image to an app
+	r.handle("POST", "/apps", r.PostApps)                                         // hk create
+	r.handle("POST", "/organizations/apps", r.PostApps)                           // hk create
+
+	// Events
+	r.handle("GET", "/events", r.GetEvents)               // emp events
+	r.handle("GET", "/apps/{app}/events", r.GetAppEvents) // emp events -a <app>
 
 	// Domains
 	r.handle("GET", "/apps/{app}/domains", r.GetDomains)                 // hk domains
@@ -60,16 +69,36 @@ func New(e *empire.Empire) *Server {
 	r.handle("DELETE", "/apps/{app}/domains/{hostname}", r.DeleteDomain) // hk domain-remove
 
 	// Deploys
-	r.handle("POST", "/deploys", r.PostDeploys) // Deploy an app
+	r.handleScope(empire.ScopeDeploy, "POST", "/deploys", r.PostDeploys)                         // Deploy an app
+	r.handle("GET", "/apps/{app}/deployments/{id}", r.GetDeployment)                             // emp deploy:status
+	r.handleScope(empire.ScopeDeploy, "POST", "/apps/{app}/canary/promote", r.PostCanaryPromote) // emp deploy:promote
+	r.handleScope(empire.ScopeDeploy, "POST", "/apps/{app}/canary/abort", r.PostCanaryAbort)     // emp deploy:abort
 
 	// Releases
-	r.handle("GET", "/apps/{app}/releases", r.GetReleases)          // hk releases
-	r.handle("GET", "/apps/{app}/releases/{version}", r.GetRelease) // hk release-info
-	r.handle("POST", "/apps/{app}/releases", r.PostReleases)        // hk rollback
+	r.handle("GET", "/apps/{app}/releases", r.GetReleases)                       // hk releases
+	r.handle("GET", "/apps/{app}/releases/{from}...{to}/diff", r.GetReleaseDiff) // emp release-diff
+	r.handle("GET", "/apps/{app}/releases/{version}", r.GetRelease)              // hk release-info
+	r.handle("POST", "/apps/{app}/releases", r.PostReleases)                     // hk rollback
 
 	// Configs
-	r.handle("GET", "/apps/{app}/config-vars", r.GetConfigs)     // hk env, hk get
-	r.handle("PATCH", "/apps/{app}/config-vars", r.PatchConfigs) // hk set, hk unset
+	r.handle("GET", "/apps/{app}/config-vars", r.GetConfigs)                           // hk env, hk get
+	r.handle("PATCH", "/apps/{app}/config-vars", r.PatchConfigs)                       // hk set, hk unset
+	r.handle("GET", "/apps/{app}/config-vars/sensitivity", r.GetConfigSensitivity)     // emp env-sensitivity
+	r.handle("PATCH", "/apps/{app}/config-vars/sensitivity", r.PatchConfigSensitivity) // emp env-sensitivity
+
+	// Config groups
+	r.handle("GET", "/config-groups", r.GetConfigGroups)                            // emp config-groups
+	r.handle("GET", "/config-groups/{group}/config-vars", r.GetConfigGroupVars)     // emp config-group-env
+	r.handle("PATCH", "/config-groups/{group}/config-vars", r.PatchConfigGroupVars) // emp config-group-set, emp config-group-unset
+	r.handle("DELETE", "/config-groups/{group}", r.DeleteConfigGroup)               // emp config-group-destroy
+	r.handle("POST", "/apps/{app}/config-groups", r.PostAppConfigGroups)            // emp config-group-attach
+	r.handle("DELETE", "/apps/{app}/config-groups/{group}", r.DeleteAppConfigGroup) // emp config-group-detach
+
+	// Pipelines
+	r.handle("GET", "/pipelines", r.GetPipelines)                                         // emp pipelines
+	r.handle("PUT", "/pipelines/{pipeline}", r.PutPipeline)                               // emp pipeline-set
+	r.handle("DELETE", "/pipelines/{pipeline}", r.DeletePipeline)                         // emp pipeline-destroy
+	r.handleScope(empire.ScopeDeploy, "POST", "/apps/{app}/promotions", r.PostPromotions) // emp promote
 
 	// Processes
 	r.handle("GET", "/apps/{app}/dynos", r.GetProcesses)                     // hk dynos
@@ -82,8 +111,25 @@ func New(e *empire.Empire) *Server {
 	r.handle("GET", "/apps/{app}/formation", r.GetFormation)     // hk scale -l
 	r.handle("PATCH", "/apps/{app}/formation", r.PatchFormation) // hk scale
 
+	// Autoscaling
+	r.handle("GET", "/apps/{app}/autoscaling", r.GetAutoscaling)                 // emp autoscale
+	r.handle("PUT", "/apps/{app}/autoscaling/{process}", r.PutAutoscaling)       // emp autoscale web 2-10 cpu=50
+	r.handle("DELETE", "/apps/{app}/autoscaling/{process}", r.DeleteAutoscaling) // emp autoscale web off
+
+	// Scale schedules
+	r.handle("GET", "/apps/{app}/scale-schedules", r.GetScaleSchedules)           // emp scale:schedule
+	r.handle("POST", "/apps/{app}/scale-schedules", r.PostScaleSchedules)         // emp scale:schedule worker=20 "0 20 * * *"
+	r.handle("DELETE", "/apps/{app}/scale-schedules/{id}", r.DeleteScaleSchedule) // emp scale:schedule -r <id>
+
+	// Permissions
+	r.handle("GET", "/permissions", r.GetPermissions)                   // emp access
+	r.handle("POST", "/permissions", r.PostPermissions)                 // emp access-grant
+	r.handle("DELETE", "/permissions/{permission}", r.DeletePermission) // emp access-revoke
+
 	// OAuth
-	r.handle("POST", "/oauth/authorizations", r.PostAuthorizations)
+	r.handle("GET", "/oauth/authorizations", r.GetAuthorizations)           // emp tokens
+	r.handle("POST", "/oauth/authorizations", r.PostAuthorizations)         // emp login, emp token-create
+	r.handle("DELETE", "/oauth/authorizations/{id}", r.DeleteAuthorization) // emp token-revoke
 
 	// SSL
 	sslRemoved := errHandler(ErrSSLRemoved)
@@ -93,14 +139,14 @@ func New(e *empire.Empire) *Server {
 	r.mux.Handle("/apps/{app}/ssl-endpoints/{cert}", sslRemoved).Methods("DELETE") // hk ssl-destroy
 
 	// Logs
-	r.handle("POST", "/apps/{app}/log-sessions", r.PostLogs) // hk log
+	r.handleScope(empire.ScopeRead, "POST", "/apps/{app}/log-sessions", r.PostLogs) // hk log
 
 	return r
 }
 
 // ServeHTTPContext implements the httpx.Handler interface.
 func (s *Server) ServeHTTPContext(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	h := Authenticate(s.mux, s.Authenticator)
+	h := Authenticate(s.mux, s.Authenticator, s.requiredScope)
 
 	err := h.ServeHTTPContext(ctx, w, r)
 	if err != nil {
@@ -112,7 +158,15 @@ func (s *Server) ServeHTTPContext(ctx context.Context, w http.ResponseWriter, r
 }
 
 // handle adds a new handler to the router, which also increments a counter.
+// GET requests can be made with any access token, and other requests require
+// full access.
 func (s *Server) handle(method, path string, h httpx.HandlerFunc) {
+	s.handleScope(methodScope(method), method, path, h)
+}
+
+// handleScope is like handle, but the route can be used with any access token
+// that grants the given scope.
+func (s *Server) handleScope(scope empire.Scope, method, path string, h httpx.HandlerFunc) {
 	name := handlerName(h)
 
 	fn := httpx.HandlerFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
@@ -128,7 +182,29 @@ func (s *Server) handle(method, path string, h httpx.HandlerFunc) {
 		return err
 	})
 
-	s.mux.HandleFunc(path, fn).Methods(method)
+	route := s.mux.HandleFunc(path, fn).Methods(method)
+	s.scopes[route] = scope
+}
+
+// requiredScope returns the access token scope that's required to make the
+// request.
+func (s *Server) requiredScope(r *http.Request) empire.Scope {
+	route, _, _ := s.mux.Handler(r)
+	if scope, ok := s.scopes[route]; ok {
+		return scope
+	}
+	return methodScope(r.Method)
+}
+
+// methodScope returns the access token scope that's required for requests
+// with the given method, when the route doesn't require a specific scope.
+func methodScope(method string) empire.Scope {
+	switch method {
+	case "GET", "HEAD":
+		return empire.ScopeRead
+	default:
+		return empire.ScopeFull
+	}
 }
 
 // Encode json encodes v into w.
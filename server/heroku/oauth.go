@@ -1,38 +1,159 @@
 package heroku
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/jinzhu/gorm"
 	"github.com/remind101/empire"
 	"github.com/remind101/empire/pkg/heroku"
+	"github.com/remind101/pkg/httpx"
+	"github.com/remind101/pkg/timex"
 	"golang.org/x/net/context"
 )
 
 const (
 	HeaderTwoFactor = "Heroku-Two-Factor-Code"
+
+	// MaxExpiresIn is the longest that an access token can be created for,
+	// in seconds. Tokens that should last longer than a year can be created
+	// without an expiration.
+	MaxExpiresIn = 365 * 24 * 60 * 60
 )
 
 type Authorization heroku.OAuthAuthorization
 
 func newAuthorization(token *empire.AccessToken) *Authorization {
-	return &Authorization{
+	a := &Authorization{
+		Id:          token.ID,
+		Description: token.Description,
+		Scope:       token.Scopes.Strings(),
+		LastUsedAt:  token.LastUsedAt,
+		RevokedAt:   token.RevokedAt,
 		AccessToken: &struct {
 			ExpiresIn *int   `json:"expires_in"`
 			Id        string `json:"id"`
 			Token     string `json:"token"`
 		}{
+			Id:    token.ID,
 			Token: token.Token,
 		},
 	}
+
+	if token.CreatedAt != nil {
+		a.CreatedAt = *token.CreatedAt
+	}
+
+	if token.ExpiresAt != nil {
+		expiresIn := int(token.ExpiresAt.Sub(timex.Now()) / time.Second)
+		if expiresIn < 0 {
+			expiresIn = 0
+		}
+		a.AccessToken.ExpiresIn = &expiresIn
+	}
+
+	return a
+}
+
+func newAuthorizations(ts []*empire.AccessToken) []*Authorization {
+	authorizations := make([]*Authorization, len(ts))
+
+	for i := 0; i < len(ts); i++ {
+		authorizations[i] = newAuthorization(ts[i])
+	}
+
+	return authorizations
+}
+
+type PostAuthorizationsForm struct {
+	Scope       []string `json:"scope"`
+	Description string   `json:"description"`
+	ExpiresIn   *int     `json:"expires_in"`
 }
 
 func (h *Server) PostAuthorizations(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
-	at, err := h.Empire.AccessTokensCreate(&empire.AccessToken{
+	var form PostAuthorizationsForm
+
+	// The body is optional, for compatibility with older clients.
+	if r.ContentLength != 0 {
+		if err := Decode(r, &form); err != nil {
+			return err
+		}
+	}
+
+	scopes, err := empire.ParseScopes(form.Scope)
+	if err != nil {
+		return err
+	}
+
+	var expiresAt *time.Time
+	if form.ExpiresIn != nil {
+		if *form.ExpiresIn <= 0 {
+			return &ErrorResource{
+				Status:  http.StatusBadRequest,
+				ID:      "bad_request",
+				Message: "expires_in must be greater than 0.",
+			}
+		}
+		if *form.ExpiresIn > MaxExpiresIn {
+			return &ErrorResource{
+				Status:  http.StatusBadRequest,
+				ID:      "bad_request",
+				Message: fmt.Sprintf("expires_in can't be more than %d seconds (1 year). Omit it to create a token that doesn't expire.", MaxExpiresIn),
+			}
+		}
+		t := timex.Now().Add(time.Duration(*form.ExpiresIn) * time.Second)
+		expiresAt = &t
+	}
+
+	token := &empire.AccessToken{
+		User:        UserFromContext(ctx),
+		Description: form.Description,
+		Scopes:      scopes,
+		ExpiresAt:   expiresAt,
+	}
+
+	at, err := h.Empire.AccessTokensCreate(token)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(201)
+	return Encode(w, newAuthorization(at))
+}
+
+// GetAuthorizations returns the access tokens that belong to the user.
+func (h *Server) GetAuthorizations(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ts, err := h.AccessTokens(empire.AccessTokensQuery{
 		User: UserFromContext(ctx),
 	})
 	if err != nil {
 		return err
 	}
 
-	return Encode(w, newAuthorization(at))
+	w.WriteHeader(200)
+	return Encode(w, newAuthorizations(ts))
+}
+
+// DeleteAuthorization revokes an access token.
+func (h *Server) DeleteAuthorization(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	vars := httpx.Vars(ctx)
+
+	err := h.AccessTokensRevoke(empire.RevokeAccessTokenOpts{
+		User: UserFromContext(ctx),
+		ID:   vars["id"],
+	})
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			return &ErrorResource{
+				Status:  http.StatusNotFound,
+				ID:      "not_found",
+				Message: "Couldn't find that authorization.",
+			}
+		}
+		return err
+	}
+
+	return NoContent(w)
 }
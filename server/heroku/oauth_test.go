@@ -0,0 +1,35 @@
+package heroku
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
+)
+
+func TestPostAuthorizations_InvalidExpiresIn(t *testing.T) {
+	h := &Server{}
+
+	tests := []struct {
+		body string
+		err  string
+	}{
+		{`{"expires_in": 0}`, "expires_in must be greater than 0."},
+		{`{"expires_in": -60}`, "expires_in must be greater than 0."},
+		{`{"expires_in": 9223372036}`, "expires_in can't be more than 31536000 seconds (1 year). Omit it to create a token that doesn't expire."},
+	}
+
+	for _, tt := range tests {
+		resp := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/oauth/authorizations", bytes.NewBufferString(tt.body))
+
+		err := h.PostAuthorizations(context.Background(), resp, req)
+		if assert.IsType(t, &ErrorResource{}, err) {
+			assert.Equal(t, http.StatusBadRequest, err.(*ErrorResource).Status)
+			assert.EqualError(t, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package heroku
+
+import (
+	"net/http"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/pkg/heroku"
+	"github.com/remind101/pkg/httpx"
+	"golang.org/x/net/context"
+)
+
+type Permission heroku.Permission
+
+func newPermission(p *empire.Permission) *Permission {
+	return &Permission{
+		Id:        p.ID,
+		App:       p.App,
+		Principal: p.Principal,
+		Action:    p.Action,
+		CreatedAt: *p.CreatedAt,
+	}
+}
+
+func newPermissions(ps []*empire.Permission) []*Permission {
+	permissions := make([]*Permission, len(ps))
+
+	for i := 0; i < len(ps); i++ {
+		permissions[i] = newPermission(ps[i])
+	}
+
+	return permissions
+}
+
+// GetPermissions returns the granted permissions, optionally filtered to a
+// single app.
+func (h *Server) GetPermissions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var q empire.PermissionsQuery
+	if app := r.URL.Query().Get("app"); app != "" {
+		q.Apps = []string{app, empire.Wildcard}
+	}
+
+	ps, err := h.Permissions(q)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, newPermissions(ps))
+}
+
+type PostPermissionsForm struct {
+	App       string `json:"app"`
+	Principal string `json:"principal"`
+	Action    string `json:"action"`
+}
+
+// PostPermissions grants a new permission.
+func (h *Server) PostPermissions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var form PostPermissionsForm
+
+	if err := Decode(r, &form); err != nil {
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	p, err := h.Grant(ctx, empire.GrantOpts{
+		User:      UserFromContext(ctx),
+		App:       form.App,
+		Principal: form.Principal,
+		Action:    form.Action,
+		Message:   m,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(201)
+	return Encode(w, newPermission(p))
+}
+
+// DeletePermission revokes a permission.
+func (h *Server) DeletePermission(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	vars := httpx.Vars(ctx)
+	id := vars["permission"]
+
+	p, err := h.PermissionsFind(empire.PermissionsQuery{ID: &id})
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			return &ErrorResource{
+				Status:  http.StatusNotFound,
+				ID:      "not_found",
+				Message: "Couldn't find that permission.",
+			}
+		}
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	if err := h.Revoke(ctx, empire.RevokeOpts{
+		User:       UserFromContext(ctx),
+		Permission: p,
+		Message:    m,
+	}); err != nil {
+		return err
+	}
+
+	return NoContent(w)
+}
@@ -0,0 +1,163 @@
+package heroku
+
+import (
+	"net/http"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/pkg/heroku"
+	streamhttp "github.com/remind101/empire/pkg/stream/http"
+	"github.com/remind101/pkg/httpx"
+	"golang.org/x/net/context"
+)
+
+type Pipeline heroku.Pipeline
+
+func newPipeline(p *empire.Pipeline, apps []*empire.App) *Pipeline {
+	names := make([]string, len(apps))
+	for i, a := range apps {
+		names[i] = a.Name
+	}
+
+	return &Pipeline{
+		Name:      p.Name,
+		Apps:      names,
+		CreatedAt: *p.CreatedAt,
+		UpdatedAt: *p.UpdatedAt,
+	}
+}
+
+// GetPipelines returns all of the pipelines, and the apps in them.
+func (h *Server) GetPipelines(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	ps, err := h.Pipelines(empire.PipelinesQuery{})
+	if err != nil {
+		return err
+	}
+
+	pipelines := make([]*Pipeline, len(ps))
+	for i, p := range ps {
+		apps, err := h.PipelineApps(p)
+		if err != nil {
+			return err
+		}
+		pipelines[i] = newPipeline(p, apps)
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, pipelines)
+}
+
+type PutPipelineForm struct {
+	Apps []string `json:"apps"`
+}
+
+// PutPipeline creates a pipeline, or replaces the apps in an existing one.
+func (h *Server) PutPipeline(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var form PutPipelineForm
+
+	if err := Decode(r, &form); err != nil {
+		return err
+	}
+
+	var apps []*empire.App
+	for _, name := range form.Apps {
+		name := name
+		app, err := h.AppsFind(empire.AppsQuery{Name: &name})
+		if err != nil {
+			if err == gorm.RecordNotFound {
+				return ErrNotFound
+			}
+			return err
+		}
+		apps = append(apps, app)
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	p, err := h.SetPipeline(ctx, empire.SetPipelineOpts{
+		User:    UserFromContext(ctx),
+		Name:    httpx.Vars(ctx)["pipeline"],
+		Apps:    apps,
+		Message: m,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, newPipeline(p, apps))
+}
+
+// DeletePipeline destroys a pipeline.
+func (h *Server) DeletePipeline(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	name := httpx.Vars(ctx)["pipeline"]
+	p, err := h.PipelinesFind(empire.PipelinesQuery{Name: &name})
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			return &ErrorResource{
+				Status:  http.StatusNotFound,
+				ID:      "not_found",
+				Message: "Couldn't find that pipeline.",
+			}
+		}
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	if err := h.DestroyPipeline(ctx, empire.DestroyPipelineOpts{
+		User:     UserFromContext(ctx),
+		Pipeline: p,
+		Message:  m,
+	}); err != nil {
+		return err
+	}
+
+	return NoContent(w)
+}
+
+// PostPromotionsForm is the form object that represents the POST body.
+type PostPromotionsForm struct {
+	Version *int `json:"version"`
+	Stream  bool `json:"stream"`
+}
+
+// PostPromotions promotes a release of the app to the next app in its
+// pipeline, and streams the output.
+func (h *Server) PostPromotions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var form PostPromotionsForm
+
+	if err := Decode(r, &form); err != nil {
+		return err
+	}
+
+	app, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json; boundary=NL")
+
+	// We ignore errors here since this is a streaming endpoint,
+	// and the error is handled in the response message
+	_, _ = h.Promote(ctx, empire.PromoteOpts{
+		User:    UserFromContext(ctx),
+		App:     app,
+		Version: form.Version,
+		Output:  empire.NewDeploymentStream(streamhttp.StreamingResponseWriter(w)),
+		Message: m,
+		Stream:  form.Stream,
+	})
+	return nil
+}
@@ -1,8 +1,10 @@
 package heroku
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/remind101/empire"
 	"github.com/remind101/empire/pkg/heroku"
@@ -13,8 +15,14 @@ import (
 type Release heroku.Release
 
 func newRelease(r *empire.Release) *Release {
+	status := "succeeded"
+	if r.Failed {
+		status = "failed"
+	}
+
 	return &Release{
 		Id:      r.ID,
+		Status:  status,
 		Version: r.Version,
 		Slug: &struct {
 			Id string `json:"id"`
@@ -125,3 +133,98 @@ func (h *Server) PostReleases(ctx context.Context, w http.ResponseWriter, r *htt
 	w.WriteHeader(200)
 	return Encode(w, newRelease(release))
 }
+
+type ReleaseDiff heroku.ReleaseDiff
+
+// newReleaseDiff converts an empire.ReleaseDiff to a ReleaseDiff. Config var
+// values are only included if values is true.
+func newReleaseDiff(d *empire.ReleaseDiff, values bool) *ReleaseDiff {
+	rd := &ReleaseDiff{
+		From: d.From.Version,
+		To:   d.To.Version,
+	}
+
+	if d.Image != nil {
+		f := newReleaseDiffField(d.Image)
+		rd.Image = &f
+	}
+
+	for _, c := range d.Config {
+		v := heroku.ReleaseDiffConfigVar{
+			Name:   string(c.Name),
+			Change: string(c.Change),
+		}
+		if values {
+			v.From, v.To = c.From, c.To
+		}
+		rd.Config = append(rd.Config, v)
+	}
+
+	for _, p := range d.Processes {
+		dp := heroku.ReleaseDiffProcess{
+			Name:   p.Name,
+			Change: string(p.Change),
+		}
+		for _, f := range p.Fields {
+			dp.Fields = append(dp.Fields, newReleaseDiffField(f))
+		}
+		rd.Processes = append(rd.Processes, dp)
+	}
+
+	return rd
+}
+
+func newReleaseDiffField(f *empire.FieldDiff) heroku.ReleaseDiffField {
+	return heroku.ReleaseDiffField{
+		Field: f.Field,
+		From:  f.From,
+		To:    f.To,
+	}
+}
+
+func (h *Server) GetReleaseDiff(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	a, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	vars := httpx.Vars(ctx)
+	from, err := parseReleaseVersion(vars["from"])
+	if err != nil {
+		return err
+	}
+	to, err := parseReleaseVersion(vars["to"])
+	if err != nil {
+		return err
+	}
+
+	values := r.URL.Query().Get("values") == "true"
+
+	d, err := h.ReleasesDiff(empire.ReleaseDiffOpts{
+		App:    a,
+		From:   from,
+		To:     to,
+		User:   UserFromContext(ctx),
+		Reveal: values,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, newReleaseDiff(d, values))
+}
+
+// parseReleaseVersion parses a release version like "v3" or "3", returning a
+// bad request error if it's not a number.
+func parseReleaseVersion(s string) (int, error) {
+	vers, err := strconv.Atoi(strings.TrimPrefix(s, "v"))
+	if err != nil {
+		return 0, &ErrorResource{
+			Status:  http.StatusBadRequest,
+			ID:      "bad_request",
+			Message: fmt.Sprintf("%q is not a valid release version. Versions look like v3 or 3.", s),
+		}
+	}
+	return vers, nil
+}
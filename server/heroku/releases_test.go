@@ -0,0 +1,24 @@
+package heroku
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReleaseVersion(t *testing.T) {
+	vers, err := parseReleaseVersion("v3")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, vers)
+
+	vers, err = parseReleaseVersion("3")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, vers)
+
+	_, err = parseReleaseVersion("latest")
+	if assert.IsType(t, &ErrorResource{}, err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*ErrorResource).Status)
+		assert.EqualError(t, err, `"latest" is not a valid release version. Versions look like v3 or 3.`)
+	}
+}
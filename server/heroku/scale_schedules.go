@@ -0,0 +1,131 @@
+package heroku
+
+import (
+	"net/http"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/pkg/heroku"
+	"github.com/remind101/pkg/httpx"
+	"golang.org/x/net/context"
+)
+
+type ScaleSchedule heroku.ScaleSchedule
+
+func newScaleSchedule(s *empire.ScaleSchedule) *ScaleSchedule {
+	return &ScaleSchedule{
+		Id:        s.ID,
+		Process:   s.Process,
+		Cron:      s.Cron,
+		Quantity:  s.Quantity,
+		Size:      s.Size,
+		User:      s.UserName,
+		NextRunAt: s.NextRunAt,
+		LastRunAt: s.LastRunAt,
+		CreatedAt: *s.CreatedAt,
+	}
+}
+
+func newScaleSchedules(ss []*empire.ScaleSchedule) []*ScaleSchedule {
+	schedules := make([]*ScaleSchedule, len(ss))
+
+	for i := 0; i < len(ss); i++ {
+		schedules[i] = newScaleSchedule(ss[i])
+	}
+
+	return schedules
+}
+
+// GetScaleSchedules returns the scale schedules for an app.
+func (h *Server) GetScaleSchedules(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	app, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	ss, err := h.ScaleSchedules(empire.ScaleSchedulesQuery{App: app})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(200)
+	return Encode(w, newScaleSchedules(ss))
+}
+
+type PostScaleSchedulesForm struct {
+	Process  string              `json:"process"`
+	Cron     string              `json:"cron"`
+	Quantity int                 `json:"quantity"`
+	Size     *empire.Constraints `json:"size"`
+}
+
+// PostScaleSchedules schedules a process to be scaled.
+func (h *Server) PostScaleSchedules(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var form PostScaleSchedulesForm
+
+	if err := Decode(r, &form); err != nil {
+		return err
+	}
+
+	app, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	s, err := h.ScaleScheduleCreate(ctx, empire.CreateScaleScheduleOpts{
+		User:        UserFromContext(ctx),
+		App:         app,
+		Process:     form.Process,
+		Cron:        form.Cron,
+		Quantity:    form.Quantity,
+		Constraints: form.Size,
+		Message:     m,
+	})
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(201)
+	return Encode(w, newScaleSchedule(s))
+}
+
+// DeleteScaleSchedule removes a scale schedule.
+func (h *Server) DeleteScaleSchedule(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	app, err := findApp(ctx, h)
+	if err != nil {
+		return err
+	}
+
+	id := httpx.Vars(ctx)["id"]
+	s, err := h.ScaleSchedulesFind(empire.ScaleSchedulesQuery{ID: &id, App: app})
+	if err != nil {
+		if err == gorm.RecordNotFound {
+			return &ErrorResource{
+				Status:  http.StatusNotFound,
+				ID:      "not_found",
+				Message: "Couldn't find that scale schedule.",
+			}
+		}
+		return err
+	}
+
+	m, err := findMessage(r)
+	if err != nil {
+		return err
+	}
+
+	if err := h.ScaleScheduleDestroy(ctx, empire.DestroyScaleScheduleOpts{
+		User:     UserFromContext(ctx),
+		Schedule: s,
+		Message:  m,
+	}); err != nil {
+		return err
+	}
+
+	return NoContent(w)
+}
@@ -6,6 +6,7 @@ package server
 import (
 	"io"
 	"net/http"
+	"text/template"
 
 	"github.com/remind101/empire"
 	"github.com/remind101/empire/server/auth"
@@ -31,6 +32,21 @@ type Options struct {
 			Environments []string
 			ImageBuilder github.ImageBuilder
 			TugboatURL   string
+
+			// If provided, a template that's executed with the
+			// *empire.Release to determine the URL that's linked
+			// from deployment statuses.
+			URL *template.Template
+		}
+
+		// If provided, used to create GitHub deployments and post
+		// deployment statuses.
+		Client github.DeploymentsClient
+
+		// Review apps for pull requests
+		ReviewApps struct {
+			Templates map[string]string
+			URL       *template.Template
 		}
 	}
 }
@@ -44,6 +60,7 @@ func New(e *empire.Empire, options Options) httpx.Handler {
 			Secret:       options.GitHub.Webhooks.Secret,
 			Environments: options.GitHub.Deployments.Environments,
 			Deployer:     newDeployer(e, options),
+			ReviewApps:   newReviewApps(e, options),
 		})
 		r.Match(githubWebhook, g)
 	}
@@ -93,6 +110,21 @@ func (h *HealthHandler) ServeHTTPContext(_ context.Context, w http.ResponseWrite
 	return nil
 }
 
+// newReviewApps returns a new github.ReviewApps instance for the given options,
+// or nil if review apps aren't enabled.
+func newReviewApps(e *empire.Empire, options Options) *github.ReviewApps {
+	if len(options.GitHub.ReviewApps.Templates) == 0 {
+		return nil
+	}
+
+	r := github.NewReviewApps(e)
+	r.Templates = options.GitHub.ReviewApps.Templates
+	r.URL = options.GitHub.ReviewApps.URL
+	r.GitHub = options.GitHub.Client
+	r.ImageBuilder = options.GitHub.Deployments.ImageBuilder
+	return r
+}
+
 // newDeployer generates a new github.Deployer implementation for the given
 // options.
 func newDeployer(e *empire.Empire, options Options) github.Deployer {
@@ -102,9 +134,14 @@ func newDeployer(e *empire.Empire, options Options) github.Deployer {
 	var d github.Deployer = ed
 
 	// Enables the Tugboat integration, which will send logs to a Tugboat
-	// instance.
+	// instance. Otherwise, if we have a GitHub client, deployment statuses
+	// are posted to GitHub directly.
 	if url := options.GitHub.Deployments.TugboatURL; url != "" {
 		d = github.NotifyTugboat(d, url)
+	} else if options.GitHub.Client != nil {
+		gd := github.NotifyGitHub(ed, options.GitHub.Client)
+		gd.URL = options.GitHub.Deployments.URL
+		d = gd
 	}
 
 	// Add tracing information so we know about errors.
@@ -35,7 +35,7 @@ func TestAttachCert(t *testing.T) {
 	cert := "serverCertificate"
 	app, err := c.AppUpdate(appName, &heroku.AppUpdateOpts{
 		Cert: &cert,
-	})
+	}, "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -43,6 +43,34 @@ func TestAttachCert(t *testing.T) {
 	assert.Equal(t, cert, app.Cert)
 }
 
+func TestAppRename(t *testing.T) {
+	c, s := NewTestClient(t)
+	defer s.Close()
+
+	mustAppCreate(t, c, empire.App{
+		Name: "acme-inc",
+	})
+	mustAppCreate(t, c, empire.App{
+		Name: "acme-corp",
+	})
+
+	name := "acme-corp"
+	_, err := c.AppUpdate("acme-inc", &heroku.AppUpdateOpts{
+		Name: &name,
+	}, "")
+	assert.EqualError(t, err, "Request invalid, validate usage and try again")
+
+	name = "acme-inc-2"
+	app, err := c.AppUpdate("acme-inc", &heroku.AppUpdateOpts{
+		Name: &name,
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "acme-inc-2", app.Name)
+}
+
 func TestAppList(t *testing.T) {
 	c, s := NewTestClient(t)
 	defer s.Close()
@@ -21,8 +21,9 @@ func TestConfigVarUpdate(t *testing.T) {
 		"RAILS_ENV": &env,
 	})
 
+	// Sensitive values are masked in the response.
 	expected := map[string]string{
-		"RAILS_ENV": "production",
+		"RAILS_ENV": "****",
 	}
 
 	if got, want := v, expected; !reflect.DeepEqual(got, want) {
@@ -70,6 +71,30 @@ func TestConfigVarInfo(t *testing.T) {
 	v := mustConfigVarInfo(t, c, "acme-inc")
 
 	expected := map[string]string{
+		"RAILS_ENV": "****",
+	}
+
+	if got, want := v, expected; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Config => %v; want %v", got, want)
+	}
+
+	// Revealing config vars has to be granted.
+	if _, err := c.ConfigVarReveal("acme-inc"); err == nil {
+		t.Fatal("Expected an error revealing config vars without the reveal permission")
+	}
+
+	for _, action := range []string{"access", "reveal"} {
+		if _, err := c.PermissionCreate("acme-inc", "user:fake", action, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	v, err := c.ConfigVarReveal("acme-inc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = map[string]string{
 		"RAILS_ENV": "production",
 	}
 
@@ -78,6 +103,37 @@ func TestConfigVarInfo(t *testing.T) {
 	}
 }
 
+func TestConfigVarSensitivityUpdate(t *testing.T) {
+	c, s := NewTestClient(t)
+	defer s.Close()
+
+	mustAppCreate(t, c, empire.App{
+		Name: "acme-inc",
+	})
+
+	env := "production"
+	mustConfigVarUpdate(t, c, "acme-inc", map[string]*string{
+		"RAILS_ENV": &env,
+	})
+
+	sensitivity, err := c.ConfigVarSensitivityUpdate("acme-inc", map[string]bool{
+		"RAILS_ENV": false,
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := sensitivity, map[string]bool{"RAILS_ENV": false}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Sensitivity => %v; want %v", got, want)
+	}
+
+	v := mustConfigVarInfo(t, c, "acme-inc")
+
+	if got, want := v, map[string]string{"RAILS_ENV": "production"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Config => %v; want %v", got, want)
+	}
+}
+
 func mustConfigVarUpdate(t testing.TB, c *heroku.Client, appName string, options map[string]*string) map[string]string {
 	vars, err := c.ConfigVarUpdate(appName, options, "")
 	if err != nil {
@@ -0,0 +1,39 @@
+package cli_test
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestAccess(t *testing.T) {
+	run(t, []Command{
+		{
+			"create acme-inc",
+			"Created acme-inc.",
+		},
+		{
+			"access -a acme-inc",
+			"",
+		},
+		{
+			"access-grant user:fake access -a acme-inc",
+			"Granted user:fake access to access acme-inc.",
+		},
+		{
+			"access-grant user:fake set -a acme-inc",
+			"Granted user:fake access to set acme-inc.",
+		},
+		{
+			"access-grant team:1234 * -a *",
+			"Granted team:1234 access to * *.",
+		},
+		{
+			"access -a acme-inc",
+			regexp.MustCompile(`^[0-9a-f-]{36}  \*         team:1234  \*\n[0-9a-f-]{36}  acme-inc  user:fake  access\n[0-9a-f-]{36}  acme-inc  user:fake  set\n$`),
+		},
+		{
+			"set DATABASE_URL=postgres://localhost -a acme-inc",
+			"Set env vars and restarted acme-inc.",
+		},
+	})
+}
@@ -43,3 +43,20 @@ func TestAppInfo(t *testing.T) {
 		},
 	})
 }
+
+func TestRename(t *testing.T) {
+	run(t, []Command{
+		{
+			"create acme-inc",
+			"Created acme-inc.",
+		},
+		{
+			"rename acme-inc acme-corp",
+			"Renamed acme-inc to acme-corp.\nEnsure you update your git remote URL.",
+		},
+		{
+			"apps",
+			"acme-corp    Dec 31  2014",
+		},
+	})
+}
@@ -11,12 +11,24 @@ func TestConfig(t *testing.T) {
 			"create acme-inc",
 			"Created acme-inc.",
 		},
+		{
+			"access-grant user:fake access -a acme-inc",
+			"Granted user:fake access to access acme-inc.",
+		},
+		{
+			"access-grant user:fake reveal -a acme-inc",
+			"Granted user:fake access to reveal acme-inc.",
+		},
 		{
 			"set RAILS_ENV=production -a acme-inc",
 			"Set env vars and restarted acme-inc.",
 		},
 		{
 			"env -a acme-inc",
+			"RAILS_ENV=****",
+		},
+		{
+			"env -r -a acme-inc",
 			"RAILS_ENV=production",
 		},
 		{
@@ -29,8 +41,16 @@ func TestConfig(t *testing.T) {
 		},
 		{
 			"env -a acme-inc",
-			`AUTH=foo
-DATABASE_URL=postgres://localhost`,
+			`AUTH=****
+DATABASE_URL=****host`,
+		},
+		{
+			"env-sensitivity AUTH=false -a acme-inc",
+			"Marked env vars on acme-inc.",
+		},
+		{
+			"env-sensitivity -a acme-inc",
+			"AUTH",
 		},
 		{
 			"set EMPTY_VAR= -a acme-inc",
@@ -39,6 +59,12 @@ DATABASE_URL=postgres://localhost`,
 		{
 			"env -a acme-inc",
 			`AUTH=foo
+DATABASE_URL=****host
+EMPTY_VAR=`,
+		},
+		{
+			"env -r -a acme-inc",
+			`AUTH=foo
 DATABASE_URL=postgres://localhost
 EMPTY_VAR=`,
 		},
@@ -87,12 +113,20 @@ acme-inc.2.web.2    running   5d  "./bin/web"`,
 func TestConfigConsistency(t *testing.T) {
 	run(t, []Command{
 		DeployCommand("latest", "v1"),
+		{
+			"access-grant user:fake access -a acme-inc",
+			"Granted user:fake access to access acme-inc.",
+		},
+		{
+			"access-grant user:fake reveal -a acme-inc",
+			"Granted user:fake access to reveal acme-inc.",
+		},
 		{
 			"set FOO1=foo1 -a acme-inc",
 			"Set env vars and restarted acme-inc.",
 		},
 		{
-			"env -a acme-inc",
+			"env -r -a acme-inc",
 			"FOO1=foo1",
 		},
 		{
@@ -100,7 +134,7 @@ func TestConfigConsistency(t *testing.T) {
 			"Set env vars and restarted acme-inc.",
 		},
 		{
-			"env -a acme-inc",
+			"env -r -a acme-inc",
 			"FOO1=foo1\nFOO2=foo2",
 		},
 		{
@@ -108,7 +142,7 @@ func TestConfigConsistency(t *testing.T) {
 			"Set env vars and restarted acme-inc.",
 		},
 		{
-			"env -a acme-inc",
+			"env -r -a acme-inc",
 			"FOO1=foo1\nFOO2=foo2\nFOO3=foo3",
 		},
 	})
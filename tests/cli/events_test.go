@@ -0,0 +1,27 @@
+package cli_test
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+	run(t, []Command{
+		{
+			"create acme-inc",
+			"Created acme-inc.",
+		},
+		{
+			"set DATABASE_URL=postgres://localhost -a acme-inc",
+			"Set env vars and restarted acme-inc.",
+		},
+		{
+			"events -a acme-inc -t set",
+			regexp.MustCompile(`^.*fake  set  fake changed environment variables on acme-inc \(DATABASE_URL\)\n$`),
+		},
+		{
+			"events -u fake",
+			regexp.MustCompile(`^.*fake  set     fake changed environment variables on acme-inc \(DATABASE_URL\)\n.*fake  create  fake created acme-inc\n$`),
+		},
+	})
+}
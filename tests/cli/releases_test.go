@@ -0,0 +1,35 @@
+package cli_test
+
+import "testing"
+
+func TestReleaseDiff(t *testing.T) {
+	run(t, []Command{
+		DeployCommand("latest", "v1"),
+		{
+			"access-grant user:fake access -a acme-inc",
+			"Granted user:fake access to access acme-inc.",
+		},
+		{
+			"access-grant user:fake reveal -a acme-inc",
+			"Granted user:fake access to reveal acme-inc.",
+		},
+		{
+			"set FOO=bar -a acme-inc",
+			"Set env vars and restarted acme-inc.",
+		},
+		{
+			"release-diff v1 v2 -a acme-inc",
+			`Config:
+  +  FOO`,
+		},
+		{
+			"release-diff -v v2 -a acme-inc",
+			`Config:
+  +  FOO   -> bar`,
+		},
+		{
+			"release-diff v2 v2 -a acme-inc",
+			"No changes between v2 and v2.",
+		},
+	})
+}
@@ -0,0 +1,99 @@
+package empire_test
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/empiretest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmpire_AccessTokens_Revoke(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	user := &empire.User{Name: "ejholmes"}
+
+	token, err := e.AccessTokensCreate(&empire.AccessToken{
+		User:        user,
+		Description: "CI",
+	})
+	assert.NoError(t, err)
+
+	tokens, err := e.AccessTokens(empire.AccessTokensQuery{User: user})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(tokens))
+	assert.Equal(t, "CI", tokens[0].Description)
+	assert.Equal(t, empire.Scopes{empire.ScopeFull}, tokens[0].Scopes)
+
+	// Other users can't revoke the token.
+	err = e.AccessTokensRevoke(empire.RevokeAccessTokenOpts{
+		User: &empire.User{Name: "bob"},
+		ID:   token.ID,
+	})
+	assert.Equal(t, gorm.RecordNotFound, err)
+
+	err = e.AccessTokensRevoke(empire.RevokeAccessTokenOpts{
+		User: user,
+		ID:   token.ID,
+	})
+	assert.NoError(t, err)
+
+	found, err := e.AccessTokensFind(token.Token)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestEmpire_AccessTokens_Expired(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+
+	// The JWT itself doesn't get an exp claim here, so that we're testing
+	// that the expiration stored in the database is honored.
+	expiresAt := fakeNow.Add(-time.Minute)
+	token, err := e.AccessTokensCreate(&empire.AccessToken{
+		User: &empire.User{Name: "ejholmes"},
+	})
+	assert.NoError(t, err)
+
+	err = e.DB.Exec(`UPDATE access_tokens SET expires_at = ? WHERE id = ?`, expiresAt, token.ID).Error
+	assert.NoError(t, err)
+
+	found, err := e.AccessTokensFind(token.Token)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestEmpire_AccessTokens_Scoped(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+
+	user := &empire.User{Name: "ejholmes"}
+	_, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	token, err := e.AccessTokensCreate(&empire.AccessToken{
+		User:   user,
+		Scopes: empire.Scopes{empire.ScopeDeploy},
+	})
+	assert.NoError(t, err)
+
+	token, err = e.AccessTokensFind(token.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, empire.Scopes{empire.ScopeDeploy}, token.User.Scopes)
+
+	_, err = e.Create(context.Background(), empire.CreateOpts{
+		User: token.User,
+		Name: "other-app",
+	})
+	assert.IsType(t, &empire.AccessDeniedError{}, err)
+
+	// Scoped tokens can't be used to create other tokens.
+	_, err = e.AccessTokensCreate(&empire.AccessToken{
+		User: token.User,
+	})
+	assert.IsType(t, &empire.AccessDeniedError{}, err)
+}
@@ -0,0 +1,78 @@
+package empire_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/empiretest"
+	"github.com/remind101/empire/pkg/image"
+	"github.com/remind101/empire/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEmpire_Rename(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+
+	user := &empire.User{Name: "ejholmes"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	_, err = e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-corp",
+	})
+	assert.NoError(t, err)
+
+	s.On("Submit", mock.AnythingOfType("*scheduler.App")).Return(nil)
+
+	_, err = e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  image.Image{Repository: "remind101/acme-inc"},
+	})
+	assert.NoError(t, err)
+
+	_, err = e.Rename(context.Background(), empire.RenameOpts{
+		User: user,
+		App:  app,
+		Name: "acme-corp",
+	})
+	assert.Equal(t, empire.ErrNameTaken, err)
+
+	_, err = e.Rename(context.Background(), empire.RenameOpts{
+		User: user,
+		App:  app,
+		Name: "Acme Inc",
+	})
+	assert.Equal(t, empire.ErrInvalidName, err)
+
+	app, err = e.Rename(context.Background(), empire.RenameOpts{
+		User: user,
+		App:  app,
+		Name: "acme-inc-2",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme-inc-2", app.Name)
+
+	// The latest release should have been resubmitted with the new name.
+	submitted := s.Calls[len(s.Calls)-1].Arguments.Get(0).(*scheduler.App)
+	assert.Equal(t, app.ID, submitted.ID)
+	assert.Equal(t, "acme-inc-2", submitted.Name)
+
+	app, err = e.AppsFind(empire.AppsQuery{ID: &app.ID})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme-inc-2", app.Name)
+
+	s.AssertExpectations(t)
+}
@@ -0,0 +1,145 @@
+package empire_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/empiretest"
+	"github.com/remind101/empire/pkg/image"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEmpire_Deploy_Canary(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+
+	user := &empire.User{Name: "ejholmes"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	s.On("Submit", mock.Anything).Return(nil).Once()
+	_, err = e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  image.Image{Repository: "remind101/acme-inc", Tag: "v1"},
+	})
+	assert.NoError(t, err)
+
+	// v2 runs as a canary alongside v1.
+	s.On("SubmitCanary", mock.Anything).Return(nil).Once()
+	r, err := e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  image.Image{Repository: "remind101/acme-inc", Tag: "v2"},
+		Canary: &empire.CanaryOpts{Steps: []int{5, 100}, Threshold: 1},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, r.Version)
+
+	c, err := e.CanariesFind(empire.CanariesQuery{App: app})
+	assert.NoError(t, err)
+	assert.Equal(t, empire.CanaryRunning, c.Status)
+	assert.Equal(t, 2, c.Version)
+	assert.Equal(t, 1, c.StableVersion)
+	assert.Equal(t, 5, c.Weight())
+
+	// Other releases can't be created while the canary is running.
+	_, err = e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  image.Image{Repository: "remind101/acme-inc", Tag: "v3"},
+	})
+	assert.EqualError(t, err, "Canary v2 of acme-inc is running. Promote or abort it first.")
+
+	// Too many requests to the canary fail, so it's aborted, and the app
+	// is rolled back to v1.
+	s.On("CanaryErrorRate", app.ID).Return(2.5, nil).Once()
+	s.On("Submit", mock.Anything).Return(nil).Once()
+	err = e.CheckCanaries(context.Background())
+	assert.NoError(t, err)
+
+	c, err = e.CanariesFind(empire.CanariesQuery{App: app})
+	assert.NoError(t, err)
+	assert.Equal(t, empire.CanaryAborted, c.Status)
+	assert.Equal(t, "2.50% of requests failed, which is more than the threshold of 1.00%", *c.Reason)
+
+	releases, err := e.Releases(empire.ReleasesQuery{App: app})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(releases))
+	assert.Equal(t, "Rollback to v1 (ejholmes: 'canary v2 was aborted')", releases[0].Description)
+	assert.True(t, releases[1].Failed)
+
+	events, err := e.Events(empire.EventsQuery{App: app})
+	assert.NoError(t, err)
+	assert.Equal(t, "canary", events[0].Type)
+
+	s.AssertExpectations(t)
+}
+
+func TestEmpire_PromoteCanary(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+
+	user := &empire.User{Name: "ejholmes"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	_, err = e.PromoteCanary(context.Background(), empire.PromoteCanaryOpts{
+		User: user,
+		App:  app,
+	})
+	assert.Equal(t, gorm.RecordNotFound, err)
+
+	s.On("Submit", mock.Anything).Return(nil).Once()
+	_, err = e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  image.Image{Repository: "remind101/acme-inc", Tag: "v1"},
+	})
+	assert.NoError(t, err)
+
+	s.On("SubmitCanary", mock.Anything).Return(nil).Once()
+	_, err = e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  image.Image{Repository: "remind101/acme-inc", Tag: "v2"},
+		Canary: &empire.CanaryOpts{Steps: empire.DefaultCanarySteps, Pause: empire.DefaultCanaryPause, Threshold: 1},
+	})
+	assert.NoError(t, err)
+
+	// v2 replaces v1, without a canary.
+	s.On("Submit", mock.Anything).Return(nil).Once()
+	c, err := e.PromoteCanary(context.Background(), empire.PromoteCanaryOpts{
+		User: user,
+		App:  app,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, empire.CanaryPromoted, c.Status)
+
+	releases, err := e.Releases(empire.ReleasesQuery{App: app})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(releases))
+	assert.False(t, releases[0].Failed)
+
+	s.AssertExpectations(t)
+}
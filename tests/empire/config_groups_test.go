@@ -0,0 +1,194 @@
+package empire_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/empiretest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmpire_ConfigGroups(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+
+	user := &empire.User{Name: "ejholmes"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	statsd, sentry := "statsd.internal:8125", "https://sentry.internal/1"
+	group, err := e.SetConfigGroup(context.Background(), empire.SetConfigGroupOpts{
+		User: user,
+		Name: "shared",
+		Vars: empire.Vars{"STATSD_HOST": &statsd, "SENTRY_DSN": &sentry},
+	})
+	assert.NoError(t, err)
+
+	err = e.AttachConfigGroup(context.Background(), empire.AttachConfigGroupOpts{
+		User:  user,
+		App:   app,
+		Group: group,
+	})
+	assert.NoError(t, err)
+
+	for _, action := range []string{"access", "reveal"} {
+		_, err = e.Grant(context.Background(), empire.GrantOpts{
+			User:      user,
+			App:       "acme-inc",
+			Principal: "user:ejholmes",
+			Action:    action,
+		})
+		assert.NoError(t, err)
+	}
+
+	// The app's own vars take precedence over the config group's.
+	override := "https://sentry.internal/2"
+	_, err = e.Set(context.Background(), empire.SetOpts{
+		User: user,
+		App:  app,
+		Vars: empire.Vars{"SENTRY_DSN": &override},
+	})
+	assert.NoError(t, err)
+
+	readConfig := func() empire.Vars {
+		c, err := e.ReadConfig(context.Background(), empire.ReadConfigOpts{
+			User:   user,
+			App:    app,
+			Reveal: true,
+		})
+		assert.NoError(t, err)
+		return c.Vars
+	}
+
+	vars := readConfig()
+	assert.Equal(t, "statsd.internal:8125", *vars["STATSD_HOST"])
+	assert.Equal(t, "https://sentry.internal/2", *vars["SENTRY_DSN"])
+
+	// Changing the config group queues the app for a release.
+	statsd = "statsd.internal:9125"
+	_, err = e.SetConfigGroup(context.Background(), empire.SetConfigGroupOpts{
+		User: user,
+		Name: "shared",
+		Vars: empire.Vars{"STATSD_HOST": &statsd},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "statsd.internal:8125", *readConfig()["STATSD_HOST"])
+
+	err = e.RunConfigGroupReleases(context.Background(), empire.DefaultConfigGroupReleaseBatchSize)
+	assert.NoError(t, err)
+	assert.Equal(t, "statsd.internal:9125", *readConfig()["STATSD_HOST"])
+
+	// Config groups can't be destroyed while they're attached.
+	err = e.DestroyConfigGroup(context.Background(), empire.DestroyConfigGroupOpts{
+		User:  user,
+		Group: group,
+	})
+	assert.Error(t, err)
+
+	err = e.DetachConfigGroup(context.Background(), empire.AttachConfigGroupOpts{
+		User:  user,
+		App:   app,
+		Group: group,
+	})
+	assert.NoError(t, err)
+
+	vars = readConfig()
+	assert.Nil(t, vars["STATSD_HOST"])
+	assert.Equal(t, "https://sentry.internal/2", *vars["SENTRY_DSN"])
+
+	err = e.DestroyConfigGroup(context.Background(), empire.DestroyConfigGroupOpts{
+		User:  user,
+		Group: group,
+	})
+	assert.NoError(t, err)
+}
+
+func TestEmpire_ConfigGroups_Authorization(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+
+	admin := &empire.User{Name: "ejholmes"}
+	bob := &empire.User{Name: "bob"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: admin,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	statsd := "statsd.internal:8125"
+	group, err := e.SetConfigGroup(context.Background(), empire.SetConfigGroupOpts{
+		User: admin,
+		Name: "shared",
+		Vars: empire.Vars{"STATSD_HOST": &statsd},
+	})
+	assert.NoError(t, err)
+
+	err = e.AttachConfigGroup(context.Background(), empire.AttachConfigGroupOpts{
+		User:  admin,
+		App:   app,
+		Group: group,
+	})
+	assert.NoError(t, err)
+
+	// Only ejholmes can change config on acme-inc, so only ejholmes can
+	// change the config groups that are attached to it.
+	for _, action := range []string{"access", "set"} {
+		_, err = e.Grant(context.Background(), empire.GrantOpts{
+			User:      admin,
+			App:       "acme-inc",
+			Principal: "user:ejholmes",
+			Action:    action,
+		})
+		assert.NoError(t, err)
+	}
+
+	statsd = "statsd.internal:9125"
+	_, err = e.SetConfigGroup(context.Background(), empire.SetConfigGroupOpts{
+		User: bob,
+		Name: "shared",
+		Vars: empire.Vars{"STATSD_HOST": &statsd},
+	})
+	assert.EqualError(t, err, "bob is not allowed to set acme-inc")
+
+	// Only ejholmes can set or destroy any config group.
+	for _, action := range []string{"access", "config_group"} {
+		_, err = e.Grant(context.Background(), empire.GrantOpts{
+			User:      admin,
+			App:       "*",
+			Principal: "user:ejholmes",
+			Action:    action,
+		})
+		assert.NoError(t, err)
+	}
+
+	_, err = e.SetConfigGroup(context.Background(), empire.SetConfigGroupOpts{
+		User: bob,
+		Name: "other",
+		Vars: empire.Vars{"STATSD_HOST": &statsd},
+	})
+	assert.EqualError(t, err, "bob is not allowed to config_group *")
+
+	err = e.DetachConfigGroup(context.Background(), empire.AttachConfigGroupOpts{
+		User:  admin,
+		App:   app,
+		Group: group,
+	})
+	assert.NoError(t, err)
+
+	err = e.DestroyConfigGroup(context.Background(), empire.DestroyConfigGroupOpts{
+		User:  bob,
+		Group: group,
+	})
+	assert.EqualError(t, err, "bob is not allowed to config_group *")
+
+	err = e.DestroyConfigGroup(context.Background(), empire.DestroyConfigGroupOpts{
+		User:  admin,
+		Group: group,
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,232 @@
+package empire_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/empiretest"
+	"github.com/remind101/empire/pkg/envelope"
+	"github.com/remind101/empire/pkg/image"
+	"github.com/remind101/empire/pkg/secrets"
+	"github.com/remind101/empire/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEmpire_Set_Encrypted(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+
+	p, err := envelope.NewLocalKeyProvider([]byte("0123456789abcdef0123456789abcdef"))
+	assert.NoError(t, err)
+	e.Encrypter = envelope.NewEncrypter(p)
+
+	user := &empire.User{Name: "ejholmes"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	s.On("Submit", mock.Anything).Return(nil)
+	_, err = e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  image.Image{Repository: "remind101/acme-inc", Tag: "v1"},
+	})
+	assert.NoError(t, err)
+
+	password := "hunter2"
+	c, err := e.Set(context.Background(), empire.SetOpts{
+		User: user,
+		App:  app,
+		Vars: empire.Vars{"DB_PASSWORD": &password},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", *c.Vars["DB_PASSWORD"])
+
+	// The value is encrypted in the database.
+	var stored empire.Vars
+	err = e.DB.DB.DB().QueryRow(`SELECT vars FROM configs WHERE id = $1`, c.ID).Scan(&stored)
+	assert.NoError(t, err)
+	assert.True(t, envelope.IsEncrypted(*stored["DB_PASSWORD"]))
+
+	c, err = e.Config(app)
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", *c.Vars["DB_PASSWORD"])
+
+	// Values that are unchanged between releases aren't shown in a diff,
+	// even though they're re-encrypted.
+	other := "bar"
+	_, err = e.Set(context.Background(), empire.SetOpts{
+		User: user,
+		App:  app,
+		Vars: empire.Vars{"FOO": &other},
+	})
+	assert.NoError(t, err)
+
+	d, err := e.ReleasesDiff(empire.ReleaseDiffOpts{App: app, From: 2, To: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(d.Config))
+	assert.Equal(t, empire.Variable("FOO"), d.Config[0].Name)
+
+	// The scheduler gets the plaintext values.
+	a := s.Calls[len(s.Calls)-1].Arguments.Get(0).(*scheduler.App)
+	assert.Equal(t, "v3", a.Release)
+	assert.Equal(t, "hunter2", a.Env["DB_PASSWORD"])
+}
+
+func TestEmpire_Set_SecretReference(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+
+	user := &empire.User{Name: "ejholmes"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	ref := "ssm:///prod/api/db_password"
+	_, err = e.Set(context.Background(), empire.SetOpts{
+		User: user,
+		App:  app,
+		Vars: empire.Vars{"DB_PASSWORD": &ref},
+	})
+	assert.EqualError(t, err, "value for DB_PASSWORD is a secret reference, but secret references aren't enabled")
+
+	e.SecretResolver = secrets.MemoryResolver{ref: "hunter2"}
+
+	c, err := e.Set(context.Background(), empire.SetOpts{
+		User: user,
+		App:  app,
+		Vars: empire.Vars{"DB_PASSWORD": &ref},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, ref, *c.Vars["DB_PASSWORD"])
+
+	s.On("Submit", mock.Anything).Return(nil)
+	_, err = e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  image.Image{Repository: "remind101/acme-inc", Tag: "v1"},
+	})
+	assert.NoError(t, err)
+
+	// The scheduler gets the value of the secret, but the config only has
+	// the reference.
+	a := s.Calls[len(s.Calls)-1].Arguments.Get(0).(*scheduler.App)
+	assert.Equal(t, "hunter2", a.Env["DB_PASSWORD"])
+
+	c, err = e.Config(app)
+	assert.NoError(t, err)
+	assert.Equal(t, ref, *c.Vars["DB_PASSWORD"])
+}
+
+func TestEmpire_ReadConfig(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+
+	admin := &empire.User{Name: "ejholmes"}
+	bob := &empire.User{Name: "bob"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: admin,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	url, env := "postgres://localhost:5432", "production"
+	_, err = e.Set(context.Background(), empire.SetOpts{
+		User: admin,
+		App:  app,
+		Vars: empire.Vars{"DATABASE_URL": &url, "RAILS_ENV": &env},
+	})
+	assert.NoError(t, err)
+
+	c, err := e.ReadConfig(context.Background(), empire.ReadConfigOpts{
+		User: bob,
+		App:  app,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "****5432", *c.Vars["DATABASE_URL"])
+	assert.Equal(t, "****", *c.Vars["RAILS_ENV"])
+
+	// Vars that aren't sensitive are shown in full.
+	vars, err := e.SetConfigSensitivity(context.Background(), empire.SetConfigSensitivityOpts{
+		User:        admin,
+		App:         app,
+		Sensitivity: map[empire.Variable]bool{"RAILS_ENV": false},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []empire.Variable{"RAILS_ENV"}, vars)
+
+	c, err = e.ReadConfig(context.Background(), empire.ReadConfigOpts{
+		User: bob,
+		App:  app,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "****5432", *c.Vars["DATABASE_URL"])
+	assert.Equal(t, "production", *c.Vars["RAILS_ENV"])
+
+	// Revealing config vars has to be granted.
+	_, err = e.ReadConfig(context.Background(), empire.ReadConfigOpts{
+		User:   admin,
+		App:    app,
+		Reveal: true,
+	})
+	assert.EqualError(t, err, "ejholmes is not allowed to reveal acme-inc: the reveal action has to be granted")
+
+	// Only ejholmes can reveal config vars on acme-inc.
+	_, err = e.Grant(context.Background(), empire.GrantOpts{
+		User:      admin,
+		App:       "acme-inc",
+		Principal: "user:ejholmes",
+		Action:    "access",
+	})
+	assert.NoError(t, err)
+
+	_, err = e.Grant(context.Background(), empire.GrantOpts{
+		User:      admin,
+		App:       "acme-inc",
+		Principal: "user:ejholmes",
+		Action:    "reveal",
+	})
+	assert.NoError(t, err)
+
+	_, err = e.ReadConfig(context.Background(), empire.ReadConfigOpts{
+		User:   bob,
+		App:    app,
+		Reveal: true,
+	})
+	assert.EqualError(t, err, "bob is not allowed to reveal acme-inc")
+
+	c, err = e.ReadConfig(context.Background(), empire.ReadConfigOpts{
+		User:   admin,
+		App:    app,
+		Reveal: true,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "postgres://localhost:5432", *c.Vars["DATABASE_URL"])
+
+	// Reads are recorded in the audit log.
+	typ := "config"
+	events, err := e.Events(empire.EventsQuery{App: app, Type: &typ})
+	assert.NoError(t, err)
+	if assert.Equal(t, 3, len(events)) {
+		var descriptions []string
+		for _, event := range events {
+			descriptions = append(descriptions, event.Description)
+		}
+		assert.Contains(t, descriptions, "bob viewed environment variables on acme-inc")
+		assert.Contains(t, descriptions, "ejholmes revealed environment variables on acme-inc")
+	}
+}
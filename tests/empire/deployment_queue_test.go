@@ -0,0 +1,174 @@
+package empire_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/empiretest"
+	"github.com/remind101/empire/pkg/image"
+	"github.com/remind101/empire/procfile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEmpire_QueueDeploy(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+	e.ProcfileExtractor = empiretest.ExtractProcfile(procfile.ExtendedProcfile{
+		"web": procfile.Process{
+			Command: []string{"./bin/web"},
+		},
+	})
+
+	user := &empire.User{Name: "ejholmes"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	s.On("Submit", mock.AnythingOfType("*scheduler.App")).Return(nil)
+
+	var deployments []*empire.Deployment
+	for _, tag := range []string{"v1", "v2"} {
+		d, err := e.QueueDeploy(context.Background(), empire.DeployOpts{
+			App:   app,
+			User:  user,
+			Image: image.Image{Repository: "remind101/acme-inc", Tag: tag},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "pending", d.Status)
+		deployments = append(deployments, d)
+	}
+
+	w := empire.NewDeploymentWorker(e)
+	w.PollInterval = 10 * time.Millisecond
+	go w.Start()
+	defer w.Stop()
+
+	// Deployments to the same app are performed in the order they were
+	// queued.
+	for i, d := range deployments {
+		for d.Status == "pending" || d.Status == "running" {
+			time.Sleep(10 * time.Millisecond)
+			d, err = e.DeploymentsFind(empire.DeploymentsQuery{ID: &d.ID, App: app})
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, "succeeded", d.Status)
+		assert.Nil(t, d.Error)
+		if assert.NotNil(t, d.ReleaseVersion) {
+			assert.Equal(t, i+1, *d.ReleaseVersion)
+		}
+		assert.Contains(t, d.Output, fmt.Sprintf("Status: Created new release v%d for acme-inc", i+1))
+	}
+
+	s.AssertExpectations(t)
+}
+
+func TestEmpire_QueueDeploy_Revoked(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+
+	user := &empire.User{Name: "ejholmes", Scopes: empire.Scopes{empire.ScopeDeploy}}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: &empire.User{Name: "ejholmes"},
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	d, err := e.QueueDeploy(context.Background(), empire.DeployOpts{
+		App:   app,
+		User:  user,
+		Image: image.Image{Repository: "remind101/acme-inc", Tag: "v1"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, empire.Scopes{empire.ScopeDeploy}, d.UserScopes)
+
+	// Access to deploy acme-inc is revoked while the deployment is
+	// queued.
+	for _, action := range []string{"access", "deploy"} {
+		_, err = e.Grant(context.Background(), empire.GrantOpts{
+			User:      &empire.User{Name: "ejholmes"},
+			App:       "acme-inc",
+			Principal: "user:bob",
+			Action:    action,
+		})
+		assert.NoError(t, err)
+	}
+
+	w := empire.NewDeploymentWorker(e)
+	w.PollInterval = 10 * time.Millisecond
+	go w.Start()
+	defer w.Stop()
+
+	for d.Status == "pending" || d.Status == "running" {
+		time.Sleep(10 * time.Millisecond)
+		d, err = e.DeploymentsFind(empire.DeploymentsQuery{ID: &d.ID, App: app})
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, "failed", d.Status)
+	assert.EqualError(t, d.Err(), "ejholmes is not allowed to deploy acme-inc")
+	assert.Nil(t, d.ReleaseVersion)
+
+	s.AssertExpectations(t)
+}
+
+func TestEmpire_QueueDeployAndWait(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+	e.ProcfileExtractor = empiretest.ExtractProcfile(procfile.ExtendedProcfile{
+		"web": procfile.Process{
+			Command: []string{"./bin/web"},
+		},
+	})
+
+	w := empire.NewDeploymentWorker(e)
+	w.PollInterval = 10 * time.Millisecond
+	go w.Start()
+	defer w.Stop()
+
+	s.On("Submit", mock.AnythingOfType("*scheduler.App")).Return(nil)
+
+	var b bytes.Buffer
+	r, err := e.QueueDeployAndWait(context.Background(), empire.DeployOpts{
+		User:   &empire.User{Name: "ejholmes"},
+		Output: empire.NewDeploymentStream(&b),
+		Image:  image.Image{Repository: "remind101/acme-inc", Tag: "latest"},
+	})
+	assert.NoError(t, err)
+	if assert.NotNil(t, r) {
+		assert.Equal(t, 1, r.Version)
+		assert.Equal(t, "acme-inc", r.App.Name)
+	}
+	assert.Contains(t, b.String(), "Status: Created new release v1 for acme-inc")
+
+	// A failed deployment returns its error.
+	e.ProcfileExtractor = empire.ProcfileExtractorFunc(func(ctx context.Context, img image.Image, w io.Writer) ([]byte, error) {
+		return nil, errors.New("image not found")
+	})
+
+	b.Reset()
+	_, err = e.QueueDeployAndWait(context.Background(), empire.DeployOpts{
+		User:   &empire.User{Name: "ejholmes"},
+		Output: empire.NewDeploymentStream(&b),
+		Image:  image.Image{Repository: "remind101/acme-inc", Tag: "v2"},
+	})
+	assert.EqualError(t, err, "image not found")
+	assert.Contains(t, b.String(), "image not found")
+
+	s.AssertExpectations(t)
+}
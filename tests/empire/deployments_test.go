@@ -0,0 +1,171 @@
+package empire_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/empiretest"
+	"github.com/remind101/empire/pkg/image"
+	"github.com/remind101/empire/procfile"
+	"github.com/remind101/empire/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEmpire_Deploy_ReleasePhase(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+	e.ProcfileExtractor = empiretest.ExtractProcfile(procfile.StandardProcfile{
+		"release": "./bin/migrate",
+	})
+
+	user := &empire.User{Name: "ejholmes"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	img := image.Image{Repository: "remind101/acme-inc"}
+	s.On("Run", &scheduler.App{
+		ID:      app.ID,
+		Name:    "acme-inc",
+		Release: "v1",
+		Env: map[string]string{
+			"EMPIRE_APPID":   app.ID,
+			"EMPIRE_APPNAME": "acme-inc",
+			"EMPIRE_RELEASE": "v1",
+		},
+		Labels: map[string]string{
+			"empire.app.name":    "acme-inc",
+			"empire.app.id":      app.ID,
+			"empire.app.release": "v1",
+		},
+	}, &scheduler.Process{
+		Type:        "release",
+		Image:       img,
+		Command:     []string{"./bin/migrate"},
+		Instances:   1,
+		MemoryLimit: 536870912,
+		CPUShares:   256,
+		Nproc:       256,
+		Env: map[string]string{
+			"EMPIRE_PROCESS":       "release",
+			"EMPIRE_PROCESS_SCALE": "1",
+			"SOURCE":               "acme-inc.release.v1",
+		},
+		Labels: map[string]string{
+			"empire.app.process": "release",
+			"empire.user":        "ejholmes",
+		},
+	}, nil, mock.Anything).Return(&scheduler.ExitError{Code: 1}).Once()
+
+	_, err = e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  img,
+	})
+	assert.EqualError(t, err, "release phase failed: process exited with status 1")
+
+	// The release should have been discarded.
+	releases, err := e.Releases(empire.ReleasesQuery{App: app})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(releases))
+
+	s.On("Run", mock.Anything, mock.Anything, nil, mock.Anything).Return(nil)
+	s.On("Submit", mock.Anything).Return(nil)
+
+	r, err := e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  img,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, r.Version)
+
+	s.AssertExpectations(t)
+}
+
+func TestEmpire_Deploy_ReleasePhase_NewApp(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+	e.ProcfileExtractor = empiretest.ExtractProcfile(procfile.StandardProcfile{
+		"release": "./bin/migrate",
+	})
+
+	s.On("Run", mock.Anything, mock.Anything, nil, mock.Anything).Return(&scheduler.ExitError{Code: 1}).Once()
+
+	_, err := e.Deploy(context.Background(), empire.DeployOpts{
+		User:   &empire.User{Name: "ejholmes"},
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  image.Image{Repository: "remind101/acme-inc"},
+	})
+	assert.EqualError(t, err, "release phase failed: process exited with status 1")
+
+	// The app shouldn't have been created.
+	apps, err := e.Apps(empire.AppsQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(apps))
+
+	s.AssertExpectations(t)
+}
+
+func TestEmpire_Deploy_AutoRollback(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+	e.AutoRollback = true
+
+	user := &empire.User{Name: "ejholmes"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	s.On("Submit", mock.Anything).Return(nil).Once()
+	_, err = e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  image.Image{Repository: "remind101/acme-inc", Tag: "v1"},
+		Stream: true,
+	})
+	assert.NoError(t, err)
+
+	// The second release fails to stabilize, then the rollback to v1 is
+	// submitted.
+	s.On("Submit", mock.Anything).Return(&scheduler.UnstableError{Reason: "web failed"}).Once()
+	s.On("Submit", mock.Anything).Return(nil).Once()
+	_, err = e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  image.Image{Repository: "remind101/acme-inc", Tag: "v2"},
+		Stream: true,
+	})
+	assert.IsType(t, &scheduler.UnstableError{}, err)
+
+	releases, err := e.Releases(empire.ReleasesQuery{App: app})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(releases))
+	assert.Equal(t, "Rollback to v1 (ejholmes: 'v2 failed to stabilize')", releases[0].Description)
+	assert.False(t, releases[0].Failed)
+	assert.True(t, releases[1].Failed)
+	assert.False(t, releases[2].Failed)
+
+	events, err := e.Events(empire.EventsQuery{App: app})
+	assert.NoError(t, err)
+	assert.Equal(t, "rollback", events[0].Type)
+
+	s.AssertExpectations(t)
+}
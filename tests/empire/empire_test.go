@@ -672,8 +672,27 @@ func (m *mockScheduler) Submit(_ context.Context, app *scheduler.App, ss schedul
 	return args.Error(0)
 }
 
+func (m *mockScheduler) RunsAttached() bool {
+	return true
+}
+
+func (m *mockScheduler) SubmitCanary(_ context.Context, app *scheduler.App, ss scheduler.StatusStream) error {
+	args := m.Called(app)
+	return args.Error(0)
+}
+
+func (m *mockScheduler) CanaryErrorRate(_ context.Context, appID string, since time.Time) (float64, error) {
+	args := m.Called(appID)
+	return args.Get(0).(float64), args.Error(1)
+}
+
 func (m *mockScheduler) Run(_ context.Context, app *scheduler.App, process *scheduler.Process, in io.Reader, out io.Writer) error {
 	app.Processes = nil // This is bogus and doesn't actually matter for Runs.
 	args := m.Called(app, process, in, out)
 	return args.Error(0)
 }
+
+func (m *mockScheduler) Remove(_ context.Context, appID string) error {
+	args := m.Called(appID)
+	return args.Error(0)
+}
@@ -0,0 +1,63 @@
+package empire_test
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/empiretest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmpire_Events(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+
+	user := &empire.User{Name: "ejholmes"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	url := "postgres://localhost"
+	_, err = e.Set(context.Background(), empire.SetOpts{
+		User: &empire.User{Name: "bob"},
+		App:  app,
+		Vars: empire.Vars{
+			"DATABASE_URL": &url,
+		},
+	})
+	assert.NoError(t, err)
+
+	events, err := e.Events(empire.EventsQuery{App: app})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(events))
+
+	typ := "set"
+	events, err = e.Events(empire.EventsQuery{App: app, Type: &typ})
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(events)) {
+		assert.Equal(t, "bob", events[0].User)
+		assert.Equal(t, app.ID, *events[0].AppID)
+		assert.Equal(t, "bob changed environment variables on acme-inc (DATABASE_URL)", events[0].Description)
+	}
+
+	name := "ejholmes"
+	events, err = e.Events(empire.EventsQuery{User: &name})
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(events)) {
+		assert.Equal(t, "create", events[0].Type)
+	}
+
+	since := fakeNow.Add(time.Hour)
+	events, err = e.Events(empire.EventsQuery{Since: &since})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(events))
+
+	s.AssertExpectations(t)
+}
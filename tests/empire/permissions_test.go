@@ -0,0 +1,178 @@
+package empire_test
+
+import (
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/empiretest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmpire_Permissions(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+
+	admin := &empire.User{Name: "ejholmes"}
+	bob := &empire.User{Name: "bob"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: admin,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	// Only ejholmes can manage permissions or change config on acme-inc.
+	_, err = e.Grant(context.Background(), empire.GrantOpts{
+		User:      admin,
+		App:       "acme-inc",
+		Principal: "user:ejholmes",
+		Action:    "access",
+	})
+	assert.NoError(t, err)
+
+	p, err := e.Grant(context.Background(), empire.GrantOpts{
+		User:      admin,
+		App:       "acme-inc",
+		Principal: "user:ejholmes",
+		Action:    "set",
+	})
+	assert.NoError(t, err)
+
+	_, err = e.Grant(context.Background(), empire.GrantOpts{
+		User:      admin,
+		App:       "acme-inc",
+		Principal: "user:ejholmes",
+		Action:    "set",
+	})
+	assert.Equal(t, empire.ErrPermissionExists, err)
+
+	_, err = e.Grant(context.Background(), empire.GrantOpts{
+		User:      bob,
+		App:       "acme-inc",
+		Principal: "user:bob",
+		Action:    "set",
+	})
+	assert.EqualError(t, err, "bob is not allowed to access acme-inc")
+
+	url := "postgres://localhost"
+	_, err = e.Set(context.Background(), empire.SetOpts{
+		User: bob,
+		App:  app,
+		Vars: empire.Vars{
+			"DATABASE_URL": &url,
+		},
+	})
+	assert.IsType(t, &empire.AccessDeniedError{}, err)
+	assert.EqualError(t, err, "bob is not allowed to set acme-inc")
+
+	_, err = e.Set(context.Background(), empire.SetOpts{
+		User: admin,
+		App:  app,
+		Vars: empire.Vars{
+			"DATABASE_URL": &url,
+		},
+	})
+	assert.NoError(t, err)
+
+	// Actions without any permissions are still allowed.
+	_, err = e.Rename(context.Background(), empire.RenameOpts{
+		User: bob,
+		App:  app,
+		Name: "acme-corp",
+	})
+	assert.NoError(t, err)
+
+	// Permissions follow the app when it's renamed.
+	ps, err := e.Permissions(empire.PermissionsQuery{Apps: []string{"acme-corp"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(ps))
+
+	p, err = e.PermissionsFind(empire.PermissionsQuery{ID: &p.ID})
+	assert.NoError(t, err)
+	err = e.Revoke(context.Background(), empire.RevokeOpts{
+		User:       admin,
+		Permission: p,
+	})
+	assert.NoError(t, err)
+
+	_, err = e.Set(context.Background(), empire.SetOpts{
+		User: bob,
+		App:  app,
+		Vars: empire.Vars{
+			"DATABASE_URL": &url,
+		},
+	})
+	assert.NoError(t, err)
+
+	s.AssertExpectations(t)
+}
+
+func TestEmpire_Permissions_AccessRequired(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+
+	admin := &empire.User{Name: "ejholmes"}
+	bob := &empire.User{Name: "bob"}
+
+	_, err := e.Create(context.Background(), empire.CreateOpts{
+		User: admin,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	// Restricting set without restricting access would let anyone grant
+	// themselves set.
+	_, err = e.Grant(context.Background(), empire.GrantOpts{
+		User:      admin,
+		App:       "acme-inc",
+		Principal: "user:ejholmes",
+		Action:    "set",
+	})
+	assert.Equal(t, empire.ErrAccessUnrestricted, err)
+
+	access, err := e.Grant(context.Background(), empire.GrantOpts{
+		User:      admin,
+		App:       "acme-inc",
+		Principal: "user:ejholmes",
+		Action:    "access",
+	})
+	assert.NoError(t, err)
+
+	_, err = e.Grant(context.Background(), empire.GrantOpts{
+		User:      admin,
+		App:       "acme-inc",
+		Principal: "user:ejholmes",
+		Action:    "set",
+	})
+	assert.NoError(t, err)
+
+	// bob has no grants, so can't grant himself set.
+	_, err = e.Grant(context.Background(), empire.GrantOpts{
+		User:      bob,
+		App:       "acme-inc",
+		Principal: "user:bob",
+		Action:    "set",
+	})
+	assert.IsType(t, &empire.AccessDeniedError{}, err)
+
+	_, err = e.Grant(context.Background(), empire.GrantOpts{
+		User:      bob,
+		App:       "acme-inc",
+		Principal: "user:bob",
+		Action:    "access",
+	})
+	assert.IsType(t, &empire.AccessDeniedError{}, err)
+
+	// The access permission can't be revoked while set is restricted.
+	err = e.Revoke(context.Background(), empire.RevokeOpts{
+		User:       admin,
+		Permission: access,
+	})
+	assert.Equal(t, empire.ErrAccessRequired, err)
+
+	ps, err := e.Permissions(empire.PermissionsQuery{Apps: []string{"acme-inc"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(ps))
+}
@@ -0,0 +1,143 @@
+package empire_test
+
+import (
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/empiretest"
+	"github.com/remind101/empire/pkg/image"
+	"github.com/remind101/empire/procfile"
+	"github.com/remind101/empire/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEmpire_Promote(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+
+	var extracted int
+	extractor := empiretest.ExtractProcfile(procfile.ExtendedProcfile{
+		"web": procfile.Process{
+			Command: []string{"./bin/web"},
+		},
+	})
+	e.ProcfileExtractor = empire.ProcfileExtractorFunc(func(ctx context.Context, img image.Image, w io.Writer) ([]byte, error) {
+		extracted++
+		return extractor.Extract(ctx, img, w)
+	})
+
+	user := &empire.User{Name: "ejholmes"}
+
+	var apps []*empire.App
+	for _, name := range []string{"acme-inc-staging", "acme-inc"} {
+		app, err := e.Create(context.Background(), empire.CreateOpts{
+			User: user,
+			Name: name,
+		})
+		assert.NoError(t, err)
+
+		env := "staging"
+		if name == "acme-inc" {
+			env = "production"
+		}
+		_, err = e.Set(context.Background(), empire.SetOpts{
+			User: user,
+			App:  app,
+			Vars: empire.Vars{"RAILS_ENV": &env},
+		})
+		assert.NoError(t, err)
+
+		apps = append(apps, app)
+	}
+	staging, prod := apps[0], apps[1]
+
+	s.On("Submit", mock.AnythingOfType("*scheduler.App")).Return(nil)
+
+	img := image.Image{Repository: "remind101/acme-inc", Tag: "master"}
+	_, err := e.Deploy(context.Background(), empire.DeployOpts{
+		App:    staging,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  img,
+	})
+	assert.NoError(t, err)
+
+	// Apps need to be in a pipeline to be promoted.
+	_, err = e.Promote(context.Background(), empire.PromoteOpts{
+		User:   user,
+		App:    staging,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+	})
+	assert.EqualError(t, err, "acme-inc-staging isn't in a pipeline")
+
+	pipeline, err := e.SetPipeline(context.Background(), empire.SetPipelineOpts{
+		User: user,
+		Name: "acme-inc",
+		Apps: []*empire.App{staging, prod},
+	})
+	assert.NoError(t, err)
+
+	r, err := e.Promote(context.Background(), empire.PromoteOpts{
+		User:   user,
+		App:    staging,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, prod.ID, r.App.ID)
+	assert.Equal(t, 1, r.Version)
+	assert.Equal(t, "Promote acme-inc-staging v1 (remind101/acme-inc:master) (ejholmes)", r.Description)
+
+	// The image isn't extracted again, and the new release uses the
+	// config of the next app.
+	assert.Equal(t, 1, extracted)
+	a := s.Calls[len(s.Calls)-1].Arguments.Get(0).(*scheduler.App)
+	assert.Equal(t, "acme-inc", a.Name)
+	assert.Equal(t, "production", a.Env["RAILS_ENV"])
+	assert.Equal(t, img, a.Processes[0].Image)
+
+	// The last app in the pipeline can't be promoted.
+	_, err = e.Promote(context.Background(), empire.PromoteOpts{
+		User:   user,
+		App:    prod,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+	})
+	assert.EqualError(t, err, "acme-inc is the last app in pipeline acme-inc")
+
+	typ := "promote"
+	events, err := e.Events(empire.EventsQuery{App: prod, Type: &typ})
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(events)) {
+		assert.Equal(t, "ejholmes promoted acme-inc-staging v1 (remind101/acme-inc:master) to acme-inc (v1)", events[0].Description)
+	}
+
+	// Destroying the pipeline requires access to deploy every app in it.
+	for _, action := range []string{"access", "deploy"} {
+		_, err = e.Grant(context.Background(), empire.GrantOpts{
+			User:      user,
+			App:       "acme-inc",
+			Principal: "user:ejholmes",
+			Action:    action,
+		})
+		assert.NoError(t, err)
+	}
+
+	err = e.DestroyPipeline(context.Background(), empire.DestroyPipelineOpts{
+		User:     &empire.User{Name: "bob"},
+		Pipeline: pipeline,
+	})
+	assert.EqualError(t, err, "bob is not allowed to deploy acme-inc")
+
+	err = e.DestroyPipeline(context.Background(), empire.DestroyPipelineOpts{
+		User:     user,
+		Pipeline: pipeline,
+	})
+	assert.NoError(t, err)
+
+	s.AssertExpectations(t)
+}
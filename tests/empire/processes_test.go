@@ -0,0 +1,93 @@
+package empire_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/empiretest"
+	"github.com/remind101/empire/pkg/image"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEmpire_Autoscale(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+
+	user := &empire.User{Name: "ejholmes"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	s.On("Submit", mock.Anything).Return(nil)
+	_, err = e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  image.Image{Repository: "remind101/acme-inc", Tag: "v1"},
+	})
+	assert.NoError(t, err)
+
+	// Enabling autoscaling scales the process up to the minimum.
+	p, err := e.Autoscale(context.Background(), empire.AutoscaleOpts{
+		User:        user,
+		App:         app,
+		Process:     "web",
+		Autoscaling: &empire.Autoscaling{Min: 2, Max: 10, Metric: "cpu", Target: 50},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, p.Quantity)
+
+	// Manual scaling has to be within the bounds.
+	_, err = e.Scale(context.Background(), empire.ScaleOpts{
+		User:    user,
+		App:     app,
+		Updates: []*empire.ProcessUpdate{{Process: "web", Quantity: 20}},
+	})
+	assert.EqualError(t, err, "web is autoscaled, and can only be scaled between 2 and 10")
+
+	_, err = e.Scale(context.Background(), empire.ScaleOpts{
+		User:    user,
+		App:     app,
+		Updates: []*empire.ProcessUpdate{{Process: "web", Quantity: 5}},
+	})
+	assert.NoError(t, err)
+
+	// Autoscaling settings are kept across deployments.
+	_, err = e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  image.Image{Repository: "remind101/acme-inc", Tag: "v2"},
+	})
+	assert.NoError(t, err)
+
+	formation, err := e.ListScale(context.Background(), app)
+	assert.NoError(t, err)
+	assert.Equal(t, &empire.Autoscaling{Min: 2, Max: 10, Metric: "cpu", Target: 50}, formation["web"].Autoscaling)
+	assert.Equal(t, 5, formation["web"].Quantity)
+
+	// Disabling autoscaling keeps the current quantity.
+	p, err = e.Autoscale(context.Background(), empire.AutoscaleOpts{
+		User:    user,
+		App:     app,
+		Process: "web",
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, p.Autoscaling)
+	assert.Equal(t, 5, p.Quantity)
+
+	events, err := e.Events(empire.EventsQuery{App: app})
+	assert.NoError(t, err)
+	assert.Equal(t, "autoscale", events[0].Type)
+	assert.Equal(t, "ejholmes disabled autoscaling for `web` on acme-inc", events[0].Description)
+
+	s.AssertExpectations(t)
+}
@@ -0,0 +1,160 @@
+package empire_test
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/jinzhu/gorm"
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/empiretest"
+	"github.com/remind101/empire/pkg/image"
+	"github.com/remind101/empire/procfile"
+	"github.com/remind101/empire/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEmpire_DeployReviewApp(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+	e.MaxReviewApps = 1
+	e.ProcfileExtractor = empiretest.ExtractProcfile(procfile.ExtendedProcfile{
+		"web": procfile.Process{
+			Command: []string{"./bin/web"},
+		},
+	})
+
+	user := &empire.User{Name: "ejholmes"}
+
+	tmpl, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	env := "review"
+	_, err = e.Set(context.Background(), empire.SetOpts{
+		User: user,
+		App:  tmpl,
+		Vars: empire.Vars{"RAILS_ENV": &env},
+	})
+	assert.NoError(t, err)
+
+	s.On("Submit", mock.AnythingOfType("*scheduler.App")).Return(nil)
+
+	img := image.Image{Repository: "remind101/acme-inc", Tag: "abcd123"}
+	r, err := e.DeployReviewApp(context.Background(), empire.DeployReviewAppOpts{
+		User:        user,
+		Template:    tmpl,
+		Repo:        "remind101/acme-inc",
+		PullRequest: 42,
+		Image:       img,
+		Output:      empire.NewDeploymentStream(ioutil.Discard),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme-inc-pr-42", r.App.Name)
+	assert.Equal(t, 1, r.Version)
+
+	// The review app gets a copy of the template's config.
+	a := s.Calls[len(s.Calls)-1].Arguments.Get(0).(*scheduler.App)
+	assert.Equal(t, "acme-inc-pr-42", a.Name)
+	assert.Equal(t, "review", a.Env["RAILS_ENV"])
+
+	// Deploying again releases the existing review app.
+	r, err = e.DeployReviewApp(context.Background(), empire.DeployReviewAppOpts{
+		User:        user,
+		Template:    tmpl,
+		Repo:        "remind101/acme-inc",
+		PullRequest: 42,
+		Image:       img,
+		Output:      empire.NewDeploymentStream(ioutil.Discard),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, r.Version)
+
+	// Only one review app can exist.
+	_, err = e.DeployReviewApp(context.Background(), empire.DeployReviewAppOpts{
+		User:        user,
+		Template:    tmpl,
+		Repo:        "remind101/acme-inc",
+		PullRequest: 43,
+		Image:       img,
+		Output:      empire.NewDeploymentStream(ioutil.Discard),
+	})
+	assert.Equal(t, empire.ErrTooManyReviewApps, err)
+
+	repo, number := "remind101/acme-inc", 42
+	ra, err := e.ReviewAppsFind(empire.ReviewAppsQuery{Repo: &repo, PullRequest: &number})
+	assert.NoError(t, err)
+	assert.Equal(t, r.App.ID, ra.AppID)
+
+	s.On("Remove", r.App.ID).Return(nil)
+
+	// Review apps that haven't been deployed to within the ttl are
+	// destroyed.
+	err = e.DestroyExpiredReviewApps(context.Background(), -time.Minute)
+	assert.NoError(t, err)
+
+	_, err = e.ReviewAppsFind(empire.ReviewAppsQuery{Repo: &repo, PullRequest: &number})
+	assert.Equal(t, gorm.RecordNotFound, err)
+
+	s.AssertExpectations(t)
+}
+
+func TestEmpire_DeployReviewApp_Concurrent(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+	e.MaxReviewApps = 1
+	e.ProcfileExtractor = empiretest.ExtractProcfile(procfile.ExtendedProcfile{
+		"web": procfile.Process{
+			Command: []string{"./bin/web"},
+		},
+	})
+
+	user := &empire.User{Name: "ejholmes"}
+
+	tmpl, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	s.On("Submit", mock.AnythingOfType("*scheduler.App")).Return(nil)
+
+	// Pull requests that are opened at the same time can't exceed the
+	// maximum number of review apps.
+	errs := make(chan error, 5)
+	for i := 0; i < cap(errs); i++ {
+		go func(pr int) {
+			_, err := e.DeployReviewApp(context.Background(), empire.DeployReviewAppOpts{
+				User:        user,
+				Template:    tmpl,
+				Repo:        "remind101/acme-inc",
+				PullRequest: pr,
+				Image:       image.Image{Repository: "remind101/acme-inc", Tag: "abcd123"},
+				Output:      empire.NewDeploymentStream(ioutil.Discard),
+			})
+			errs <- err
+		}(i + 1)
+	}
+
+	var created int
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err == nil {
+			created++
+		} else {
+			assert.Equal(t, empire.ErrTooManyReviewApps, err)
+		}
+	}
+	assert.Equal(t, 1, created)
+
+	// The template, and one review app.
+	apps, err := e.Apps(empire.AppsQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(apps))
+}
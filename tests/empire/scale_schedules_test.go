@@ -0,0 +1,100 @@
+package empire_test
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/remind101/empire"
+	"github.com/remind101/empire/empiretest"
+	"github.com/remind101/empire/pkg/image"
+	"github.com/remind101/pkg/timex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEmpire_ScaleSchedules(t *testing.T) {
+	e := empiretest.NewEmpire(t)
+	s := new(mockScheduler)
+	e.Scheduler = s
+
+	user := &empire.User{Name: "ejholmes"}
+
+	app, err := e.Create(context.Background(), empire.CreateOpts{
+		User: user,
+		Name: "acme-inc",
+	})
+	assert.NoError(t, err)
+
+	s.On("Submit", mock.Anything).Return(nil)
+	_, err = e.Deploy(context.Background(), empire.DeployOpts{
+		App:    app,
+		User:   user,
+		Output: empire.NewDeploymentStream(ioutil.Discard),
+		Image:  image.Image{Repository: "remind101/acme-inc", Tag: "v1"},
+	})
+	assert.NoError(t, err)
+
+	_, err = e.ScaleScheduleCreate(context.Background(), empire.CreateScaleScheduleOpts{
+		User:     user,
+		App:      app,
+		Process:  "foo",
+		Cron:     "0 20 * * *",
+		Quantity: 20,
+	})
+	assert.EqualError(t, err, "no foo process type in release")
+
+	schedule, err := e.ScaleScheduleCreate(context.Background(), empire.CreateScaleScheduleOpts{
+		User:     user,
+		App:      app,
+		Process:  "worker",
+		Cron:     "0 20 * * *",
+		Quantity: 20,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2015, time.January, 1, 20, 0, 0, 0, time.UTC), schedule.NextRunAt)
+
+	// Nothing is due yet.
+	assert.NoError(t, e.RunScaleSchedules(context.Background()))
+	formation, err := e.ListScale(context.Background(), app)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, formation["worker"].Quantity)
+
+	timex.Now = func() time.Time {
+		return time.Date(2015, time.January, 1, 20, 0, 30, 0, time.UTC)
+	}
+	defer func() {
+		timex.Now = func() time.Time {
+			return fakeNow
+		}
+	}()
+
+	assert.NoError(t, e.RunScaleSchedules(context.Background()))
+	formation, err = e.ListScale(context.Background(), app)
+	assert.NoError(t, err)
+	assert.Equal(t, 20, formation["worker"].Quantity)
+
+	schedules, err := e.ScaleSchedules(empire.ScaleSchedulesQuery{App: app})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(schedules))
+	assert.True(t, schedules[0].NextRunAt.Equal(time.Date(2015, time.January, 2, 20, 0, 0, 0, time.UTC)))
+
+	events, err := e.Events(empire.EventsQuery{App: app})
+	assert.NoError(t, err)
+	assert.Equal(t, "scale", events[0].Type)
+	assert.Contains(t, events[0].Description, "`worker` on acme-inc was automatically scaled from 0")
+
+	err = e.ScaleScheduleDestroy(context.Background(), empire.DestroyScaleScheduleOpts{
+		User:     user,
+		Schedule: schedules[0],
+	})
+	assert.NoError(t, err)
+
+	schedules, err = e.ScaleSchedules(empire.ScaleSchedulesQuery{App: app})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(schedules))
+
+	s.AssertExpectations(t)
+}
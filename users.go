@@ -7,6 +7,10 @@ type User struct {
 
 	// GitHubToken is a GitHub access token.
 	GitHubToken string `json:"-"`
+
+	// If the user authenticated with an access token, the scopes that the
+	// token was granted. Nil means that the user is unrestricted.
+	Scopes Scopes `json:"-"`
 }
 
 // IsValid returns nil if the User is valid.